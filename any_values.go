@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/ttrtcixy/fast-slog-handler/internal/escape"
+)
+
+// anyTime returns the time of an Any value, slog.AnyValue converts only time.Time values to KindTime.
+func anyTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t != nil {
+			return *t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// anySource returns the source of an Any value, e.g. a "source" attr of a record forwarded from another handler.
+func anySource(v any) (*slog.Source, bool) {
+	switch s := v.(type) {
+	case slog.Source:
+		return &s, true
+	case *slog.Source:
+		return s, s != nil
+	}
+	return nil, false
+}
+
+// appendSourceJSON appends the source as the object written by slog.JSONHandler.
+func appendSourceJSON(buf []byte, src *slog.Source) []byte {
+	buf = append(buf, `{"function":"`...)
+	buf = escape.AppendJSON(buf, src.Function)
+	buf = append(buf, `","file":"`...)
+	buf = escape.AppendJSON(buf, src.File)
+	buf = append(buf, `","line":`...)
+	buf = strconv.AppendInt(buf, int64(src.Line), 10)
+	return append(buf, '}')
+}
+
+// appendSourceLine appends the source as "file:line".
+func appendSourceLine(buf []byte, src *slog.Source) []byte {
+	buf = append(buf, src.File...)
+	buf = append(buf, ':')
+	return strconv.AppendInt(buf, int64(src.Line), 10)
+}
@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"sync"
+	"unsafe"
+)
+
+const (
+	// size of the slabs precomputed attrs are carved from.
+	arenaSlabSize = 4096
+	// larger precomputed attrs get their own allocation, so a slab isn't wasted on one of them.
+	maxArenaString = arenaSlabSize / 4
+)
+
+// arenaPool holds partly used slabs, a slab is never written below its length again,
+// so the strings carved from it stay immutable while handlers reference them.
+var arenaPool = sync.Pool{
+	New: func() any {
+		slab := make([]byte, 0, arenaSlabSize)
+		return &slab
+	},
+}
+
+// arenaString copies b into a shared slab and returns it as a string, WithAttrs calls then cost
+// no allocation for the precomputed attrs except for a new slab every few kilobytes.
+// A slab stays in memory as long as any handler using one of its strings.
+func arenaString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	if len(b) > maxArenaString {
+		return string(b)
+	}
+
+	pSlab := arenaPool.Get().(*[]byte)
+	slab := *pSlab
+
+	if cap(slab)-len(slab) < len(b) {
+		slab = make([]byte, 0, arenaSlabSize)
+	}
+
+	start := len(slab)
+	slab = append(slab, b...)
+	s := unsafe.String(&slab[start], len(b))
+
+	*pSlab = slab
+	arenaPool.Put(pSlab)
+
+	return s
+}
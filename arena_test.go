@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestArenaStringsStayIntact(t *testing.T) {
+	var buf bytes.Buffer
+	base := NewJsonHandler(&buf, nil)
+
+	// Enough handlers to fill several slabs from concurrent goroutines.
+	handlers := make([]slog.Handler, 2000)
+	var wg sync.WaitGroup
+	for g := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := g; i < len(handlers); i += 4 {
+				handlers[i] = base.WithAttrs([]slog.Attr{slog.Int("id", i), slog.String("pad", strings.Repeat("x", 1+i%50))})
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, h := range handlers {
+		buf.Reset()
+		slog.New(h).Info("m")
+		if want := fmt.Sprintf(`"id":%d,"pad":"%s"}`, i, strings.Repeat("x", 1+i%50)); !strings.Contains(buf.String(), want) {
+			t.Fatalf("handler %d: %q doesn't contain %q", i, buf.String(), want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultAsyncQueueSize = 1024
+
+// CloneRecord returns a copy of the record that shares no mutable state with the caller:
+// LogValuer values are resolved now, group attrs and []byte values are copied.
+// Use it before handing a record to another goroutine, slog.Record.Clone copies only the top level attrs,
+// group values still point to the caller's slices.
+func CloneRecord(r slog.Record) slog.Record {
+	c := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, cloneAttr(attr))
+		return true
+	})
+	c.AddAttrs(attrs...)
+
+	return c
+}
+
+func cloneAttr(attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		cloned := make([]slog.Attr, len(group))
+		for i, a := range group {
+			cloned[i] = cloneAttr(a)
+		}
+		attr.Value = slog.GroupValue(cloned...)
+	case slog.KindAny:
+		if b, ok := attr.Value.Any().([]byte); ok {
+			attr.Value = slog.AnyValue(slices.Clone(b))
+		}
+	}
+
+	return attr
+}
+
+type asyncItem struct {
+	handler slog.Handler
+	ctx     context.Context
+	record  slog.Record
+}
+
+// batchFeeder is a handler taking the queued records at once, see BatchEncoder.
+type batchFeeder interface {
+	// feeds reports whether the records of h can be passed to handleQueued of the feeder.
+	feeds(h slog.Handler) bool
+	handleQueued(items []asyncItem)
+}
+
+// asyncQueue is shared by an AsyncHandler and its clones.
+type asyncQueue struct {
+	items chan asyncItem
+	// wg waits for the worker on Close.
+	wg      sync.WaitGroup
+	closed  atomic.Bool
+	closeMu sync.RWMutex
+
+	dropped atomic.Uint64
+}
+
+// AsyncHandler moves the work of the wrapped handler to a background goroutine.
+// Records are cloned with CloneRecord before they are queued, so callers may reuse their attrs right away.
+// When the queue is full the record is dropped and counted, logging never blocks the caller.
+type AsyncHandler struct {
+	handler slog.Handler
+	queue   *asyncQueue
+}
+
+// NewAsyncHandler starts the worker writing records to h, queueSize is the count of queued records, 0 - 1024.
+// Close must be called to write the queued records.
+func NewAsyncHandler(h slog.Handler, queueSize int) *AsyncHandler {
+	if queueSize <= 0 {
+		queueSize = defaultAsyncQueueSize
+	}
+
+	q := &asyncQueue{items: make(chan asyncItem, queueSize)}
+
+	q.wg.Add(1)
+	go q.run()
+
+	return &AsyncHandler{handler: h, queue: q}
+}
+
+func (q *asyncQueue) run() {
+	defer q.wg.Done()
+
+	var batch []asyncItem
+	for item := range q.items {
+		for {
+			feeder, ok := item.handler.(batchFeeder)
+			if !ok {
+				_ = item.handler.Handle(item.ctx, item.record)
+				break
+			}
+
+			batch = append(batch[:0], item)
+			next, more := q.drain(feeder, &batch)
+			feeder.handleQueued(batch)
+			// The queued records must not be kept until the next batch.
+			clear(batch)
+
+			if !more {
+				break
+			}
+			item = next
+		}
+	}
+}
+
+// drain appends the items queued for the feeder to batch without waiting for new ones,
+// next is the first queued item of another handler.
+func (q *asyncQueue) drain(feeder batchFeeder, batch *[]asyncItem) (next asyncItem, ok bool) {
+	for len(*batch) < cap(q.items) {
+		select {
+		case item, open := <-q.items:
+			if !open {
+				return asyncItem{}, false
+			}
+			if !feeder.feeds(item.handler) {
+				return item, true
+			}
+			*batch = append(*batch, item)
+		default:
+			return asyncItem{}, false
+		}
+	}
+	return asyncItem{}, false
+}
+
+func (a *AsyncHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return !a.queue.closed.Load() && a.handler.Enabled(ctx, level)
+}
+
+func (a *AsyncHandler) Handle(ctx context.Context, record slog.Record) error {
+	q := a.queue
+
+	q.closeMu.RLock()
+	defer q.closeMu.RUnlock()
+
+	// Logger.LogE waits for the result of the write.
+	persist := mustPersist(ctx)
+
+	if q.closed.Load() {
+		if persist {
+			return ErrAlreadyClosed
+		}
+		return nil
+	}
+
+	if persist {
+		return a.handler.Handle(ctx, record)
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	item := asyncItem{
+		handler: a.handler,
+		// The record is written after the caller returned, its cancellation must not affect it.
+		ctx:    context.WithoutCancel(ctx),
+		record: CloneRecord(record),
+	}
+
+	select {
+	case q.items <- item:
+		return nil
+	default:
+		q.dropped.Add(1)
+		return ErrRecordDropped
+	}
+}
+
+func (a *AsyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return a
+	}
+	return &AsyncHandler{handler: a.handler.WithAttrs(attrs), queue: a.queue}
+}
+
+func (a *AsyncHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return a
+	}
+	return &AsyncHandler{handler: a.handler.WithGroup(name), queue: a.queue}
+}
+
+// Dropped returns the count of records dropped because the queue was full.
+func (a *AsyncHandler) Dropped() uint64 {
+	return a.queue.dropped.Load()
+}
+
+// Close stops accepting records, waits until the queued ones are written or ctx is done
+// and closes the wrapped handler if it has a Close(ctx) method.
+func (a *AsyncHandler) Close(ctx context.Context) error {
+	q := a.queue
+
+	q.closeMu.Lock()
+	if q.closed.Swap(true) {
+		q.closeMu.Unlock()
+		return ErrAlreadyClosed
+	}
+	close(q.items)
+	q.closeMu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if c, ok := a.handler.(interface{ Close(context.Context) error }); ok {
+		if err := c.Close(ctx); err != nil && !errors.Is(err, ErrNothingToClose) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAsyncHandlerClonesRecords(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewAsyncHandler(NewJsonHandler(&buf, nil), 16)
+	log := slog.New(h)
+
+	// The caller reuses the group slice right after the call, the queued record must keep the old values.
+	group := []slog.Attr{slog.String("id", "first")}
+	log.Info("msg", slog.Attr{Key: "user", Value: slog.GroupValue(group...)})
+	group[0] = slog.String("id", "second")
+
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := buf.String(); !strings.Contains(out, `"user":{"id":"first"}`) {
+		t.Fatalf("output = %q", out)
+	}
+}
+
+// recordingFeeder is a batchFeeder keeping the messages of every handleQueued call.
+type recordingFeeder struct {
+	nopHandler
+	batches [][]string
+}
+
+func (f *recordingFeeder) feeds(h slog.Handler) bool { return h == slog.Handler(f) }
+
+func (f *recordingFeeder) handleQueued(items []asyncItem) {
+	var msgs []string
+	for _, item := range items {
+		msgs = append(msgs, item.record.Message)
+	}
+	f.batches = append(f.batches, msgs)
+}
+
+func TestAsyncQueueFeedsBatches(t *testing.T) {
+	feeder := &recordingFeeder{}
+	other := &recordingFeeder{}
+	q := &asyncQueue{items: make(chan asyncItem, 8)}
+
+	for _, item := range []struct {
+		h   slog.Handler
+		msg string
+	}{{feeder, "a"}, {feeder, "b"}, {other, "c"}, {nopHandler{}, "d"}, {feeder, "e"}, {feeder, "f"}} {
+		q.items <- asyncItem{handler: item.h, ctx: context.Background(), record: slog.NewRecord(time.Time{}, slog.LevelInfo, item.msg, 0)}
+	}
+	close(q.items)
+
+	q.wg.Add(1)
+	q.run()
+
+	if got := fmt.Sprint(feeder.batches, other.batches); got != "[[a b] [e f]] [[c]]" {
+		t.Fatalf("batches = %s", got)
+	}
+}
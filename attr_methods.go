@@ -0,0 +1,108 @@
+// Code generated by gen_attr_methods.go; DO NOT EDIT.
+
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Trace1 logs 1 attr at LevelTrace without the args slice of LogAttrs.
+func (l *Logger) Trace1(ctx context.Context, msg string, a1 slog.Attr) {
+	l.logAttrs(ctx, LevelTrace, msg, a1)
+}
+
+// Trace2 logs 2 attrs at LevelTrace without the args slice of LogAttrs.
+func (l *Logger) Trace2(ctx context.Context, msg string, a1, a2 slog.Attr) {
+	l.logAttrs(ctx, LevelTrace, msg, a1, a2)
+}
+
+// Trace3 logs 3 attrs at LevelTrace without the args slice of LogAttrs.
+func (l *Logger) Trace3(ctx context.Context, msg string, a1, a2, a3 slog.Attr) {
+	l.logAttrs(ctx, LevelTrace, msg, a1, a2, a3)
+}
+
+// Trace4 logs 4 attrs at LevelTrace without the args slice of LogAttrs.
+func (l *Logger) Trace4(ctx context.Context, msg string, a1, a2, a3, a4 slog.Attr) {
+	l.logAttrs(ctx, LevelTrace, msg, a1, a2, a3, a4)
+}
+
+// Debug1 logs 1 attr at slog.LevelDebug without the args slice of LogAttrs.
+func (l *Logger) Debug1(ctx context.Context, msg string, a1 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelDebug, msg, a1)
+}
+
+// Debug2 logs 2 attrs at slog.LevelDebug without the args slice of LogAttrs.
+func (l *Logger) Debug2(ctx context.Context, msg string, a1, a2 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelDebug, msg, a1, a2)
+}
+
+// Debug3 logs 3 attrs at slog.LevelDebug without the args slice of LogAttrs.
+func (l *Logger) Debug3(ctx context.Context, msg string, a1, a2, a3 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelDebug, msg, a1, a2, a3)
+}
+
+// Debug4 logs 4 attrs at slog.LevelDebug without the args slice of LogAttrs.
+func (l *Logger) Debug4(ctx context.Context, msg string, a1, a2, a3, a4 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelDebug, msg, a1, a2, a3, a4)
+}
+
+// Info1 logs 1 attr at slog.LevelInfo without the args slice of LogAttrs.
+func (l *Logger) Info1(ctx context.Context, msg string, a1 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelInfo, msg, a1)
+}
+
+// Info2 logs 2 attrs at slog.LevelInfo without the args slice of LogAttrs.
+func (l *Logger) Info2(ctx context.Context, msg string, a1, a2 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelInfo, msg, a1, a2)
+}
+
+// Info3 logs 3 attrs at slog.LevelInfo without the args slice of LogAttrs.
+func (l *Logger) Info3(ctx context.Context, msg string, a1, a2, a3 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelInfo, msg, a1, a2, a3)
+}
+
+// Info4 logs 4 attrs at slog.LevelInfo without the args slice of LogAttrs.
+func (l *Logger) Info4(ctx context.Context, msg string, a1, a2, a3, a4 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelInfo, msg, a1, a2, a3, a4)
+}
+
+// Warn1 logs 1 attr at slog.LevelWarn without the args slice of LogAttrs.
+func (l *Logger) Warn1(ctx context.Context, msg string, a1 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelWarn, msg, a1)
+}
+
+// Warn2 logs 2 attrs at slog.LevelWarn without the args slice of LogAttrs.
+func (l *Logger) Warn2(ctx context.Context, msg string, a1, a2 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelWarn, msg, a1, a2)
+}
+
+// Warn3 logs 3 attrs at slog.LevelWarn without the args slice of LogAttrs.
+func (l *Logger) Warn3(ctx context.Context, msg string, a1, a2, a3 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelWarn, msg, a1, a2, a3)
+}
+
+// Warn4 logs 4 attrs at slog.LevelWarn without the args slice of LogAttrs.
+func (l *Logger) Warn4(ctx context.Context, msg string, a1, a2, a3, a4 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelWarn, msg, a1, a2, a3, a4)
+}
+
+// Error1 logs 1 attr at slog.LevelError without the args slice of LogAttrs.
+func (l *Logger) Error1(ctx context.Context, msg string, a1 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelError, msg, a1)
+}
+
+// Error2 logs 2 attrs at slog.LevelError without the args slice of LogAttrs.
+func (l *Logger) Error2(ctx context.Context, msg string, a1, a2 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelError, msg, a1, a2)
+}
+
+// Error3 logs 3 attrs at slog.LevelError without the args slice of LogAttrs.
+func (l *Logger) Error3(ctx context.Context, msg string, a1, a2, a3 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelError, msg, a1, a2, a3)
+}
+
+// Error4 logs 4 attrs at slog.LevelError without the args slice of LogAttrs.
+func (l *Logger) Error4(ctx context.Context, msg string, a1, a2, a3, a4 slog.Attr) {
+	l.logAttrs(ctx, slog.LevelError, msg, a1, a2, a3, a4)
+}
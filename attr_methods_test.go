@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// nopHandler accepts every record and drops it.
+type nopHandler struct{}
+
+func (nopHandler) Enabled(context.Context, slog.Level) bool  { return true }
+func (nopHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h nopHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h nopHandler) WithGroup(string) slog.Handler           { return h }
+
+func TestAttrMethods(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(NewJsonHandler(&buf, &Config{AddSource: true}))
+
+	l.Info2(context.Background(), "done", slog.Int("status", 200), slog.String("path", "/v1"))
+	if got := buf.String(); !strings.Contains(got, `"level":"INFO","source":"attr_methods_test.go:`) ||
+		!strings.Contains(got, `"msg":"done","status":200,"path":"/v1"}`) {
+		t.Errorf("got %s", got)
+	}
+
+	nop := New(nopHandler{})
+	allocs := testing.AllocsPerRun(100, func() {
+		nop.Warn3(context.Background(), "slow", slog.Int("a", 1), slog.Int("b", 2), slog.Bool("c", true))
+	})
+	if allocs != 0 {
+		t.Errorf("Warn3 allocates %v times", allocs)
+	}
+}
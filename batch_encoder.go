@@ -0,0 +1,391 @@
+package logger
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultBatchRecords       = 1000
+	defaultBatchFlushInterval = time.Second
+)
+
+// BatchFormat is the framing of a BatchEncoder payload.
+type BatchFormat int
+
+const (
+	// BatchNDJSON - records encoded like by the JSON handler, one per line.
+	BatchNDJSON BatchFormat = iota
+	// BatchMsgpack - a MessagePack array of maps with time (timestamp extension), level, msg and the attrs.
+	BatchMsgpack
+	// BatchOTLP - an OTLP/JSON ExportLogsServiceRequest with one ResourceLogs.
+	BatchOTLP
+)
+
+// BatchOptions controls how records are batched and uploaded.
+type BatchOptions struct {
+	// framing of the payload, default - BatchNDJSON
+	Format BatchFormat
+	// Upload sends a payload to the sink, it's called by the record filling a batch, by the flusher and by Close.
+	// The payload is reused after it returns.
+	Upload func(ctx context.Context, payload []byte) error
+	// count of records in a payload, 0 - 1000
+	MaxRecords int
+	// encoded size flushing the batch early, 0 - no limit
+	MaxBytes int
+	// max time a record waits for its batch to fill up, 0 - 1s
+	FlushInterval time.Duration
+	// attrs of the OTLP resource (e.g. service.name), ignored by other formats
+	Resource []slog.Attr
+}
+
+// batchState is shared by a BatchEncoder and its clones.
+type batchState struct {
+	opts BatchOptions
+	// json encodes NDJSON records, its level is the one of the Config.
+	json *Handler
+
+	mu      sync.Mutex
+	payload []byte
+	count   int
+	// flushMu keeps the batches in order and guards framed, the buffer handed to Upload,
+	// so records are encoded while the previous batch is uploaded.
+	flushMu sync.Mutex
+	framed  []byte
+
+	done   chan struct{}
+	wg     sync.WaitGroup
+	closed atomic.Bool
+}
+
+// BatchEncoder is a slog.Handler encoding records into a single framed payload for sinks accepting bulk uploads,
+// the buffers are reused for every batch. Wrap it with NewAsyncHandler to move encoding and uploads off the
+// callers' goroutines, its worker feeds all the queued records to the encoder at once. WithAttrs/WithGroup state
+// is folded into the records. Close must be called to upload the last batch.
+type BatchEncoder struct {
+	state  *batchState
+	frames attrFrames
+	// pipeline is the Config.Middleware chain ending with handlePrepared, nil without middleware.
+	pipeline HandleFunc
+}
+
+// NewBatchEncoder creates an encoder, cfg sets the level and encoding options, its writer options are ignored.
+// Config.Middleware runs before the records are encoded.
+func NewBatchEncoder(opts BatchOptions, cfg *Config) (*BatchEncoder, error) {
+	if opts.Upload == nil {
+		return nil, fmt.Errorf("%w: BatchOptions.Upload is required", ErrInvalidConfig)
+	}
+	if opts.Format < BatchNDJSON || opts.Format > BatchOTLP {
+		return nil, fmt.Errorf("%w: unknown BatchFormat %d", ErrInvalidConfig, opts.Format)
+	}
+	if opts.MaxRecords < 0 || opts.MaxBytes < 0 || opts.FlushInterval < 0 {
+		return nil, fmt.Errorf("%w: MaxRecords, MaxBytes and FlushInterval must not be negative", ErrInvalidConfig)
+	}
+
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	c := *cfg
+	c.BufferedOutput = false
+	c.ErrorOutput = nil
+
+	opts.MaxRecords = cmp.Or(opts.MaxRecords, defaultBatchRecords)
+
+	state := &batchState{
+		opts: opts,
+		json: NewJsonHandler(io.Discard, &c),
+		done: make(chan struct{}),
+	}
+
+	e := (&BatchEncoder{state: state, frames: attrFrames{{}}}).withPipeline()
+
+	state.wg.Add(1)
+	go state.flusher(cmp.Or(opts.FlushInterval, defaultBatchFlushInterval))
+
+	return e, nil
+}
+
+func (e *BatchEncoder) Enabled(ctx context.Context, level slog.Level) bool {
+	return !e.state.closed.Load() && e.state.json.Enabled(ctx, level)
+}
+
+// Handle adds the record to the batch, the record that fills it uploads the batch and reports its error.
+func (e *BatchEncoder) Handle(ctx context.Context, record slog.Record) error {
+	s := e.state
+	if s.closed.Load() {
+		return ErrAlreadyClosed
+	}
+
+	if ok, err := s.json.prepare(ctx, &record, false); !ok {
+		return err
+	}
+
+	if e.pipeline != nil {
+		return e.pipeline(ctx, record)
+	}
+	return e.handlePrepared(ctx, record)
+}
+
+// handlePrepared adds a record that passed prepare to the batch, it's the last stage of the middleware chain.
+func (e *BatchEncoder) handlePrepared(ctx context.Context, record slog.Record) error {
+	s := e.state
+
+	prepared := e.checkRecord(record)
+
+	s.mu.Lock()
+	err := s.appendPrepared(prepared)
+	full := s.full()
+	s.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if !full {
+		return nil
+	}
+
+	// The batch must be uploaded even if the caller's ctx is canceled right after the call.
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return s.flush(context.WithoutCancel(ctx))
+}
+
+// batchRecord is a record checked and ready to be appended to the batch.
+type batchRecord struct {
+	record slog.Record
+	err    error
+	// report is the misuse report of the record, if there are problems
+	report    slog.Record
+	hasReport bool
+}
+
+// checkRecord folds the frames of e into a record that passed prepare and runs the checks.
+func (e *BatchEncoder) checkRecord(record slog.Record) batchRecord {
+	h := e.state.json
+
+	// The WithAttrs and WithGroup frames are attrs of the folded record, so the checks see the full paths.
+	record = e.frames.fold(record)
+
+	msg := record.Message
+	record, problems, err := h.checkEncoded(record)
+	prepared := batchRecord{record: record, err: err}
+	if len(problems) > 0 {
+		// PanicOnMisuse panics here, before the lock is taken.
+		prepared.report = h.misuseRecord(msg, problems)
+		prepared.hasReport = true
+	}
+
+	return prepared
+}
+
+// appendPrepared appends the record and its misuse report to the batch, s.mu must be held.
+func (s *batchState) appendPrepared(r batchRecord) error {
+	h := s.json
+
+	err := r.err
+	if err == nil {
+		err = s.append(h, r.record)
+		h.shared.stats.count(err)
+		h.shared.diagnoseWrite(r.record, err)
+	}
+	if r.hasReport {
+		if reportErr := s.append(h.topLevel(), r.report); err == nil {
+			err = reportErr
+		}
+	}
+
+	return err
+}
+
+// full reports whether the batch must be uploaded, s.mu must be held.
+func (s *batchState) full() bool {
+	return s.count >= s.opts.MaxRecords || s.opts.MaxBytes > 0 && len(s.payload) >= s.opts.MaxBytes
+}
+
+// feeds reports whether the records of h go to the batch of e, h is e or one of its clones.
+func (e *BatchEncoder) feeds(h slog.Handler) bool {
+	other, ok := h.(*BatchEncoder)
+	return ok && other.state == e.state
+}
+
+// handleQueued encodes the records queued by AsyncHandler for e and its clones, the batch lock is taken once
+// for all of them instead of once per record. Nobody waits for the errors, the failed uploads are reported
+// to Handler.Diagnostics of the encoder like the ones of the flusher. With Config.Middleware the records
+// are handled one by one, the chain decides about every record before it's encoded.
+func (e *BatchEncoder) handleQueued(items []asyncItem) {
+	s := e.state
+	if s.closed.Load() {
+		return
+	}
+
+	if e.pipeline != nil {
+		for _, item := range items {
+			_ = item.handler.Handle(item.ctx, item.record)
+		}
+		return
+	}
+
+	prepared := make([]batchRecord, 0, len(items))
+	for _, item := range items {
+		record := item.record
+		if ok, _ := s.json.prepare(item.ctx, &record, false); ok {
+			prepared = append(prepared, item.handler.(*BatchEncoder).checkRecord(record))
+		}
+	}
+
+	for len(prepared) > 0 {
+		s.mu.Lock()
+		for len(prepared) > 0 && !s.full() {
+			_ = s.appendPrepared(prepared[0])
+			prepared = prepared[1:]
+		}
+		full := s.full()
+		s.mu.Unlock()
+
+		if !full {
+			return
+		}
+		if err := s.flush(context.Background()); err != nil {
+			s.json.shared.diagnose(Diagnostic{Kind: DiagnosticWriteError, Err: err})
+		}
+	}
+}
+
+// append encodes the record after the pending ones, a record whose encoding panicked is cut off.
+func (s *batchState) append(h *Handler, record slog.Record) (err error) {
+	mark := len(s.payload)
+	defer func() {
+		if r := recover(); r != nil {
+			s.payload = s.payload[:mark]
+			err = fmt.Errorf("%w: %v", ErrEncodePanic, r)
+		}
+	}()
+
+	builder := h.builder.(*jsonBuilder)
+	switch s.opts.Format {
+	case BatchNDJSON:
+		s.payload = builder.buildLog(s.payload, record, "", "", "", "")
+	case BatchMsgpack:
+		s.payload = builder.appendMsgpackRecord(s.payload, record)
+	case BatchOTLP:
+		if s.count > 0 {
+			s.payload = append(s.payload, ',')
+		}
+		s.payload = builder.appendOTLPRecord(s.payload, record)
+	}
+	s.count++
+
+	return nil
+}
+
+// flush frames the pending records and uploads them.
+func (s *batchState) flush(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	if s.count == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	s.framed = s.frame(s.framed[:0])
+	s.payload = s.payload[:0]
+	s.count = 0
+	s.mu.Unlock()
+
+	return s.opts.Upload(ctx, s.framed)
+}
+
+// frame wraps the pending records into the payload of the format.
+func (s *batchState) frame(buf []byte) []byte {
+	builder := s.json.builder.(*jsonBuilder)
+
+	switch s.opts.Format {
+	case BatchMsgpack:
+		buf = appendMsgpackArrayHeader(buf, s.count)
+		return append(buf, s.payload...)
+	case BatchOTLP:
+		buf = builder.appendOTLPHeader(buf, s.opts.Resource)
+		buf = append(buf, s.payload...)
+		return append(buf, otlpFooter...)
+	default:
+		return append(buf, s.payload...)
+	}
+}
+
+// flusher uploads partial batches every interval.
+func (s *batchState) flusher(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			// Nobody waits for the error, it's only reported to Handler.Diagnostics of the encoder.
+			if err := s.flush(context.Background()); err != nil {
+				s.json.shared.diagnose(Diagnostic{Kind: DiagnosticWriteError, Err: err})
+			}
+		}
+	}
+}
+
+// Flush uploads the pending records.
+func (e *BatchEncoder) Flush(ctx context.Context) error {
+	return e.state.flush(ctx)
+}
+
+// Close stops the flusher and uploads the pending records.
+func (e *BatchEncoder) Close(ctx context.Context) error {
+	s := e.state
+	if !s.closed.CompareAndSwap(false, true) {
+		return ErrAlreadyClosed
+	}
+
+	close(s.done)
+	s.wg.Wait()
+
+	return s.flush(ctx)
+}
+
+func (e *BatchEncoder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return e
+	}
+	return (&BatchEncoder{state: e.state, frames: e.frames.withAttrs(attrs)}).withPipeline()
+}
+
+func (e *BatchEncoder) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return e
+	}
+	return (&BatchEncoder{state: e.state, frames: e.frames.withGroup(name)}).withPipeline()
+}
+
+// withPipeline builds the middleware chain of the encoder.
+func (e *BatchEncoder) withPipeline() *BatchEncoder {
+	e.pipeline = e.state.json.pipelineTo(e.handlePrepared)
+	return e
+}
+
+// Stats returns the counters of the encoded records, failed uploads are not counted.
+func (e *BatchEncoder) Stats() Stats {
+	return e.state.json.Stats()
+}
+
+// Diagnostics returns the encoder health events, including the failed uploads of the flusher.
+func (e *BatchEncoder) Diagnostics() <-chan Diagnostic {
+	return e.state.json.Diagnostics()
+}
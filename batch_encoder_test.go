@@ -0,0 +1,345 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// batchUploads keeps a copy of every uploaded payload.
+type batchUploads struct{ payloads [][]byte }
+
+func (u *batchUploads) upload(_ context.Context, payload []byte) error {
+	u.payloads = append(u.payloads, bytes.Clone(payload))
+	return nil
+}
+
+func newTestBatchEncoder(t *testing.T, format BatchFormat, uploads *batchUploads) *BatchEncoder {
+	t.Helper()
+
+	e, err := NewBatchEncoder(BatchOptions{
+		Format:        format,
+		Upload:        uploads.upload,
+		MaxRecords:    2,
+		FlushInterval: time.Hour,
+		Resource:      []slog.Attr{slog.String("service.name", "api")},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func logBatch(e *BatchEncoder) error {
+	l := slog.New(e).With("service", "api").WithGroup("req")
+	l.Info("a", "id", 1, slog.Group("user", "name", "bob"))
+	l.Warn("b", "ok", true, "elapsed", 1500*time.Millisecond)
+	slog.New(e).Error("c", "n", -70000)
+
+	return e.Close(context.Background())
+}
+
+func TestBatchEncoderNDJSON(t *testing.T) {
+	var uploads batchUploads
+	if err := logBatch(newTestBatchEncoder(t, BatchNDJSON, &uploads)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(uploads.payloads) != 2 {
+		t.Fatalf("%d uploads, want 2", len(uploads.payloads))
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(uploads.payloads[0], []byte{'\n'}), []byte{'\n'})
+	if len(lines) != 2 {
+		t.Fatalf("first payload has %d lines, want 2: %s", len(lines), uploads.payloads[0])
+	}
+
+	var rec map[string]any
+	if err := json.Unmarshal(lines[0], &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["service"] != "api" || rec["req"].(map[string]any)["user"].(map[string]any)["name"] != "bob" {
+		t.Errorf("first record = %s", lines[0])
+	}
+}
+
+func TestBatchEncoderMsgpack(t *testing.T) {
+	var uploads batchUploads
+	if err := logBatch(newTestBatchEncoder(t, BatchMsgpack, &uploads)); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &msgpackDecoder{buf: uploads.payloads[0]}
+	batch, ok := d.decode().([]any)
+	if !ok || len(batch) != 2 || len(d.buf) != 0 {
+		t.Fatalf("payload decoded to %v with %d bytes left", batch, len(d.buf))
+	}
+
+	first := batch[0].(map[string]any)
+	if first["msg"] != "a" || first["level"] != "INFO" || first["service"] != "api" {
+		t.Errorf("first record = %v", first)
+	}
+	if _, ok = first["time"].(time.Time); !ok {
+		t.Errorf("time = %#v, want the timestamp extension", first["time"])
+	}
+	req := first["req"].(map[string]any)
+	if req["id"] != int64(1) || req["user"].(map[string]any)["name"] != "bob" {
+		t.Errorf("req = %v", req)
+	}
+
+	second := batch[1].(map[string]any)["req"].(map[string]any)
+	if second["ok"] != true || second["elapsed"] != int64(1500*time.Millisecond) {
+		t.Errorf("second req = %v", second)
+	}
+
+	last := (&msgpackDecoder{buf: uploads.payloads[1]}).decode().([]any)
+	if n := last[0].(map[string]any)["n"]; n != int64(-70000) {
+		t.Errorf("n = %v, want -70000", n)
+	}
+}
+
+func TestBatchEncoderOTLP(t *testing.T) {
+	var uploads batchUploads
+	if err := logBatch(newTestBatchEncoder(t, BatchOTLP, &uploads)); err != nil {
+		t.Fatal(err)
+	}
+
+	type keyValue struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+	var req struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []keyValue `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				LogRecords []struct {
+					TimeUnixNano   string         `json:"timeUnixNano"`
+					SeverityNumber int            `json:"severityNumber"`
+					SeverityText   string         `json:"severityText"`
+					Body           map[string]any `json:"body"`
+					Attributes     []keyValue     `json:"attributes"`
+				} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	if err := json.Unmarshal(uploads.payloads[0], &req); err != nil {
+		t.Fatalf("%v: %s", err, uploads.payloads[0])
+	}
+
+	resource := req.ResourceLogs[0].Resource.Attributes
+	if len(resource) != 1 || resource[0].Key != "service.name" || resource[0].Value["stringValue"] != "api" {
+		t.Errorf("resource = %v", resource)
+	}
+
+	records := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+	if len(records) != 2 {
+		t.Fatalf("%d records, want 2", len(records))
+	}
+	if r := records[1]; r.SeverityNumber != 13 || r.SeverityText != "WARN" || r.Body["stringValue"] != "b" || r.TimeUnixNano == "" {
+		t.Errorf("second record = %+v", r)
+	}
+
+	attrs := records[0].Attributes
+	if len(attrs) != 2 || attrs[0].Key != "service" || attrs[1].Key != "req" {
+		t.Fatalf("attributes = %+v", attrs)
+	}
+	got := fmt.Sprint(attrs[1].Value)
+	if want := "map[kvlistValue:map[values:[map[key:id value:map[intValue:1]] map[key:user value:map[kvlistValue:map[values:[map[key:name value:map[stringValue:bob]]]]]]]]]"; got != want {
+		t.Errorf("req = %s, want %s", got, want)
+	}
+}
+
+func TestBatchEncoderFlushInterval(t *testing.T) {
+	uploaded := make(chan []byte, 1)
+	e, err := NewBatchEncoder(BatchOptions{
+		Upload: func(_ context.Context, payload []byte) error {
+			uploaded <- bytes.Clone(payload)
+			return nil
+		},
+		FlushInterval: 10 * time.Millisecond,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close(context.Background())
+
+	slog.New(e).Info("a")
+
+	select {
+	case payload := <-uploaded:
+		if !bytes.Contains(payload, []byte(`"msg":"a"`)) {
+			t.Errorf("payload = %s", payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the partial batch was not uploaded")
+	}
+}
+
+// msgpackDecoder decodes the subset of MessagePack written by the batch encoder.
+type msgpackDecoder struct{ buf []byte }
+
+func (d *msgpackDecoder) next(n int) []byte {
+	p := d.buf[:n]
+	d.buf = d.buf[n:]
+	return p
+}
+
+func (d *msgpackDecoder) decode() any {
+	b := d.next(1)[0]
+	switch {
+	case b < 0x80:
+		return int64(b)
+	case b >= 0xe0:
+		return int64(int8(b))
+	case b&0xf0 == 0x90:
+		return d.array(int(b & 0x0f))
+	case b&0xe0 == 0xa0:
+		return string(d.next(int(b & 0x1f)))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil
+	case 0xc2:
+		return false
+	case 0xc3:
+		return true
+	case 0xc7:
+		d.next(2)
+		nsec := binary.BigEndian.Uint32(d.next(4))
+		sec := binary.BigEndian.Uint64(d.next(8))
+		return time.Unix(int64(sec), int64(nsec))
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(d.next(8)))
+	case 0xcf:
+		return binary.BigEndian.Uint64(d.next(8))
+	case 0xd2:
+		return int64(int32(binary.BigEndian.Uint32(d.next(4))))
+	case 0xd3:
+		return int64(binary.BigEndian.Uint64(d.next(8)))
+	case 0xd9:
+		return string(d.next(int(d.next(1)[0])))
+	case 0xdc:
+		return d.array(int(binary.BigEndian.Uint16(d.next(2))))
+	case 0xdd:
+		return d.array(int(binary.BigEndian.Uint32(d.next(4))))
+	case 0xdf:
+		n := int(binary.BigEndian.Uint32(d.next(4)))
+		m := make(map[string]any, n)
+		for range n {
+			key := d.decode().(string)
+			m[key] = d.decode()
+		}
+		return m
+	}
+	panic(fmt.Sprintf("unexpected msgpack byte %#x", b))
+}
+
+func (d *msgpackDecoder) array(n int) []any {
+	a := make([]any, n)
+	for i := range a {
+		a[i] = d.decode()
+	}
+	return a
+}
+
+func TestBatchEncoderRedaction(t *testing.T) {
+	var uploads batchUploads
+	e, err := NewBatchEncoder(BatchOptions{Upload: uploads.upload, FlushInterval: time.Hour},
+		&Config{ReplaceAttr: RedactPaths("req.password", "req.token")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slog.New(e).WithGroup("req").Info("login", "password", "hunter2", "token", "t0k")
+	if err = e.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(uploads.payloads) != 1 || bytes.Contains(uploads.payloads[0], []byte("hunter2")) ||
+		!bytes.Contains(uploads.payloads[0], []byte(`"req":{"password":"[REDACTED]","token":"[REDACTED]"}`)) {
+		t.Errorf("payloads %q", uploads.payloads)
+	}
+}
+
+func TestBatchEncoderAsync(t *testing.T) {
+	var uploads batchUploads
+	e, err := NewBatchEncoder(BatchOptions{Upload: uploads.upload, MaxRecords: 3, FlushInterval: time.Hour}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewAsyncHandler(e, 16)
+	l := slog.New(h)
+	ctx := AppendAttrsToCtx(context.Background(), slog.String("trace_id", "af82"))
+	for i := range 4 {
+		l.InfoContext(ctx, "a", "i", i)
+		l.With("clone", true).Info("b", "i", i)
+	}
+	if err = h.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, payload := range uploads.payloads {
+		for _, line := range bytes.Split(bytes.TrimSuffix(payload, []byte{'\n'}), []byte{'\n'}) {
+			var record map[string]any
+			if err = json.Unmarshal(line, &record); err != nil {
+				t.Fatalf("%v: %s", err, line)
+			}
+			lines = append(lines, fmt.Sprintf("%v %v %v %v", record["msg"], record["i"], record["trace_id"], record["clone"]))
+		}
+	}
+
+	want := "[a 0 af82 <nil> b 0 <nil> true a 1 af82 <nil> b 1 <nil> true a 2 af82 <nil> b 2 <nil> true a 3 af82 <nil> b 3 <nil> true]"
+	if got := fmt.Sprint(lines); got != want {
+		t.Fatalf("records = %s", got)
+	}
+	if len(uploads.payloads) != 3 {
+		t.Fatalf("%d uploads, want 3", len(uploads.payloads))
+	}
+}
+
+func TestBatchEncoderPrecompiledAndMsgf(t *testing.T) {
+	p := NewJsonHandler(io.Discard, nil).Precompile(slog.String("region", "eu"))
+
+	for _, format := range []BatchFormat{BatchMsgpack, BatchOTLP} {
+		var uploads batchUploads
+		e, err := NewBatchEncoder(BatchOptions{Format: format, Upload: uploads.upload, FlushInterval: time.Hour}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l := New(e)
+		l.LogAttrs(context.Background(), slog.LevelInfo, "attrs", p.Attr())
+		l.Msgf(context.Background(), slog.LevelInfo, "user {id} logged in", 42)
+		if err = e.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if format == BatchMsgpack {
+			records := (&msgpackDecoder{buf: uploads.payloads[0]}).decode().([]any)
+			first, second := records[0].(map[string]any), records[1].(map[string]any)
+			if first["region"] != "eu" || second["msg"] != "user 42 logged in" {
+				t.Errorf("msgpack records = %v", records)
+			}
+			continue
+		}
+
+		got := string(uploads.payloads[0])
+		if !strings.Contains(got, `{"key":"region","value":{"stringValue":"eu"}}`) ||
+			!strings.Contains(got, `"body":{"stringValue":"user 42 logged in"}`) {
+			t.Errorf("otlp payload = %s", got)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package logger
+
+import (
+	"encoding/binary"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+	"unsafe"
+
+	"github.com/ttrtcixy/fast-slog-handler/internal/escape"
+)
+
+// appendMsgpackRecord appends the record as a map of time, level, msg and its attrs.
+func (b *jsonBuilder) appendMsgpackRecord(buf []byte, record slog.Record) []byte {
+	header := len(buf)
+	buf = append(buf, 0xdf, 0, 0, 0, 0)
+	n := 2
+
+	if !record.Time.IsZero() {
+		buf = appendMsgpackString(buf, slog.TimeKey)
+		buf = appendMsgpackTime(buf, record.Time)
+		n++
+	}
+	buf = appendMsgpackString(buf, slog.LevelKey)
+	buf = appendMsgpackString(buf, levelName(record.Level))
+	buf = appendMsgpackString(buf, slog.MessageKey)
+	// The length of a rendered Msgf message is only known after it's rendered.
+	pBuf := bufPool.Get().(*[]byte)
+	if msg, ok := appendTemplateMessage((*pBuf)[:0], record, appendRaw); ok {
+		buf = appendMsgpackString(buf, unsafe.String(unsafe.SliceData(msg), len(msg)))
+		if cap(msg) <= maxPoolBufSize {
+			*pBuf = msg
+		}
+	} else {
+		buf = appendMsgpackString(buf, record.Message)
+	}
+	bufPool.Put(pBuf)
+
+	var pathBuf [128]byte
+	record.Attrs(func(attr slog.Attr) bool {
+		buf, n = b.appendMsgpackAttr(buf, n, pathBuf[:0], attr)
+		return true
+	})
+
+	// The count of a map is only known after its attrs, map32 leaves room for any.
+	binary.BigEndian.PutUint32(buf[header+1:], uint32(n))
+	return buf
+}
+
+// appendMsgpackAttr appends the key and value of the attr to a map of n entries and returns the new count,
+// path is the dotted path of its groups, built only for keyRenames with paths.
+func (b *jsonBuilder) appendMsgpackAttr(buf []byte, n int, path []byte, attr slog.Attr) ([]byte, int) {
+	// The bytes of Precompile tokens are JSON, their attrs are encoded again.
+	if p, ok := precompiledFrom(attr); ok {
+		attr = p.inline()
+	}
+	value := attr.Value.Resolve()
+
+	if value.Kind() == slog.KindGroup {
+		attrs := value.Group()
+		if len(attrs) == 0 {
+			return buf, n
+		}
+		// Attrs of a group without a key are inlined in the current group.
+		if attr.Key == "" {
+			for _, a := range attrs {
+				buf, n = b.appendMsgpackAttr(buf, n, path, a)
+			}
+			return buf, n
+		}
+
+		buf = appendMsgpackString(buf, attr.Key)
+		if b.renames != nil && b.renames.paths {
+			path = append(path, attr.Key...)
+			path = append(path, '.')
+		}
+		header := len(buf)
+		buf = append(buf, 0xdf, 0, 0, 0, 0)
+		count := 0
+		for _, a := range attrs {
+			buf, count = b.appendMsgpackAttr(buf, count, path, a)
+		}
+		binary.BigEndian.PutUint32(buf[header+1:], uint32(count))
+		return buf, n + 1
+	}
+
+	if attr.Key == "" {
+		return buf, n
+	}
+
+	buf = appendMsgpackString(buf, b.renames.rename(path, attr.Key))
+	return b.appendMsgpackValue(buf, value), n + 1
+}
+
+func (b *jsonBuilder) appendMsgpackValue(buf []byte, value slog.Value) []byte {
+	switch value.Kind() {
+	case slog.KindString:
+		return appendMsgpackString(buf, value.String())
+	case slog.KindInt64:
+		return appendMsgpackInt(buf, value.Int64())
+	case slog.KindUint64:
+		if n := value.Uint64(); n > math.MaxInt64 {
+			buf = append(buf, 0xcf)
+			return binary.BigEndian.AppendUint64(buf, n)
+		}
+		return appendMsgpackInt(buf, int64(value.Uint64()))
+	case slog.KindFloat64:
+		buf = append(buf, 0xcb)
+		return binary.BigEndian.AppendUint64(buf, math.Float64bits(value.Float64()))
+	case slog.KindBool:
+		if value.Bool() {
+			return append(buf, 0xc3)
+		}
+		return append(buf, 0xc2)
+	case slog.KindDuration:
+		return appendMsgpackInt(buf, value.Duration().Nanoseconds())
+	case slog.KindTime:
+		return appendMsgpackTime(buf, value.Time())
+	case slog.KindAny:
+		v := value.Any()
+		if v == nil {
+			return append(buf, 0xc0)
+		}
+		if err, ok := v.(error); ok {
+			return appendMsgpackString(buf, err.Error())
+		}
+		if tm, ok := anyTime(v); ok {
+			return appendMsgpackTime(buf, tm)
+		}
+		if uv, ok := v.(unitValue); ok {
+			return b.appendMsgpackValue(buf, uv.number())
+		}
+		// Other values are embedded as their JSON encoding.
+		pBuf := bufPool.Get().(*[]byte)
+		encoded := b.writeValue((*pBuf)[:0], value)
+		// The bytes are appended before the buffer goes back to the pool, they are not copied into a string.
+		buf = appendMsgpackString(buf, unsafe.String(unsafe.SliceData(encoded), len(encoded)))
+		if cap(encoded) <= maxPoolBufSize {
+			*pBuf = encoded
+			bufPool.Put(pBuf)
+		}
+		return buf
+	default:
+		return appendMsgpackString(buf, value.String())
+	}
+}
+
+func appendMsgpackArrayHeader(buf []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		buf = append(buf, 0xdc)
+		return binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, 0xdd)
+		return binary.BigEndian.AppendUint32(buf, uint32(n))
+	}
+}
+
+func appendMsgpackString(buf []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		buf = append(buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		buf = append(buf, 0xda)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, 0xdb)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(n))
+	}
+	return append(buf, s...)
+}
+
+func appendMsgpackInt(buf []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n < 128:
+		return append(buf, byte(n))
+	case n < 0 && n >= -32:
+		return append(buf, byte(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		buf = append(buf, 0xd2)
+		return binary.BigEndian.AppendUint32(buf, uint32(n))
+	default:
+		buf = append(buf, 0xd3)
+		return binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+}
+
+// appendMsgpackTime appends the time as the timestamp 96 extension (type -1).
+func appendMsgpackTime(buf []byte, t time.Time) []byte {
+	buf = append(buf, 0xc7, 12, 0xff)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(t.Nanosecond()))
+	return binary.BigEndian.AppendUint64(buf, uint64(t.Unix()))
+}
+
+// otlpFooter closes the logRecords, scopeLogs and resourceLogs of appendOTLPHeader.
+const otlpFooter = `]}]}]}`
+
+// appendOTLPHeader opens an ExportLogsServiceRequest with the resource attrs, the records follow it.
+func (b *jsonBuilder) appendOTLPHeader(buf []byte, resource []slog.Attr) []byte {
+	buf = append(buf, `{"resourceLogs":[{"resource":{"attributes":`...)
+	buf = b.appendOTLPAttrs(buf, nil, nil, resource)
+	return append(buf, `},"scopeLogs":[{"scope":{"name":"github.com/ttrtcixy/fast-slog-handler"},"logRecords":[`...)
+}
+
+// appendOTLPRecord appends the record as an OTLP/JSON LogRecord, the severity follows MapLevel(SeverityOTel).
+func (b *jsonBuilder) appendOTLPRecord(buf []byte, record slog.Record) []byte {
+	severity := MapLevel(SeverityOTel, record.Level)
+
+	buf = append(buf, '{')
+	if !record.Time.IsZero() {
+		buf = append(buf, `"timeUnixNano":"`...)
+		buf = strconv.AppendInt(buf, record.Time.UnixNano(), 10)
+		buf = append(buf, `",`...)
+	}
+	buf = append(buf, `"severityNumber":`...)
+	buf = strconv.AppendInt(buf, int64(severity.Number), 10)
+	buf = append(buf, `,"severityText":"`...)
+	buf = escape.AppendJSON(buf, severity.Text)
+	buf = append(buf, `","body":{"stringValue":"`...)
+	if msgBuf, ok := appendTemplateMessage(buf, record, escape.AppendJSON); ok {
+		buf = msgBuf
+	} else {
+		buf = escape.AppendJSON(buf, record.Message)
+	}
+	buf = append(buf, `"},"attributes":`...)
+
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	var pathBuf [128]byte
+	buf = b.appendOTLPAttrs(buf, b.renames, pathBuf[:0], attrs)
+
+	return append(buf, '}')
+}
+
+// appendOTLPAttrs appends the attrs as an array of KeyValue, groups become kvlistValue.
+// The keys are renamed by renames (nil for the resource), path is the dotted path of the groups.
+func (b *jsonBuilder) appendOTLPAttrs(buf []byte, renames *keyRenames, path []byte, attrs []slog.Attr) []byte {
+	buf = append(buf, '[')
+	start := len(buf)
+	buf = b.appendOTLPKeyValues(buf, start, renames, path, attrs)
+	return append(buf, ']')
+}
+
+func (b *jsonBuilder) appendOTLPKeyValues(buf []byte, start int, renames *keyRenames, path []byte, attrs []slog.Attr) []byte {
+	for _, attr := range attrs {
+		// The bytes of Precompile tokens are JSON, their attrs are encoded again.
+		if p, ok := precompiledFrom(attr); ok {
+			attr = p.inline()
+		}
+		value := attr.Value.Resolve()
+
+		if value.Kind() == slog.KindGroup {
+			group := value.Group()
+			if len(group) == 0 {
+				continue
+			}
+			// Attrs of a group without a key are inlined in the current group.
+			if attr.Key == "" {
+				buf = b.appendOTLPKeyValues(buf, start, renames, path, group)
+				continue
+			}
+		} else if attr.Key == "" {
+			continue
+		}
+
+		if len(buf) > start {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, `{"key":"`...)
+		if value.Kind() == slog.KindGroup {
+			buf = escape.AppendJSON(buf, attr.Key)
+			buf = append(buf, `","value":{"kvlistValue":{"values":`...)
+			groupPath := path
+			if renames != nil && renames.paths {
+				groupPath = append(append(groupPath, attr.Key...), '.')
+			}
+			buf = b.appendOTLPAttrs(buf, renames, groupPath, value.Group())
+			buf = append(buf, `}}}`...)
+			continue
+		}
+		buf = escape.AppendJSON(buf, renames.rename(path, attr.Key))
+		buf = append(buf, `","value":`...)
+		buf = b.appendOTLPValue(buf, value)
+		buf = append(buf, '}')
+	}
+	return buf
+}
+
+// appendOTLPValue appends the value as an AnyValue, 64-bit integers are strings like in the protobuf JSON mapping.
+func (b *jsonBuilder) appendOTLPValue(buf []byte, value slog.Value) []byte {
+	switch value.Kind() {
+	case slog.KindString:
+		buf = append(buf, `{"stringValue":"`...)
+		buf = escape.AppendJSON(buf, value.String())
+		return append(buf, `"}`...)
+	case slog.KindInt64:
+		buf = append(buf, `{"intValue":"`...)
+		buf = strconv.AppendInt(buf, value.Int64(), 10)
+		return append(buf, `"}`...)
+	case slog.KindUint64:
+		if n := value.Uint64(); n > math.MaxInt64 {
+			buf = append(buf, `{"stringValue":"`...)
+			buf = strconv.AppendUint(buf, n, 10)
+			return append(buf, `"}`...)
+		}
+		buf = append(buf, `{"intValue":"`...)
+		buf = strconv.AppendUint(buf, value.Uint64(), 10)
+		return append(buf, `"}`...)
+	case slog.KindFloat64:
+		f := value.Float64()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			buf = append(buf, `{"doubleValue":"`...)
+			buf = strconv.AppendFloat(buf, f, 'g', -1, 64)
+			return append(buf, `"}`...)
+		}
+		buf = append(buf, `{"doubleValue":`...)
+		buf = strconv.AppendFloat(buf, f, 'g', -1, 64)
+		return append(buf, '}')
+	case slog.KindBool:
+		buf = append(buf, `{"boolValue":`...)
+		buf = strconv.AppendBool(buf, value.Bool())
+		return append(buf, '}')
+	case slog.KindDuration:
+		buf = append(buf, `{"intValue":"`...)
+		buf = strconv.AppendInt(buf, value.Duration().Nanoseconds(), 10)
+		return append(buf, `"}`...)
+	case slog.KindTime:
+		buf = append(buf, `{"stringValue":"`...)
+		buf = value.Time().AppendFormat(buf, time.RFC3339Nano)
+		return append(buf, `"}`...)
+	default:
+		if uv, ok := value.Any().(unitValue); ok {
+			return b.appendOTLPValue(buf, uv.number())
+		}
+		buf = append(buf, `{"stringValue":"`...)
+		if err, ok := value.Any().(error); ok {
+			buf = escape.AppendJSON(buf, err.Error())
+		} else {
+			// Other values are embedded as their JSON encoding.
+			pBuf := bufPool.Get().(*[]byte)
+			encoded := b.writeValue((*pBuf)[:0], value)
+			buf = escape.AppendJSON(buf, unsafe.String(unsafe.SliceData(encoded), len(encoded)))
+			if cap(encoded) <= maxPoolBufSize {
+				*pBuf = encoded
+				bufPool.Put(pBuf)
+			}
+		}
+		return append(buf, `"}`...)
+	}
+}
@@ -0,0 +1,229 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// attrFrame is one WithGroup level of a handler keeping records unencoded, with the WithAttrs attrs added in it.
+type attrFrame struct {
+	group string
+	attrs []slog.Attr
+}
+
+// attrFrames are the WithAttrs/WithGroup state of a handler keeping records unencoded,
+// frames[0] holds the top-level WithAttrs attrs, every WithGroup adds a frame.
+type attrFrames []attrFrame
+
+// fold nests the record attrs into the groups of the frames and adds the WithAttrs attrs of every level.
+func (f attrFrames) fold(record slog.Record) slog.Record {
+	if len(f) == 1 && len(f[0].attrs) == 0 {
+		return record
+	}
+
+	var attrs []slog.Attr
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	for i := len(f) - 1; i >= 0; i-- {
+		frame := f[i]
+		attrs = append(frame.attrs[:len(frame.attrs):len(frame.attrs)], attrs...)
+		if i > 0 && len(attrs) > 0 {
+			attrs = []slog.Attr{{Key: frame.group, Value: slog.GroupValue(attrs...)}}
+		}
+	}
+
+	r := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	r.AddAttrs(attrs...)
+	return r
+}
+
+func (f attrFrames) withAttrs(attrs []slog.Attr) attrFrames {
+	frames := append(attrFrames(nil), f...)
+	last := &frames[len(frames)-1]
+	last.attrs = append(last.attrs[:len(last.attrs):len(last.attrs)], attrs...)
+	return frames
+}
+
+func (f attrFrames) withGroup(name string) attrFrames {
+	return append(f[:len(f):len(f)], attrFrame{group: name})
+}
+
+type bufferedRecord struct {
+	record slog.Record
+	size   int
+}
+
+// bufferState is shared by a BufferHandler and its clones.
+type bufferState struct {
+	mu         sync.Mutex
+	maxRecords int
+	maxBytes   int
+	maxAge     time.Duration
+
+	records []bufferedRecord
+	size    int
+}
+
+// BufferHandler keeps the most recent records in memory, bounded by count, approximate size and age.
+// The records can be fetched or replayed into any handler on demand, e.g. CLIs printing logs only on failure
+// or bug reports embedding recent logs. WithAttrs/WithGroup state is folded into the kept records.
+type BufferHandler struct {
+	state  *bufferState
+	frames attrFrames
+}
+
+// NewBufferHandler keeps at most maxRecords records of at most maxBytes in total, 0 - no limit.
+// Sizes are estimated from the message, keys and values.
+func NewBufferHandler(maxRecords, maxBytes int) *BufferHandler {
+	return &BufferHandler{
+		state:  &bufferState{maxRecords: max(maxRecords, 0), maxBytes: max(maxBytes, 0)},
+		frames: attrFrames{{}},
+	}
+}
+
+// SetMaxAge drops records older than d (by record time), 0 - no limit.
+func (b *BufferHandler) SetMaxAge(d time.Duration) {
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	b.state.maxAge = max(d, 0)
+	b.state.expire(time.Now())
+}
+
+func (b *BufferHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (b *BufferHandler) Handle(ctx context.Context, record slog.Record) error {
+	record = CloneRecord(record)
+	// Ctx attrs are added like by Handler, in the current group.
+	if ctx != nil {
+		if attrs, _ := ctx.Value(AttrsKey).([]slog.Attr); len(attrs) > 0 {
+			record.AddAttrs(attrs...)
+		}
+	}
+	r := b.frames.fold(record)
+
+	s := b.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := bufferedRecord{record: r, size: recordSize(r)}
+	s.records = append(s.records, entry)
+	s.size += entry.size
+
+	for len(s.records) > 0 && (s.maxRecords > 0 && len(s.records) > s.maxRecords || s.maxBytes > 0 && s.size > s.maxBytes) {
+		s.dropOldest()
+	}
+	s.expire(time.Now())
+
+	return nil
+}
+
+func (s *bufferState) dropOldest() {
+	s.size -= s.records[0].size
+	s.records[0] = bufferedRecord{}
+	s.records = s.records[1:]
+}
+
+// expire drops the records older than maxAge.
+func (s *bufferState) expire(now time.Time) {
+	if s.maxAge == 0 {
+		return
+	}
+	for len(s.records) > 0 && now.Sub(s.records[0].record.Time) > s.maxAge {
+		s.dropOldest()
+	}
+}
+
+// recordSize estimates the encoded size of the record.
+func recordSize(r slog.Record) int {
+	// time, level and the separators
+	size := 48 + len(r.Message)
+	r.Attrs(func(attr slog.Attr) bool {
+		size += attrSize(attr)
+		return true
+	})
+	return size
+}
+
+func attrSize(attr slog.Attr) int {
+	size := len(attr.Key) + 4
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, a := range attr.Value.Group() {
+			size += attrSize(a)
+		}
+		return size
+	}
+	if attr.Value.Kind() == slog.KindString {
+		return size + len(attr.Value.String())
+	}
+	return size + 16
+}
+
+// Records returns copies of the kept records, oldest first.
+func (b *BufferHandler) Records() []slog.Record {
+	s := b.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+
+	records := make([]slog.Record, len(s.records))
+	for i, entry := range s.records {
+		records[i] = entry.record.Clone()
+	}
+	return records
+}
+
+// Replay passes the kept records to h in order, e.g. a text handler writing to stderr after a failure.
+// Records below the level of h are skipped.
+func (b *BufferHandler) Replay(ctx context.Context, h slog.Handler) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var errs []error
+	for _, record := range b.Records() {
+		if !h.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Reset drops the kept records.
+func (b *BufferHandler) Reset() {
+	s := b.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.records)
+	s.records = nil
+	s.size = 0
+}
+
+func (b *BufferHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return b
+	}
+
+	return &BufferHandler{state: b.state, frames: b.frames.withAttrs(attrs)}
+}
+
+func (b *BufferHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return b
+	}
+
+	return &BufferHandler{state: b.state, frames: b.frames.withGroup(name)}
+}
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBufferHandler(t *testing.T) {
+	b := NewBufferHandler(2, 0)
+	l := slog.New(b).With("a", 1).WithGroup("g").With("b", 2)
+
+	l.Info("dropped")
+	l.Info("kept", "c", 3)
+	slog.New(b).Warn("top")
+
+	records := b.Records()
+	if len(records) != 2 || records[0].Message != "kept" || records[1].Message != "top" {
+		t.Fatalf("records %v", records)
+	}
+
+	var direct, replayed bytes.Buffer
+	slog.New(NewJsonHandler(&direct, nil)).With("a", 1).WithGroup("g").With("b", 2).Info("kept", "c", 3)
+	if err := b.Replay(context.Background(), NewJsonHandler(&replayed, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The time differs, compare from the level on.
+	want := direct.String()[strings.Index(direct.String(), `"level"`):]
+	if got := strings.SplitAfter(replayed.String(), "\n")[0]; !strings.HasSuffix(got, want) {
+		t.Errorf("replayed %q, want suffix %q", got, want)
+	}
+}
+
+func TestBufferHandlerLimits(t *testing.T) {
+	b := NewBufferHandler(0, 100)
+	l := slog.New(b)
+	l.Info(strings.Repeat("x", 40))
+	l.Info(strings.Repeat("y", 40))
+	if n := len(b.Records()); n != 1 {
+		t.Errorf("%d records kept within 100 bytes, want 1", n)
+	}
+
+	old := slog.NewRecord(time.Now().Add(-time.Hour), slog.LevelInfo, "old", 0)
+	_ = b.Handle(context.Background(), old)
+	b.SetMaxAge(time.Minute)
+	if records := b.Records(); len(records) != 0 {
+		t.Errorf("records %v are kept after SetMaxAge", records)
+	}
+}
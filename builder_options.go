@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+)
+
+// TextOptions are the options of the text handler only, see NewHandler.
+type TextOptions struct {
+	// order of the line parts, omitted segments are not written, default - time, level, source, msg, attrs
+	Layout []TextSegment
+	// dim the attrs of a line with one escape sequence pair instead of coloring every key
+	CoalesceColors bool
+	// write true bools as bare flags ("retry" instead of "retry=true"), false stays "retry=false"
+	BareBoolFlags bool
+	// symbols written before levels, default - MarkersNone
+	LevelMarkers LevelMarkers
+	// color scheme, default - ThemeBright
+	Theme TextTheme
+}
+
+// JSONOptions are the options of the JSON handler only, see NewHandler.
+type JSONOptions struct {
+	// encoding of durations, default - DurationNanos
+	Durations DurationFormat
+	// write integers beyond ±(2^53-1) as strings, smaller integers stay numbers
+	QuoteBigInts bool
+}
+
+// BuilderOptions are the option structs of the builders, the type picks the builder of NewHandler.
+type BuilderOptions interface {
+	TextOptions | JSONOptions
+}
+
+// NewHandler creates the handler of the builder picked by the options type, e.g.
+// NewHandler(w, cfg, logger.JSONOptions{QuoteBigInts: true}). The options struct only has the fields
+// of its builder, a text option set on JSONOptions doesn't compile. The deprecated builder-specific
+// fields of Config still compile for any builder, NewHandler reports the ones of the other builder.
+//
+// The options replace these Config fields, cfg must not set the fields of the other builder.
+// Like NewJsonHandlerE, a nil writer or invalid config is reported.
+func NewHandler[O BuilderOptions](w io.Writer, cfg *Config, opts O) (*Handler, error) {
+	var c Config
+	if cfg != nil {
+		c = *cfg
+	}
+
+	switch o := any(opts).(type) {
+	case TextOptions:
+		if c.JSONDurations != DurationNanos || c.QuoteBigInts {
+			return nil, fmt.Errorf("%w: JSONDurations and QuoteBigInts are JSON options, the handler is text", ErrInvalidConfig)
+		}
+		c.TextLayout = o.Layout
+		c.CoalesceColors = o.CoalesceColors
+		c.BareBoolFlags = o.BareBoolFlags
+		c.LevelMarkers = o.LevelMarkers
+		c.textTheme = o.Theme
+		return NewTextHandlerE(w, &c)
+	case JSONOptions:
+		if len(c.TextLayout) > 0 || c.CoalesceColors || c.BareBoolFlags || c.LevelMarkers != MarkersNone {
+			return nil, fmt.Errorf("%w: TextLayout, CoalesceColors, BareBoolFlags and LevelMarkers are text options, the handler is JSON", ErrInvalidConfig)
+		}
+		c.JSONDurations = o.Durations
+		c.QuoteBigInts = o.QuoteBigInts
+		return NewJsonHandlerE(w, &c)
+	default:
+		// BuilderOptions lists every case.
+		panic(fmt.Sprintf("logger: unknown builder options %T", opts))
+	}
+}
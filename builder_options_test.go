@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewHandler(&buf, &Config{Level: int(slog.LevelInfo)}, JSONOptions{Durations: DurationString, QuoteBigInts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.New(h).Info("msg", "took", 1500*time.Millisecond, "id", int64(1)<<60)
+	if got := buf.String(); !strings.Contains(got, `"took":"1.5s","id":"1152921504606846976"}`) {
+		t.Errorf("json: %s", got)
+	}
+
+	buf.Reset()
+	h, err = NewHandler(&buf, nil, TextOptions{Layout: []TextSegment{SegmentMessage, SegmentAttrs}, CoalesceColors: true, BareBoolFlags: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.New(h).Info("msg", "retry", true)
+	if got := ansiRe.ReplaceAllString(buf.String(), ""); got != "msg retry\n" {
+		t.Errorf("text: %q", got)
+	}
+
+	// Options of the other builder left in the Config are reported.
+	if _, err = NewHandler(&buf, &Config{QuoteBigInts: true}, TextOptions{}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("text with JSON options: err = %v", err)
+	}
+	if _, err = NewHandler(&buf, &Config{BareBoolFlags: true}, JSONOptions{}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("JSON with text options: err = %v", err)
+	}
+}
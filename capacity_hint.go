@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"log/slog"
+	"math/bits"
+	"sync"
+)
+
+const (
+	// bytes of a record besides its attrs: time, level, source and message
+	hintRecordSize = 128
+	// bytes of an attr besides its value: key, separators and quotes
+	hintAttrSize = 24
+	// largest buffer of a hinted pool, larger hints are clamped
+	maxHintBufSize = 1 << 20
+)
+
+// bufferPool is a pool of record buffers of one size, shared by all handlers hinting that size.
+type bufferPool struct {
+	pool sync.Pool
+	// size is the capacity of new buffers, buffers grown beyond maxSize are not returned to the pool.
+	size    int
+	maxSize int
+}
+
+// bufferPools holds a *bufferPool per power of two size, so hints of similar records share buffers.
+var bufferPools sync.Map
+
+// hintedPool returns the pool for records of nAttrs attrs with values of avgValueLen bytes,
+// nil if the default pool fits them.
+func hintedPool(nAttrs, avgValueLen int) *bufferPool {
+	if nAttrs <= 0 || avgValueLen < 0 {
+		return nil
+	}
+
+	size := hintRecordSize + min(nAttrs, maxHintBufSize)*(min(avgValueLen, maxHintBufSize)+hintAttrSize)
+	if size <= basePoolBufferSize {
+		return nil
+	}
+	size = min(1<<bits.Len(uint(size-1)), maxHintBufSize)
+
+	if p, ok := bufferPools.Load(size); ok {
+		return p.(*bufferPool)
+	}
+
+	p := &bufferPool{size: size, maxSize: 2 * size}
+	p.pool.New = func() any {
+		b := make([]byte, 0, p.size)
+		return &b
+	}
+	actual, _ := bufferPools.LoadOrStore(size, p)
+	return actual.(*bufferPool)
+}
+
+// getBuf returns a buffer of the handler's pool, bufPool if there is no capacity hint.
+func (h *Handler) getBuf() *[]byte {
+	if h.pool != nil {
+		return h.pool.pool.Get().(*[]byte)
+	}
+	return bufPool.Get().(*[]byte)
+}
+
+// putBuf returns buf to the handler's pool unless it has grown too large.
+func (h *Handler) putBuf(pBuf *[]byte, buf []byte) {
+	if h.pool != nil {
+		if cap(buf) <= h.pool.maxSize {
+			*pBuf = buf
+			h.pool.pool.Put(pBuf)
+		}
+		return
+	}
+
+	if cap(buf) <= maxPoolBufSize {
+		*pBuf = buf
+		bufPool.Put(pBuf)
+	}
+}
+
+// WithCapacityHint returns a handler encoding its records into buffers sized for nAttrs attrs with values
+// of avgValueLen bytes. Records larger than the default pool buffers grow them past the pool cap, so such
+// buffers are dropped after every record; a hinted pool keeps them. The output is not changed.
+// The hint replaces the previous one, a hint fitting the default buffers (or nAttrs <= 0) removes it.
+func (h *Handler) WithCapacityHint(nAttrs, avgValueLen int) slog.Handler {
+	pool := hintedPool(nAttrs, avgValueLen)
+	if pool == h.pool {
+		return h
+	}
+
+	h2 := h.clone()
+	h2.pool = pool
+	return h2
+}
+
+// WithCapacityHint applies the hint to every handler.
+func (m *MultiHandler) WithCapacityHint(nAttrs, avgValueLen int) slog.Handler {
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = withCapacityHint(h, nAttrs, avgValueLen)
+	}
+
+	return &MultiHandler{handlers: handlers, closers: m.closers, source: m.source}
+}
+
+// WithCapacityHint applies the hint to the wrapped handler.
+func (a *AsyncHandler) WithCapacityHint(nAttrs, avgValueLen int) slog.Handler {
+	return &AsyncHandler{handler: withCapacityHint(a.handler, nAttrs, avgValueLen), queue: a.queue}
+}
+
+// WithCapacityHint applies the hint to the wrapped handler.
+func (s *SamplingHandler) WithCapacityHint(nAttrs, avgValueLen int) slog.Handler {
+	return &SamplingHandler{handler: withCapacityHint(s.handler, nAttrs, avgValueLen), state: s.state}
+}
+
+// WithCapacityHint applies the hint to the handler of every format.
+func (t *TeeHandler) WithCapacityHint(nAttrs, avgValueLen int) slog.Handler {
+	t2 := &TeeHandler{encoders: make([]teeEncoder, len(t.encoders))}
+	for i, enc := range t.encoders {
+		t2.encoders[i] = teeEncoder{handler: enc.handler.WithCapacityHint(nAttrs, avgValueLen).(*Handler), children: enc.children}
+	}
+	return t2
+}
+
+// withCapacityHint leaves handlers without hint support as they are, the hint doesn't change the output.
+func withCapacityHint(h slog.Handler, nAttrs, avgValueLen int) slog.Handler {
+	if c, ok := h.(interface {
+		WithCapacityHint(nAttrs, avgValueLen int) slog.Handler
+	}); ok {
+		return c.WithCapacityHint(nAttrs, avgValueLen)
+	}
+	return h
+}
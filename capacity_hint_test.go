@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWithCapacityHint(t *testing.T) {
+	var plain, hinted bytes.Buffer
+	// The constant time layout keeps the records comparable.
+	cfg := &Config{TimeFormat: "-"}
+	plainLog := New(NewJsonHandler(&plain, cfg)).With("svc", "api")
+	hintedLog := New(NewJsonHandler(&hinted, cfg)).With("svc", "api").WithCapacityHint(30, 64)
+
+	args := make([]any, 0, 60)
+	for i := range 30 {
+		args = append(args, "key"+strconv.Itoa(i), strings.Repeat("v", 64))
+	}
+	plainLog.Info("big", args...)
+	hintedLog.Info("big", args...)
+
+	if plain.String() != hinted.String() {
+		t.Errorf("the hint changed the output:\n%s\n%s", plain.String(), hinted.String())
+	}
+
+	h := hintedLog.Handler().(*Handler)
+	if h.pool == nil {
+		t.Fatal("hinted handler uses the default pool")
+	}
+	// 128 + 30*(64+24) bytes rounded up to 4KiB.
+	if h.pool.size != 4096 {
+		t.Errorf("pool size %d, want 4096", h.pool.size)
+	}
+	if pBuf := h.getBuf(); cap(*pBuf) < 4096 {
+		t.Errorf("hinted buffer capacity %d", cap(*pBuf))
+	}
+
+	if h2 := New(h).WithCapacityHint(28, 70).Handler().(*Handler); h2.pool != h.pool {
+		t.Error("hints of the same size class don't share the pool")
+	}
+	if h2 := New(h).WithCapacityHint(3, 10).Handler().(*Handler); h2.pool != nil {
+		t.Error("small hint keeps the hinted pool")
+	}
+	if h2 := New(h).WithCapacityHint(1<<30, 1<<30).Handler().(*Handler); h2.pool.size != maxHintBufSize {
+		t.Errorf("huge hint pool size %d", h2.pool.size)
+	}
+
+	// Handlers without hint support are kept.
+	nop := New(nopHandler{})
+	if nop.WithCapacityHint(30, 64).Handler() != slog.Handler(nopHandler{}) {
+		t.Error("unsupported handler is wrapped")
+	}
+}
@@ -0,0 +1,227 @@
+package logger
+
+import (
+	"container/list"
+	"encoding/binary"
+	"hash/maphash"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+)
+
+// cloneKey identifies a WithGroup/WithAttrs call on a handler with the given state,
+// every field of Handler changed by WithGroup, WithAttrs, WithPrefix or WithCapacityHint is a part of it.
+type cloneKey struct {
+	groupPrefix       string
+	precomputed       string
+	precomputedGroups string
+	prefix            string
+	pool              *bufferPool
+
+	groupDepth      int
+	groupsFlattened bool
+	// slices holds the groups, devKeys and schemaAttrs of the handler, see appendSliceState.
+	slices string
+
+	// group is set for WithGroup calls, fingerprint for WithAttrs calls.
+	group       string
+	fingerprint uint64
+}
+
+type cloneEntry struct {
+	key cloneKey
+	// attrs are compared on lookup to protect against fingerprint collisions.
+	attrs   []slog.Attr
+	handler *Handler
+}
+
+// cloneCache is a small bounded LRU of handler clones shared by all clones of a handler.
+type cloneCache struct {
+	mu    sync.Mutex
+	size  int
+	seed  maphash.Seed
+	lru   *list.List
+	items map[cloneKey]*list.Element
+}
+
+func newCloneCache(size int) *cloneCache {
+	return &cloneCache{
+		size:  size,
+		seed:  maphash.MakeSeed(),
+		lru:   list.New(),
+		items: make(map[cloneKey]*list.Element, size),
+	}
+}
+
+func (h *Handler) cloneKey(group string, fingerprint uint64) cloneKey {
+	key := cloneKey{
+		groupPrefix:       h.groupPrefix,
+		precomputed:       h.precomputed,
+		precomputedGroups: h.precomputedGroups,
+		prefix:            h.prefix,
+		pool:              h.pool,
+		groupDepth:        h.groupDepth,
+		groupsFlattened:   h.groupsFlattened,
+		group:             group,
+		fingerprint:       fingerprint,
+	}
+
+	// The slices are tracked only for ReplaceAttr, Schema and DevChecks, other handlers have none.
+	if len(h.groups) > 0 || len(h.devKeys) > 0 || len(h.schemaAttrs) > 0 {
+		var buf [128]byte
+		key.slices = string(h.appendSliceState(buf[:0]))
+	}
+
+	return key
+}
+
+// appendSliceState appends the groups, devKeys and schemaAttrs, every string is prefixed by its length,
+// so "a.b" and "a", "b" groups differ.
+func (h *Handler) appendSliceState(buf []byte) []byte {
+	appendStrings := func(buf []byte, values []string) []byte {
+		buf = binary.AppendUvarint(buf, uint64(len(values)))
+		for _, v := range values {
+			buf = binary.AppendUvarint(buf, uint64(len(v)))
+			buf = append(buf, v...)
+		}
+		return buf
+	}
+
+	buf = appendStrings(buf, h.groups)
+	buf = appendStrings(buf, h.devKeys)
+
+	buf = binary.AppendUvarint(buf, uint64(len(h.schemaAttrs)))
+	for _, attr := range h.schemaAttrs {
+		buf = binary.AppendUvarint(buf, uint64(len(attr.path)))
+		buf = append(buf, attr.path...)
+		buf = append(buf, byte(attr.kind))
+	}
+
+	return buf
+}
+
+// get returns the cached clone for key or nil.
+func (c *cloneCache) get(key cloneKey, attrs []slog.Attr) *Handler {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+
+	entry := el.Value.(*cloneEntry)
+	if !slices.EqualFunc(entry.attrs, attrs, sameAttr) {
+		return nil
+	}
+
+	c.lru.MoveToFront(el)
+	return entry.handler
+}
+
+// sameAttr is slog.Attr.Equal that also compares the zones of times, Equal compares only the instants
+// and the clone renders the time in the zone it was built with.
+func sameAttr(a, b slog.Attr) bool {
+	if !a.Equal(b) {
+		return false
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindTime:
+		aName, aOffset := a.Value.Time().Zone()
+		bName, bOffset := b.Value.Time().Zone()
+		return aName == bName && aOffset == bOffset
+	case slog.KindGroup:
+		return slices.EqualFunc(a.Value.Group(), b.Value.Group(), sameAttr)
+	}
+	return true
+}
+
+// put stores the clone, evicting the least recently used one if the cache is full.
+func (c *cloneCache) put(key cloneKey, attrs []slog.Attr, h *Handler) {
+	entry := &cloneEntry{
+		key:     key,
+		attrs:   slices.Clone(attrs),
+		handler: h,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(entry)
+
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*cloneEntry).key)
+	}
+}
+
+// fingerprint hashes attrs, it reports false for attrs whose value may change between calls (Any, LogValuer).
+func (c *cloneCache) fingerprint(attrs []slog.Attr) (uint64, bool) {
+	var h maphash.Hash
+	h.SetSeed(c.seed)
+
+	if !hashAttrs(&h, attrs) {
+		return 0, false
+	}
+
+	return h.Sum64(), true
+}
+
+func hashAttrs(h *maphash.Hash, attrs []slog.Attr) bool {
+	var num [8]byte
+
+	for _, attr := range attrs {
+		_, _ = h.WriteString(attr.Key)
+		_ = h.WriteByte(byte(attr.Value.Kind()))
+
+		switch attr.Value.Kind() {
+		case slog.KindString:
+			_, _ = h.WriteString(attr.Value.String())
+			continue
+		case slog.KindInt64:
+			binary.LittleEndian.PutUint64(num[:], uint64(attr.Value.Int64()))
+		case slog.KindUint64:
+			binary.LittleEndian.PutUint64(num[:], attr.Value.Uint64())
+		case slog.KindFloat64:
+			binary.LittleEndian.PutUint64(num[:], math.Float64bits(attr.Value.Float64()))
+		case slog.KindBool:
+			if attr.Value.Bool() {
+				num = [8]byte{1}
+			} else {
+				num = [8]byte{}
+			}
+		case slog.KindDuration:
+			binary.LittleEndian.PutUint64(num[:], uint64(attr.Value.Duration()))
+		case slog.KindTime:
+			// The zone is a part of the rendered time.
+			name, offset := attr.Value.Time().Zone()
+			_, _ = h.WriteString(name)
+			binary.LittleEndian.PutUint64(num[:], uint64(offset))
+			_, _ = h.Write(num[:])
+			binary.LittleEndian.PutUint64(num[:], uint64(attr.Value.Time().UnixNano()))
+		case slog.KindGroup:
+			group := attr.Value.Group()
+			binary.LittleEndian.PutUint64(num[:], uint64(len(group)))
+			_, _ = h.Write(num[:])
+			if !hashAttrs(h, group) {
+				return false
+			}
+			continue
+		default:
+			return false
+		}
+
+		_, _ = h.Write(num[:])
+	}
+
+	return true
+}
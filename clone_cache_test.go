@@ -0,0 +1,147 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// groupsRecorder is a ReplaceAttr recording the groups of every attr by its key.
+type groupsRecorder map[string][]string
+
+func (r groupsRecorder) replace(groups []string, attr slog.Attr) slog.Attr {
+	r[attr.Key] = slices.Clone(groups)
+	return attr
+}
+
+func TestCloneCacheGroupPaths(t *testing.T) {
+	paths := groupsRecorder{}
+	h := NewTextHandler(io.Discard, &Config{CloneCacheSize: 16, ReplaceAttr: paths.replace})
+
+	// Both text handlers have the "a.b." prefix, the groups of the attrs differ.
+	slog.New(h.WithGroup("a").WithGroup("b").WithGroup("c")).Info("m", "split", 1)
+	slog.New(h.WithGroup("a.b").WithGroup("c")).Info("m", "dotted", 1)
+
+	if got := strings.Join(paths["split"], "|"); got != "a|b|c" {
+		t.Errorf("split groups %q", got)
+	}
+	if got := strings.Join(paths["dotted"], "|"); got != "a.b|c" {
+		t.Errorf("dotted groups %q", got)
+	}
+}
+
+// cloneStep is a WithGroup (group set) or WithAttrs call.
+type cloneStep struct {
+	group string
+	attrs []slog.Attr
+}
+
+func applySteps(h slog.Handler, steps []cloneStep) slog.Handler {
+	for _, step := range steps {
+		if step.group != "" {
+			h = h.WithGroup(step.group)
+		} else {
+			h = h.WithAttrs(step.attrs)
+		}
+	}
+	return h
+}
+
+func TestCloneCache(t *testing.T) {
+	attrs := func(args ...any) cloneStep {
+		r := slog.NewRecord(time.Time{}, 0, "", 0)
+		r.Add(args...)
+		var list []slog.Attr
+		r.Attrs(func(a slog.Attr) bool { list = append(list, a); return true })
+		return cloneStep{attrs: list}
+	}
+	group := func(name string) cloneStep { return cloneStep{group: name} }
+
+	chains := [][]cloneStep{
+		{group("a"), attrs("x", 1), group("b")},
+		{attrs("x", 1), group("a"), group("b")},
+		{group("a"), group("b"), attrs("x", 1)},
+		{group("a.b"), attrs("x", 1)},
+		{group("a"), attrs("x", 2), attrs("y", "z")},
+		{attrs("y", "z"), attrs("x", 2), group("a")},
+	}
+
+	for _, format := range []string{"json", "text"} {
+		newHandler := NewJsonHandler
+		if format == "text" {
+			newHandler = NewTextHandler
+		}
+
+		render := func(cacheSize int) (string, groupsRecorder) {
+			var buf bytes.Buffer
+			paths := groupsRecorder{}
+			// The constant time layout keeps the records comparable.
+			h := newHandler(&buf, &Config{TimeFormat: "-", CloneCacheSize: cacheSize, ReplaceAttr: paths.replace})
+
+			// The chains run twice, so the second run is served by the cache.
+			for run := range 2 {
+				for i, chain := range chains {
+					slog.New(applySteps(h, chain)).Info("m", "leaf", fmt.Sprint(run, i))
+				}
+			}
+			return buf.String(), paths
+		}
+
+		cached, cachedPaths := render(64)
+		uncached, uncachedPaths := render(0)
+
+		if cached != uncached {
+			t.Errorf("%s: cached output differs:\n%s\nuncached:\n%s", format, cached, uncached)
+		}
+		if fmt.Sprint(cachedPaths) != fmt.Sprint(uncachedPaths) {
+			t.Errorf("%s: cached ReplaceAttr paths %v, uncached %v", format, cachedPaths, uncachedPaths)
+		}
+	}
+}
+
+func TestCloneCacheReuse(t *testing.T) {
+	h := NewJsonHandler(io.Discard, &Config{CloneCacheSize: 2})
+
+	if h.WithGroup("a") != h.WithGroup("a") {
+		t.Error("WithGroup clone is not reused")
+	}
+	if h.WithAttrs([]slog.Attr{slog.Int("x", 1)}) != h.WithAttrs([]slog.Attr{slog.Int("x", 1)}) {
+		t.Error("WithAttrs clone is not reused")
+	}
+	if h.WithAttrs([]slog.Attr{slog.Int("x", 1)}) == h.WithAttrs([]slog.Attr{slog.Int("x", 2)}) {
+		t.Error("clone of other attrs is reused")
+	}
+	// Any values may change between calls, they are never cached.
+	if h.WithAttrs([]slog.Attr{slog.Any("v", []int{1})}) == h.WithAttrs([]slog.Attr{slog.Any("v", []int{1})}) {
+		t.Error("clone of Any attrs is reused")
+	}
+
+	// The cache holds 2 clones, the least recently used one is evicted.
+	first := h.WithGroup("a")
+	h.WithGroup("b")
+	h.WithGroup("c")
+	if h.WithGroup("a") == first {
+		t.Error("evicted clone is reused")
+	}
+}
+
+func TestCloneCacheTimeZones(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewJsonHandler(&buf, &Config{CloneCacheSize: 16})
+
+	instant := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, loc := range []*time.Location{time.UTC, time.FixedZone("CET", 3600)} {
+		slog.New(h.WithAttrs([]slog.Attr{slog.Time("at", instant.In(loc))})).Info("m")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"at":"2024-01-02 03:04:05"`) ||
+		!strings.Contains(lines[1], `"at":"2024-01-02 04:04:05"`) {
+		t.Errorf("output %s", buf.String())
+	}
+}
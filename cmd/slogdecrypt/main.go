@@ -0,0 +1,94 @@
+// Command slogdecrypt decrypts logs written through logger.EncryptingWriter.
+//
+// Usage:
+//
+//	slogdecrypt -key-file master.key [file ...]
+//	SLOG_KEY=<hex> slogdecrypt < app.log.enc | slogfmt
+//
+// The master key is 32 bytes in hex, read from -key-file or the SLOG_KEY environment variable.
+// Without files the log is read from stdin, the plaintext is written to stdout.
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	logger "github.com/ttrtcixy/fast-slog-handler"
+)
+
+const keyEnv = "SLOG_KEY"
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "slogdecrypt:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("slogdecrypt", flag.ContinueOnError)
+	keyFile := flags.String("key-file", "", "file with the hex encoded master key (default $"+keyEnv+")")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	key, err := readKey(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return decrypt(stdin, stdout, key)
+	}
+
+	for _, name := range flags.Args() {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+
+		err = decrypt(file, stdout, key)
+		_ = file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func readKey(keyFile string) ([]byte, error) {
+	encoded := os.Getenv(keyEnv)
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(data)
+	}
+
+	if encoded == "" {
+		return nil, errors.New("no key, use -key-file or $" + keyEnv)
+	}
+
+	return hex.DecodeString(strings.TrimSpace(encoded))
+}
+
+func decrypt(r io.Reader, w io.Writer, key []byte) error {
+	dr, err := logger.NewDecryptingReader(r, key)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(w, dr); errors.Is(err, io.ErrUnexpectedEOF) {
+		return errors.New("the last frame is incomplete, the writer probably crashed")
+	}
+
+	return err
+}
@@ -0,0 +1,301 @@
+// Command slogfmt re-renders NDJSON produced by the JSON handler with the colorized text handler.
+//
+// Usage:
+//
+//	slogfmt [-f] [--level WARN] [--since 15m] [--key request_id=af82] [file ...]
+//
+// Without files the logs are read from stdin until it is closed, so piping a following
+// producer (kubectl logs -f app | slogfmt) works as is. With -f the files are followed
+// like tail -f, truncated files are read again from the start.
+// Lines that are not JSON records are printed as is.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	logger "github.com/ttrtcixy/fast-slog-handler"
+)
+
+const (
+	// max size of a single log line, longer lines are split
+	maxLineSize = 1024 * 1024
+	// how often a followed file is checked for new data
+	pollInterval = 250 * time.Millisecond
+)
+
+// keyFilters collects repeated --key=value flags.
+type keyFilters map[string]string
+
+func (f keyFilters) String() string {
+	pairs := make([]string, 0, len(f))
+	for k, v := range f {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f keyFilters) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return errors.New("filter must be in key=value form")
+	}
+	f[key] = value
+	return nil
+}
+
+type filter struct {
+	level slog.Level
+	since time.Time
+	keys  keyFilters
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "slogfmt:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("slogfmt", flag.ContinueOnError)
+
+	f := filter{keys: keyFilters{}}
+
+	level := flags.String("level", "TRACE", "minimum level to print (TRACE, DEBUG, INFO, WARN, ERROR)")
+	since := flags.String("since", "", `print records newer than a duration ago ("15m") or a time ("2006-01-02 15:04:05")`)
+	flags.Var(f.keys, "key", "print records where group.key equals value (key=value, repeatable)")
+	follow := flags.Bool("f", false, "follow the files, waiting for new records at the end")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var err error
+	if f.level, err = logger.ParseLevelName(*level); err != nil {
+		return fmt.Errorf("--level: %w", err)
+	}
+
+	if *since != "" {
+		if f.since, err = parseSince(*since); err != nil {
+			return fmt.Errorf("--since: %w", err)
+		}
+	}
+
+	handler := logger.NewTextHandler(stdout, &logger.Config{Level: int(f.level)})
+
+	if flags.NArg() == 0 {
+		return render(stdin, nil, stdout, handler, f)
+	}
+
+	if !*follow {
+		for _, name := range flags.Args() {
+			if err := renderFile(name, false, stdout, handler, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Followed files are rendered concurrently, the handler writes whole records so lines don't mix.
+	errs := make(chan error, flags.NArg())
+	for _, name := range flags.Args() {
+		go func() {
+			errs <- renderFile(name, true, stdout, handler, f)
+		}()
+	}
+
+	for range flags.NArg() {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func renderFile(name string, follow bool, w io.Writer, handler slog.Handler, f filter) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var tail *os.File
+	if follow {
+		tail = file
+	}
+
+	if err = render(file, tail, w, handler, f); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	return nil
+}
+
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	if t, err := time.ParseInLocation(time.DateTime, s, time.Local); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
+
+// render prints every line of r, if follow is set r is the followed file and EOF waits for more data.
+func render(r io.Reader, follow *os.File, w io.Writer, handler slog.Handler, f filter) error {
+	return readLines(r, follow, func(line []byte) error {
+		record, err := logger.DecodeJSONLine(line)
+		if err != nil {
+			// Not a record of the JSON handler, keep it visible.
+			_, err = fmt.Fprintf(w, "%s\n", line)
+			return err
+		}
+
+		if !f.match(record) {
+			return nil
+		}
+
+		return handler.Handle(context.Background(), record)
+	})
+}
+
+// readLines calls fn for every complete line. A partially written line is kept until its newline arrives
+// when following, otherwise it is passed to fn at EOF.
+func readLines(r io.Reader, follow *os.File, fn func(line []byte) error) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+
+	var line []byte
+	for {
+		chunk, err := br.ReadSlice('\n')
+		line = append(line, chunk...)
+
+		switch {
+		case err == nil:
+			line = bytes.TrimSuffix(line[:len(line)-1], []byte{'\r'})
+			if err = fn(line); err != nil {
+				return err
+			}
+			line = line[:0]
+		case errors.Is(err, bufio.ErrBufferFull):
+			if len(line) >= maxLineSize {
+				if err = fn(line); err != nil {
+					return err
+				}
+				line = line[:0]
+			}
+		case errors.Is(err, io.EOF):
+			if follow == nil {
+				if len(line) > 0 {
+					return fn(line)
+				}
+				return nil
+			}
+
+			time.Sleep(pollInterval)
+
+			if err = reopenTruncated(follow, br); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+}
+
+// reopenTruncated starts reading from the beginning if the file was truncated (e.g. by copytruncate rotation).
+func reopenTruncated(file *os.File, br *bufio.Reader) error {
+	pos, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() >= pos {
+		return nil
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	br.Reset(file)
+
+	return nil
+}
+
+func (f filter) match(record slog.Record) bool {
+	if record.Level < f.level {
+		return false
+	}
+
+	if !f.since.IsZero() && record.Time.Before(f.since) {
+		return false
+	}
+
+	for key, want := range f.keys {
+		value, ok := lookup(record, key)
+		if !ok || value.String() != want {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lookup finds the attr by its dot separated path (group.key).
+func lookup(record slog.Record, path string) (slog.Value, bool) {
+	var (
+		value slog.Value
+		found bool
+	)
+
+	record.Attrs(func(attr slog.Attr) bool {
+		value, found = lookupAttr(attr, path)
+		return !found
+	})
+
+	return value, found
+}
+
+func lookupAttr(attr slog.Attr, path string) (slog.Value, bool) {
+	if attr.Key == path {
+		return attr.Value, true
+	}
+
+	if attr.Value.Kind() != slog.KindGroup {
+		return slog.Value{}, false
+	}
+
+	// Members of inline groups are matched without a prefix.
+	rest := path
+	if attr.Key != "" {
+		var ok bool
+		if rest, ok = strings.CutPrefix(path, attr.Key+"."); !ok {
+			return slog.Value{}, false
+		}
+	}
+
+	for _, member := range attr.Value.Group() {
+		if value, ok := lookupAttr(member, rest); ok {
+			return value, true
+		}
+	}
+
+	return slog.Value{}, false
+}
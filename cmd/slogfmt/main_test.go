@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	logger "github.com/ttrtcixy/fast-slog-handler"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// checkGolden compares got with testdata/name.golden, -update rewrites the file.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s: got\n%s\nwant\n%s", name, got, want)
+	}
+}
+
+func TestRunGolden(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "records.ndjson"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"all", nil},
+		{"level", []string{"--level", "WARN"}},
+		{"key", []string{"--key", "req.id=af82"}},
+		{"keys", []string{"--key", "req.id=af82", "--key", "status=200"}},
+		{"since", []string{"--since", "2026-10-14 12:00:03"}},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := run(tt.args, bytes.NewReader(input), &out); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		checkGolden(t, tt.name, out.Bytes())
+	}
+
+	// Files are rendered like stdin, one after the other.
+	var out bytes.Buffer
+	name := filepath.Join("testdata", "records.ndjson")
+	if err := run([]string{"--level", "WARN", name, name}, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	level, _ := os.ReadFile(filepath.Join("testdata", "level.golden"))
+	if !*update && out.String() != strings.Repeat(string(level), 2) {
+		t.Errorf("files: got\n%s", out.String())
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"--level", "LOUD"},
+		{"--since", "yesterday"},
+		{"--key", "novalue"},
+		{"--key", "=x"},
+		{"testdata/missing.ndjson"},
+	} {
+		if err := run(args, strings.NewReader(""), &bytes.Buffer{}); err == nil {
+			t.Errorf("%q: no error", args)
+		}
+	}
+}
+
+func TestRunPartialLines(t *testing.T) {
+	// CRLF line ends are dropped, the last line without a newline is rendered at EOF.
+	input, err := os.ReadFile(filepath.Join("testdata", "partial.ndjson"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := run(nil, bytes.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "partial", out.Bytes())
+}
+
+func TestReadLinesLong(t *testing.T) {
+	long := strings.Repeat("x", maxLineSize+10)
+
+	var lines []int
+	err := readLines(strings.NewReader(long+"\nend\n"), nil, func(line []byte) error {
+		lines = append(lines, len(line))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The long line is split once it reaches maxLineSize, a chunk of the reader buffer at a time.
+	if total := lines[0] + lines[1]; len(lines) != 3 || lines[0] < maxLineSize || total != len(long) || lines[2] != 3 {
+		t.Errorf("line lengths %v", lines)
+	}
+}
+
+// errStop ends a followed render once the expected lines are written.
+var errStop = errors.New("stop")
+
+// stopWriter returns errStop from the write of the last line.
+type stopWriter struct {
+	bytes.Buffer
+	last string
+}
+
+func (w *stopWriter) Write(p []byte) (int, error) {
+	n, _ := w.Buffer.Write(p)
+	if string(p) == w.last {
+		return n, errStop
+	}
+	return n, nil
+}
+
+func TestRenderFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	records, err := os.ReadFile(filepath.Join("testdata", "records.ndjson"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, rest, _ := bytes.Cut(records, []byte("\n"))
+	if err = os.WriteFile(path, append(first, '\n'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	go func() {
+		// Half of a line first: it waits for its newline.
+		appendFile(t, path, rest[:10])
+		time.Sleep(2 * pollInterval)
+		appendFile(t, path, rest[10:])
+		time.Sleep(2 * pollInterval)
+
+		// A copytruncate rotation: the file is read again from the start.
+		if err := os.WriteFile(path, []byte("rotated\n"), 0o644); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	out := &stopWriter{last: "rotated\n"}
+	handler := logger.NewTextHandler(out, &logger.Config{Level: int(logger.LevelTrace)})
+	if err = render(file, file, out, handler, filter{level: logger.LevelTrace, keys: keyFilters{}}); !errors.Is(err, errStop) {
+		t.Fatal(err)
+	}
+	checkGolden(t, "follow", out.Bytes())
+}
+
+func appendFile(t *testing.T, path string, data []byte) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer file.Close()
+
+	if _, err = file.Write(data); err != nil {
+		t.Error(err)
+	}
+}
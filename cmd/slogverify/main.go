@@ -0,0 +1,56 @@
+// Command slogverify checks the hash chain of logs written with Config.HashChain.
+//
+// Usage:
+//
+//	slogverify [file ...]
+//
+// Without files the log is read from stdin. Every file is verified separately,
+// the exit code is 1 if any of them is broken. The lines where chains start are printed,
+// records cut right before a chain start are only found by comparing them with the restarts.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	logger "github.com/ttrtcixy/fast-slog-handler"
+)
+
+func main() {
+	if !run(os.Args[1:], os.Stdin, os.Stdout) {
+		os.Exit(1)
+	}
+}
+
+func run(files []string, stdin io.Reader, stdout io.Writer) bool {
+	if len(files) == 0 {
+		return verify("stdin", stdin, stdout)
+	}
+
+	ok := true
+	for _, name := range files {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(stdout, "slogverify:", err)
+			ok = false
+			continue
+		}
+
+		ok = verify(name, file, stdout) && ok
+		_ = file.Close()
+	}
+
+	return ok
+}
+
+func verify(name string, r io.Reader, stdout io.Writer) bool {
+	report, err := logger.VerifyHashChain(r)
+	if err != nil {
+		fmt.Fprintf(stdout, "%s: FAIL after %d records: %v\n", name, report.Records, err)
+		return false
+	}
+
+	fmt.Fprintf(stdout, "%s: OK, %d records in %d chains starting at lines %v\n", name, report.Records, report.Chains, report.Starts)
+	return true
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"cmp"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Compression is the format of a CompressedWriter.
+// zstd is not available, the package depends on the standard library only.
+type Compression int
+
+const (
+	Gzip Compression = iota
+	Zlib
+)
+
+// compressor is implemented by gzip.Writer and zlib.Writer.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// CompressedWriter is a streaming compressing writer for high volume local logs.
+// It flushes the compressor itself flushEvery after the first write following a flush, so written data
+// becomes readable in w without a buffered handler; less frequent flushes give a better compression ratio.
+// Close must be called to write the stream trailer.
+type CompressedWriter struct {
+	mu sync.Mutex
+
+	zw         compressor
+	flushEvery time.Duration
+	lastFlush  time.Time
+	// dirty is set when data was written after the last flush, timer is the pending flush of that data.
+	dirty  bool
+	timer  *time.Timer
+	closed bool
+}
+
+// NewCompressedWriter creates a writer compressing into w, flushEvery is the delay of the flushes, 0 - 250ms.
+func NewCompressedWriter(w io.Writer, compression Compression, flushEvery time.Duration) (*CompressedWriter, error) {
+	if w == nil {
+		return nil, ErrNilWriter
+	}
+
+	if flushEvery < 0 {
+		return nil, fmt.Errorf("%w: flushEvery must not be negative, got %s", ErrInvalidConfig, flushEvery)
+	}
+
+	cw := &CompressedWriter{flushEvery: cmp.Or(flushEvery, flushTime), lastFlush: time.Now()}
+
+	switch compression {
+	case Gzip:
+		cw.zw = gzip.NewWriter(w)
+	case Zlib:
+		cw.zw = zlib.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("%w: unknown compression %d", ErrInvalidConfig, compression)
+	}
+
+	return cw, nil
+}
+
+func (cw *CompressedWriter) Write(p []byte) (int, error) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	if cw.closed {
+		return 0, ErrAlreadyClosed
+	}
+
+	if !cw.dirty {
+		cw.dirty = true
+		if cw.timer == nil {
+			cw.timer = time.AfterFunc(cw.flushEvery, cw.flushPending)
+		} else {
+			cw.timer.Reset(cw.flushEvery)
+		}
+	}
+	return cw.zw.Write(p)
+}
+
+// flushPending is the timer flush of the data written after the last flush.
+func (cw *CompressedWriter) flushPending() {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	if !cw.dirty || cw.closed {
+		return
+	}
+
+	cw.dirty = false
+	cw.lastFlush = time.Now()
+	// The error is returned by the next Write, the compressor keeps it.
+	_ = cw.zw.Flush()
+}
+
+// Flush writes the compressed data to the underlying writer if flushEvery has passed since the last flush,
+// the flusher of a buffered handler calls it every 250ms.
+func (cw *CompressedWriter) Flush() error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	if !cw.dirty || cw.closed || time.Since(cw.lastFlush) < cw.flushEvery {
+		return nil
+	}
+
+	cw.dirty = false
+	cw.lastFlush = time.Now()
+	return cw.zw.Flush()
+}
+
+// Close flushes the data and writes the stream trailer, the underlying writer is not closed.
+func (cw *CompressedWriter) Close() error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	if cw.closed {
+		return ErrAlreadyClosed
+	}
+	cw.closed = true
+
+	if cw.timer != nil {
+		cw.timer.Stop()
+	}
+	cw.dirty = false
+	return cw.zw.Close()
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer written by the flush timer and read by the test.
+type syncBuffer struct {
+	buf *bytes.Buffer
+	mu  *sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func TestCompressedWriter(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+
+	cw, err := NewCompressedWriter(&syncBuffer{buf: &out, mu: &mu}, Gzip, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The writer doesn't make the handler buffered.
+	h := NewJsonHandler(cw, nil)
+	if err = h.Close(context.Background()); !errors.Is(err, ErrNothingToClose) {
+		t.Errorf("Close of an unbuffered handler = %v", err)
+	}
+
+	h = NewJsonHandler(cw, nil)
+	slog.New(h).Info("compressed", "n", 1)
+
+	// The writer flushes the compressor itself, the stream is readable before cw.Close.
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		compressed := bytes.Clone(out.Bytes())
+		mu.Unlock()
+
+		zr, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err == nil {
+			// The stream has no trailer yet, the data before it is read with an unexpected EOF.
+			plain, _ := io.ReadAll(zr)
+			if strings.Contains(string(plain), `"msg":"compressed"`) {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("compressed data not flushed: %q", compressed)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err = cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cw.Write([]byte("late\n")); !errors.Is(err, ErrAlreadyClosed) {
+		t.Errorf("Write after Close = %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// DurationFormat is the JSON encoding of duration values.
+type DurationFormat int
+
+const (
+	// "latency":15000000 (nanoseconds)
+	DurationNanos DurationFormat = iota
+	// "latency":"15ms"
+	DurationString
+	// "latency_ns":15000000,"latency":"15ms"
+	DurationBoth
+)
+
+// TextSegment is a part of a text handler line, see Config.TextLayout.
+type TextSegment int
+
+const (
+	SegmentTime TextSegment = iota + 1
+	SegmentLevel
+	// call site, written only with Config.AddSource
+	SegmentSource
+	SegmentMessage
+	// attrs of the record and WithAttrs, wrapped in [] unless they are the last segment
+	SegmentAttrs
+)
+
+// defaultTextLayout is the text line layout used when Config.TextLayout is empty.
+var defaultTextLayout = []TextSegment{SegmentTime, SegmentLevel, SegmentSource, SegmentMessage, SegmentAttrs}
+
+type Config struct {
+	// logger level
+	Level int
+	// write an INFO record with the old and new level on every SetLevel call that changes it
+	AuditLevelChanges bool
+	// start buffered output to minimize count of syscall, buff size - 4096
+	BufferedOutput bool
+	// cache the encoded form of repeated string values (e.g. status="ok") keyed by string identity,
+	// useful when values are mostly literals or long-lived strings
+	InternValues bool
+	// max count of memoized WithGroup/WithAttrs clones, repeated calls with the same group/attrs
+	// return the cached handler instead of encoding attrs again, 0 - disabled
+	CloneCacheSize int
+	// if set, records >= LevelWarn are written here instead of the handler writer,
+	// both writers share WithAttrs/WithGroup state of the handler
+	ErrorOutput io.Writer
+	// start buffered output for ErrorOutput, independent of BufferedOutput
+	ErrorOutputBuffered bool
+	// group for attrs added with AppendAttrsToCtx (e.g. "request"), empty - attrs are mixed with record attrs
+	CtxAttrsGroup string
+	// how ctx attrs with a repeated key are encoded, default - DuplicatesKeep
+	CtxAttrsDuplicates DuplicatePolicy
+	// write ctx attrs before the record attrs, default - after them
+	CtxAttrsFirst bool
+	// write WithAttrs attrs after the record and ctx attrs, in JSON after the nested groups of the same level,
+	// default - before them. Parsers that keep the first occurrence of a key then prefer the record attrs.
+	WithAttrsLast bool
+	// max count of nested WithGroup groups, deeper groups are flattened into the last one
+	// and reported once with a GroupLimitKey attr, 0 - 32
+	MaxGroupDepth int
+	// extractors called on every record to add attrs derived from ctx (e.g. TraceparentExtractor)
+	ContextExtractors []ContextExtractor
+	// called for every non-group attr with the path of the groups it's in (WithGroup groups, then group attrs),
+	// the returned attr is encoded instead, an empty key drops it; time, level, msg and source are not passed
+	ReplaceAttr func(groups []string, attr slog.Attr) slog.Attr
+	// new names of attr keys applied while encoding (e.g. "latency": "duration_ms"), a dotted key matches
+	// the attr at that group path ("http.latency") and wins over the bare key; group names are not renamed
+	RenameKeys map[string]string
+	// stages every record passes in order before it's written, e.g. redaction or filtering, see Middleware
+	Middleware []Middleware
+	// records at or above this level get a "stack" attr with the goroutine stack, nil - disabled
+	StackTraceLevel slog.Leveler
+	// level of the summary record written when a BeginScope scope ends, nil - no summaries
+	ScopeSummaryLevel slog.Leveler
+	// add the "source" of the log call as "internal/api/user.go:42 (GetUser)", paths are relative to the main module
+	AddSource bool
+	// prefix stripped from source file paths instead of making them relative to the main module
+	TrimSourcePrefix string
+	// max time to wait for the output (and the write itself for writers with SetWriteDeadline, e.g. net.Conn),
+	// records that can't be written in time are dropped and counted in Stats, 0 - wait forever
+	WriteTimeout time.Duration
+	// drop records whose ctx is done, including while waiting for a blocked output
+	DropOnCtxDone bool
+	// writes to the underlying writer slower than this are counted by the watchdog, after SlowWriteLimit
+	// consecutive slow writes the output switches to SlowWriteFallback and reports it with a WARN record, 0 - disabled
+	SlowWriteThreshold time.Duration
+	// consecutive slow writes before switching, default - 3
+	SlowWriteLimit int
+	// writer used after the switch, default - os.Stderr
+	SlowWriteFallback io.Writer
+	// layout of the record time and time attrs, default - time.DateTime (text record time - time.Stamp)
+	TimeFormat string
+	// order of the text handler line parts, omitted segments are not written,
+	// default - time, level, source, msg, attrs
+	//
+	// Deprecated: set TextOptions.Layout of NewHandler, the other builder ignores the field.
+	TextLayout []TextSegment
+	// dim the attrs of a text line with one escape sequence pair instead of coloring every key,
+	// saves 8 bytes per attr
+	//
+	// Deprecated: set TextOptions.CoalesceColors of NewHandler, the other builder ignores the field.
+	CoalesceColors bool
+	// text handler writes true bools as bare flags ("retry" instead of "retry=true"), false stays "retry=false"
+	//
+	// Deprecated: set TextOptions.BareBoolFlags of NewHandler, the other builder ignores the field.
+	BareBoolFlags bool
+	// symbols written before levels in text output (for color-blind readers), ASCII ones
+	// if the locale isn't UTF-8, default - MarkersNone
+	//
+	// Deprecated: set TextOptions.LevelMarkers of NewHandler, the other builder ignores the field.
+	LevelMarkers LevelMarkers
+	// labels replacing the level names in text and JSON output (e.g. full words, localized labels, single letters),
+	// text labels are padded to the widest one, unlisted levels keep the default labels
+	LevelLabels map[slog.Level]string
+	// encoding of durations in JSON, default - DurationNanos
+	//
+	// Deprecated: set JSONOptions.Durations of NewHandler, the other builder ignores the field.
+	JSONDurations DurationFormat
+	// JSON handler writes integers beyond ±(2^53-1) as strings, so JavaScript-based log UIs don't round IDs,
+	// smaller integers stay numbers
+	//
+	// Deprecated: set JSONOptions.QuoteBigInts of NewHandler, the other builder ignores the field.
+	QuoteBigInts bool
+	// string values longer than this are cut at a rune boundary and marked with "…", 0 - disabled
+	MaxValueLen int
+	// append "#<hash>" (8 hex digits of the full value hash) to cut values, so identical payloads can be grouped
+	TruncateHashSuffix bool
+	// measure encode and write latency per level, histograms are returned by Handler.Stats
+	ProfileLatency bool
+	// start every record with its sd-daemon priority (<3> ERROR, <4> WARN, <6> INFO, <7> DEBUG and TRACE, see MapLevel),
+	// so systemd assigns the priorities to plain stderr output without the journald protocol
+	PriorityPrefix bool
+	// tamper evidence: every record gets prev_hash and hash (SHA-256 chain over the encoded records),
+	// the log is checked with VerifyHashChain, outputs are chained separately
+	HashChain bool
+	// development mode: detect odd key/value args, duplicate keys, keys colliding with time/level/msg/source
+	// and non UTF-8 keys, every misuse is reported with a WARN record after the offending one
+	DevChecks bool
+	// panic on misuse detected by DevChecks instead of reporting it, useful in tests
+	PanicOnMisuse bool
+	// required attrs and attr kinds checked on every record (e.g. service, env, request_id in dev and staging),
+	// violating records are fixed, annotated or rejected, nil - disabled
+	Schema *Schema
+	// logger of the handler's own notices (the events of Handler.Diagnostics), its handler level filters them:
+	// ERROR - encode panics and failed writes, WARN - dropped records and slow writer switches; nil - not logged.
+	// It must not write to the same handler.
+	InternalLogger *slog.Logger
+
+	// color scheme of text output, set by TextOptions.Theme only
+	textTheme TextTheme
+}
+
+// Validate reports every misconfigured option, each error wraps ErrInvalidConfig.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.CloneCacheSize < 0 {
+		errs = append(errs, fmt.Errorf("%w: CloneCacheSize must not be negative, got %d", ErrInvalidConfig, c.CloneCacheSize))
+	}
+
+	for i, mw := range c.Middleware {
+		if mw == nil {
+			errs = append(errs, fmt.Errorf("%w: Middleware[%d] is nil", ErrInvalidConfig, i))
+		}
+	}
+
+	for level, label := range c.LevelLabels {
+		if label == "" {
+			errs = append(errs, fmt.Errorf("%w: empty LevelLabels label for %s", ErrInvalidConfig, level))
+		}
+	}
+
+	for from, to := range c.RenameKeys {
+		if from == "" || to == "" {
+			errs = append(errs, fmt.Errorf("%w: RenameKeys %q: %q must not have an empty key", ErrInvalidConfig, from, to))
+		}
+	}
+
+	if c.Schema != nil {
+		if c.Schema.Action < SchemaAnnotate || c.Schema.Action > SchemaReject {
+			errs = append(errs, fmt.Errorf("%w: unknown Schema.Action %d", ErrInvalidConfig, c.Schema.Action))
+		}
+		for _, paths := range []map[string]slog.Kind{c.Schema.Required, c.Schema.Kinds} {
+			if _, ok := paths[""]; ok {
+				errs = append(errs, fmt.Errorf("%w: empty Schema attr path", ErrInvalidConfig))
+			}
+		}
+	}
+
+	if c.MaxGroupDepth < 0 {
+		errs = append(errs, fmt.Errorf("%w: MaxGroupDepth must not be negative, got %d", ErrInvalidConfig, c.MaxGroupDepth))
+	}
+
+	if c.CtxAttrsDuplicates < DuplicatesKeep || c.CtxAttrsDuplicates > DuplicatesFirst {
+		errs = append(errs, fmt.Errorf("%w: unknown CtxAttrsDuplicates policy %d", ErrInvalidConfig, c.CtxAttrsDuplicates))
+	}
+
+	if c.WriteTimeout < 0 {
+		errs = append(errs, fmt.Errorf("%w: WriteTimeout must not be negative, got %s", ErrInvalidConfig, c.WriteTimeout))
+	}
+
+	if c.SlowWriteThreshold < 0 {
+		errs = append(errs, fmt.Errorf("%w: SlowWriteThreshold must not be negative, got %s", ErrInvalidConfig, c.SlowWriteThreshold))
+	}
+
+	if c.SlowWriteLimit < 0 {
+		errs = append(errs, fmt.Errorf("%w: SlowWriteLimit must not be negative, got %d", ErrInvalidConfig, c.SlowWriteLimit))
+	}
+
+	if (c.SlowWriteLimit != 0 || c.SlowWriteFallback != nil) && c.SlowWriteThreshold == 0 {
+		errs = append(errs, fmt.Errorf("%w: SlowWriteLimit and SlowWriteFallback require SlowWriteThreshold", ErrInvalidConfig))
+	}
+
+	var seen [SegmentAttrs + 1]bool
+	for _, segment := range c.TextLayout {
+		switch {
+		case segment < SegmentTime || segment > SegmentAttrs:
+			errs = append(errs, fmt.Errorf("%w: unknown TextLayout segment %d", ErrInvalidConfig, segment))
+		case seen[segment]:
+			errs = append(errs, fmt.Errorf("%w: duplicate TextLayout segment %d", ErrInvalidConfig, segment))
+		default:
+			seen[segment] = true
+		}
+	}
+
+	if c.LevelMarkers < MarkersNone || c.LevelMarkers > MarkersSymbols {
+		errs = append(errs, fmt.Errorf("%w: unknown LevelMarkers set %d", ErrInvalidConfig, c.LevelMarkers))
+	}
+
+	if c.JSONDurations < DurationNanos || c.JSONDurations > DurationBoth {
+		errs = append(errs, fmt.Errorf("%w: unknown JSONDurations format %d", ErrInvalidConfig, c.JSONDurations))
+	}
+
+	if c.MaxValueLen < 0 {
+		errs = append(errs, fmt.Errorf("%w: MaxValueLen must not be negative, got %d", ErrInvalidConfig, c.MaxValueLen))
+	}
+
+	if c.TruncateHashSuffix && c.MaxValueLen == 0 {
+		errs = append(errs, fmt.Errorf("%w: TruncateHashSuffix requires MaxValueLen", ErrInvalidConfig))
+	}
+
+	if c.TrimSourcePrefix != "" && !c.AddSource {
+		errs = append(errs, fmt.Errorf("%w: TrimSourcePrefix requires AddSource", ErrInvalidConfig))
+	}
+
+	if c.PanicOnMisuse && !c.DevChecks {
+		errs = append(errs, fmt.Errorf("%w: PanicOnMisuse requires DevChecks", ErrInvalidConfig))
+	}
+
+	// journald strips the prefix, the stored records wouldn't match their hashes.
+	if c.PriorityPrefix && c.HashChain {
+		errs = append(errs, fmt.Errorf("%w: PriorityPrefix can't be combined with HashChain", ErrInvalidConfig))
+	}
+
+	if c.ErrorOutputBuffered && c.ErrorOutput == nil {
+		errs = append(errs, fmt.Errorf("%w: ErrorOutputBuffered requires ErrorOutput", ErrInvalidConfig))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate checks the constructor arguments of the *HandlerE variants.
+func validate(w io.Writer, cfg *Config) error {
+	if w == nil {
+		return ErrNilWriter
+	}
+
+	if cfg == nil {
+		return nil
+	}
+
+	return cfg.Validate()
+}
+
+// sameWriter reports whether a and b are the same writer, writers that can't be compared are never the same:
+// == panics on uncomparable values, also on the ones held by interface fields of comparable structs.
+func sameWriter(a, b io.Writer) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+// watchdog returns the slow-writer watchdog for w or nil if it's disabled.
+func (c *Config) watchdog(w io.Writer) *watchdogWriter {
+	if c.SlowWriteThreshold <= 0 {
+		return nil
+	}
+
+	fallback := c.SlowWriteFallback
+	if fallback == nil {
+		fallback = os.Stderr
+	}
+
+	// Switching a writer to itself would only hide the problem.
+	if sameWriter(fallback, w) {
+		return nil
+	}
+
+	return newWatchdogWriter(w, fallback, c.SlowWriteThreshold, c.SlowWriteLimit)
+}
@@ -0,0 +1,304 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigFile describes a handler topology, see NewFromConfigFile.
+type ConfigFile struct {
+	Outputs []OutputConfig `json:"outputs"`
+}
+
+// OutputConfig describes a single output of a ConfigFile.
+type OutputConfig struct {
+	// console (text to stdout), json (json to stdout), file or syslog
+	Type string `json:"type"`
+	// minimal level of the output (TRACE, DEBUG, INFO, WARN, ERROR), default - INFO
+	Level string `json:"level"`
+	// per-package levels, see Handler.SetLevelRules, e.g. {"github.com/our/repo/internal/db/*": "DEBUG"}
+	LevelRules map[string]string `json:"level_rules"`
+	// one of every sample_every records is written, records >= ERROR are always written, 0 and 1 - all records
+	SampleEvery uint64 `json:"sample_every"`
+	// same as Config.BufferedOutput
+	Buffered bool `json:"buffered"`
+	// format of file and syslog outputs: json or text, default - json
+	Format string `json:"format"`
+	// color scheme of console and text outputs: bright, basic or none, default - bright
+	Theme string `json:"theme"`
+
+	// file output: path, size in megabytes that triggers rotation (0 - never rotate) and count of kept old files
+	Path       string `json:"path"`
+	MaxSizeMB  int    `json:"max_size_mb"`
+	MaxBackups int    `json:"max_backups"`
+	// file output: fsync policy, sync_every is a duration ("1s") or a count of records ("100"),
+	// sync_on_level syncs records at or above the level right away, see SyncPolicy
+	SyncEvery   string `json:"sync_every"`
+	SyncOnLevel string `json:"sync_on_level"`
+	// file output: other processes log to the same path, see FileWriter.SetShared
+	Shared bool `json:"shared"`
+
+	// syslog output: empty network and address - local syslog daemon
+	Network string `json:"network"`
+	Address string `json:"address"`
+	Tag     string `json:"tag"`
+}
+
+// NewFromConfigFile builds a MultiHandler from a JSON (.json) or YAML (.yaml, .yml) config file, e.g.:
+//
+//	{"outputs": [
+//		{"type": "console", "level": "DEBUG", "theme": "basic"},
+//		{"type": "file", "path": "/var/log/app.log", "level": "INFO", "buffered": true, "max_size_mb": 100, "max_backups": 5}
+//	]}
+//
+// YAML files support the block mappings and sequences, plain and quoted scalars and comments of the
+// usual config files, anchors, flow collections and multi-line scalars are rejected:
+//
+//	outputs:
+//	  - type: console
+//	    level: DEBUG
+//	  - type: file
+//	    path: /var/log/app.log
+//	    max_size_mb: 100
+//
+// Files opened for the outputs are closed by MultiHandler.Close.
+func NewFromConfigFile(path string) (*MultiHandler, error) {
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := cfg.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	m.source.path = path
+	return m, nil
+}
+
+// readConfigFile decodes the file by its extension: .json, .yaml or .yml.
+func readConfigFile(path string) (*ConfigFile, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".json" && ext != ".yaml" && ext != ".yml" {
+		return nil, fmt.Errorf("%w: unsupported config file %q, want .json, .yaml or .yml", ErrInvalidConfig, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg ConfigFile
+
+	if ext == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&cfg)
+	} else {
+		err = decodeYAML(data, &cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidConfig, path, err)
+	}
+
+	return &cfg, nil
+}
+
+// Build creates the handlers of all outputs, nothing is left open if any output fails.
+func (c *ConfigFile) Build() (*MultiHandler, error) {
+	if len(c.Outputs) == 0 {
+		return nil, fmt.Errorf("%w: no outputs", ErrInvalidConfig)
+	}
+
+	m := &MultiHandler{
+		source: &configSource{cfg: slices.Clone(c.Outputs)},
+	}
+
+	for i, out := range c.Outputs {
+		rules, err := out.levelRules()
+		var h *Handler
+		var closer io.Closer
+		if err == nil {
+			h, closer, err = out.build()
+		}
+		if err != nil {
+			for _, closer := range m.closers {
+				_ = closer.Close()
+			}
+			return nil, fmt.Errorf("output %d (%s): %w", i, out.Type, err)
+		}
+		// The patterns are checked by levelRules.
+		_ = h.SetLevelRules(rules)
+
+		// Every output is sampled, so a reload can change its rate.
+		sampler := NewSamplingHandler(h, SamplingOptions{Every: out.SampleEvery})
+
+		m.handlers = append(m.handlers, sampler)
+		m.source.outputs = append(m.source.outputs, h)
+		m.source.samplers = append(m.source.samplers, sampler)
+		if closer != nil {
+			m.closers = append(m.closers, closer)
+		}
+	}
+
+	return m, nil
+}
+
+func (o *OutputConfig) build() (*Handler, io.Closer, error) {
+	level, err := o.level()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg := &Config{
+		Level:          int(level),
+		BufferedOutput: o.Buffered,
+	}
+
+	theme, err := parseTheme(o.Theme)
+	if err != nil {
+		return nil, nil, err
+	}
+	if o.Theme != "" && (o.Type == "json" || o.Type != "console" && o.Format != "text") {
+		return nil, nil, fmt.Errorf("%w: theme requires console or text output", ErrInvalidConfig)
+	}
+	cfg.textTheme = theme
+
+	switch o.Type {
+	case "console":
+		return NewTextHandler(os.Stdout, cfg), nil, nil
+	case "json":
+		return NewJsonHandler(os.Stdout, cfg), nil, nil
+	case "file":
+		if o.Path == "" {
+			return nil, nil, fmt.Errorf("%w: file output requires path", ErrInvalidConfig)
+		}
+		if o.MaxSizeMB < 0 || o.MaxBackups < 0 {
+			return nil, nil, fmt.Errorf("%w: max_size_mb and max_backups must not be negative", ErrInvalidConfig)
+		}
+
+		policy, err := o.syncPolicy()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		w, err := NewFileWriter(o.Path, int64(o.MaxSizeMB)<<20, o.MaxBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = w.SetSyncPolicy(policy); err == nil {
+			err = w.SetShared(o.Shared)
+		}
+		if err != nil {
+			_ = w.Close()
+			return nil, nil, err
+		}
+
+		h, err := o.formatHandler(w, cfg)
+		if err != nil {
+			_ = w.Close()
+			return nil, nil, err
+		}
+		return h, w, nil
+	case "syslog":
+		w, err := openSyslog(o.Network, o.Address, o.Tag)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The writer takes the severity of every record from the prefix.
+		cfg.PriorityPrefix = true
+
+		h, err := o.formatHandler(w, cfg)
+		if err != nil {
+			_ = w.Close()
+			return nil, nil, err
+		}
+		return h, w, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown output type %q", ErrInvalidConfig, o.Type)
+	}
+}
+
+func (o *OutputConfig) level() (slog.Level, error) {
+	if o.Level == "" {
+		return slog.LevelInfo, nil
+	}
+
+	level, err := ParseLevelName(o.Level)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return level, nil
+}
+
+// levelRules parses the level rules, nil if there are none.
+func (o *OutputConfig) levelRules() (map[string]slog.Level, error) {
+	if len(o.LevelRules) == 0 {
+		return nil, nil
+	}
+
+	rules := make(map[string]slog.Level, len(o.LevelRules))
+	for pattern, name := range o.LevelRules {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: level rule %q: %w", ErrInvalidConfig, pattern, err)
+		}
+
+		level, err := ParseLevelName(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: level rule %q: %w", ErrInvalidConfig, pattern, err)
+		}
+		rules[pattern] = level
+	}
+	return rules, nil
+}
+
+func (o *OutputConfig) syncPolicy() (SyncPolicy, error) {
+	var policy SyncPolicy
+
+	if o.SyncEvery != "" {
+		if n, err := strconv.Atoi(o.SyncEvery); err == nil {
+			policy.EveryRecords = n
+		} else if d, err := time.ParseDuration(o.SyncEvery); err == nil {
+			policy.Every = d
+		} else {
+			return policy, fmt.Errorf("%w: sync_every %q is neither a duration nor a count of records", ErrInvalidConfig, o.SyncEvery)
+		}
+	}
+
+	if o.SyncOnLevel != "" {
+		level, err := ParseLevelName(o.SyncOnLevel)
+		if err != nil {
+			return policy, fmt.Errorf("%w: sync_on_level: %w", ErrInvalidConfig, err)
+		}
+		policy.OnLevel = level
+	}
+
+	return policy, nil
+}
+
+func (o *OutputConfig) formatHandler(w io.Writer, cfg *Config) (*Handler, error) {
+	return newFormatHandler(o.Format, w, cfg)
+}
+
+// newFormatHandler creates the handler of a format name: json or text, "" - json.
+func newFormatHandler(format string, w io.Writer, cfg *Config) (*Handler, error) {
+	switch format {
+	case "", "json":
+		return NewJsonHandler(w, cfg), nil
+	case "text":
+		return NewTextHandler(w, cfg), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidConfig, format)
+	}
+}
@@ -0,0 +1,354 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewFromConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	cfgPath := filepath.Join(dir, "logger.json")
+
+	cfg := `{"outputs": [{"type": "file", "path": "` + logPath + `", "level": "WARN", "format": "json"}]}`
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewFromConfigFile(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := slog.New(h)
+	log.Info("skipped")
+	log.Warn("written")
+
+	if err = h.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out := string(data); strings.Contains(out, "skipped") || !strings.Contains(out, `"msg":"written"`) {
+		t.Fatalf("file content = %q", out)
+	}
+}
+
+func TestConfigFileTheme(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	cfgPath := filepath.Join(dir, "logger.json")
+
+	cfg := `{"outputs": [{"type": "file", "path": "` + logPath + `", "format": "text", "theme": "none"}]}`
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewFromConfigFile(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.New(h).Info("written", "a", 1)
+	if err = h.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := string(data); strings.Contains(out, "\u001b") || !strings.HasSuffix(out, " INFO written a=1\n") {
+		t.Fatalf("file content = %q", out)
+	}
+}
+
+func TestNewFromConfigFileErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"unknown_type.json":  `{"outputs": [{"type": "kafka"}]}`,
+		"unknown_field.json": `{"outputs": [{"type": "console", "colour": true}]}`,
+		"bad_level.json":     `{"outputs": [{"type": "console", "level": "LOUD"}]}`,
+		"no_outputs.json":    `{"outputs": []}`,
+		"bad_sync.json":      `{"outputs": [{"type": "file", "path": "app.log", "sync_every": "often"}]}`,
+		"config.toml":        `outputs = []`,
+		"no_outputs.yaml":    "outputs:\n",
+		"bad_theme.json":     `{"outputs": [{"type": "console", "theme": "neon"}]}`,
+		"json_theme.json":    `{"outputs": [{"type": "json", "theme": "none"}]}`,
+		"file_theme.json":    `{"outputs": [{"type": "file", "path": "app.log", "theme": "none"}]}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := NewFromConfigFile(path); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%s: err = %v, want ErrInvalidConfig", name, err)
+		}
+	}
+}
+
+func TestFileWriterRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	w, err := NewFileWriter(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"line-1\n", "line-2\n", "line-3\n", "line-4\n"} {
+		if _, err = w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = w.Close()
+
+	for name, want := range map[string]string{
+		path:        "line-4\n",
+		path + ".1": "line-3\n",
+		path + ".2": "line-2\n",
+	} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(name), data, want)
+		}
+	}
+
+	if _, err = os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 exists, want at most 2 backups", filepath.Base(path))
+	}
+}
+
+func TestFileWriterSyncPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	w, err := NewFileWriter(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err = w.SetSyncPolicy(SyncPolicy{EveryRecords: 2, OnLevel: slog.LevelError}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewJsonHandler(w, &Config{BufferedOutput: true})
+	l := slog.New(h)
+
+	l.Info("buffered")
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Fatalf("INFO record written before a flush: %q", data)
+	}
+
+	// The ERROR record flushes the buffer and is synced with the INFO one.
+	l.Error("synced")
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "buffered") || !strings.Contains(string(data), "synced") {
+		t.Fatalf("file content = %q", data)
+	}
+	if w.dirty || w.unsynced != 0 {
+		t.Errorf("dirty = %t, unsynced = %d after the ERROR record", w.dirty, w.unsynced)
+	}
+
+	if _, err = w.Write([]byte("a\n")); err != nil {
+		t.Fatal(err)
+	}
+	if !w.dirty {
+		t.Error("the first write of two was synced")
+	}
+	if _, err = w.Write([]byte("b\n")); err != nil {
+		t.Fatal(err)
+	}
+	if w.dirty {
+		t.Error("EveryRecords: 2 didn't sync the second write")
+	}
+}
+
+func TestFileWriterShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	// Two writers stand for two processes logging to the same path.
+	var writers [2]*FileWriter
+	for i := range writers {
+		w, err := NewFileWriter(path, 16, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer w.Close()
+		if err = w.SetShared(true); err != nil {
+			t.Fatal(err)
+		}
+		writers[i] = w
+	}
+
+	write := func(w *FileWriter, line string) {
+		t.Helper()
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(writers[0], "a-1\n")
+	write(writers[1], "b-1\n")
+	write(writers[0], "a-2-long-record\n")
+	// The first writer rotated, the second one finds the new file under the lock instead of rotating it again.
+	write(writers[1], "b-2-long-record\n")
+
+	for name, want := range map[string]string{
+		path + ".1": "a-1\nb-1\n",
+		path:        "a-2-long-record\nb-2-long-record\n",
+	} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(name), data, want)
+		}
+	}
+
+	// A rotation by logrotate is noticed by the periodic check.
+	if err := os.Rename(path, path+".rotated"); err != nil {
+		t.Fatal(err)
+	}
+	writers[0].checked = time.Time{}
+	write(writers[0], "a-3\n")
+	if data, _ := os.ReadFile(path); string(data) != "a-3\n" {
+		t.Errorf("after the external rotation the file = %q, want a-3", data)
+	}
+}
+
+func TestFileWriterAtomicChunks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	w, err := NewFileWriter(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err = w.SetShared(true); err != nil {
+		t.Fatal(err)
+	}
+
+	line := strings.Repeat("x", 999) + "\n"
+	big := strings.Repeat("y", 2*pipeBuf) + "\n"
+	chunk := strings.Repeat(line, 6) + big + line
+
+	if n, err := w.Write([]byte(chunk)); err != nil || n != len(chunk) {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != chunk {
+		t.Error("the chunk was not written as is")
+	}
+}
+
+func TestMultiHandlerReload(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	cfgPath := filepath.Join(dir, "logger.json")
+
+	write := func(level, format string) {
+		cfg := `{"outputs": [{"type": "file", "path": "` + logPath + `", "level": "` + level + `", "format": "` + format + `"}]}`
+		if err := os.WriteFile(cfgPath, []byte(cfg), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("WARN", "json")
+	h, err := NewFromConfigFile(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close(context.Background())
+
+	write("DEBUG", "json")
+	if err = h.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("new level is not applied")
+	}
+
+	write("ERROR", "text")
+	if err = h.Reload(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("err = %v, want ErrInvalidConfig for a format change", err)
+	}
+	if !h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("rejected reload changed the level")
+	}
+}
+
+func TestMultiHandlerReloadRulesAndSampling(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	cfgPath := filepath.Join(dir, "logger.json")
+
+	write := func(extra string) {
+		cfg := `{"outputs": [{"type": "file", "path": "` + logPath + `"` + extra + `}]}`
+		if err := os.WriteFile(cfgPath, []byte(cfg), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func(msg string) int {
+		data, err := os.ReadFile(logPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(string(data), `"msg":"`+msg+`"`)
+	}
+
+	write("")
+	h, err := NewFromConfigFile(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close(context.Background())
+	log := slog.New(h)
+
+	const pkg = "github.com/ttrtcixy/fast-slog-handler"
+	write(`, "level_rules": {"` + pkg + `": "ERROR"}`)
+	if err = h.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	log.Info("filtered")
+	log.Error("kept")
+	if count("filtered") != 0 || count("kept") != 1 {
+		t.Error("level rules are not applied")
+	}
+
+	write(`, "sample_every": 5`)
+	if err = h.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	for range 10 {
+		log.Info("sampled")
+	}
+	if n := count("sampled"); n != 2 {
+		t.Errorf("%d sampled records written, want 2", n)
+	}
+
+	// A bad rule keeps every previous setting.
+	write(`, "sample_every": 1, "level_rules": {"` + pkg + `": "LOUD"}`)
+	if err = h.Reload(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("err = %v, want ErrInvalidConfig", err)
+	}
+	for range 5 {
+		log.Info("still sampled")
+	}
+	if n := count("still sampled"); n != 1 {
+		t.Errorf("%d records written after a rejected reload, want 1", n)
+	}
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	var w bytes.Buffer
+
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"negative CloneCacheSize", Config{CloneCacheSize: -1}},
+		{"nil Middleware", Config{Middleware: []Middleware{nil}}},
+		{"empty LevelLabels label", Config{LevelLabels: map[slog.Level]string{slog.LevelInfo: ""}}},
+		{"empty RenameKeys key", Config{RenameKeys: map[string]string{"": "to"}}},
+		{"empty RenameKeys target", Config{RenameKeys: map[string]string{"from": ""}}},
+		{"unknown Schema.Action", Config{Schema: &Schema{Action: SchemaReject + 1}}},
+		{"empty Schema required path", Config{Schema: &Schema{Required: map[string]slog.Kind{"": slog.KindString}}}},
+		{"empty Schema kind path", Config{Schema: &Schema{Kinds: map[string]slog.Kind{"": slog.KindString}}}},
+		{"negative MaxGroupDepth", Config{MaxGroupDepth: -1}},
+		{"unknown CtxAttrsDuplicates", Config{CtxAttrsDuplicates: DuplicatesFirst + 1}},
+		{"negative WriteTimeout", Config{WriteTimeout: -1}},
+		{"negative SlowWriteThreshold", Config{SlowWriteThreshold: -1}},
+		{"negative SlowWriteLimit", Config{SlowWriteLimit: -1, SlowWriteThreshold: 1}},
+		{"SlowWriteLimit without threshold", Config{SlowWriteLimit: 3}},
+		{"SlowWriteFallback without threshold", Config{SlowWriteFallback: &w}},
+		{"unknown TextLayout segment", Config{TextLayout: []TextSegment{SegmentAttrs + 1}}},
+		{"duplicate TextLayout segment", Config{TextLayout: []TextSegment{SegmentTime, SegmentTime}}},
+		{"unknown LevelMarkers", Config{LevelMarkers: MarkersSymbols + 1}},
+		{"unknown JSONDurations", Config{JSONDurations: DurationBoth + 1}},
+		{"negative MaxValueLen", Config{MaxValueLen: -1}},
+		{"TruncateHashSuffix without MaxValueLen", Config{TruncateHashSuffix: true}},
+		{"TrimSourcePrefix without AddSource", Config{TrimSourcePrefix: "/src/"}},
+		{"PanicOnMisuse without DevChecks", Config{PanicOnMisuse: true}},
+		{"PriorityPrefix with HashChain", Config{PriorityPrefix: true, HashChain: true}},
+		{"ErrorOutputBuffered without ErrorOutput", Config{ErrorOutputBuffered: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Validate() = %v, want ErrInvalidConfig", err)
+			}
+			if _, err := NewJsonHandlerE(&w, &tt.cfg); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("NewJsonHandlerE() = %v, want ErrInvalidConfig", err)
+			}
+			if _, err := NewTextHandlerE(&w, &tt.cfg); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("NewTextHandlerE() = %v, want ErrInvalidConfig", err)
+			}
+		})
+	}
+
+	valid := Config{
+		CloneCacheSize: 8, MaxGroupDepth: 4, MaxValueLen: 64, TruncateHashSuffix: true,
+		AddSource: true, TrimSourcePrefix: "/src/", DevChecks: true, PanicOnMisuse: true,
+		SlowWriteThreshold: 1, SlowWriteLimit: 3, ErrorOutput: &w, ErrorOutputBuffered: true,
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+
+	// Every problem is reported.
+	err := (&Config{CloneCacheSize: -1, MaxGroupDepth: -1}).Validate()
+	if joined, ok := err.(interface{ Unwrap() []error }); !ok || len(joined.Unwrap()) != 2 {
+		t.Errorf("Validate() = %v, want two errors", err)
+	}
+}
+
+func TestHandlerENilWriter(t *testing.T) {
+	if _, err := NewJsonHandlerE(nil, nil); !errors.Is(err, ErrNilWriter) {
+		t.Errorf("NewJsonHandlerE(nil) = %v, want ErrNilWriter", err)
+	}
+	if _, err := NewTextHandlerE(nil, &Config{}); !errors.Is(err, ErrNilWriter) {
+		t.Errorf("NewTextHandlerE(nil) = %v, want ErrNilWriter", err)
+	}
+
+	var w bytes.Buffer
+	if h, err := NewJsonHandlerE(&w, nil); err != nil || h == nil {
+		t.Errorf("NewJsonHandlerE(w, nil) = %v, %v", h, err)
+	}
+}
@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const configReloadFailedMsg = "logger config reload failed"
+
+var ErrNotReloadable = errors.New("handler is not built from a config file")
+
+// configSource keeps what NewFromConfigFile built, so the file can be applied again.
+type configSource struct {
+	// mu serializes reloads.
+	mu sync.Mutex
+
+	path string
+	// cfg is the last applied config, outputs are its handlers in the same order and samplers wrap them.
+	cfg      []OutputConfig
+	outputs  []*Handler
+	samplers []*SamplingHandler
+}
+
+// Reload reads the config file again and applies the new output levels, level rules and sampling rates
+// to the running handler tree. They are swapped atomically, so records being written or flushed are
+// not affected. Any other change (outputs, paths, formats, buffering) requires a restart and is rejected.
+func (m *MultiHandler) Reload() error {
+	src := m.source
+	if src == nil || src.path == "" {
+		return ErrNotReloadable
+	}
+
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	cfg, err := readConfigFile(src.path)
+	if err != nil {
+		return err
+	}
+
+	if !sameOutputs(src.cfg, cfg.Outputs) {
+		return fmt.Errorf("%w: only output levels, level rules and sampling can be reloaded, restart to change outputs", ErrInvalidConfig)
+	}
+
+	// Parse every output first, so a bad one doesn't leave the tree half updated.
+	levels := make([]slog.Level, len(cfg.Outputs))
+	rules := make([]map[string]slog.Level, len(cfg.Outputs))
+	for i := range cfg.Outputs {
+		if levels[i], err = cfg.Outputs[i].level(); err == nil {
+			rules[i], err = cfg.Outputs[i].levelRules()
+		}
+		if err != nil {
+			return fmt.Errorf("output %d (%s): %w", i, cfg.Outputs[i].Type, err)
+		}
+	}
+
+	for i, h := range src.outputs {
+		h.SetLevel(levels[i], "config reload")
+		// The patterns are checked by levelRules.
+		_ = h.SetLevelRules(rules[i])
+		src.samplers[i].SetEvery(cfg.Outputs[i].SampleEvery)
+	}
+
+	src.cfg = cfg.Outputs
+	return nil
+}
+
+// Watch reloads the config file on SIGHUP and, if pollInterval > 0, when its modification time changes,
+// until ctx is done. A failed reload is reported with a WARN record and the previous settings are kept.
+func (m *MultiHandler) Watch(ctx context.Context, pollInterval time.Duration) error {
+	if m.source == nil || m.source.path == "" {
+		return ErrNotReloadable
+	}
+
+	modTime := fileModTime(m.source.path)
+
+	signals := make(chan os.Signal, 1)
+	if len(reloadSignals) > 0 {
+		signal.Notify(signals, reloadSignals...)
+	}
+
+	var poll <-chan time.Time
+	var ticker *time.Ticker
+	if pollInterval > 0 {
+		ticker = time.NewTicker(pollInterval)
+		poll = ticker.C
+	}
+
+	go func() {
+		defer signal.Stop(signals)
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				m.reload()
+			case <-poll:
+				if t := fileModTime(m.source.path); !t.Equal(modTime) {
+					modTime = t
+					m.reload()
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (m *MultiHandler) reload() {
+	if err := m.Reload(); err != nil {
+		record := slog.NewRecord(time.Now(), slog.LevelWarn, configReloadFailedMsg, 0)
+		record.AddAttrs(slog.String("path", m.source.path), slog.String("error", err.Error()))
+		_ = m.Handle(context.Background(), record)
+	}
+}
+
+// sameOutputs reports whether the configs differ only in the reloadable fields.
+func sameOutputs(applied, loaded []OutputConfig) bool {
+	if len(applied) != len(loaded) {
+		return false
+	}
+
+	for i := range applied {
+		a, b := applied[i], loaded[i]
+		a.Level, b.Level = "", ""
+		a.LevelRules, b.LevelRules = nil, nil
+		a.SampleEvery, b.SampleEvery = 0, 0
+		if !reflect.DeepEqual(a, b) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,418 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The YAML config files are read with a decoder of the subset the config schema needs: block mappings
+// and sequences, plain and quoted scalars and comments. Anchors, tags, flow collections and multi-line
+// scalars are rejected instead of being read as plain strings.
+
+// yamlLine is a line of a YAML document without its indentation and comment.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// yamlNode is a parsed YAML node: a scalar, a mapping or a sequence.
+type yamlNode struct {
+	line int
+	// scalar value, null is a scalar with null set
+	value  string
+	null   bool
+	fields []yamlField
+	items  []*yamlNode
+	kind   yamlKind
+}
+
+type yamlKind int
+
+const (
+	yamlScalar yamlKind = iota
+	yamlMapping
+	yamlSequence
+)
+
+type yamlField struct {
+	key   string
+	value *yamlNode
+}
+
+// decodeYAML decodes the YAML document into v, a pointer to a struct with json tags.
+// Unknown keys are errors, like DisallowUnknownFields of the JSON files.
+func decodeYAML(data []byte, v any) error {
+	lines, err := yamlLines(string(data))
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return errors.New("empty document")
+	}
+
+	p := &yamlParser{lines: lines}
+	node, err := p.node(lines[0].indent)
+	if err != nil {
+		return err
+	}
+	if p.pos < len(lines) {
+		return fmt.Errorf("line %d: unexpected indentation", lines[p.pos].num)
+	}
+
+	return assignYAML(reflect.ValueOf(v).Elem(), node)
+}
+
+// yamlLines splits the document into lines, dropping blank lines, comments and the document markers.
+func yamlLines(doc string) ([]yamlLine, error) {
+	var lines []yamlLine
+	for i, text := range strings.Split(doc, "\n") {
+		num := i + 1
+		text = strings.TrimRight(stripYAMLComment(text), " \t\r")
+
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || trimmed == "---" && len(lines) == 0 {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", num)
+		}
+		if trimmed == "..." {
+			break
+		}
+
+		lines = append(lines, yamlLine{num: num, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	return lines, nil
+}
+
+// stripYAMLComment cuts a comment: a '#' at the line start or after a space, outside of quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || line[i-1] == ' '):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// node parses the mapping or sequence starting at the current line with the indent.
+func (p *yamlParser) node(indent int) (*yamlNode, error) {
+	line := p.lines[p.pos]
+	if isYAMLItem(line.text) {
+		return p.sequence(indent)
+	}
+	if _, _, ok := cutYAMLKey(line.text); ok {
+		return p.mapping(indent)
+	}
+
+	// A lone scalar document or value on its own line.
+	p.pos++
+	return yamlScalarNode(line.num, line.text)
+}
+
+func (p *yamlParser) sequence(indent int) (*yamlNode, error) {
+	seq := &yamlNode{kind: yamlSequence, line: p.lines[p.pos].num}
+
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || line.indent == indent && !isYAMLItem(line.text) {
+			break
+		}
+		if line.indent > indent || !isYAMLItem(line.text) {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if rest == "" {
+			p.pos++
+			item, err := p.child(indent, line.num)
+			if err != nil {
+				return nil, err
+			}
+			seq.items = append(seq.items, item)
+			continue
+		}
+
+		// "- key: value" starts a mapping indented to the key, the item line is parsed again as its first line.
+		itemIndent := indent + len(line.text) - len(rest)
+		if isYAMLItem(rest) || hasYAMLKey(rest) {
+			p.lines[p.pos] = yamlLine{num: line.num, indent: itemIndent, text: rest}
+			item, err := p.node(itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			seq.items = append(seq.items, item)
+			continue
+		}
+
+		p.pos++
+		item, err := yamlScalarNode(line.num, rest)
+		if err != nil {
+			return nil, err
+		}
+		seq.items = append(seq.items, item)
+	}
+
+	return seq, nil
+}
+
+func (p *yamlParser) mapping(indent int) (*yamlNode, error) {
+	m := &yamlNode{kind: yamlMapping, line: p.lines[p.pos].num}
+	seen := make(map[string]bool)
+
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+
+		key, value, ok := cutYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: want key: value", line.num)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		seen[key] = true
+		p.pos++
+
+		var node *yamlNode
+		var err error
+		if value == "" {
+			node, err = p.child(indent, line.num)
+		} else {
+			node, err = yamlScalarNode(line.num, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.fields = append(m.fields, yamlField{key: key, value: node})
+	}
+
+	return m, nil
+}
+
+// child parses the block value of a key or item on the line num: the deeper indented lines, a sequence
+// at the same indent of a mapping key, or null.
+func (p *yamlParser) child(indent, num int) (*yamlNode, error) {
+	if p.pos < len(p.lines) {
+		next := p.lines[p.pos]
+		if next.indent > indent {
+			return p.node(next.indent)
+		}
+		// A sequence may be indented like its key ("outputs:\n- type: console").
+		if next.indent == indent && isYAMLItem(next.text) && !isYAMLItem(p.lines[p.pos-1].text) {
+			return p.sequence(indent)
+		}
+	}
+	return &yamlNode{line: num, null: true}, nil
+}
+
+func isYAMLItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func hasYAMLKey(text string) bool {
+	_, _, ok := cutYAMLKey(text)
+	return ok
+}
+
+// cutYAMLKey splits "key: value", the key may be quoted.
+func cutYAMLKey(text string) (key, value string, ok bool) {
+	if text != "" && (text[0] == '"' || text[0] == '\'') {
+		end := closingYAMLQuote(text)
+		if end < 0 || !strings.HasPrefix(text[end+1:], ":") {
+			return "", "", false
+		}
+		rest := text[end+2:]
+		if rest != "" && rest[0] != ' ' {
+			return "", "", false
+		}
+		node, err := yamlScalarNode(0, text[:end+1])
+		if err != nil {
+			return "", "", false
+		}
+		return node.value, strings.TrimSpace(rest), true
+	}
+
+	i := strings.Index(text, ": ")
+	if i < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", false
+		}
+		i = len(text) - 1
+	}
+	key = text[:i]
+	if key == "" || strings.ContainsAny(key[:1], "-?[]{}&*!|>%@`") {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(text[i+1:]), true
+}
+
+// closingYAMLQuote returns the index of the quote closing the scalar at the text start, -1 if there is none.
+func closingYAMLQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// yamlScalarNode parses a plain or quoted scalar.
+func yamlScalarNode(num int, text string) (*yamlNode, error) {
+	node := &yamlNode{line: num}
+
+	switch text[0] {
+	case '"', '\'':
+		end := closingYAMLQuote(text)
+		if end != len(text)-1 {
+			return nil, fmt.Errorf("line %d: unterminated or trailing text after quoted value", num)
+		}
+		if text[0] == '\'' {
+			node.value = strings.ReplaceAll(text[1:end], "''", "'")
+			return node, nil
+		}
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", num, err)
+		}
+		node.value = value
+		return node, nil
+	case '[', '{', '&', '*', '!', '|', '>', '%', '@', '`':
+		return nil, fmt.Errorf("line %d: %q: flow collections, anchors, tags and block scalars are not supported", num, text)
+	}
+
+	switch text {
+	case "~", "null", "Null", "NULL":
+		node.null = true
+	default:
+		node.value = text
+	}
+	return node, nil
+}
+
+// assignYAML stores the node in v, struct fields are matched by their json tags.
+func assignYAML(v reflect.Value, node *yamlNode) error {
+	if node.null {
+		v.SetZero()
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if node.kind != yamlMapping {
+			return fmt.Errorf("line %d: want a mapping", node.line)
+		}
+		for _, field := range node.fields {
+			fv, ok := yamlStructField(v, field.key)
+			if !ok {
+				return fmt.Errorf("line %d: unknown field %q", field.value.line, field.key)
+			}
+			if err := assignYAML(fv, field.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if node.kind != yamlMapping {
+			return fmt.Errorf("line %d: want a mapping", node.line)
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(node.fields))
+		for _, field := range node.fields {
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := assignYAML(value, field.value); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(field.key).Convert(v.Type().Key()), value)
+		}
+		v.Set(m)
+		return nil
+	case reflect.Slice:
+		if node.kind != yamlSequence {
+			return fmt.Errorf("line %d: want a sequence", node.line)
+		}
+		v.Set(reflect.MakeSlice(v.Type(), len(node.items), len(node.items)))
+		for i, item := range node.items {
+			if err := assignYAML(v.Index(i), item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if node.kind != yamlScalar {
+		return fmt.Errorf("line %d: want a value", node.line)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(node.value)
+	case reflect.Bool:
+		switch node.value {
+		case "true", "True", "TRUE":
+			v.SetBool(true)
+		case "false", "False", "FALSE":
+			v.SetBool(false)
+		default:
+			return fmt.Errorf("line %d: %q is not a bool", node.line, node.value)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(node.value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("line %d: %q is not an integer", node.line, node.value)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(node.value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("line %d: %q is not an unsigned integer", node.line, node.value)
+		}
+		v.SetUint(n)
+	default:
+		// The config schema has no other kinds.
+		return fmt.Errorf("line %d: unsupported field type %s", node.line, v.Type())
+	}
+	return nil
+}
+
+// yamlStructField returns the field of the struct with the json tag name.
+func yamlStructField(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := range t.NumField() {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	doc := `---
+# topology of the api service
+outputs:
+  - type: console   # local debugging
+    level: DEBUG
+    theme: 'basic'
+  - type: file
+    path: "/var/log/app #1.log"
+    buffered: true
+    max_size_mb: 100
+    sync_every: 100
+    shared: false
+    sample_every: 10
+    level_rules:
+      "github.com/our/repo/internal/db/*": DEBUG
+  -
+    type: syslog
+    tag: it's-app
+...
+ignored: after the document end
+`
+	var cfg ConfigFile
+	if err := decodeYAML([]byte(doc), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []OutputConfig{
+		{Type: "console", Level: "DEBUG", Theme: "basic"},
+		{Type: "file", Path: "/var/log/app #1.log", Buffered: true, MaxSizeMB: 100, SyncEvery: "100", SampleEvery: 10,
+			LevelRules: map[string]string{"github.com/our/repo/internal/db/*": "DEBUG"}},
+		{Type: "syslog", Tag: "it's-app"},
+	}
+	if !reflect.DeepEqual(cfg.Outputs, want) {
+		t.Errorf("got %+v", cfg.Outputs)
+	}
+
+	// A sequence may be indented like its key.
+	cfg = ConfigFile{}
+	if err := decodeYAML([]byte("outputs:\n- type: json\n  level: WARN\n- {}\n"), &cfg); err == nil {
+		t.Error("flow mapping accepted")
+	}
+	if err := decodeYAML([]byte("outputs:\n- type: json\n  level: WARN\n- type: console\n"), &cfg); err != nil ||
+		!reflect.DeepEqual(cfg.Outputs, []OutputConfig{{Type: "json", Level: "WARN"}, {Type: "console"}}) {
+		t.Errorf("got %+v, %v", cfg.Outputs, err)
+	}
+}
+
+func TestDecodeYAMLErrors(t *testing.T) {
+	for name, doc := range map[string]string{
+		"empty":           "# nothing\n",
+		"unknown field":   "outputs:\n  - type: console\n    colour: true\n",
+		"duplicate key":   "outputs:\n  - type: console\n    type: json\n",
+		"bad int":         "outputs:\n  - type: file\n    max_size_mb: big\n",
+		"negative uint":   "outputs:\n  - type: file\n    sample_every: -1\n",
+		"rules sequence":  "outputs:\n  - type: file\n    level_rules:\n      - DEBUG\n",
+		"bad bool":        "outputs:\n  - type: console\n    buffered: yes\n",
+		"scalar outputs":  "outputs: console\n",
+		"mapping value":   "outputs:\n  - type:\n      name: console\n",
+		"bad indentation": "outputs:\n  - type: console\n      level: INFO\n",
+		"tab":             "outputs:\n\t- type: console\n",
+		"flow sequence":   "outputs: [console]\n",
+		"anchor":          "outputs:\n  - type: &t console\n",
+		"block scalar":    "outputs:\n  - path: |\n      app.log\n",
+		"unterminated":    "outputs:\n  - path: \"app.log\n",
+		"not a mapping":   "- type: console\n",
+	} {
+		var cfg ConfigFile
+		if err := decodeYAML([]byte(doc), &cfg); err == nil {
+			t.Errorf("%s: no error, got %+v", name, cfg)
+		}
+	}
+}
+
+func TestNewFromYAMLConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	cfgPath := filepath.Join(dir, "logger.yml")
+
+	cfg := "outputs:\n  - type: file\n    path: " + logPath + "\n    level: WARN\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewFromConfigFile(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := slog.New(h)
+	log.Info("skipped")
+	log.Warn("written")
+
+	// The YAML file is reloaded like a JSON one.
+	if err = os.WriteFile(cfgPath, []byte(strings.Replace(cfg, "WARN", "INFO", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	log.Info("after reload")
+
+	if err = h.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := string(data); strings.Contains(out, "skipped") || !strings.Contains(out, `"msg":"written"`) ||
+		!strings.Contains(out, `"msg":"after reload"`) {
+		t.Fatalf("file content = %q", out)
+	}
+}
@@ -0,0 +1,219 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// DuplicatePolicy controls how ctx attrs with a repeated key are encoded.
+type DuplicatePolicy int
+
+const (
+	// DuplicatesKeep emits every ctx attr, even if the key is repeated.
+	DuplicatesKeep DuplicatePolicy = iota
+	// DuplicatesLast emits only the last ctx attr with the key.
+	DuplicatesLast
+	// DuplicatesFirst emits only the first ctx attr with the key.
+	DuplicatesFirst
+)
+
+// AppendAttrsToCtx adds attrs to ctx with AttrsKey, if the ctx already contains attrs, they are added to the existing ones.
+func AppendAttrsToCtx(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+
+	if val, _ := ctx.Value(AttrsKey).([]slog.Attr); len(val) != 0 {
+		attrs = append(val[:len(val):len(val)], attrs...)
+	}
+
+	return context.WithValue(ctx, AttrsKey, attrs)
+}
+
+// SetAttrInCtx adds attr to ctx with AttrsKey, replacing the attrs with the same key instead of appending a duplicate.
+func SetAttrInCtx(ctx context.Context, attr slog.Attr) context.Context {
+	val, _ := ctx.Value(AttrsKey).([]slog.Attr)
+
+	attrs := make([]slog.Attr, 0, len(val)+1)
+	replaced := false
+	for _, v := range val {
+		if v.Key != attr.Key {
+			attrs = append(attrs, v)
+			continue
+		}
+
+		// The first attr with the key is replaced in place, the rest are dropped.
+		if !replaced {
+			attrs = append(attrs, attr)
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		attrs = append(attrs, attr)
+	}
+
+	return context.WithValue(ctx, AttrsKey, attrs)
+}
+
+// CtxAttrs returns the attrs added to ctx with AppendAttrsToCtx or SetAttrInCtx.
+// The returned slice must not be modified.
+func CtxAttrs(ctx context.Context) []slog.Attr {
+	val, _ := ctx.Value(AttrsKey).([]slog.Attr)
+	return slices.Clip(val)
+}
+
+// addCtxAttrs adds attrs from AppendAttrsToCtx/SetAttrInCtx and the ctx extractors to the record.
+func (h *Handler) addCtxAttrs(ctx context.Context, record *slog.Record) {
+	val, _ := ctx.Value(AttrsKey).([]slog.Attr)
+
+	for _, extract := range h.shared.extractors {
+		if attrs := extract(ctx); len(attrs) > 0 {
+			// Full slice expression, so the attrs stored in ctx are never modified.
+			val = append(val[:len(val):len(val)], attrs...)
+		}
+	}
+
+	if len(val) == 0 {
+		return
+	}
+
+	val = dedupeAttrs(val, h.shared.ctxDuplicates)
+
+	if h.shared.ctxAttrsGroup != "" {
+		val = []slog.Attr{{Key: h.shared.ctxAttrsGroup, Value: slog.GroupValue(val...)}}
+	}
+
+	if !h.shared.ctxFirst || record.NumAttrs() == 0 {
+		record.AddAttrs(val...)
+		return
+	}
+
+	// slog.Record can only append, the record attrs are added again after the ctx attrs.
+	r := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	r.AddAttrs(val...)
+	record.Attrs(func(attr slog.Attr) bool {
+		r.AddAttrs(attr)
+		return true
+	})
+	*record = r
+}
+
+// dedupeScanLimit is the count of ctx attrs up to which duplicates are found by scanning, without a map.
+const dedupeScanLimit = 16
+
+// dedupeAttrs applies the duplicate policy, attrs is returned as is if there are no duplicates.
+func dedupeAttrs(attrs []slog.Attr, policy DuplicatePolicy) []slog.Attr {
+	if policy == DuplicatesKeep || len(attrs) < 2 {
+		return attrs
+	}
+	if len(attrs) > dedupeScanLimit {
+		return dedupeAttrsMap(attrs, policy)
+	}
+	if !hasDuplicateKeys(attrs) {
+		return attrs
+	}
+
+	deduped := make([]slog.Attr, 0, len(attrs))
+	for i, attr := range attrs {
+		if policy == DuplicatesFirst && containsKey(attrs[:i], attr.Key) ||
+			policy == DuplicatesLast && containsKey(attrs[i+1:], attr.Key) {
+			continue
+		}
+		deduped = append(deduped, attr)
+	}
+
+	return deduped
+}
+
+// dedupeAttrsMap is dedupeAttrs for many attrs, it stays linear in their count.
+func dedupeAttrsMap(attrs []slog.Attr, policy DuplicatePolicy) []slog.Attr {
+	// kept is the index of the attr emitted for every key.
+	kept := make(map[string]int, len(attrs))
+	for i, attr := range attrs {
+		if _, ok := kept[attr.Key]; !ok || policy == DuplicatesLast {
+			kept[attr.Key] = i
+		}
+	}
+	if len(kept) == len(attrs) {
+		return attrs
+	}
+
+	deduped := make([]slog.Attr, 0, len(kept))
+	for i, attr := range attrs {
+		if kept[attr.Key] == i {
+			deduped = append(deduped, attr)
+		}
+	}
+
+	return deduped
+}
+
+func hasDuplicateKeys(attrs []slog.Attr) bool {
+	for i := range attrs {
+		if containsKey(attrs[i+1:], attrs[i].Key) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKey(attrs []slog.Attr, key string) bool {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ctxAttrsHandler adds the ctx attrs to records of a handler that doesn't read AttrsKey itself.
+type ctxAttrsHandler struct {
+	handler slog.Handler
+}
+
+// WithCtxAttrs wraps any slog.Handler (stdlib, zap-slog, etc.), so records logged with a ctx
+// carrying AppendAttrsToCtx/SetAttrInCtx attrs get them appended before delegating to h.
+// The wrapped handler receives a ctx without the attrs, so handlers of this package don't add them twice.
+func WithCtxAttrs(h slog.Handler) slog.Handler {
+	if _, ok := h.(*ctxAttrsHandler); ok {
+		return h
+	}
+	return &ctxAttrsHandler{handler: h}
+}
+
+func (c *ctxAttrsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return c.handler.Enabled(ctx, level)
+}
+
+func (c *ctxAttrsHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx == nil {
+		return c.handler.Handle(ctx, record)
+	}
+
+	attrs, _ := ctx.Value(AttrsKey).([]slog.Attr)
+	if len(attrs) == 0 {
+		return c.handler.Handle(ctx, record)
+	}
+
+	// The record may be shared with other handlers by the caller, so the attrs are added to a clone.
+	record = record.Clone()
+	record.AddAttrs(attrs...)
+
+	return c.handler.Handle(context.WithValue(ctx, AttrsKey, []slog.Attr(nil)), record)
+}
+
+func (c *ctxAttrsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return c
+	}
+	return &ctxAttrsHandler{handler: c.handler.WithAttrs(attrs)}
+}
+
+func (c *ctxAttrsHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return c
+	}
+	return &ctxAttrsHandler{handler: c.handler.WithGroup(name)}
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestCtxAttrs(t *testing.T) {
+	base := AppendAttrsToCtx(context.Background(), slog.String("trace_id", "af82"), slog.Int("user", 1))
+	// Appending to a derived ctx doesn't change the parent attrs.
+	a := AppendAttrsToCtx(base, slog.String("a", "1"))
+	b := AppendAttrsToCtx(base, slog.String("b", "2"))
+
+	if got := CtxAttrs(a); len(got) != 3 || got[2].Key != "a" {
+		t.Errorf("a: %v", got)
+	}
+	if got := CtxAttrs(b); len(got) != 3 || got[2].Key != "b" {
+		t.Errorf("b: %v", got)
+	}
+	if AppendAttrsToCtx(base) != base {
+		t.Error("AppendAttrsToCtx without attrs returns another ctx")
+	}
+
+	// SetAttrInCtx replaces the attr in place and drops the later duplicates.
+	set := SetAttrInCtx(AppendAttrsToCtx(base, slog.String("trace_id", "dup")), slog.String("trace_id", "c91d"))
+	if got := CtxAttrs(set); len(got) != 2 || got[0].String() != "trace_id=c91d" || got[1].Key != "user" {
+		t.Errorf("set: %v", got)
+	}
+	if got := CtxAttrs(SetAttrInCtx(context.Background(), slog.Bool("new", true))); len(got) != 1 {
+		t.Errorf("set in empty ctx: %v", got)
+	}
+	if got := CtxAttrs(base); len(got) != 2 || got[0].String() != "trace_id=af82" {
+		t.Errorf("base changed: %v", got)
+	}
+}
+
+func TestCtxAttrsGroup(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewJsonHandler(&buf, &Config{CtxAttrsGroup: "request"}))
+	ctx := AppendAttrsToCtx(context.Background(), slog.String("id", "r-1"))
+
+	l.WithGroup("g").InfoContext(ctx, "m", "n", 1)
+	l.InfoContext(context.Background(), "no ctx attrs")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.HasSuffix(lines[0], `"msg":"m","g":{"n":1,"request":{"id":"r-1"}}}`) {
+		t.Errorf("grouped ctx attrs: %s", lines[0])
+	}
+	if strings.Contains(lines[1], "request") {
+		t.Errorf("empty ctx group written: %s", lines[1])
+	}
+}
+
+func TestDedupeAttrs(t *testing.T) {
+	// naive keeps the attrs that have no other attr with the key before (first) or after (last) them.
+	naive := func(attrs []slog.Attr, policy DuplicatePolicy) string {
+		var b strings.Builder
+		for i, attr := range attrs {
+			dup := false
+			for j, other := range attrs {
+				if other.Key == attr.Key && (policy == DuplicatesFirst && j < i || policy == DuplicatesLast && j > i) {
+					dup = true
+				}
+			}
+			if !dup {
+				b.WriteString(attr.String() + " ")
+			}
+		}
+		return b.String()
+	}
+	join := func(attrs []slog.Attr) string {
+		var b strings.Builder
+		for _, attr := range attrs {
+			b.WriteString(attr.String() + " ")
+		}
+		return b.String()
+	}
+
+	// Small sets are scanned, large ones go through a map.
+	for _, n := range []int{4, dedupeScanLimit + 4} {
+		for _, distinct := range []int{3, n} {
+			attrs := make([]slog.Attr, 0, n)
+			for i := range n {
+				attrs = append(attrs, slog.Int(string(rune('a'+i%distinct)), i))
+			}
+
+			for _, policy := range []DuplicatePolicy{DuplicatesKeep, DuplicatesFirst, DuplicatesLast} {
+				got := dedupeAttrs(attrs, policy)
+				if join(got) != naive(attrs, policy) {
+					t.Errorf("n=%d distinct=%d policy=%d: %s, want %s", n, distinct, policy, join(got), naive(attrs, policy))
+				}
+				// Attrs without duplicates are not copied.
+				if (distinct == n || policy == DuplicatesKeep) && &got[0] != &attrs[0] {
+					t.Errorf("n=%d distinct=%d policy=%d: attrs copied", n, distinct, policy)
+				}
+			}
+		}
+	}
+}
+
+func TestCtxAttrsDuplicates(t *testing.T) {
+	ctx := AppendAttrsToCtx(context.Background(), slog.String("id", "1"), slog.String("user", "u"), slog.String("id", "2"))
+
+	for policy, want := range map[DuplicatePolicy]string{
+		DuplicatesKeep:  `"msg":"m","id":"1","user":"u","id":"2"}`,
+		DuplicatesFirst: `"msg":"m","id":"1","user":"u"}`,
+		DuplicatesLast:  `"msg":"m","user":"u","id":"2"}`,
+	} {
+		var buf bytes.Buffer
+		slog.New(NewJsonHandler(&buf, &Config{CtxAttrsDuplicates: policy})).InfoContext(ctx, "m")
+		if !strings.HasSuffix(strings.TrimSpace(buf.String()), want) {
+			t.Errorf("policy %d: %s, want suffix %s", policy, buf.String(), want)
+		}
+	}
+}
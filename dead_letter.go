@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrDeadLetterPending is returned by DeadLetterWriter.Close if spooled records couldn't be replayed.
+var ErrDeadLetterPending = errors.New("dead letter records not replayed")
+
+// DeadLetterStats are the counters of a DeadLetterWriter in records (lines).
+type DeadLetterStats struct {
+	// records kept because a write failed
+	Spooled uint64
+	// spooled records written after the writer recovered
+	Recovered uint64
+	// spooled records evicted to stay within the size limit
+	Dropped uint64
+	// bytes waiting for replay
+	Pending int
+}
+
+// DeadLetterWriter keeps the encoded records whose write failed (network sink down, disk full) in a bounded
+// in-memory spool and replays them in order once the writer accepts data again. Failed writes are reported as
+// successful to the handler, so buffered output keeps working. Replay is tried before every write and on Flush,
+// which handlers call from their flusher. When the spool exceeds maxBytes the oldest records are dropped.
+type DeadLetterWriter struct {
+	mu sync.Mutex
+
+	w        io.Writer
+	flusher  writeFlusher
+	maxBytes int
+
+	spool   [][]byte
+	pending int
+	stats   DeadLetterStats
+}
+
+// NewDeadLetterWriter wraps w with a spool of at most maxBytes.
+func NewDeadLetterWriter(w io.Writer, maxBytes int) (*DeadLetterWriter, error) {
+	if w == nil {
+		return nil, ErrNilWriter
+	}
+
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("%w: maxBytes must be positive, got %d", ErrInvalidConfig, maxBytes)
+	}
+
+	d := &DeadLetterWriter{w: w, maxBytes: maxBytes}
+	d.flusher, _ = w.(writeFlusher)
+
+	return d, nil
+}
+
+func (d *DeadLetterWriter) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// New records wait behind the spooled ones to keep the order.
+	if !d.replay() {
+		d.push(p)
+		return len(p), nil
+	}
+
+	if n, err := d.w.Write(p); err != nil {
+		d.push(p[n:])
+	}
+
+	return len(p), nil
+}
+
+// Flush replays the spool and flushes the writer if it supports that.
+func (d *DeadLetterWriter) Flush() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.replay() || d.flusher == nil {
+		return nil
+	}
+	return d.flusher.Flush()
+}
+
+// Stats returns the current counters.
+func (d *DeadLetterWriter) Stats() DeadLetterStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := d.stats
+	stats.Pending = d.pending
+	return stats
+}
+
+// Close makes a last replay attempt, spooled records that are still pending are lost.
+// It doesn't close the wrapped writer.
+func (d *DeadLetterWriter) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.replay() {
+		return fmt.Errorf("%w: %d bytes", ErrDeadLetterPending, d.pending)
+	}
+	return nil
+}
+
+// replay writes the spooled records, it reports false if the writer failed again.
+func (d *DeadLetterWriter) replay() bool {
+	for len(d.spool) > 0 {
+		chunk := d.spool[0]
+
+		n, err := d.w.Write(chunk)
+		d.pending -= n
+		if err != nil {
+			d.spool[0] = chunk[n:]
+			return false
+		}
+
+		d.stats.Recovered += records(chunk)
+		d.spool[0] = nil
+		d.spool = d.spool[1:]
+	}
+
+	d.spool = nil
+	return true
+}
+
+// push spools a copy of p, evicting the oldest records above maxBytes.
+func (d *DeadLetterWriter) push(p []byte) {
+	if len(p) == 0 {
+		return
+	}
+
+	n := records(p)
+	d.stats.Spooled += n
+
+	if len(p) > d.maxBytes {
+		d.stats.Dropped += n
+		return
+	}
+
+	d.spool = append(d.spool, bytes.Clone(p))
+	d.pending += len(p)
+
+	for d.pending > d.maxBytes {
+		oldest := d.spool[0]
+		d.stats.Dropped += records(oldest)
+		d.pending -= len(oldest)
+		d.spool[0] = nil
+		d.spool = d.spool[1:]
+	}
+}
+
+// records is the count of records in a chunk written by a handler.
+func records(p []byte) uint64 {
+	return uint64(max(1, bytes.Count(p, []byte{'\n'})))
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+// flakyWriter fails while down is set.
+type flakyWriter struct {
+	bytes.Buffer
+	down bool
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	if w.down {
+		return 0, errors.New("sink down")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestDeadLetterWriter(t *testing.T) {
+	sink := &flakyWriter{down: true}
+	dl, err := NewDeadLetterWriter(sink, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = dl.Write([]byte("first record\n"))
+	_, _ = dl.Write([]byte("second record\n"))
+	// Doesn't fit with the others, the oldest one is evicted.
+	_, _ = dl.Write([]byte("third record, a bit longer than the others\n"))
+
+	sink.down = false
+	_, _ = dl.Write([]byte("fourth\n"))
+
+	if want := "second record\nthird record, a bit longer than the others\nfourth\n"; sink.String() != want {
+		t.Errorf("sink = %q, want %q", sink.String(), want)
+	}
+
+	want := DeadLetterStats{Spooled: 3, Recovered: 2, Dropped: 1}
+	if got := dl.Stats(); got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestDeadLetterWriterWithHandler(t *testing.T) {
+	sink := &flakyWriter{down: true}
+	dl, _ := NewDeadLetterWriter(sink, 1<<20)
+	h := NewJsonHandler(dl, nil)
+	logger := slog.New(h)
+
+	logger.Info("while down")
+	sink.down = false
+	logger.Info("recovered")
+	_ = h.Close(context.Background())
+
+	if h.Stats().WriteErrors != 0 {
+		t.Error("spooled writes are counted as errors")
+	}
+
+	if n := bytes.Count(sink.Bytes(), []byte("\n")); n != 2 {
+		t.Errorf("sink got %d records: %q", n, sink.String())
+	}
+}
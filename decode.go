@@ -0,0 +1,210 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+var ErrMalformedLine = errors.New("malformed json log line")
+
+// DecodeJSONLine parses a line written by the JSON handler back into a slog.Record.
+// Nested objects become groups, arrays are decoded as slog.AnyValue([]any).
+// Values are typed by their JSON representation, so durations come back as int64 nanoseconds
+// and time attrs as strings.
+func DecodeJSONLine(line []byte) (slog.Record, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return slog.Record{}, err
+	}
+
+	var (
+		t     time.Time
+		level slog.Level
+		msg   string
+		attrs []slog.Attr
+
+		seenTime, seenLevel, seenMsg bool
+	)
+
+	for dec.More() {
+		key, err := decodeKey(dec)
+		if err != nil {
+			return slog.Record{}, err
+		}
+
+		value, err := decodeValue(dec)
+		if err != nil {
+			return slog.Record{}, err
+		}
+
+		// Built-in fields are taken only once, a repeated key is kept as an attr.
+		switch {
+		case key == slog.TimeKey && !seenTime && value.Kind() == slog.KindString:
+			t, err = time.ParseInLocation(time.DateTime, value.String(), time.Local)
+			if err != nil {
+				return slog.Record{}, fmt.Errorf("%w: time: %w", ErrMalformedLine, err)
+			}
+			seenTime = true
+		case key == slog.LevelKey && !seenLevel && value.Kind() == slog.KindString:
+			if level, err = ParseLevelName(value.String()); err != nil {
+				return slog.Record{}, fmt.Errorf("%w: level: %w", ErrMalformedLine, err)
+			}
+			seenLevel = true
+		case key == slog.MessageKey && !seenMsg && value.Kind() == slog.KindString:
+			msg = value.String()
+			seenMsg = true
+		default:
+			attrs = append(attrs, slog.Attr{Key: key, Value: value})
+		}
+	}
+
+	if err := expectDelim(dec, '}'); err != nil {
+		return slog.Record{}, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return slog.Record{}, fmt.Errorf("%w: unexpected data after object", ErrMalformedLine)
+	}
+
+	record := slog.NewRecord(t, level, msg, 0)
+	record.AddAttrs(attrs...)
+
+	return record, nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedLine, err)
+	}
+
+	if tok != delim {
+		return fmt.Errorf("%w: expected %q, got %v", ErrMalformedLine, delim, tok)
+	}
+
+	return nil
+}
+
+func decodeKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrMalformedLine, err)
+	}
+
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: expected key, got %v", ErrMalformedLine, tok)
+	}
+
+	if key == "!EMPTY_KEY" {
+		return "", nil
+	}
+
+	return key, nil
+}
+
+func decodeValue(dec *json.Decoder) (slog.Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return slog.Value{}, fmt.Errorf("%w: %w", ErrMalformedLine, err)
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			list, err := decodeList(dec)
+			if err != nil {
+				return slog.Value{}, err
+			}
+			return slog.AnyValue(list), nil
+		}
+
+		var group []slog.Attr
+		for dec.More() {
+			key, err := decodeKey(dec)
+			if err != nil {
+				return slog.Value{}, err
+			}
+
+			value, err := decodeValue(dec)
+			if err != nil {
+				return slog.Value{}, err
+			}
+
+			group = append(group, slog.Attr{Key: key, Value: value})
+		}
+
+		if err = expectDelim(dec, '}'); err != nil {
+			return slog.Value{}, err
+		}
+
+		return slog.GroupValue(group...), nil
+	case string:
+		if tok == "!EMPTY_VALUE" {
+			return slog.StringValue(""), nil
+		}
+		return slog.StringValue(tok), nil
+	case json.Number:
+		return numberValue(tok), nil
+	case bool:
+		return slog.BoolValue(tok), nil
+	default:
+		return slog.AnyValue(nil), nil
+	}
+}
+
+// decodeList decodes the rest of an array whose '[' is already consumed.
+func decodeList(dec *json.Decoder) ([]any, error) {
+	list := []any{}
+
+	for dec.More() {
+		value, err := decodeValue(dec)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, groupToAny(value))
+	}
+
+	if err := expectDelim(dec, ']'); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// groupToAny converts decoded values nested in arrays to plain Go values.
+func groupToAny(value slog.Value) any {
+	if value.Kind() != slog.KindGroup {
+		return value.Any()
+	}
+
+	obj := make(map[string]any, len(value.Group()))
+	for _, attr := range value.Group() {
+		obj[attr.Key] = groupToAny(attr.Value)
+	}
+
+	return obj
+}
+
+// numberValue keeps integers as int64/uint64 and falls back to float64.
+func numberValue(n json.Number) slog.Value {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return slog.Int64Value(i)
+	}
+
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		return slog.Uint64Value(u)
+	}
+
+	f, _ := strconv.ParseFloat(string(n), 64)
+	return slog.Float64Value(f)
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestDecodeJSONLineRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewJsonHandler(&buf, &Config{Level: int(slog.LevelDebug)}))
+
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local)
+	record := slog.NewRecord(now, slog.LevelWarn, `quoted "msg"`, 0)
+	record.AddAttrs(
+		slog.String("user_id", "user_99"),
+		slog.Int("amount", -500),
+		slog.Uint64("big", 1<<63),
+		slog.Float64("ratio", 0.5),
+		slog.Bool("retry", true),
+		slog.Group("group", slog.String("key", "val"), slog.Group("under_group", slog.String("qwe", "tte"))),
+		slog.Any("list", []int{1, 2}),
+	)
+
+	if err := logger.WithGroup("g1").WithGroup("g2").Handler().Handle(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DecodeJSONLine(buf.Bytes())
+	if err != nil {
+		t.Fatalf("DecodeJSONLine(%s): %v", buf.Bytes(), err)
+	}
+
+	if !got.Time.Equal(now) || got.Level != slog.LevelWarn || got.Message != record.Message {
+		t.Errorf("got time=%v level=%v msg=%q", got.Time, got.Level, got.Message)
+	}
+
+	var attrs []slog.Attr
+	got.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	if len(attrs) != 1 || attrs[0].Key != "g1" {
+		t.Fatalf("expected single group g1, got %v", attrs)
+	}
+
+	inner := attrs[0].Value.Group()
+	if len(inner) != 1 || inner[0].Key != "g2" {
+		t.Fatalf("expected single group g2, got %v", inner)
+	}
+
+	want := []slog.Attr{
+		slog.String("user_id", "user_99"),
+		slog.Int64("amount", -500),
+		slog.Uint64("big", 1<<63),
+		slog.Float64("ratio", 0.5),
+		slog.Bool("retry", true),
+		slog.Group("group", slog.String("key", "val"), slog.Group("under_group", slog.String("qwe", "tte"))),
+	}
+
+	members := inner[0].Value.Group()
+	if len(members) != len(want)+1 {
+		t.Fatalf("got %d attrs, want %d: %v", len(members), len(want)+1, members)
+	}
+
+	for i, attr := range want {
+		if !members[i].Equal(attr) {
+			t.Errorf("attr %d: got %v, want %v", i, members[i], attr)
+		}
+	}
+
+	if list, ok := members[len(want)].Value.Any().([]any); !ok || len(list) != 2 {
+		t.Errorf("list: got %v", members[len(want)])
+	}
+}
+
+func TestDecodeJSONLineMalformed(t *testing.T) {
+	for _, line := range []string{``, `[]`, `{"time":1`, `{"level":"NOPE"}`, `{"msg":"m"} {}`} {
+		if _, err := DecodeJSONLine([]byte(line)); !errors.Is(err, ErrMalformedLine) {
+			t.Errorf("DecodeJSONLine(%q) err = %v", line, err)
+		}
+	}
+}
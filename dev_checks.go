@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// key slog uses for a value without a key (odd count of key/value args).
+	badKey = "!BADKEY"
+	// message of the records reporting misuse.
+	misuseMsg = "logger misuse"
+)
+
+// checkRecord returns the mistakes found in the record attrs.
+func (h *Handler) checkRecord(record slog.Record) []string {
+	var problems []string
+
+	// Clip, so appending record keys never modifies the keys of the handler.
+	keys := slices.Clip(h.devKeys)
+	topLevel := h.groupPrefix == ""
+
+	record.Attrs(func(attr slog.Attr) bool {
+		problems, keys = checkAttr(problems, keys, attr, topLevel)
+		return true
+	})
+
+	return problems
+}
+
+// checkWithAttrs stores the keys of WithAttrs() for the duplicate check and reports mistakes in the attrs.
+func (h *Handler) checkWithAttrs(attrs []slog.Attr) {
+	var problems []string
+
+	keys := slices.Clip(h.devKeys)
+	for _, attr := range attrs {
+		problems, keys = checkAttr(problems, keys, attr, h.groupPrefix == "")
+	}
+	h.devKeys = keys
+
+	if len(problems) > 0 {
+		_ = h.reportMisuse("WithAttrs", problems)
+	}
+}
+
+// checkAttr checks a single attr, keys holds the keys already used at the attr level.
+func checkAttr(problems []string, keys []string, attr slog.Attr, topLevel bool) ([]string, []string) {
+	if attr.Equal(slog.Attr{}) {
+		return problems, keys
+	}
+
+	switch {
+	case attr.Key == badKey:
+		problems = append(problems, fmt.Sprintf("odd number of key/value args, value %q has no key", attr.Value.String()))
+	case !utf8.ValidString(attr.Key):
+		problems = append(problems, fmt.Sprintf("key %q is not valid UTF-8", attr.Key))
+	case topLevel && isReservedKey(attr.Key):
+		problems = append(problems, fmt.Sprintf("key %q collides with a built-in field", attr.Key))
+	}
+
+	if attr.Key != "" && attr.Key != badKey {
+		if slices.Contains(keys, attr.Key) {
+			problems = append(problems, fmt.Sprintf("duplicate key %q", attr.Key))
+		}
+		keys = append(keys, attr.Key)
+	}
+
+	if attr.Value.Kind() != slog.KindGroup {
+		return problems, keys
+	}
+
+	// Members of an inline group share the level of the group.
+	if attr.Key == "" {
+		for _, member := range attr.Value.Group() {
+			problems, keys = checkAttr(problems, keys, member, topLevel)
+		}
+		return problems, keys
+	}
+
+	var groupKeys []string
+	for _, member := range attr.Value.Group() {
+		problems, groupKeys = checkAttr(problems, groupKeys, member, false)
+	}
+
+	return problems, keys
+}
+
+func isReservedKey(key string) bool {
+	switch key {
+	case slog.TimeKey, slog.LevelKey, slog.MessageKey, slog.SourceKey:
+		return true
+	default:
+		return false
+	}
+}
+
+// reportMisuse writes a WARN record listing the problems, or panics if Config.PanicOnMisuse is set.
+func (h *Handler) reportMisuse(msg string, problems []string) error {
+	return h.topLevel().write(nil, h.misuseRecord(msg, problems))
+}
+
+// misuseRecord returns the WARN record listing the problems of the record with msg as "problem_1",
+// "problem_2"... attrs, it panics if Config.PanicOnMisuse is set. The record is written by topLevel.
+func (h *Handler) misuseRecord(msg string, problems []string) slog.Record {
+	if h.shared.panicOnMisuse {
+		panic(misuseMsg + ": " + strings.Join(problems, "; "))
+	}
+
+	record := slog.NewRecord(time.Now(), slog.LevelWarn, misuseMsg, 0)
+	record.AddAttrs(slog.String("record_msg", msg))
+	for i, problem := range problems {
+		record.AddAttrs(slog.String("problem_"+strconv.Itoa(i+1), problem))
+	}
+	return record
+}
+
+// topLevel returns a clone of the handler without groups, attrs and prefix, misuse reports are written
+// with it, so they are not nested in the groups of the misused handler.
+func (h *Handler) topLevel() *Handler {
+	return &Handler{shared: h.shared, builder: h.builder, pool: h.pool}
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestDevChecks(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l *slog.Logger)
+		want []string
+	}{
+		{"odd args", func(l *slog.Logger) {
+			// A slice, so vet doesn't catch the mistake.
+			args := []any{"a", 1, "orphan"}
+			l.Info("m", args...)
+		},
+			[]string{`odd number of key/value args, value "orphan" has no key`}},
+		{"duplicate keys", func(l *slog.Logger) { l.With("id", 1).Info("m", "id", 2, "x", 1, "x", 2) },
+			[]string{`duplicate key "id"`, `duplicate key "x"`}},
+		{"reserved key", func(l *slog.Logger) { l.Info("m", "msg", "x") },
+			[]string{`key "msg" collides with a built-in field`}},
+		{"invalid key", func(l *slog.Logger) { l.Info("m", "\xff", 1) },
+			[]string{`key "\xff" is not valid UTF-8`}},
+		{"reserved key in a group", func(l *slog.Logger) { l.WithGroup("g").Info("m", "msg", "x") }, nil},
+		{"same key in groups", func(l *slog.Logger) { l.With("id", 1).WithGroup("g").Info("m", "id", 2) }, nil},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		tt.log(slog.New(NewJsonHandler(&buf, &Config{DevChecks: true})))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if tt.want == nil {
+			if len(lines) != 1 {
+				t.Errorf("%s: unexpected report %s", tt.name, buf.String())
+			}
+			continue
+		}
+		if len(lines) != 2 {
+			t.Errorf("%s: got %s", tt.name, buf.String())
+			continue
+		}
+
+		var report map[string]any
+		if err := json.Unmarshal([]byte(lines[1]), &report); err != nil {
+			t.Fatal(err)
+		}
+		if report["msg"] != misuseMsg || report["level"] != "WARN" || report["record_msg"] != "m" {
+			t.Errorf("%s: report %s", tt.name, lines[1])
+		}
+		for i, problem := range tt.want {
+			if key := "problem_" + string(rune('1'+i)); report[key] != problem {
+				t.Errorf("%s: %s = %v, want %q", tt.name, key, report[key], problem)
+			}
+		}
+		// Every key is unique: the object has exactly the known fields.
+		if len(report) != 4+len(tt.want) {
+			t.Errorf("%s: report %s", tt.name, lines[1])
+		}
+	}
+}
+
+func TestDevChecksReportTopLevel(t *testing.T) {
+	var jsonBuf bytes.Buffer
+	slog.New(NewJsonHandler(&jsonBuf, &Config{DevChecks: true})).
+		With("svc", "api").WithGroup("req").With("id", 1).Info("m", "id", 2)
+
+	lines := strings.Split(strings.TrimSpace(jsonBuf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %s", jsonBuf.String())
+	}
+	if !strings.HasSuffix(lines[1], `"msg":"logger misuse","record_msg":"m","problem_1":"duplicate key \"id\""}`) {
+		t.Errorf("json report %s", lines[1])
+	}
+
+	var textBuf bytes.Buffer
+	New(NewTextHandler(&textBuf, &Config{DevChecks: true})).
+		WithPrefix("[db]").With("svc", "api").WithGroup("req").Info("m", "a", 1, "a", 2)
+	report := ansiRe.ReplaceAllString(strings.Split(strings.TrimSpace(textBuf.String()), "\n")[1], "")
+	if strings.Contains(report, "svc=") || strings.Contains(report, "req.") || strings.Contains(report, "[db]") ||
+		!strings.Contains(report, `problem_1="duplicate key \"a\""`) {
+		t.Errorf("text report %q", report)
+	}
+
+	// The WithAttrs misuse is reported once the attrs are added.
+	jsonBuf.Reset()
+	slog.New(NewJsonHandler(&jsonBuf, &Config{DevChecks: true})).WithGroup("g").With("a", 1, "a", 2)
+	if got := jsonBuf.String(); !strings.Contains(got, `"msg":"logger misuse","record_msg":"WithAttrs","problem_1":"duplicate key \"a\""}`) {
+		t.Errorf("WithAttrs report %s", got)
+	}
+}
+
+func TestPanicOnMisuse(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewJsonHandler(&buf, &Config{DevChecks: true, PanicOnMisuse: true}))
+
+	defer func() {
+		r := recover()
+		if msg, _ := r.(string); !strings.HasPrefix(msg, misuseMsg+": ") || !strings.Contains(msg, `duplicate key "a"`) {
+			t.Errorf("recovered %v", r)
+		}
+	}()
+	l.Info("m", "a", 1, "a", 2)
+	t.Error("no panic")
+}
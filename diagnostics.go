@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// size of the diagnostics channel, diagnostics are dropped while it's full.
+const diagnosticsBufferSize = 64
+
+var ErrEncodePanic = errors.New("logger encoder panicked")
+
+// DiagnosticKind is the kind of a logger health event.
+type DiagnosticKind int
+
+const (
+	// DiagnosticEncodePanic - encoding of a record panicked (e.g. in an Error or String method), the record was not written.
+	DiagnosticEncodePanic DiagnosticKind = iota + 1
+	// DiagnosticDropped - a record was dropped, see Stats.Dropped.
+	DiagnosticDropped
+	// DiagnosticWriteError - the writer failed to write a record or to flush the buffer.
+	DiagnosticWriteError
+	// DiagnosticSlowWriter - the output was switched to Config.SlowWriteFallback.
+	DiagnosticSlowWriter
+)
+
+// diagnosticKinds is the size of arrays indexed by DiagnosticKind.
+const diagnosticKinds = int(DiagnosticSlowWriter) + 1
+
+func (k DiagnosticKind) String() string {
+	switch k {
+	case DiagnosticEncodePanic:
+		return "encode_panic"
+	case DiagnosticDropped:
+		return "dropped"
+	case DiagnosticWriteError:
+		return "write_error"
+	case DiagnosticSlowWriter:
+		return "slow_writer"
+	default:
+		return fmt.Sprintf("DiagnosticKind(%d)", int(k))
+	}
+}
+
+// Diagnostic is a logger health event sent to the channel returned by Handler.Diagnostics.
+type Diagnostic struct {
+	Kind DiagnosticKind
+	Time time.Time
+	// level and message of the affected record, empty for buffer flushes and slow writer switches.
+	Level   slog.Level
+	Message string
+	// cause of the event, nil for DiagnosticSlowWriter.
+	Err error
+	// latency of the write that caused the switch, DiagnosticSlowWriter only.
+	Latency time.Duration
+}
+
+// Diagnostics returns the channel of logger health events shared by all clones of the handler,
+// so applications can report them to their own monitoring. The channel is created on the first call,
+// events before it are not kept. Events are dropped while the channel is full, it's never closed.
+func (h *Handler) Diagnostics() <-chan Diagnostic {
+	s := h.shared
+	if ch := s.diagnostics.Load(); ch != nil {
+		return *ch
+	}
+
+	ch := make(chan Diagnostic, diagnosticsBufferSize)
+	if !s.diagnostics.CompareAndSwap(nil, &ch) {
+		return *s.diagnostics.Load()
+	}
+	return ch
+}
+
+// diagnose sends the event if Diagnostics was called, without blocking, and logs it with Config.InternalLogger.
+func (s *shared) diagnose(d Diagnostic) {
+	ch := s.diagnostics.Load()
+	if ch == nil && s.internal == nil {
+		return
+	}
+
+	d.Time = time.Now()
+	if ch != nil {
+		select {
+		case *ch <- d:
+		default:
+		}
+	}
+	if s.internal != nil {
+		s.internal.log(d)
+	}
+}
+
+// diagnoseWrite reports a failed or dropped write of the record.
+func (s *shared) diagnoseWrite(record slog.Record, err error) {
+	if err == nil {
+		return
+	}
+
+	kind := DiagnosticWriteError
+	switch {
+	case errors.Is(err, ErrEncodePanic):
+		kind = DiagnosticEncodePanic
+	case errors.Is(err, ErrRecordDropped):
+		kind = DiagnosticDropped
+	}
+
+	s.diagnose(Diagnostic{Kind: kind, Level: record.Level, Message: record.Message, Err: err})
+}
@@ -0,0 +1,26 @@
+package logger
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAppendDuration(t *testing.T) {
+	for _, d := range []time.Duration{
+		0, 1, 999, time.Microsecond, 1500 * time.Nanosecond, time.Millisecond, 15 * time.Millisecond,
+		time.Second, 1500 * time.Millisecond, time.Minute + time.Nanosecond, 90 * time.Minute,
+		-time.Millisecond, -2 * time.Hour, math.MaxInt64, math.MinInt64,
+	} {
+		if got, want := string(appendDuration(nil, d)), d.String(); got != want {
+			t.Errorf("appendDuration(%d) = %q, want %q", int64(d), got, want)
+		}
+	}
+}
+
+func TestAppendDurationAllocs(t *testing.T) {
+	buf := make([]byte, 0, 64)
+	if n := testing.AllocsPerRun(100, func() { buf = appendDuration(buf[:0], 1234567*time.Microsecond) }); n != 0 {
+		t.Errorf("appendDuration allocates %v times", n)
+	}
+}
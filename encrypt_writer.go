@@ -0,0 +1,277 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+const (
+	// frame types of the encrypted stream.
+	frameKey  = 'K'
+	frameData = 'D'
+
+	// frame header: type and big-endian payload length.
+	frameHeaderSize = 5
+	// max payload of a frame, bigger lengths mean a corrupted stream.
+	maxFrameSize = 64 << 20
+
+	// default count of data frames encrypted with one data key.
+	defaultFramesPerKey = 1 << 16
+)
+
+var ErrDecrypt = errors.New("log decryption failed")
+
+// EncryptingWriter encrypts every Write as a separate AES-256-GCM frame, so the log stays streamable
+// and a crash loses at most the last frame.
+//
+// The stream is a sequence of length-prefixed frames: key frames carry a random data key encrypted with the
+// master key, data frames carry the records encrypted with the current data key and authenticated with their
+// sequence number. Key frames are authenticated with their epoch number and the count of data frames of the
+// previous epoch, so removed or reordered frames and whole removed epochs are detected, except at the end of
+// the stream: frames cut from the tail look like a crash. The data key is rotated every framesPerKey frames.
+// Use NewDecryptingReader or cmd/slogdecrypt to read the log.
+type EncryptingWriter struct {
+	mu sync.Mutex
+
+	w      io.Writer
+	master cipher.AEAD
+
+	data         cipher.AEAD
+	seq          uint64
+	epoch        uint64
+	framesPerKey uint64
+
+	// frame is reused for encoding, frames are written with a single Write.
+	frame []byte
+}
+
+// NewEncryptingWriter creates a writer encrypting into w with the 32 byte master key,
+// framesPerKey is the count of frames encrypted with one data key, 0 - 65536.
+func NewEncryptingWriter(w io.Writer, masterKey []byte, framesPerKey int) (*EncryptingWriter, error) {
+	if w == nil {
+		return nil, ErrNilWriter
+	}
+
+	if framesPerKey < 0 {
+		return nil, fmt.Errorf("%w: framesPerKey must not be negative, got %d", ErrInvalidConfig, framesPerKey)
+	}
+
+	master, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ew := &EncryptingWriter{
+		w:            w,
+		master:       master,
+		framesPerKey: uint64(framesPerKey),
+	}
+	if ew.framesPerKey == 0 {
+		ew.framesPerKey = defaultFramesPerKey
+	}
+
+	return ew, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%w: encryption key must be 32 bytes, got %d", ErrInvalidConfig, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Write encrypts p into a single data frame, starting with a key frame if the data key is rotated.
+func (ew *EncryptingWriter) Write(p []byte) (int, error) {
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+
+	if ew.data == nil || ew.seq >= ew.framesPerKey {
+		if err := ew.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], ew.seq)
+
+	if err := ew.writeFrame(frameData, ew.data, p, seq[:]); err != nil {
+		return 0, err
+	}
+
+	ew.seq++
+	return len(p), nil
+}
+
+// rotate generates a new data key and writes it encrypted with the master key.
+func (ew *EncryptingWriter) rotate() error {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+
+	data, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	if err = ew.writeFrame(frameKey, ew.master, key, keyFrameAD(ew.epoch, ew.seq)); err != nil {
+		return err
+	}
+
+	ew.data = data
+	ew.seq = 0
+	ew.epoch++
+	return nil
+}
+
+// keyFrameAD returns the associated data of a key frame: its epoch number and the count of data frames
+// of the previous epoch, it chains the epochs of the stream.
+func keyFrameAD(epoch, prevFrames uint64) []byte {
+	var ad [16]byte
+	binary.BigEndian.PutUint64(ad[:8], epoch)
+	binary.BigEndian.PutUint64(ad[8:], prevFrames)
+	return ad[:]
+}
+
+func (ew *EncryptingWriter) writeFrame(typ byte, aead cipher.AEAD, plaintext, additional []byte) error {
+	size := aead.NonceSize() + len(plaintext) + aead.Overhead()
+	if size > maxFrameSize {
+		return fmt.Errorf("%w: frame of %d bytes is too large", ErrInvalidConfig, size)
+	}
+
+	frame := slices.Grow(ew.frame[:0], frameHeaderSize+size)[:frameHeaderSize+aead.NonceSize()]
+	frame[0] = typ
+	binary.BigEndian.PutUint32(frame[1:frameHeaderSize], uint32(size))
+
+	nonce := frame[frameHeaderSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	frame = aead.Seal(frame, nonce, plaintext, additional)
+	ew.frame = frame
+
+	_, err := ew.w.Write(frame)
+	return err
+}
+
+// decryptingReader decodes a stream written by EncryptingWriter.
+type decryptingReader struct {
+	r      *bufio.Reader
+	master cipher.AEAD
+
+	data  cipher.AEAD
+	seq   uint64
+	epoch uint64
+
+	// pending is the decrypted part of the current data frame not read yet.
+	pending []byte
+	frame   []byte
+}
+
+// NewDecryptingReader returns a reader of the plaintext log written by EncryptingWriter with masterKey.
+// Frames that fail authentication are reported with ErrDecrypt, a frame cut by a crash with io.ErrUnexpectedEOF.
+func NewDecryptingReader(r io.Reader, masterKey []byte) (io.Reader, error) {
+	master, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptingReader{r: bufio.NewReaderSize(r, writerBufSize), master: master}, nil
+}
+
+func (dr *decryptingReader) Read(p []byte) (int, error) {
+	for len(dr.pending) == 0 {
+		if err := dr.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, dr.pending)
+	dr.pending = dr.pending[n:]
+	return n, nil
+}
+
+// next reads frames until a data frame is decrypted into pending.
+func (dr *decryptingReader) next() error {
+	var header [frameHeaderSize]byte
+	// io.EOF at a frame boundary is the normal end of the log.
+	if _, err := io.ReadFull(dr.r, header[:]); err != nil {
+		return err
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxFrameSize {
+		return fmt.Errorf("%w: frame of %d bytes is too large", ErrDecrypt, size)
+	}
+
+	if cap(dr.frame) < int(size) {
+		dr.frame = make([]byte, size)
+	}
+	frame := dr.frame[:size]
+
+	if _, err := io.ReadFull(dr.r, frame); err != nil {
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	switch header[0] {
+	case frameKey:
+		// A key frame of another epoch or after a cut epoch fails authentication.
+		key, err := openFrame(dr.master, frame, keyFrameAD(dr.epoch, dr.seq))
+		if err != nil {
+			return fmt.Errorf("%w: key frame %d: %w", ErrDecrypt, dr.epoch, err)
+		}
+
+		if dr.data, err = newGCM(key); err != nil {
+			return fmt.Errorf("%w: key frame %d: %w", ErrDecrypt, dr.epoch, err)
+		}
+		dr.seq = 0
+		dr.epoch++
+	case frameData:
+		if dr.data == nil {
+			return fmt.Errorf("%w: data frame before the first key frame", ErrDecrypt)
+		}
+
+		var seq [8]byte
+		binary.BigEndian.PutUint64(seq[:], dr.seq)
+
+		plaintext, err := openFrame(dr.data, frame, seq[:])
+		if err != nil {
+			return fmt.Errorf("%w: data frame %d: %w", ErrDecrypt, dr.seq, err)
+		}
+
+		dr.seq++
+		dr.pending = plaintext
+	default:
+		return fmt.Errorf("%w: unknown frame type %q", ErrDecrypt, header[0])
+	}
+
+	return nil
+}
+
+func openFrame(aead cipher.AEAD, frame, additional []byte) ([]byte, error) {
+	if len(frame) < aead.NonceSize() {
+		return nil, errors.New("frame is too short")
+	}
+
+	nonce, ciphertext := frame[:aead.NonceSize()], frame[aead.NonceSize():]
+	// Decrypt in place, the frame buffer is reused only after pending is consumed.
+	return aead.Open(ciphertext[:0], nonce, ciphertext, additional)
+}
@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestEncryptingWriter(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+
+	var enc bytes.Buffer
+	ew, err := NewEncryptingWriter(&enc, key, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := slog.New(NewJsonHandler(ew, nil))
+	for _, msg := range []string{"card", "ssn", "iban"} {
+		log.Info(msg, "secret", "4111-1111")
+	}
+
+	if bytes.Contains(enc.Bytes(), []byte("4111-1111")) {
+		t.Fatal("plaintext leaked into the encrypted stream")
+	}
+
+	dr, err := NewDecryptingReader(bytes.NewReader(enc.Bytes()), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := io.ReadAll(dr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Count(string(plain), "\n"); lines != 3 || !strings.Contains(string(plain), `"msg":"iban"`) {
+		t.Fatalf("decrypted = %q", plain)
+	}
+
+	tampered := bytes.Clone(enc.Bytes())
+	tampered[len(tampered)-1] ^= 1
+	dr, _ = NewDecryptingReader(bytes.NewReader(tampered), key)
+	if _, err = io.ReadAll(dr); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("tampered stream: err = %v, want ErrDecrypt", err)
+	}
+
+	dr, _ = NewDecryptingReader(bytes.NewReader(enc.Bytes()[:enc.Len()-3]), key)
+	if _, err = io.ReadAll(dr); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("cut stream: err = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestEncryptingWriterEpochs(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+
+	var enc bytes.Buffer
+	ew, err := NewEncryptingWriter(&enc, key, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"a\n", "b\n", "c\n"} {
+		if _, err = ew.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// K D K D K D
+	var frames [][]byte
+	for rest := enc.Bytes(); len(rest) > 0; {
+		size := frameHeaderSize + int(binary.BigEndian.Uint32(rest[1:frameHeaderSize]))
+		frames = append(frames, rest[:size])
+		rest = rest[size:]
+	}
+	if len(frames) != 6 {
+		t.Fatalf("frames = %d, want 6", len(frames))
+	}
+
+	tests := []struct {
+		name   string
+		frames []int
+		want   error
+	}{
+		{"intact", []int{0, 1, 2, 3, 4, 5}, nil},
+		{"epoch removed", []int{0, 1, 4, 5}, ErrDecrypt},
+		{"data frame removed before a key frame", []int{0, 2, 3, 4, 5}, ErrDecrypt},
+		{"epochs swapped", []int{0, 1, 4, 5, 2, 3}, ErrDecrypt},
+		{"tail epoch cut", []int{0, 1, 2, 3}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stream []byte
+			for _, i := range tt.frames {
+				stream = append(stream, frames[i]...)
+			}
+
+			dr, _ := NewDecryptingReader(bytes.NewReader(stream), key)
+			if _, err := io.ReadAll(dr); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"log/slog"
+	"reflect"
+	"strings"
+
+	"github.com/ttrtcixy/fast-slog-handler/internal/escape"
+)
+
+// maxErrChainLen bounds the chain of errors with broken Unwrap methods.
+const maxErrChainLen = 32
+
+// errChainValue holds the messages of an error chain from the outermost error.
+type errChainValue []string
+
+// ErrChain returns an "error" attr with the messages of err and the errors it wraps, JSON renders
+// them as an array ("error":["query user","conn reset"]), text as "query user: conn reset".
+// fmt.Errorf wrappers contribute their own text only, errors.Join branches are flattened in order.
+// Errors repeating in the chain are skipped.
+func ErrChain(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+
+	var chain errChainValue
+	seen := make(map[error]struct{})
+	chain.collect(err, seen)
+
+	return slog.Any("error", chain)
+}
+
+func (c *errChainValue) collect(err error, seen map[error]struct{}) {
+	for err != nil && len(*c) < maxErrChainLen {
+		// Errors of uncomparable types can't be map keys, they are bounded by maxErrChainLen only.
+		if reflect.TypeOf(err).Comparable() {
+			if _, ok := seen[err]; ok {
+				return
+			}
+			seen[err] = struct{}{}
+		}
+
+		msg := err.Error()
+
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			inner := u.Unwrap()
+			if inner == nil {
+				*c = append(*c, msg)
+				return
+			}
+			if own, ok := strings.CutSuffix(msg, ": "+inner.Error()); ok {
+				msg = own
+			}
+			*c = append(*c, msg)
+			err = inner
+		case interface{ Unwrap() []error }:
+			errs := u.Unwrap()
+			// A wrapper with its own text (fmt.Errorf with several %w) is kept as the whole message.
+			if msg != joinMessages(errs) {
+				*c = append(*c, msg)
+				return
+			}
+			for _, e := range errs {
+				c.collect(e, seen)
+			}
+			return
+		default:
+			*c = append(*c, msg)
+			return
+		}
+	}
+}
+
+// joinMessages is the message of errors.Join(errs...).
+func joinMessages(errs []error) string {
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		if e != nil {
+			msgs = append(msgs, e.Error())
+		}
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (c errChainValue) appendJSON(buf []byte) []byte {
+	buf = append(buf, '[')
+	for i, msg := range c {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, '"')
+		buf = escape.AppendJSON(buf, msg)
+		buf = append(buf, '"')
+	}
+	return append(buf, ']')
+}
+
+func (c errChainValue) appendText(buf []byte) []byte {
+	for i, msg := range c {
+		if i > 0 {
+			buf = append(buf, ": "...)
+		}
+		buf = append(buf, msg...)
+	}
+	return buf
+}
+
+// MarshalText renders the compact form for other handlers.
+func (c errChainValue) MarshalText() ([]byte, error) { return c.appendText(nil), nil }
@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// loopErr unwraps to itself.
+type loopErr struct{}
+
+func (e *loopErr) Error() string { return "loop" }
+func (e *loopErr) Unwrap() error { return e }
+
+func TestErrChain(t *testing.T) {
+	reset := errors.New("conn reset")
+	timeout := errors.New("timeout")
+	err := fmt.Errorf("query user: %w", errors.Join(fmt.Errorf("primary: %w", reset), timeout))
+
+	var js, text bytes.Buffer
+	slog.New(NewJsonHandler(&js, nil)).Error("failed", ErrChain(err))
+	slog.New(NewTextHandler(&text, nil)).Error("failed", ErrChain(err))
+
+	if want := `"error":["query user","primary","conn reset","timeout"]`; !strings.Contains(js.String(), want) {
+		t.Errorf("json = %q", js.String())
+	}
+	if want := `error="query user: primary: conn reset: timeout"`; !strings.Contains(ansiRe.ReplaceAllString(text.String(), ""), want) {
+		t.Errorf("text = %q", text.String())
+	}
+
+	chain := ErrChain(fmt.Errorf("outer: %w", &loopErr{})).Value.Any().(errChainValue)
+	if len(chain) != 2 {
+		t.Errorf("cycle is not cut: %q", chain)
+	}
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/ttrtcixy/fast-slog-handler/internal/escape"
+)
+
+// EventKey is the key of the event name of Logger.Event records.
+const EventKey = "event"
+
+// eventName marks the EventKey attr of a Logger.Event record, other handlers render it as the name.
+type eventName string
+
+func (e eventName) LogValue() slog.Value {
+	return slog.StringValue(string(e))
+}
+
+// Event logs an INFO record of a named event without a message, for product analytics and other consumers
+// that need machine-stable names apart from human log prose:
+//
+//	l.Event(ctx, "user.signup", slog.String("plan", "pro"))
+//	// JSON: {"time":"...","level":"INFO","event":"user.signup","plan":"pro"}
+//	// text: INFO event=user.signup plan=pro
+//
+// The name is a top-level field in place of the message, outside of the WithGroup groups. Handlers of other
+// packages get an empty message and an EventKey attr.
+func (l *Logger) Event(ctx context.Context, name string, attrs ...slog.Attr) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	h := l.Handler()
+	if !h.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+
+	var pcs [1]uintptr
+	// skip [runtime.Callers, Event]
+	runtime.Callers(2, pcs[:])
+
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "", pcs[0])
+	record.AddAttrs(slog.Any(EventKey, eventName(name)))
+	record.AddAttrs(attrs...)
+
+	_ = h.Handle(ctx, record)
+}
+
+// splitEvent returns the name of a Logger.Event record and the record without the EventKey attr,
+// ok is false for other records.
+func splitEvent(record slog.Record) (name string, rest slog.Record, ok bool) {
+	if record.Message != "" || record.NumAttrs() == 0 {
+		return "", record, false
+	}
+
+	// The ctx attrs may come first (Config.CtxAttrsFirst), the marker is searched among all attrs.
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == EventKey && attr.Value.Kind() == slog.KindLogValuer {
+			var e eventName
+			if e, ok = attr.Value.LogValuer().(eventName); ok {
+				name = string(e)
+				return false
+			}
+		}
+		return true
+	})
+	if !ok {
+		return "", record, false
+	}
+
+	rest = slog.NewRecord(record.Time, record.Level, "", record.PC)
+	found := false
+	record.Attrs(func(attr slog.Attr) bool {
+		if !found && attr.Key == EventKey && attr.Value.Kind() == slog.KindLogValuer {
+			if _, found = attr.Value.LogValuer().(eventName); found {
+				return true
+			}
+		}
+		rest.AddAttrs(attr)
+		return true
+	})
+	return name, rest, true
+}
+
+// appendTextEvent appends the event name as a logfmt pair in place of the message of a text line.
+func appendTextEvent(buf []byte, name string) []byte {
+	buf = append(buf, EventKey+"="...)
+	if escape.NeedsQuoting(name) {
+		return strconv.AppendQuote(buf, name)
+	}
+	return append(buf, name...)
+}
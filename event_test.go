@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestEvent(t *testing.T) {
+	ctx := context.Background()
+
+	var jsonBuf bytes.Buffer
+	New(NewJsonHandler(&jsonBuf, nil)).WithGroup("req").Event(ctx, "user.signup", slog.String("plan", "pro"))
+	if got := jsonBuf.String(); !strings.Contains(got, `"level":"INFO","event":"user.signup","req":{"plan":"pro"}}`) ||
+		strings.Contains(got, `"msg"`) {
+		t.Errorf("json: %s", got)
+	}
+
+	var textBuf bytes.Buffer
+	New(NewTextHandler(&textBuf, &Config{CoalesceColors: true})).Event(ctx, "cart checkout", slog.Int("items", 3))
+	if got := textBuf.String(); !strings.Contains(got, ` event="cart checkout"`) || !strings.Contains(got, "items=3") ||
+		strings.Count(got, "event=") != 1 {
+		t.Errorf("text: %q", got)
+	}
+
+	var herokuBuf bytes.Buffer
+	New(NewHerokuHandler(&herokuBuf, nil)).Event(ctx, "user.signup")
+	if got := herokuBuf.String(); got != "at=info event=user.signup\n" {
+		t.Errorf("heroku: %q", got)
+	}
+
+	// A plain attr with the key is not an event.
+	jsonBuf.Reset()
+	slog.New(NewJsonHandler(&jsonBuf, nil)).Info("", EventKey, "user.signup")
+	if got := jsonBuf.String(); !strings.Contains(got, `"msg":"","event":"user.signup"`) {
+		t.Errorf("plain attr: %s", got)
+	}
+
+	// With ctx attrs first, the event still takes the message place.
+	jsonBuf.Reset()
+	h := NewJsonHandler(&jsonBuf, &Config{CtxAttrsFirst: true})
+	New(h).Event(AppendAttrsToCtx(ctx, slog.String("trace_id", "af82")), "user.signup")
+	if got := jsonBuf.String(); !strings.Contains(got, `"event":"user.signup","trace_id":"af82"}`) {
+		t.Errorf("ctx attrs first: %s", got)
+	}
+
+	// Other handlers get an empty message and the name attr.
+	var stdBuf bytes.Buffer
+	New(slog.NewTextHandler(&stdBuf, nil)).Event(ctx, "user.signup")
+	if got := stdBuf.String(); !strings.Contains(got, `msg="" event=user.signup`) {
+		t.Errorf("stdlib: %q", got)
+	}
+}
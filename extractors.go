@@ -0,0 +1,199 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"runtime/pprof"
+	"slices"
+	"strings"
+)
+
+// ContextExtractor returns attrs derived from ctx, it's called for every handled record
+// and the attrs are encoded like the ones added with AppendAttrsToCtx.
+type ContextExtractor func(ctx context.Context) []slog.Attr
+
+type traceparentCtxKey struct {
+}
+
+type baggageCtxKey struct {
+}
+
+// ContextWithTraceparent stores the raw W3C traceparent header value in ctx for TraceparentExtractor.
+func ContextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	return context.WithValue(ctx, traceparentCtxKey{}, traceparent)
+}
+
+// ContextWithBaggage stores the raw W3C baggage header value in ctx for BaggageExtractor.
+func ContextWithBaggage(ctx context.Context, baggage string) context.Context {
+	return context.WithValue(ctx, baggageCtxKey{}, baggage)
+}
+
+// TraceparentExtractor emits trace_id, span_id and trace_flags from the traceparent stored with ContextWithTraceparent.
+// Malformed values are emitted as is in the traceparent attr.
+func TraceparentExtractor(ctx context.Context) []slog.Attr {
+	header, ok := ctx.Value(traceparentCtxKey{}).(string)
+	if !ok || header == "" {
+		return nil
+	}
+
+	traceID, spanID, flags, ok := parseTraceparent(header)
+	if !ok {
+		return []slog.Attr{slog.String("traceparent", header)}
+	}
+
+	return []slog.Attr{
+		slog.String("trace_id", traceID),
+		slog.String("span_id", spanID),
+		slog.String("trace_flags", flags),
+	}
+}
+
+// parseTraceparent parses "version-trace_id-span_id-flags", e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func parseTraceparent(header string) (traceID, spanID, flags string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 {
+		return "", "", "", false
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+
+	// Future versions may append fields, version 00 must have exactly four.
+	if !isLowerHex(version, 2) || version == "ff" || version == "00" && len(parts) != 4 {
+		return "", "", "", false
+	}
+
+	if !isLowerHex(traceID, 32) || !isLowerHex(spanID, 16) || !isLowerHex(flags, 2) {
+		return "", "", "", false
+	}
+
+	// All zero ids are invalid.
+	if strings.Trim(traceID, "0") == "" || strings.Trim(spanID, "0") == "" {
+		return "", "", "", false
+	}
+
+	return traceID, spanID, flags, true
+}
+
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// BaggageExtractor emits members of the baggage stored with ContextWithBaggage as a "baggage" group.
+// If keys are passed, only these members are emitted.
+func BaggageExtractor(keys ...string) ContextExtractor {
+	return func(ctx context.Context) []slog.Attr {
+		header, ok := ctx.Value(baggageCtxKey{}).(string)
+		if !ok || header == "" {
+			return nil
+		}
+
+		members := parseBaggage(header, keys)
+		if len(members) == 0 {
+			return nil
+		}
+
+		return []slog.Attr{{Key: "baggage", Value: slog.GroupValue(members...)}}
+	}
+}
+
+// parseBaggage parses "key1=value1;property,key2=value2", properties are dropped and values are percent-decoded.
+func parseBaggage(header string, keys []string) []slog.Attr {
+	var members []slog.Attr
+
+	for member := range strings.SplitSeq(header, ",") {
+		member, _, _ = strings.Cut(member, ";")
+
+		key, value, ok := strings.Cut(member, "=")
+		if !ok {
+			continue
+		}
+
+		key = strings.TrimSpace(key)
+		if key == "" || len(keys) > 0 && !slices.Contains(keys, key) {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if decoded, err := url.PathUnescape(value); err == nil {
+			value = decoded
+		}
+
+		members = append(members, slog.String(key, value))
+	}
+
+	return members
+}
+
+// PprofLabelsExtractor emits the runtime/pprof labels of ctx (set with pprof.Do or WithPprofLabels) as attrs,
+// so CPU profiles and logs can be correlated by e.g. request_id. If keys are passed, only these labels are emitted.
+func PprofLabelsExtractor(keys ...string) ContextExtractor {
+	return func(ctx context.Context) []slog.Attr {
+		var attrs []slog.Attr
+
+		if len(keys) == 0 {
+			pprof.ForLabels(ctx, func(key, value string) bool {
+				attrs = append(attrs, slog.String(key, value))
+				return true
+			})
+			return attrs
+		}
+
+		for _, key := range keys {
+			if value, ok := pprof.Label(ctx, key); ok {
+				attrs = append(attrs, slog.String(key, value))
+			}
+		}
+		return attrs
+	}
+}
+
+// WithPprofLabels returns ctx with the attrs added as pprof labels and sets them on the calling goroutine,
+// so the CPU samples of the request carry the attrs it's logged with. Values are written as by Value.String,
+// group attrs and attrs without a key are skipped. Goroutines started later inherit the labels.
+// The goroutine keeps the labels after the request, a pooled worker goroutine should use DoWithPprofLabels.
+func WithPprofLabels(ctx context.Context, attrs ...slog.Attr) context.Context {
+	labels := pprofLabels(attrs)
+	if len(labels) == 0 {
+		return ctx
+	}
+
+	ctx = pprof.WithLabels(ctx, pprof.Labels(labels...))
+	pprof.SetGoroutineLabels(ctx)
+	return ctx
+}
+
+// DoWithPprofLabels calls fn with ctx and the attrs set as pprof labels like WithPprofLabels,
+// the goroutine labels are restored to the ones of ctx when fn returns (see pprof.Do).
+func DoWithPprofLabels(ctx context.Context, attrs []slog.Attr, fn func(ctx context.Context)) {
+	labels := pprofLabels(attrs)
+	if len(labels) == 0 {
+		fn(ctx)
+		return
+	}
+
+	pprof.Do(ctx, pprof.Labels(labels...), fn)
+}
+
+// pprofLabels returns the key-value pairs of the attrs for pprof.Labels.
+func pprofLabels(attrs []slog.Attr) []string {
+	labels := make([]string, 0, 2*len(attrs))
+	for _, attr := range attrs {
+		value := attr.Value.Resolve()
+		if attr.Key == "" || value.Kind() == slog.KindGroup {
+			continue
+		}
+		labels = append(labels, attr.Key, value.String())
+	}
+	return labels
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+func TestContextExtractors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewJsonHandler(&buf, &Config{
+		ContextExtractors: []ContextExtractor{TraceparentExtractor, BaggageExtractor("tenant", "region")},
+	}))
+
+	ctx := ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx = ContextWithBaggage(ctx, "tenant=acme%20corp;ttl=10, region = eu-west-1,other=x")
+
+	logger.InfoContext(ctx, "msg")
+
+	want := `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":"01",` +
+		`"baggage":{"tenant":"acme corp","region":"eu-west-1"}}`
+	if got := strings.TrimSpace(buf.String()); !strings.HasSuffix(got, want) {
+		t.Errorf("got %s\nwant suffix %s", got, want)
+	}
+}
+
+func TestPprofLabels(t *testing.T) {
+	ctx := WithPprofLabels(context.Background(), slog.String("request_id", "r-1"), slog.Int("shard", 3),
+		slog.Group("skipped", slog.String("a", "b")))
+	defer pprof.SetGoroutineLabels(context.Background())
+
+	if value, ok := pprof.Label(ctx, "shard"); !ok || value != "3" {
+		t.Errorf("shard label = %q, %v", value, ok)
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(NewJsonHandler(&buf, &Config{ContextExtractors: []ContextExtractor{PprofLabelsExtractor("request_id")}}))
+	logger.InfoContext(ctx, "msg")
+	slog.New(NewJsonHandler(&buf, &Config{ContextExtractors: []ContextExtractor{PprofLabelsExtractor()}})).InfoContext(ctx, "msg")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.HasSuffix(lines[0], `"msg":"msg","request_id":"r-1"}`) {
+		t.Errorf("selected labels: %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], `"msg":"msg","request_id":"r-1","shard":"3"}`) {
+		t.Errorf("all labels: %s", lines[1])
+	}
+}
+
+func TestDoWithPprofLabels(t *testing.T) {
+	// The goroutine profile lists the labels of every goroutine, the test one included.
+	goroutineLabels := func() string {
+		var buf bytes.Buffer
+		_ = pprof.Lookup("goroutine").WriteTo(&buf, 1)
+		return buf.String()
+	}
+
+	DoWithPprofLabels(context.Background(), []slog.Attr{slog.String("do_request_id", "r-2")}, func(ctx context.Context) {
+		if value, ok := pprof.Label(ctx, "do_request_id"); !ok || value != "r-2" {
+			t.Errorf("do_request_id label = %q, %v", value, ok)
+		}
+		if !strings.Contains(goroutineLabels(), `"do_request_id":"r-2"`) {
+			t.Error("the goroutine has no labels within fn")
+		}
+	})
+
+	if strings.Contains(goroutineLabels(), "do_request_id") {
+		t.Error("the goroutine labels are not restored")
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		header string
+		ok     bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-01", false},
+	}
+
+	for _, tt := range tests {
+		if _, _, _, ok := parseTraceparent(tt.header); ok != tt.ok {
+			t.Errorf("parseTraceparent(%q) ok = %v, want %v", tt.header, ok, tt.ok)
+		}
+	}
+}
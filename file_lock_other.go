@@ -0,0 +1,10 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package logger
+
+import "os"
+
+// lockFile is a no-op without flock, rotations of a shared FileWriter are not coordinated.
+func lockFile(*os.File) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,405 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// pipeBuf is the PIPE_BUF of Linux, appends up to it are not interleaved with writes of other processes
+	// on local file systems.
+	pipeBuf = 4096
+	// sharedCheckInterval is how often a shared FileWriter checks whether the file was rotated underneath it.
+	sharedCheckInterval = time.Second
+)
+
+// SyncPolicy controls how often a FileWriter commits the file to stable storage (fsync), the zero policy
+// leaves it to the OS. Audit logs trade throughput for durability, e.g. SyncPolicy{EveryRecords: 1}.
+type SyncPolicy struct {
+	// max time written data stays unsynced, 0 - no limit
+	Every time.Duration
+	// count of writes that triggers a sync, 0 - no limit
+	EveryRecords int
+	// records at or above the level are synced right after they are written (the handler buffer is flushed first),
+	// nil - none
+	OnLevel slog.Leveler
+}
+
+// isZero reports whether the policy leaves the syncs to the OS, OnLevel may hold an uncomparable Leveler.
+func (p SyncPolicy) isZero() bool {
+	return p.Every == 0 && p.EveryRecords == 0 && p.OnLevel == nil
+}
+
+// FileWriter is an append-only log file rotated by size.
+// On rotation "app.log" becomes "app.log.1", "app.log.1" becomes "app.log.2" and so on up to MaxBackups.
+// The file is opened with O_APPEND, see SetShared for several processes writing the same path.
+type FileWriter struct {
+	mu sync.Mutex
+
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+
+	policy SyncPolicy
+	// dirty is set while written data is not synced, unsynced counts the writes.
+	dirty    bool
+	unsynced int
+	// timer syncs the data after SyncPolicy.Every, it runs while the file is dirty.
+	timer *time.Timer
+
+	// shared is set by SetShared, lock is the "<path>.lock" file coordinating rotations.
+	shared  bool
+	lock    *os.File
+	checked time.Time
+}
+
+// NewFileWriter opens (or creates) the file at path for appending.
+// maxSize is the size in bytes that triggers rotation (0 - never rotate), maxBackups is the count of kept old files.
+func NewFileWriter(path string, maxSize int64, maxBackups int) (*FileWriter, error) {
+	w := &FileWriter{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := w.open(0); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *FileWriter) open(mode int) error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND|mode, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// Write writes p to the file, rotating it first if p doesn't fit into maxSize.
+// A record is never split between files.
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if w.shared {
+		if err := w.checkShared(); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	var n int
+	var err error
+	if w.shared {
+		n, err = w.writeAtomic(p)
+	} else {
+		n, err = w.file.Write(p)
+	}
+	w.size += int64(n)
+	if n == 0 {
+		return n, err
+	}
+
+	w.dirty = true
+	w.unsynced++
+	if w.policy.EveryRecords > 0 && w.unsynced >= w.policy.EveryRecords {
+		if syncErr := w.sync(); err == nil {
+			err = syncErr
+		}
+	} else if w.policy.Every > 0 && w.timer == nil {
+		w.timer = time.AfterFunc(w.policy.Every, w.timedSync)
+	}
+
+	return n, err
+}
+
+// SetShared makes the writer safe for several processes logging to the same path. Buffered chunks are appended
+// in pieces of whole records up to PIPE_BUF, so lines of the processes never interleave (a record bigger than that
+// is written with a single write). Rotations are serialized with an flock on "<path>.lock", a process rotates only
+// if nobody did it while it waited for the lock. Once a second the writer checks whether the path still names its file
+// and reopens it after a rotation by another process or by logrotate (without copytruncate).
+// flock is not available on windows and plan9, rotations are not coordinated there.
+func (w *FileWriter) SetShared(shared bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	if shared == w.shared {
+		return nil
+	}
+
+	if !shared {
+		err := w.lock.Close()
+		w.shared, w.lock = false, nil
+		return err
+	}
+
+	lock, err := os.OpenFile(w.path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+
+	w.shared, w.lock = true, lock
+	return nil
+}
+
+// writeAtomic appends p in pieces of whole lines up to pipeBuf.
+func (w *FileWriter) writeAtomic(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(p) > pipeBuf {
+			if i := bytes.LastIndexByte(p[:pipeBuf], '\n'); i >= 0 {
+				chunk = p[:i+1]
+			} else if i = bytes.IndexByte(p, '\n'); i >= 0 {
+				chunk = p[:i+1]
+			}
+		}
+
+		n, err := w.file.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// checkShared reopens the path if another process rotated the file and updates the size with their writes.
+func (w *FileWriter) checkShared() error {
+	if now := time.Now(); now.Sub(w.checked) >= sharedCheckInterval {
+		w.checked = now
+	} else {
+		return nil
+	}
+
+	replaced, err := w.replaced()
+	if err != nil {
+		return err
+	}
+	if replaced {
+		return w.reopen()
+	}
+
+	info, err := w.file.Stat()
+	if err != nil {
+		return err
+	}
+	w.size = info.Size()
+	return nil
+}
+
+// replaced reports whether the path names another file than the open one or doesn't exist anymore.
+func (w *FileWriter) replaced() (bool, error) {
+	current, err := os.Stat(w.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	open, err := w.file.Stat()
+	if err != nil {
+		return false, err
+	}
+	return !os.SameFile(current, open), nil
+}
+
+// reopen closes the file and opens the path again.
+func (w *FileWriter) reopen() error {
+	if !w.policy.isZero() {
+		if err := w.sync(); err != nil {
+			return err
+		}
+	}
+	w.dirty = false
+	w.unsynced = 0
+
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	return w.open(0)
+}
+
+// SetSyncPolicy replaces the sync policy, data written before is synced by the next sync of the new one.
+func (w *FileWriter) SetSyncPolicy(policy SyncPolicy) error {
+	if policy.Every < 0 || policy.EveryRecords < 0 {
+		return fmt.Errorf("%w: SyncPolicy must not be negative", ErrInvalidConfig)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.policy = policy
+	w.stopTimer()
+	if w.dirty && policy.Every > 0 {
+		w.timer = time.AfterFunc(policy.Every, w.timedSync)
+	}
+	return nil
+}
+
+// syncsLevel reports whether records at the level are synced right after they are written.
+func (w *FileWriter) syncsLevel(level slog.Level) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.policy.OnLevel != nil && level >= w.policy.OnLevel.Level()
+}
+
+func (w *FileWriter) timedSync() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.timer = nil
+	if w.file != nil {
+		// No caller waits for the error, the next write or Sync reports a broken file.
+		_ = w.sync()
+	}
+}
+
+// sync commits the written data, it's a no-op for a clean file.
+func (w *FileWriter) sync() error {
+	if !w.dirty {
+		return nil
+	}
+
+	w.stopTimer()
+	w.dirty = false
+	w.unsynced = 0
+	return w.file.Sync()
+}
+
+func (w *FileWriter) stopTimer() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+}
+
+func (w *FileWriter) rotate() error {
+	if w.shared {
+		unlock, err := lockFile(w.lock)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		// Another process may have rotated the file while we waited for the lock.
+		replaced, err := w.replaced()
+		if err != nil {
+			return err
+		}
+		if replaced {
+			return w.reopen()
+		}
+	}
+
+	// The policy covers the rotated file as well.
+	if !w.policy.isZero() {
+		if err := w.sync(); err != nil {
+			return err
+		}
+	}
+	w.dirty = false
+	w.unsynced = 0
+
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	if w.maxBackups > 0 {
+		for i := w.maxBackups - 1; i > 0; i-- {
+			// Missing backups are fine, the log just hasn't rotated that many times yet.
+			_ = os.Rename(w.backupName(i), w.backupName(i+1))
+		}
+
+		if err := os.Rename(w.path, w.backupName(1)); err != nil {
+			return err
+		}
+	}
+
+	// Without backups the file is cut in place, other processes keep appending to it.
+	if w.maxBackups == 0 {
+		return w.open(os.O_TRUNC)
+	}
+	return w.open(0)
+}
+
+func (w *FileWriter) backupName(i int) string {
+	return fmt.Sprintf("%s.%d", w.path, i)
+}
+
+// Sync commits the file content to stable storage.
+func (w *FileWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+
+	w.dirty = true
+	return w.sync()
+}
+
+// Close closes the file, further writes fail with os.ErrClosed.
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+
+	var err error
+	if !w.policy.isZero() {
+		err = w.sync()
+	}
+	w.stopTimer()
+
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.file = nil
+
+	if w.lock != nil {
+		_ = w.lock.Close()
+		w.lock = nil
+	}
+	return err
+}
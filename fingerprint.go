@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+// fingerprintSeed is shared by all handlers, so fingerprints are comparable within a process.
+var fingerprintSeed = maphash.MakeSeed()
+
+// Fingerprint returns a hash of the handler's writers, format and its options (the Config it was created with,
+// the line template), current level, groups, precomputed attrs and prefix. Two handlers with equal fingerprints
+// write the same bytes for a record to the same destination, so a MultiHandler or router can skip one of them.
+// Funcs in the Config match only if they are the same func value, closures made twice differ.
+// Fingerprints are stable only within a process.
+func (h *Handler) Fingerprint() uint64 {
+	var hash maphash.Hash
+	hash.SetSeed(fingerprintSeed)
+
+	var num [8]byte
+	binary.LittleEndian.PutUint64(num[:], uint64(int64(h.shared.level.Level())))
+	_, _ = hash.Write(num[:])
+
+	switch b := h.builder.(type) {
+	case *jsonBuilder:
+		_ = hash.WriteByte('j')
+	case *herokuBuilder:
+		_ = hash.WriteByte('h')
+	case *templateBuilder:
+		_ = hash.WriteByte('p')
+		_, _ = hash.WriteString(b.template)
+	default:
+		_ = hash.WriteByte('t')
+	}
+	hashValue(&hash, reflect.ValueOf(h.shared.config))
+
+	hashWriter(&hash, h.shared.out)
+	if h.shared.errOut != nil {
+		hashWriter(&hash, h.shared.errOut)
+	}
+
+	// Lengths separate the fields, so "a"+"bc" and "ab"+"c" hash differently.
+	for _, s := range [...]string{h.groupPrefix, h.precomputed, h.precomputedGroups, h.prefix} {
+		binary.LittleEndian.PutUint64(num[:], uint64(len(s)))
+		_, _ = hash.Write(num[:])
+		_, _ = hash.WriteString(s)
+	}
+
+	return hash.Sum64()
+}
+
+// hashWriter hashes the identity of the output's writer. Comparable writers (pointers, e.g. *os.File)
+// are hashed by value, so two handlers created for the same writer match, other writers by the output itself.
+func hashWriter(hash *maphash.Hash, o *output) {
+	var w any = o.dest
+	if t := reflect.TypeOf(o.dest); t == nil || !t.Comparable() {
+		w = o
+	}
+
+	maphash.WriteComparable(hash, w)
+}
+
+// hashValue hashes the options of a handler. Pointers, funcs and channels are hashed by identity,
+// the entries of maps in any order.
+func hashValue(hash *maphash.Hash, v reflect.Value) {
+	var num [8]byte
+	writeNum := func(n uint64) {
+		binary.LittleEndian.PutUint64(num[:], n)
+		_, _ = hash.Write(num[:])
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			writeNum(1)
+		} else {
+			writeNum(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeNum(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeNum(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeNum(math.Float64bits(v.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		writeNum(math.Float64bits(real(v.Complex())))
+		writeNum(math.Float64bits(imag(v.Complex())))
+	case reflect.String:
+		writeNum(uint64(v.Len()))
+		_, _ = hash.WriteString(v.String())
+	case reflect.Pointer, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		writeNum(uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			writeNum(0)
+			return
+		}
+		_, _ = hash.WriteString(v.Elem().Type().String())
+		hashValue(hash, v.Elem())
+	case reflect.Slice, reflect.Array:
+		writeNum(uint64(v.Len()))
+		for i := range v.Len() {
+			hashValue(hash, v.Index(i))
+		}
+	case reflect.Map:
+		// The entries are hashed apart and summed, so the map order doesn't matter.
+		var sum uint64
+		for iter := v.MapRange(); iter.Next(); {
+			var entry maphash.Hash
+			entry.SetSeed(fingerprintSeed)
+			hashValue(&entry, iter.Key())
+			hashValue(&entry, iter.Value())
+			sum += entry.Sum64()
+		}
+		writeNum(uint64(v.Len()))
+		writeNum(sum)
+	case reflect.Struct:
+		for i := range v.NumField() {
+			hashValue(hash, v.Field(i))
+		}
+	}
+}
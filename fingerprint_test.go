@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	var w1, w2 bytes.Buffer
+
+	h := NewJsonHandler(&w1, &Config{})
+	fp := func(h slog.Handler) uint64 { return h.(*Handler).Fingerprint() }
+
+	if fp(h) != fp(NewJsonHandler(&w1, &Config{})) {
+		t.Error("handlers for the same writer and config differ")
+	}
+	if fp(h) == fp(NewJsonHandler(&w2, &Config{})) {
+		t.Error("handlers for different writers match")
+	}
+	if fp(h) == fp(NewTextHandler(&w1, &Config{})) {
+		t.Error("json and text handlers match")
+	}
+
+	// The builder options are part of the fingerprint.
+	if fp(h) == fp(NewJsonHandler(&w1, &Config{TimeFormat: time.Kitchen})) {
+		t.Error("handlers with different time formats match")
+	}
+	if fp(h) == fp(NewJsonHandler(&w1, &Config{MaxValueLen: 16})) {
+		t.Error("handlers with different value limits match")
+	}
+	label := &Config{LevelLabels: map[slog.Level]string{slog.LevelInfo: "info", slog.LevelWarn: "warn"}}
+	if fp(NewJsonHandler(&w1, label)) != fp(NewJsonHandler(&w1, &Config{LevelLabels: map[slog.Level]string{slog.LevelWarn: "warn", slog.LevelInfo: "info"}})) {
+		t.Error("handlers with equal level labels differ")
+	}
+
+	tmpl := func(template string) *Handler {
+		th, err := NewTemplateHandler(&w1, template, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return th
+	}
+	if fp(tmpl("{level} {msg}")) != fp(tmpl("{level} {msg}")) {
+		t.Error("template handlers with the same template differ")
+	}
+	if fp(tmpl("{level} {msg}")) == fp(tmpl("{msg} {level}")) || fp(tmpl("{level} {msg}")) == fp(NewTextHandler(&w1, nil)) {
+		t.Error("template handlers with different templates match")
+	}
+
+	a := h.WithGroup("g").WithAttrs([]slog.Attr{slog.Int("n", 1)})
+	if fp(a) != fp(h.WithGroup("g").WithAttrs([]slog.Attr{slog.Int("n", 1)})) {
+		t.Error("equal clones differ")
+	}
+	if fp(a) == fp(h.WithAttrs([]slog.Attr{slog.Int("n", 1)}).WithGroup("g")) {
+		t.Error("clones with attrs outside and inside the group match")
+	}
+	if fp(a) == fp(h.WithGroup("g").WithAttrs([]slog.Attr{slog.Int("n", 2)})) {
+		t.Error("clones with different attrs match")
+	}
+	if fp(h) == fp(h.WithPrefix("[db] ")) {
+		t.Error("prefix is not part of the fingerprint")
+	}
+
+	before := fp(h)
+	h.SetLevel(slog.LevelError, "test")
+	if fp(h) == before {
+		t.Error("level is not part of the fingerprint")
+	}
+}
@@ -0,0 +1,62 @@
+//go:build ignore
+
+// gen_attr_methods writes attr_methods.go: the Logger methods taking a fixed count of attrs, run by go generate.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+)
+
+// maxAttrs is the largest count of attrs of a generated method.
+const maxAttrs = 4
+
+var levels = []struct{ name, level string }{
+	{"Trace", "LevelTrace"},
+	{"Debug", "slog.LevelDebug"},
+	{"Info", "slog.LevelInfo"},
+	{"Warn", "slog.LevelWarn"},
+	{"Error", "slog.LevelError"},
+}
+
+func main() {
+	var buf bytes.Buffer
+	buf.WriteString(`// Code generated by gen_attr_methods.go; DO NOT EDIT.
+
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+`)
+
+	for _, l := range levels {
+		for n := 1; n <= maxAttrs; n++ {
+			params := make([]string, n)
+			for i := range params {
+				params[i] = fmt.Sprintf("a%d", i+1)
+			}
+			plural := "s"
+			if n == 1 {
+				plural = ""
+			}
+
+			fmt.Fprintf(&buf, "\n// %s%d logs %d attr%s at %s without the args slice of LogAttrs.\n", l.name, n, n, plural, l.level)
+			fmt.Fprintf(&buf, "func (l *Logger) %s%d(ctx context.Context, msg string, %s slog.Attr) {\n", l.name, n, strings.Join(params, ", "))
+			fmt.Fprintf(&buf, "\tl.logAttrs(ctx, %s, msg, %s)\n}\n", l.level, strings.Join(params, ", "))
+		}
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = os.WriteFile("attr_methods.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
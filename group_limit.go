@@ -0,0 +1,32 @@
+package logger
+
+import "log/slog"
+
+const (
+	// GroupLimitKey is the key of the attr added once when WithGroup calls exceed the group limits,
+	// its value is the first group that was flattened.
+	GroupLimitKey = "!GROUP_LIMIT"
+
+	// group depth used when Config.MaxGroupDepth is 0.
+	defaultMaxGroupDepth = 32
+	// groups making the encoded group prefix longer than this are flattened regardless of the depth,
+	// it catches middleware stacks adding long group names in a loop.
+	maxGroupPrefixLen = 4096
+)
+
+// groupLimitReached reports whether the group name must be flattened into the current group.
+func (h *Handler) groupLimitReached(name string) bool {
+	return h.groupDepth >= h.shared.maxGroupDepth || len(h.groupPrefix)+len(name) > maxGroupPrefixLen
+}
+
+// flattenGroup returns a handler that keeps the current group, the first flattened group is reported with GroupLimitKey.
+func (h *Handler) flattenGroup(name string) *Handler {
+	if h.groupsFlattened {
+		return h
+	}
+
+	h2 := h.WithAttrs([]slog.Attr{slog.String(GroupLimitKey, name)}).(*Handler).clone()
+	h2.groupsFlattened = true
+
+	return h2
+}
@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	hexenc "encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	prevHashKey = "prev_hash"
+	hashKey     = "hash"
+
+	hashHexLen = sha256.Size * 2
+)
+
+var ErrChainBroken = errors.New("log hash chain broken")
+
+// zeroHash is the prev_hash of the first record of a chain.
+var zeroHash = bytes.Repeat([]byte{'0'}, hashHexLen)
+
+// hashChain links the records of an output with SHA-256 hashes, so removed or modified lines can be detected.
+// The hash of a record is SHA-256(prev_hash || encoded record without the chain fields and the newline).
+// It's used under the output lock, so the chain follows the order of the records in the output.
+type hashChain struct {
+	json bool
+	prev [hashHexLen]byte
+}
+
+func newHashChain(json bool) *hashChain {
+	c := &hashChain{json: json}
+	copy(c.prev[:], zeroHash)
+	return c
+}
+
+// seal appends prev_hash and hash to the encoded record and advances the chain.
+func (c *hashChain) seal(buf []byte) []byte {
+	// Both builders end records with '\n', the JSON one also closes the object before it.
+	record := buf[:len(buf)-1]
+
+	var hash [hashHexLen]byte
+	hexenc.Encode(hash[:], chainSum(c.prev[:], record))
+
+	if c.json {
+		buf = append(buf[:len(buf)-2], `,"`+prevHashKey+`":"`...)
+		buf = append(buf, c.prev[:]...)
+		buf = append(buf, `","`+hashKey+`":"`...)
+		buf = append(buf, hash[:]...)
+		buf = append(buf, '"', '}', '\n')
+	} else {
+		buf = append(buf[:len(buf)-1], " "+prevHashKey+"="...)
+		buf = append(buf, c.prev[:]...)
+		buf = append(buf, " "+hashKey+"="...)
+		buf = append(buf, hash[:]...)
+		buf = append(buf, '\n')
+	}
+
+	c.prev = hash
+	return buf
+}
+
+func chainSum(prev, record []byte) []byte {
+	h := sha256.New()
+	h.Write(prev)
+	h.Write(record)
+	return h.Sum(nil)
+}
+
+// ChainReport describes a verified log.
+type ChainReport struct {
+	// count of verified records.
+	Records int
+	// count of chains, every process writing to the log starts a new chain with a zero prev_hash.
+	Chains int
+	// line numbers of the chain starts, to be compared with the process restarts.
+	Starts []int
+}
+
+// VerifyHashChain checks the records written with Config.HashChain (JSON or text), every line must carry
+// a valid hash of itself linked to the previous line. The first error wraps ErrChainBroken and names the line.
+// A new chain (zero prev_hash) is accepted on any line, since a restarted process can't know the last hash.
+// So records cut at the end of a chain, right before a chain start or at the end of the log, are not
+// detected: compare ChainReport.Starts with the process restarts and keep the last hash apart from the log.
+func VerifyHashChain(r io.Reader) (ChainReport, error) {
+	var (
+		report ChainReport
+		prev   []byte
+	)
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, writerBufSize), 64<<20)
+
+	for lineNum := 1; sc.Scan(); lineNum++ {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		record, prevHash, hash, ok := splitChainFields(line)
+		if !ok {
+			return report, fmt.Errorf("%w: line %d: no %s/%s fields", ErrChainBroken, lineNum, prevHashKey, hashKey)
+		}
+
+		switch {
+		case bytes.Equal(prevHash, zeroHash):
+			report.Chains++
+			report.Starts = append(report.Starts, lineNum)
+		case prev == nil:
+			return report, fmt.Errorf("%w: line %d: the log doesn't start at the beginning of a chain", ErrChainBroken, lineNum)
+		case !bytes.Equal(prevHash, prev):
+			return report, fmt.Errorf("%w: line %d: prev_hash doesn't match the previous record", ErrChainBroken, lineNum)
+		}
+
+		want := make([]byte, hashHexLen)
+		hexenc.Encode(want, chainSum(prevHash, record))
+		if !bytes.Equal(hash, want) {
+			return report, fmt.Errorf("%w: line %d: record was modified", ErrChainBroken, lineNum)
+		}
+
+		prev = append(prev[:0], hash...)
+		report.Records++
+	}
+
+	return report, sc.Err()
+}
+
+// splitChainFields cuts the chain fields appended by hashChain.seal and restores the hashed record.
+func splitChainFields(line []byte) (record, prevHash, hash []byte, ok bool) {
+	// JSON: {...,"prev_hash":"<hex>","hash":"<hex>"}
+	if rest, prevHash, hash, ok := cutChainFields(line, `,"`+prevHashKey+`":"`, `","`+hashKey+`":"`, `"}`); ok {
+		return append(bytes.Clone(rest), '}'), prevHash, hash, true
+	}
+
+	// Text: ... prev_hash=<hex> hash=<hex>
+	return cutChainFields(line, " "+prevHashKey+"=", " "+hashKey+"=", "")
+}
+
+func cutChainFields(line []byte, prefix, middle, suffix string) (rest, prevHash, hash []byte, ok bool) {
+	n := len(prefix) + hashHexLen + len(middle) + hashHexLen + len(suffix)
+	if len(line) < n {
+		return nil, nil, nil, false
+	}
+
+	fields := line[len(line)-n:]
+	prevHash = fields[len(prefix):][:hashHexLen]
+	hash = fields[len(prefix)+hashHexLen+len(middle):][:hashHexLen]
+
+	if !bytes.HasPrefix(fields, []byte(prefix)) ||
+		!bytes.HasPrefix(fields[len(prefix)+hashHexLen:], []byte(middle)) ||
+		!bytes.HasSuffix(fields, []byte(suffix)) {
+		return nil, nil, nil, false
+	}
+
+	return line[:len(line)-n], prevHash, hash, true
+}
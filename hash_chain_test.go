@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHashChain(t *testing.T) {
+	for name, newHandler := range map[string]func(*bytes.Buffer) *Handler{
+		"json": func(buf *bytes.Buffer) *Handler { return NewJsonHandler(buf, &Config{HashChain: true}) },
+		"text": func(buf *bytes.Buffer) *Handler { return NewTextHandler(buf, &Config{HashChain: true}) },
+	} {
+		var buf bytes.Buffer
+		log := slog.New(newHandler(&buf))
+		log.Info("login", "user", "alice")
+		log.Info("transfer", "amount", 100)
+		log.Info("logout")
+
+		report, err := VerifyHashChain(bytes.NewReader(buf.Bytes()))
+		if err != nil || report.Records != 3 || report.Chains != 1 {
+			t.Fatalf("%s: report = %+v, err = %v", name, report, err)
+		}
+
+		lines := strings.SplitAfter(buf.String(), "\n")
+
+		tampered := strings.Replace(buf.String(), "100", "900", 1)
+		if _, err = VerifyHashChain(strings.NewReader(tampered)); !errors.Is(err, ErrChainBroken) {
+			t.Errorf("%s: modified record: err = %v, want ErrChainBroken", name, err)
+		}
+
+		removed := lines[0] + lines[2]
+		if _, err = VerifyHashChain(strings.NewReader(removed)); !errors.Is(err, ErrChainBroken) {
+			t.Errorf("%s: removed record: err = %v, want ErrChainBroken", name, err)
+		}
+
+		truncated := lines[1] + lines[2]
+		if _, err = VerifyHashChain(strings.NewReader(truncated)); !errors.Is(err, ErrChainBroken) {
+			t.Errorf("%s: removed head: err = %v, want ErrChainBroken", name, err)
+		}
+	}
+}
+
+func TestHashChainRestarts(t *testing.T) {
+	var buf bytes.Buffer
+	for _, msg := range []string{"first run", "second run"} {
+		// Every handler is a new process writing to the same log.
+		log := slog.New(NewJsonHandler(&buf, &Config{HashChain: true}))
+		log.Info(msg)
+		log.Info("working")
+	}
+
+	report, err := VerifyHashChain(bytes.NewReader(buf.Bytes()))
+	if err != nil || report.Records != 4 || report.Chains != 2 || fmt.Sprint(report.Starts) != "[1 3]" {
+		t.Fatalf("report = %+v, err = %v", report, err)
+	}
+
+	// The tail of the first chain can be cut unnoticed, only the chain starts tell it.
+	lines := strings.SplitAfter(buf.String(), "\n")
+	report, err = VerifyHashChain(strings.NewReader(lines[0] + lines[2] + lines[3]))
+	if err != nil || fmt.Sprint(report.Starts) != "[1 2]" {
+		t.Errorf("cut tail: report = %+v, err = %v", report, err)
+	}
+}
@@ -0,0 +1,231 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/ttrtcixy/fast-slog-handler/internal/escape"
+)
+
+// herokuBuilder writes records in the logfmt dialect of the Heroku router ("at=info method=GET service=18ms"):
+// no time (logplex stamps every line), lowercase level at "at", durations as whole milliseconds
+// and no colors, so Heroku and log drains parse the metrics in the lines.
+type herokuBuilder struct {
+	// source renders the call site, nil if Config.AddSource is disabled.
+	source *sourceFormatter
+	// limit cuts long string values, nil if Config.MaxValueLen is not set.
+	limit *valueLimit
+	// withAttrsLast writes the WithAttrs attrs after the record attrs.
+	withAttrsLast bool
+	// renames replace attr keys, nil if Config.RenameKeys is not set.
+	renames *keyRenames
+}
+
+// NewHerokuHandler creates a handler for apps on Heroku, records look like
+// `at=info msg="request done" method=GET path=/ service=18ms status=200`.
+// TimeFormat, TextLayout and the color options are ignored.
+func NewHerokuHandler(w io.Writer, cfg *Config) *Handler {
+	if w == nil {
+		w = os.Stdout
+	}
+
+	if cfg == nil {
+		cfg = &Config{Level: 0, BufferedOutput: false}
+	}
+
+	builder := &herokuBuilder{}
+	if cfg.AddSource {
+		builder.source = newSourceFormatter(cfg.TrimSourcePrefix)
+	}
+	builder.limit = newValueLimit(cfg)
+	builder.withAttrsLast = cfg.WithAttrsLast
+	builder.renames = newKeyRenames(cfg)
+
+	return newHandler(w, cfg, builder)
+}
+
+func (b *herokuBuilder) buildLog(
+	buf []byte,
+	record slog.Record,
+	precomputedAttrs string,
+	_ string,
+	groupPrefix string,
+	prefix string,
+) []byte {
+	buf = append(buf, "at="...)
+	buf = append(buf, strings.ToLower(levelName(record.Level))...)
+
+	if b.source != nil {
+		if source := b.source.format(record.PC); source != "" {
+			buf = append(buf, " source="...)
+			buf = b.appendString(buf, source)
+		}
+	}
+
+	event, record, isEvent := splitEvent(record)
+	if isEvent {
+		buf = append(buf, " "+EventKey+"="...)
+		mark := len(buf)
+		buf = append(buf, event...)
+		buf = b.quote(buf, mark)
+	}
+
+	if !isEvent || prefix != "" {
+		buf = append(buf, " msg="...)
+		mark := len(buf)
+		buf = append(buf, prefix...)
+		if msgBuf, ok := appendTemplateMessage(buf, record, appendRaw); ok {
+			buf = msgBuf
+		} else {
+			buf = append(buf, record.Message...)
+		}
+		buf = b.quote(buf, mark)
+	}
+
+	if len(precomputedAttrs) > 0 && !b.withAttrsLast {
+		buf = append(buf, precomputedAttrs...)
+	}
+
+	if record.NumAttrs() > 0 {
+		var groupBuf [128]byte
+		pref := append(groupBuf[:0], groupPrefix...)
+
+		record.Attrs(func(attr slog.Attr) bool {
+			buf = b.appendAttr(buf, pref, attr)
+			return true
+		})
+	}
+
+	if len(precomputedAttrs) > 0 && b.withAttrsLast {
+		buf = append(buf, precomputedAttrs...)
+	}
+
+	return append(buf, '\n')
+}
+
+func (b *herokuBuilder) precomputeAttrs(buf []byte, _, groupPrefix string, attrs []slog.Attr) []byte {
+	var groupBuf [128]byte
+	pref := append(groupBuf[:0], groupPrefix...)
+
+	for _, attr := range attrs {
+		buf = b.appendAttr(buf, pref, attr)
+	}
+
+	return buf
+}
+
+func (b *herokuBuilder) groupPrefix(oldPrefix string, newPrefix string) string {
+	return oldPrefix + newPrefix + "."
+}
+
+func (b *herokuBuilder) appendAttr(buf []byte, groupPrefix []byte, attr slog.Attr) []byte {
+	attr.Value = attr.Value.Resolve()
+
+	if attr.Equal(slog.Attr{}) {
+		return buf
+	}
+
+	if p, ok := precompiledFrom(attr); ok {
+		if p.builder == b && p.groupPrefix == string(groupPrefix) {
+			return append(buf, p.encoded...)
+		}
+		attr = p.inline()
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			groupPrefix = append(groupPrefix, attr.Key...)
+			groupPrefix = append(groupPrefix, '.')
+		}
+
+		for _, v := range attr.Value.Group() {
+			buf = b.appendAttr(buf, groupPrefix, v)
+		}
+		return buf
+	}
+
+	if attr.Key == "" {
+		attr.Key = "!EMPTY_KEY"
+	} else if b.renames != nil {
+		attr.Key = b.renames.rename(groupPrefix, attr.Key)
+	}
+
+	buf = append(buf, ' ')
+	mark := len(buf)
+	buf = append(buf, groupPrefix...)
+	buf = append(buf, attr.Key...)
+	buf = b.quote(buf, mark)
+	buf = append(buf, '=')
+
+	return b.writeValue(buf, attr.Value)
+}
+
+func (b *herokuBuilder) writeValue(buf []byte, value slog.Value) []byte {
+	mark := len(buf)
+
+	switch value.Kind() {
+	case slog.KindString:
+		return b.appendString(buf, value.String())
+	case slog.KindInt64:
+		return strconv.AppendInt(buf, value.Int64(), 10)
+	case slog.KindUint64:
+		return strconv.AppendUint(buf, value.Uint64(), 10)
+	case slog.KindFloat64:
+		return strconv.AppendFloat(buf, value.Float64(), 'f', -1, 64)
+	case slog.KindBool:
+		return strconv.AppendBool(buf, value.Bool())
+	case slog.KindDuration:
+		// The router writes connect=1ms service=18ms.
+		buf = strconv.AppendInt(buf, value.Duration().Round(time.Millisecond).Milliseconds(), 10)
+		return append(buf, "ms"...)
+	case slog.KindTime:
+		return value.Time().AppendFormat(buf, time.RFC3339)
+	}
+
+	if src, ok := anySource(value.Any()); ok {
+		return b.quote(appendSourceLine(buf, src), mark)
+	}
+	if tm, ok := anyTime(value.Any()); ok {
+		return tm.AppendFormat(buf, time.RFC3339)
+	}
+
+	switch v := value.Any().(type) {
+	case error:
+		return b.appendString(buf, v.Error())
+	case stackTrace:
+		buf = v.appendText(buf)
+	case textValue:
+		buf = v.appendText(buf)
+	default:
+		buf = fmt.Append(buf, v)
+	}
+
+	return b.quote(buf, mark)
+}
+
+// appendString appends s, quoted if it contains spaces, '=', quotes or is empty.
+func (b *herokuBuilder) appendString(buf []byte, s string) []byte {
+	mark := len(buf)
+	buf = append(buf, b.limit.truncate(s)...)
+	return b.quote(buf, mark)
+}
+
+// quote quotes buf[mark:] in place if it is not a bare logfmt value.
+func (b *herokuBuilder) quote(buf []byte, mark int) []byte {
+	if len(buf) == mark {
+		return append(buf, `""`...)
+	}
+
+	if !escape.NeedsQuoting(unsafe.String(&buf[mark], len(buf)-mark)) {
+		return buf
+	}
+
+	s := string(buf[mark:])
+	return strconv.AppendQuote(buf[:mark], s)
+}
@@ -0,0 +1,21 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestHerokuHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewHerokuHandler(&buf, &Config{})).With("dyno", "web.1").WithGroup("req")
+
+	l.Warn("request done", "method", "GET", "path", "/a b", "service", 18400*time.Microsecond, "err", errors.New("slow"))
+
+	want := `at=warn msg="request done" dyno=web.1 req.method=GET req.path="/a b" req.service=18ms req.err=slow` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"log/slog"
+	"slices"
+	"strconv"
+)
+
+// sparkline bars from the lowest to the highest bucket.
+var sparkBars = [...]string{"▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"}
+
+// histogramValue is the value of a Histogram attr, other handlers render it as a plain array.
+type histogramValue []uint64
+
+// Histogram returns an attr with the bucket counts of an exponential histogram, for metrics embedded
+// in periodic records. JSON renders it as a compact array ("latency":[0,4,17,2]), text as a sparkline
+// with the total count (latency=▁▃█▂(23)). The buckets are copied, so the caller can keep counting.
+func Histogram(key string, buckets []uint64) slog.Attr {
+	return slog.Any(key, histogramValue(slices.Clone(buckets)))
+}
+
+func (h histogramValue) appendJSON(buf []byte) []byte {
+	buf = append(buf, '[')
+	for i, n := range h {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = strconv.AppendUint(buf, n, 10)
+	}
+	return append(buf, ']')
+}
+
+// appendSparkline scales the buckets to the highest one, empty buckets get the lowest bar.
+func (h histogramValue) appendSparkline(buf []byte) []byte {
+	var peak, total uint64
+	for _, n := range h {
+		peak = max(peak, n)
+		total += n
+	}
+
+	for _, n := range h {
+		bar := 0
+		if n > 0 {
+			// Non-empty buckets start at the second bar, so they differ from empty ones.
+			bar = 1 + int(n*uint64(len(sparkBars)-2)/peak)
+		}
+		buf = append(buf, sparkBars[bar]...)
+	}
+
+	buf = append(buf, '(')
+	buf = strconv.AppendUint(buf, total, 10)
+	return append(buf, ')')
+}
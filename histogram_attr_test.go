@@ -0,0 +1,20 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHistogramAttr(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewJsonHandler(&buf, nil)).Info("stats", Histogram("latency", []uint64{0, 4, 17, 2}))
+	if !strings.Contains(buf.String(), `"latency":[0,4,17,2]`) {
+		t.Errorf("json = %q", buf.String())
+	}
+
+	if got := string(histogramValue{0, 4, 17, 2}.appendSparkline(nil)); got != "▁▃█▂(23)" {
+		t.Errorf("sparkline = %q", got)
+	}
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	// number of slots in the intern cache, must be a power of two.
+	internCacheSize = 1024
+	// strings longer than this are rarely repeated literals, so they are never interned.
+	maxInternLen = 64
+)
+
+// internEntry stores the encoded form of a single string.
+// ptr keeps the original string data alive, so the identity key can't be reused by another string.
+type internEntry struct {
+	ptr *byte
+	len int
+	// admitted is set once the string was stored twice, only admitted entries hold the encoded form.
+	admitted bool
+	encoded  string
+}
+
+// internCache is a fixed size direct-mapped cache from string identity (data pointer and length)
+// to its already escaped/quoted form. Reads are lock-free, a colliding string simply replaces the old entry.
+// A string is admitted on its second miss, so one-off values (ids, formatted strings) don't copy their
+// encoded form or evict the repeated literals.
+type internCache struct {
+	entries [internCacheSize]atomic.Pointer[internEntry]
+}
+
+func newInternCache() *internCache {
+	return &internCache{}
+}
+
+func (c *internCache) slot(s string) *atomic.Pointer[internEntry] {
+	h := uintptr(unsafe.Pointer(unsafe.StringData(s)))>>3 ^ uintptr(len(s))
+	return &c.entries[h&(internCacheSize-1)]
+}
+
+// load returns the cached encoded form of s.
+func (c *internCache) load(s string) (string, bool) {
+	if len(s) > maxInternLen {
+		return "", false
+	}
+
+	e := c.slot(s).Load()
+	if e == nil || !e.admitted || e.ptr != unsafe.StringData(s) || e.len != len(s) {
+		return "", false
+	}
+
+	return e.encoded, true
+}
+
+// store caches encoded as the encoded form of s if s was stored before, otherwise it records s as a candidate.
+func (c *internCache) store(s string, encoded []byte) {
+	if len(s) > maxInternLen {
+		return
+	}
+
+	slot := c.slot(s)
+	e := slot.Load()
+	if e == nil || e.ptr != unsafe.StringData(s) || e.len != len(s) {
+		slot.Store(&internEntry{ptr: unsafe.StringData(s), len: len(s)})
+		return
+	}
+
+	slot.Store(&internEntry{
+		ptr:      unsafe.StringData(s),
+		len:      len(s),
+		admitted: true,
+		encoded:  string(encoded),
+	})
+}
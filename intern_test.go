@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strconv"
+	"testing"
+)
+
+func TestInternCache(t *testing.T) {
+	c := newInternCache()
+	s := "ok"
+
+	// The first miss records a candidate, the second one admits the string.
+	c.store(s, []byte(`"ok"`))
+	if _, ok := c.load(s); ok {
+		t.Fatal("string admitted after one store")
+	}
+	c.store(s, []byte(`"ok"`))
+	if encoded, ok := c.load(s); !ok || encoded != `"ok"` {
+		t.Fatalf("load = %q, %v", encoded, ok)
+	}
+
+	// Another string with the same content has another identity.
+	other := string([]byte("ok"))
+	if _, ok := c.load(other); ok {
+		t.Error("string with the same content but another identity is a hit")
+	}
+
+	long := string(bytes.Repeat([]byte("x"), maxInternLen+1))
+	c.store(long, []byte(long))
+	c.store(long, []byte(long))
+	if _, ok := c.load(long); ok {
+		t.Error("long string interned")
+	}
+}
+
+func TestInternValuesOutput(t *testing.T) {
+	// The cached forms must match the uncached encoding, values repeat to be admitted.
+	values := []string{"ok", `quo"te`, "new\nline", "ключ", "ok"}
+	for _, newHandler := range []func(*bytes.Buffer, *Config) slog.Handler{
+		func(b *bytes.Buffer, c *Config) slog.Handler { return NewJsonHandler(b, c) },
+		func(b *bytes.Buffer, c *Config) slog.Handler { return NewTextHandler(b, c) },
+	} {
+		var plain, interned bytes.Buffer
+		plainLog := slog.New(newHandler(&plain, &Config{TimeFormat: "-"}))
+		internLog := slog.New(newHandler(&interned, &Config{TimeFormat: "-", InternValues: true}))
+
+		for range 3 {
+			for i, v := range values {
+				plainLog.Info("m", "k"+strconv.Itoa(i), v)
+				internLog.Info("m", "k"+strconv.Itoa(i), v)
+			}
+		}
+		if plain.String() != interned.String() {
+			t.Errorf("interned output differs:\n%s\n%s", plain.String(), interned.String())
+		}
+	}
+}
+
+func BenchmarkInternValues(b *testing.B) {
+	ids := make([]string, 1024)
+	for i := range ids {
+		ids[i] = "req-" + strconv.Itoa(i)
+	}
+
+	for _, intern := range []bool{false, true} {
+		b.Run("intern="+strconv.FormatBool(intern), func(b *testing.B) {
+			var w countingWriter
+			logger := slog.New(NewJsonHandler(&w, &Config{InternValues: intern}))
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Repeated literals and a value from a large set per record.
+				logger.LogAttrs(ctx, slog.LevelInfo, "request",
+					slog.String("status", "ok"), slog.String("method", "GET"), slog.String("route", "/api/v1/users"),
+					slog.String("request_id", ids[i%len(ids)]))
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+// Package escape writes strings as JSON string contents and decides when logfmt values must be quoted.
+// Escaping runs on every string value of a record, so the common all-safe string is found by a scan
+// of 8 bytes at a time and copied at once.
+package escape
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const hex = "0123456789abcdef"
+
+// Masks of the word-at-a-time scan, every byte of a word is tested at once (SWAR).
+const (
+	lsb = 0x0101010101010101
+	msb = 0x8080808080808080
+)
+
+// hasLess reports whether a byte of x is less than n, n <= 128.
+func hasLess(x uint64, n byte) bool {
+	return (x-lsb*uint64(n))&^x&msb != 0
+}
+
+// hasByte reports whether a byte of x is c.
+func hasByte(x uint64, c byte) bool {
+	return hasLess(x^(lsb*uint64(c)), 1)
+}
+
+// word loads the 8 bytes of s at i, the compiler merges the loads into one.
+func word(s string, i int) uint64 {
+	_ = s[i+7]
+	return uint64(s[i]) | uint64(s[i+1])<<8 | uint64(s[i+2])<<16 | uint64(s[i+3])<<24 |
+		uint64(s[i+4])<<32 | uint64(s[i+5])<<40 | uint64(s[i+6])<<48 | uint64(s[i+7])<<56
+}
+
+// jsonSafeLen returns the length of the prefix of s written into a JSON string as is.
+func jsonSafeLen(s string) int {
+	i := 0
+	for ; i+8 <= len(s); i += 8 {
+		x := word(s, i)
+		if x&msb != 0 || hasLess(x, 0x20) || hasByte(x, '"') || hasByte(x, '\\') {
+			break
+		}
+	}
+	for ; i < len(s) && s[i] < utf8.RuneSelf && jsonSafe[s[i]]; i++ {
+	}
+	return i
+}
+
+// textSafeLen returns the length of the prefix of s that can be in an unquoted logfmt value.
+func textSafeLen(s string) int {
+	i := 0
+	for ; i+8 <= len(s); i += 8 {
+		x := word(s, i)
+		// ESC is the only safe control byte, such words are left to the byte loop.
+		if x&msb != 0 || hasLess(x, 0x21) || hasByte(x, '=') || hasByte(x, '"') {
+			break
+		}
+	}
+	for ; i < len(s); i++ {
+		if b := s[i]; b >= utf8.RuneSelf || b == ' ' || b == '=' || !textSafe[b] && b != '\\' {
+			break
+		}
+	}
+	return i
+}
+
+// AppendJSON appends s escaped as the contents of a JSON string, without the quotes.
+// Invalid UTF-8 is replaced with U+FFFD, U+2028 and U+2029 are escaped like by encoding/json.
+func AppendJSON(buf []byte, s string) []byte {
+	i := jsonSafeLen(s)
+	if i == len(s) {
+		return append(buf, s...)
+	}
+
+	start := 0
+	for i < len(s) {
+		if b := s[i]; b < utf8.RuneSelf {
+			if jsonSafe[b] {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\')
+			switch b {
+			case '\\', '"':
+				buf = append(buf, b)
+			case '\n':
+				buf = append(buf, 'n')
+			case '\r':
+				buf = append(buf, 'r')
+			case '\t':
+				buf = append(buf, 't')
+			default:
+				// This encodes bytes < 0x20 except for \t, \n and \r.
+				buf = append(buf, 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 is LINE SEPARATOR, U+2029 is PARAGRAPH SEPARATOR. They are valid in JSON strings,
+		// but not in JavaScript (JSONP), escaping them is valid JSON, so it's done unconditionally.
+		// See http://timelessrepo.com/json-isnt-a-javascript-subset for discussion.
+		if c == '\u2028' || c == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\u202`...)
+			buf = append(buf, hex[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	return append(buf, s[start:]...)
+}
+
+// NeedsQuoting reports whether s must be quoted as a logfmt key or value: it's empty or has spaces, '=',
+// quotes, control (but ESC), non-printable or invalid UTF-8 characters. Backslashes are kept as is.
+func NeedsQuoting(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	for i := textSafeLen(s); i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if b == ' ' || b == '=' || !textSafe[b] && b != '\\' {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// jsonSafe holds the ASCII bytes written into a JSON string as is, textSafe without ESC.
+var jsonSafe = func() [utf8.RuneSelf]bool {
+	set := textSafe
+	set['\u001b'] = false
+	return set
+}()
+
+// textSafe holds the ASCII bytes a logfmt value may have unquoted, besides ' ', '=' and '"' (NeedsQuoting).
+// It's the safeSet of encoding/json plus ESC, so colored values are written as is.
+var textSafe = [utf8.RuneSelf]bool{
+	' ':      true,
+	'!':      true,
+	'"':      false,
+	'#':      true,
+	'$':      true,
+	'%':      true,
+	'&':      true,
+	'\'':     true,
+	'(':      true,
+	')':      true,
+	'*':      true,
+	'+':      true,
+	',':      true,
+	'-':      true,
+	'.':      true,
+	'/':      true,
+	'0':      true,
+	'1':      true,
+	'2':      true,
+	'3':      true,
+	'4':      true,
+	'5':      true,
+	'6':      true,
+	'7':      true,
+	'8':      true,
+	'9':      true,
+	':':      true,
+	';':      true,
+	'<':      true,
+	'=':      true,
+	'>':      true,
+	'?':      true,
+	'@':      true,
+	'A':      true,
+	'B':      true,
+	'C':      true,
+	'D':      true,
+	'E':      true,
+	'F':      true,
+	'G':      true,
+	'H':      true,
+	'I':      true,
+	'J':      true,
+	'K':      true,
+	'L':      true,
+	'M':      true,
+	'N':      true,
+	'O':      true,
+	'P':      true,
+	'Q':      true,
+	'R':      true,
+	'S':      true,
+	'T':      true,
+	'U':      true,
+	'V':      true,
+	'W':      true,
+	'X':      true,
+	'Y':      true,
+	'Z':      true,
+	'[':      true,
+	'\\':     false,
+	']':      true,
+	'^':      true,
+	'_':      true,
+	'`':      true,
+	'a':      true,
+	'b':      true,
+	'c':      true,
+	'd':      true,
+	'e':      true,
+	'f':      true,
+	'g':      true,
+	'h':      true,
+	'i':      true,
+	'j':      true,
+	'k':      true,
+	'l':      true,
+	'm':      true,
+	'n':      true,
+	'o':      true,
+	'p':      true,
+	'q':      true,
+	'r':      true,
+	's':      true,
+	't':      true,
+	'u':      true,
+	'v':      true,
+	'w':      true,
+	'x':      true,
+	'y':      true,
+	'z':      true,
+	'{':      true,
+	'|':      true,
+	'}':      true,
+	'~':      true,
+	'\u007f': true,
+	'\u001b': true,
+}
@@ -0,0 +1,205 @@
+package escape
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+var seeds = []string{
+	"",
+	"plain",
+	"exactly8",
+	"sixteen bytes ok",
+	"a long string without anything to escape in it",
+	`quote " and backslash \`,
+	"tab\tnew\nline\rreturn",
+	"\x00\x01\x1f\x7f",
+	"\x1b[31mred\x1b[0m",
+	"key=value",
+	"привет, мир",
+	"line\u2028para\u2029",
+	"\xff\xfe invalid",
+	"broken \xe2\x82",
+	"nbsp\u00a0",
+	"12345678\"",
+	"1234567\\8",
+	"12345678=",
+}
+
+// jsonSafeLenRef is jsonSafeLen byte by byte.
+func jsonSafeLenRef(s string) int {
+	i := 0
+	for i < len(s) && s[i] < utf8.RuneSelf && jsonSafe[s[i]] {
+		i++
+	}
+	return i
+}
+
+// textSafeLenRef is textSafeLen byte by byte.
+func textSafeLenRef(s string) int {
+	i := 0
+	for ; i < len(s); i++ {
+		if b := s[i]; b >= utf8.RuneSelf || b == ' ' || b == '=' || !textSafe[b] && b != '\\' {
+			break
+		}
+	}
+	return i
+}
+
+// needsQuotingRef is NeedsQuoting without the fast scan.
+func needsQuotingRef(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			// DEL and ESC are written as is, ESC for colored values.
+			if r == ' ' || r == '=' || r == '"' || r < 0x20 && r != '\x1b' {
+				return true
+			}
+			continue
+		}
+		if r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkAppendJSON(t *testing.T, s string) {
+	t.Helper()
+
+	out := AppendJSON(nil, s)
+	if !json.Valid(append(append([]byte{'"'}, out...), '"')) {
+		t.Fatalf("AppendJSON(%q) = %q, invalid JSON string", s, out)
+	}
+
+	var got string
+	if err := json.Unmarshal(append(append([]byte{'"'}, out...), '"'), &got); err != nil {
+		t.Fatal(err)
+	}
+	// Invalid UTF-8 decodes as U+FFFD, like a range over the string.
+	if want := string([]rune(s)); got != want {
+		t.Fatalf("AppendJSON(%q) decodes to %q, want %q", s, got, want)
+	}
+
+	if n, ref := jsonSafeLen(s), jsonSafeLenRef(s); n != ref {
+		t.Fatalf("jsonSafeLen(%q) = %d, byte scan %d", s, n, ref)
+	}
+}
+
+func TestAppendJSON(t *testing.T) {
+	for _, s := range seeds {
+		checkAppendJSON(t, s)
+	}
+
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"\x1b[31m", `\u001b[31m`},
+		{"a\"b\\c", `a\"b\\c`},
+		{"\n\t\x00", `\n\t\u0000`},
+		{"\u2028", `\u2028`},
+		{"\xff", `\ufffd`},
+	}
+	for _, tt := range tests {
+		if got := string(AppendJSON([]byte("x"), tt.in)); got != "x"+tt.want {
+			t.Errorf("AppendJSON(%q) = %q, want %q", tt.in, got, "x"+tt.want)
+		}
+	}
+}
+
+func TestNeedsQuoting(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"", true},
+		{"plain", false},
+		{"path\\to", false},
+		{"\x1b[31mred\x1b[0m", false},
+		{"with space", true},
+		{"key=value", true},
+		{`say"hi"`, true},
+		{"tab\t", true},
+		{"привет", false},
+		{"nbsp\u00a0", true},
+		{"\xff", true},
+		{"12345678 9", true},
+	}
+	for _, tt := range tests {
+		if got := NeedsQuoting(tt.in); got != tt.want {
+			t.Errorf("NeedsQuoting(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// checkLogfmt checks a value written like by the text builder reads back: quoted values unquote to
+// the value, unquoted ones end at the next space and have no '=' or quote.
+func checkLogfmt(t *testing.T, s string) {
+	t.Helper()
+
+	if got, want := NeedsQuoting(s), needsQuotingRef(s); got != want {
+		t.Fatalf("NeedsQuoting(%q) = %v, rune scan %v", s, got, want)
+	}
+	if n, ref := textSafeLen(s), textSafeLenRef(s); n != ref {
+		t.Fatalf("textSafeLen(%q) = %d, byte scan %d", s, n, ref)
+	}
+
+	if NeedsQuoting(s) {
+		quoted := strconv.Quote(s)
+		got, err := strconv.Unquote(quoted)
+		if err != nil || got != s && utf8.ValidString(s) {
+			t.Fatalf("quoted %q reads back as %q, %v", s, got, err)
+		}
+		return
+	}
+
+	line := "k=" + s + " next=1"
+	value, rest, _ := strings.Cut(strings.TrimPrefix(line, "k="), " ")
+	if value != s || rest != "next=1" || strings.ContainsAny(s, `="`) || !utf8.ValidString(s) {
+		t.Fatalf("unquoted %q doesn't read back from %q", s, line)
+	}
+}
+
+func TestLogfmtRoundTrip(t *testing.T) {
+	for _, s := range seeds {
+		checkLogfmt(t, s)
+	}
+}
+
+func FuzzAppendJSON(f *testing.F) {
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(checkAppendJSON)
+}
+
+func FuzzNeedsQuoting(f *testing.F) {
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(checkLogfmt)
+}
+
+func BenchmarkAppendJSON(b *testing.B) {
+	s := "GET /api/v1/users/42/orders completed"
+	buf := make([]byte, 0, 128)
+	b.SetBytes(int64(len(s)))
+	for b.Loop() {
+		buf = AppendJSON(buf[:0], s)
+	}
+}
+
+func BenchmarkNeedsQuoting(b *testing.B) {
+	s := "550e8400-e29b-41d4-a716-446655440000"
+	b.SetBytes(int64(len(s)))
+	for b.Loop() {
+		NeedsQuoting(s)
+	}
+}
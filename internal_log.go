@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// internalLogInterval is the min time between two notices of the same kind, the events in between are counted.
+const internalLogInterval = time.Second
+
+// internalLog emits the diagnostics of a handler as records of Config.InternalLogger.
+type internalLog struct {
+	logger *slog.Logger
+
+	mu sync.Mutex
+	// busy is set while a notice is logged, events raised meanwhile (e.g. by the internal logger itself)
+	// are suppressed, so a broken sink can't recurse.
+	busy       bool
+	last       [diagnosticKinds]time.Time
+	suppressed [diagnosticKinds]int
+}
+
+func newInternalLog(l *slog.Logger) *internalLog {
+	if l == nil {
+		return nil
+	}
+	return &internalLog{logger: l}
+}
+
+func (k DiagnosticKind) level() slog.Level {
+	switch k {
+	case DiagnosticDropped, DiagnosticSlowWriter:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+func (k DiagnosticKind) notice() string {
+	switch k {
+	case DiagnosticEncodePanic:
+		return "logger: record encoding panicked"
+	case DiagnosticDropped:
+		return "logger: record dropped"
+	case DiagnosticSlowWriter:
+		return "logger: switched to the slow write fallback"
+	default:
+		return "logger: write failed"
+	}
+}
+
+// log emits one notice per kind and internalLogInterval, the count of the suppressed ones is added to the next.
+func (l *internalLog) log(d Diagnostic) {
+	level := d.Kind.level()
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	k := int(d.Kind)
+	if k <= 0 || k >= diagnosticKinds {
+		return
+	}
+
+	l.mu.Lock()
+	if l.busy || d.Time.Sub(l.last[k]) < internalLogInterval {
+		l.suppressed[k]++
+		l.mu.Unlock()
+		return
+	}
+	l.busy = true
+	l.last[k] = d.Time
+	suppressed := l.suppressed[k]
+	l.suppressed[k] = 0
+	l.mu.Unlock()
+
+	attrs := make([]slog.Attr, 0, 5)
+	attrs = append(attrs, slog.String("kind", d.Kind.String()))
+	if d.Message != "" {
+		attrs = append(attrs, slog.String("record_level", levelName(d.Level)), slog.String("record_msg", d.Message))
+	}
+	if d.Err != nil {
+		attrs = append(attrs, slog.String("error", d.Err.Error()))
+	}
+	if d.Latency > 0 {
+		attrs = append(attrs, slog.Duration("latency", d.Latency))
+	}
+	if suppressed > 0 {
+		attrs = append(attrs, slog.Int("suppressed", suppressed))
+	}
+
+	l.logger.LogAttrs(ctx, level, d.Kind.notice(), attrs...)
+
+	l.mu.Lock()
+	l.busy = false
+	l.mu.Unlock()
+}
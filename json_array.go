@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// JSONArrayWriter turns the lines of a JSON handler into a single JSON array document for tools that can't read
+// NDJSON: "[" before the first record, "," between the records and "]" on Close, one record per line.
+//
+// The document is valid only after Close. A crashed process leaves it without the "]" and possibly with a torn
+// last record, read such files with NewJSONArrayRepairReader. It must not wrap a rotating writer (FileWriter
+// with maxSize), every rotated file would be a part of one array.
+type JSONArrayWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+
+	// records is the count of started records, inRecord is set until the record's newline is written.
+	records  int
+	inRecord bool
+	closed   bool
+	scratch  []byte
+}
+
+// NewJSONArrayWriter creates a writer writing the array into w, Close must be called to end the document.
+func NewJSONArrayWriter(w io.Writer) (*JSONArrayWriter, error) {
+	if w == nil {
+		return nil, ErrNilWriter
+	}
+	return &JSONArrayWriter{w: w}, nil
+}
+
+// Write writes the lines of p as array elements, p may hold several lines (buffered output) or a part of one.
+func (a *JSONArrayWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return 0, ErrAlreadyClosed
+	}
+
+	buf := a.scratch[:0]
+	for rest := p; len(rest) > 0; {
+		if !a.inRecord {
+			if a.records == 0 {
+				buf = append(buf, "[\n"...)
+			} else {
+				buf = append(buf, ",\n"...)
+			}
+			a.records++
+			a.inRecord = true
+		}
+
+		// The newline is written by the separator of the next record or by Close.
+		line, tail, found := bytes.Cut(rest, []byte{'\n'})
+		buf = append(buf, line...)
+		if found {
+			a.inRecord = false
+		}
+		rest = tail
+	}
+	a.scratch = buf
+
+	if len(buf) == 0 {
+		return 0, nil
+	}
+	if _, err := a.w.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close ends the array, "[]" is written if there were no records. The underlying writer is not closed.
+func (a *JSONArrayWriter) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return ErrAlreadyClosed
+	}
+	a.closed = true
+
+	if a.records == 0 {
+		_, err := io.WriteString(a.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(a.w, "\n]\n")
+	return err
+}
+
+// jsonArrayRepairReader is the reader of NewJSONArrayRepairReader.
+type jsonArrayRepairReader struct {
+	br *bufio.Reader
+	// out is the part of the repaired document not read yet, records is the count of elements in it.
+	out     []byte
+	records int
+	done    bool
+	err     error
+}
+
+// NewJSONArrayRepairReader reads a document of a JSONArrayWriter, or NDJSON, as a valid JSON array:
+// every line holding a valid record is an element, torn records of a crash are skipped and the missing "]"
+// is added. The records must be one per line, as JSONArrayWriter writes them.
+func NewJSONArrayRepairReader(r io.Reader) io.Reader {
+	return &jsonArrayRepairReader{br: bufio.NewReader(r)}
+}
+
+func (r *jsonArrayRepairReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, r.err
+		}
+		r.next()
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// next repairs the next line, at the end of the input it closes the array.
+func (r *jsonArrayRepairReader) next() {
+	line, err := r.br.ReadBytes('\n')
+
+	element := bytes.TrimSpace(line)
+	element = bytes.TrimSuffix(element, []byte{','})
+	if len(element) > 0 && element[0] != '[' && element[0] != ']' && json.Valid(element) {
+		if r.records == 0 {
+			r.out = append(r.out[:0], "[\n"...)
+		} else {
+			r.out = append(r.out[:0], ",\n"...)
+		}
+		r.out = append(r.out, element...)
+		r.records++
+	}
+
+	if err == nil {
+		return
+	}
+
+	r.done = true
+	r.err = err
+	if errors.Is(err, io.EOF) {
+		if r.records == 0 {
+			r.out = append(r.out, "[]\n"...)
+		} else {
+			r.out = append(r.out, "\n]\n"...)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestJSONArrayWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewJSONArrayWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(NewJsonHandler(w, nil))
+	logger.Info("first", "n", 1)
+	// Buffered output hands over several lines at once, large records may come in parts.
+	_, _ = w.Write([]byte(`{"msg":"second"}` + "\n" + `{"msg":`))
+	_, _ = w.Write([]byte(`"third"}` + "\n"))
+
+	var records []map[string]any
+	if err = json.Unmarshal(buf.Bytes(), &records); err == nil {
+		t.Fatal("the array is valid before Close")
+	}
+
+	// A crash before Close: the repair reader closes the array.
+	crashed := append(bytes.Clone(buf.Bytes()), `,`+"\n"+`{"msg":"torn`...)
+	repaired, err := io.ReadAll(NewJSONArrayRepairReader(bytes.NewReader(crashed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(repaired, &records); err != nil || len(records) != 3 {
+		t.Fatalf("repaired %d records, err %v: %s", len(records), err, repaired)
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if len(records) != 3 || records[0]["msg"] != "first" || records[2]["msg"] != "third" {
+		t.Errorf("records = %v", records)
+	}
+
+	// Empty documents stay valid.
+	var empty bytes.Buffer
+	w, _ = NewJSONArrayWriter(&empty)
+	_ = w.Close()
+	repaired, _ = io.ReadAll(NewJSONArrayRepairReader(bytes.NewReader(nil)))
+	if empty.String() != "[]\n" || string(repaired) != "[]\n" {
+		t.Errorf("empty: %q, repaired: %q", empty.String(), repaired)
+	}
+}
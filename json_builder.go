@@ -1,19 +1,44 @@
 package logger
 
 import (
-	"bufio"
+	"cmp"
 	"encoding/json"
 	"io"
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
 	"time"
-	"unicode/utf8"
+
+	"github.com/ttrtcixy/fast-slog-handler/internal/escape"
 )
 
 type jsonBuilder struct {
+	// intern caches encoded string values, nil if Config.InternValues is disabled.
+	intern *internCache
+	// source renders the call site, nil if Config.AddSource is disabled.
+	source *sourceFormatter
+	// limit cuts long string values, nil if Config.MaxValueLen is not set.
+	limit *valueLimit
+	// durations is the encoding of duration values.
+	durations DurationFormat
+	// timeFormat is the layout of the record time and time attrs.
+	timeFormat string
+	// recordTime formats the record time with timeFormat.
+	recordTime *timeCache
+	// withAttrsLast writes the WithAttrs attrs of every group level after its nested groups and record attrs.
+	withAttrsLast bool
+	// labels replace the level names, nil if Config.LevelLabels is not set.
+	labels *levelLabels
+	// quoteBigInts writes integers outside ±maxSafeInteger as strings.
+	quoteBigInts bool
+	// renames replace attr keys, nil if Config.RenameKeys is not set.
+	renames *keyRenames
 }
 
+// maxSafeInteger is the largest integer a float64 (a JavaScript number) represents exactly, 2^53-1.
+const maxSafeInteger = 1<<53 - 1
+
 func NewJsonHandler(w io.Writer, cfg *Config) *Handler {
 	if w == nil {
 		w = os.Stderr
@@ -23,76 +48,299 @@ func NewJsonHandler(w io.Writer, cfg *Config) *Handler {
 		cfg = &Config{Level: 0, BufferedOutput: false}
 	}
 
-	handler := newHandler(w, slog.Level(cfg.Level), &jsonBuilder{})
+	return newHandler(w, cfg, newJSONBuilder(cfg))
+}
+
+func newJSONBuilder(cfg *Config) *jsonBuilder {
+	builder := &jsonBuilder{}
+	if cfg.InternValues {
+		builder.intern = newInternCache()
+	}
+	if cfg.AddSource {
+		builder.source = newSourceFormatter(cfg.TrimSourcePrefix)
+	}
+	builder.limit = newValueLimit(cfg)
+	builder.durations = cfg.JSONDurations
+	builder.timeFormat = cmp.Or(cfg.TimeFormat, time.DateTime)
+	builder.recordTime = newTimeCache(builder.timeFormat)
+	builder.withAttrsLast = cfg.WithAttrsLast
+	builder.quoteBigInts = cfg.QuoteBigInts
+	builder.labels = newLevelLabels(cfg.LevelLabels)
+	builder.renames = newKeyRenames(cfg)
+
+	return builder
+}
 
-	if cfg.BufferedOutput {
-		handler.shared.bw = bufio.NewWriterSize(w, writerBufSize)
-		// Start a background routine to periodically flush the buffer.
-		// This ensures logs appear even during low activity periods.
-		go handler.flusher()
+// NewJsonHandlerE is like NewJsonHandler but reports a nil writer or invalid config instead of replacing them with defaults.
+func NewJsonHandlerE(w io.Writer, cfg *Config) (*Handler, error) {
+	if err := validate(w, cfg); err != nil {
+		return nil, err
 	}
 
-	return handler
+	return NewJsonHandler(w, cfg), nil
 }
 
-func (b *jsonBuilder) buildLog(buf []byte, record slog.Record, precomputedAttrs string, groupPrefix string) []byte {
+func (b *jsonBuilder) buildLog(
+	buf []byte,
+	record slog.Record,
+	precomputedAttrs, precomputedGroups, groupPrefix, prefix string,
+) []byte {
+	event, record, isEvent := splitEvent(record)
+
 	buf = append(buf, `{"time":"`...)
-	buf = record.Time.AppendFormat(buf, time.DateTime)
+	buf = b.recordTime.appendTime(buf, record.Time)
+
+	// A plain message is spliced into the preassembled level header.
+	if !isEvent && record.NumAttrs() == 0 && precomputedAttrs == "" && prefix == "" && b.source == nil && b.labels == nil {
+		if header := jsonLevelHeader(record.Level); header != "" {
+			buf = append(buf, header...)
+			buf = escape.AppendJSON(buf, record.Message)
+			return append(buf, "\"}\n"...)
+		}
+	}
+
 	buf = append(buf, `","level":"`...)
-	buf = append(buf, levelBytes(record.Level)...)
-	buf = append(buf, `","msg":"`...) // todo if no message
-	buf = append(buf, record.Message...)
+	if b.labels != nil {
+		buf = b.labels.appendJSON(buf, record.Level)
+	} else {
+		buf = append(buf, levelName(record.Level)...)
+	}
+	if b.source != nil {
+		if source := b.source.format(record.PC); source != "" {
+			buf = append(buf, `","source":"`...)
+			buf = escape.AppendJSON(buf, source)
+		}
+	}
+	if isEvent {
+		// Events have no message, the name takes its place.
+		buf = append(buf, `","`+EventKey+`":"`...)
+		buf = escape.AppendJSON(buf, event)
+	} else {
+		buf = append(buf, `","msg":"`...) // todo if no message
+		if msgBuf, ok := appendTemplateMessage(buf, record, escape.AppendJSON); ok {
+			buf = msgBuf
+		} else {
+			buf = escape.AppendJSON(buf, record.Message)
+		}
+	}
 	buf = append(buf, '"')
+	if prefix = strings.TrimSpace(prefix); prefix != "" {
+		buf = append(buf, `,"`+PrefixKey+`":"`...)
+		buf = escape.AppendJSON(buf, prefix)
+		buf = append(buf, '"')
+	}
 
 	if record.NumAttrs() > 0 || precomputedAttrs != "" {
-		buf = append(buf, ',')
-		if groupPrefix != "" {
-			buf = append(buf, groupPrefix...)
+		if b.withAttrsLast {
+			buf = b.appendAttrsLast(buf, record, precomputedAttrs, precomputedGroups, groupPrefix)
+		} else {
+			buf = b.appendAttrsFirst(buf, record, precomputedAttrs, precomputedGroups, groupPrefix)
 		}
+	}
 
-		if record.NumAttrs() > 0 {
+	buf = append(buf, '}', '\n')
 
-			if precomputedAttrs != "" {
-				buf = append(buf, precomputedAttrs...)
-				buf = append(buf, ',')
-			}
+	return buf
+}
 
-			var isFirst = true
-			record.Attrs(func(attr slog.Attr) bool {
-				//attr.Value = attr.Value.Resolve()
-				if attr.Equal(slog.Attr{}) {
-					return true
-				}
-
-				if !isFirst {
-					buf = append(buf, ',')
-				} else {
-					isFirst = false
-				}
-				buf = b.appendAttr(buf, nil, attr)
-				return true
-			})
+// appendAttrsObject writes the WithPrefix prefix, the WithAttrs and record attrs as one JSON object,
+// "{}" if there are none.
+func (b *jsonBuilder) appendAttrsObject(
+	buf []byte,
+	record slog.Record,
+	precomputedAttrs, precomputedGroups, groupPrefix, prefix string,
+) []byte {
+	start := len(buf)
+	buf = append(buf, '{')
+
+	if prefix = strings.TrimSpace(prefix); prefix != "" {
+		buf = append(buf, `,"`+PrefixKey+`":"`...)
+		buf = escape.AppendJSON(buf, prefix)
+		buf = append(buf, '"')
+	}
+
+	if record.NumAttrs() > 0 || precomputedAttrs != "" {
+		if b.withAttrsLast {
+			buf = b.appendAttrsLast(buf, record, precomputedAttrs, precomputedGroups, groupPrefix)
 		} else {
-			buf = append(buf, precomputedAttrs...)
+			buf = b.appendAttrsFirst(buf, record, precomputedAttrs, precomputedGroups, groupPrefix)
 		}
+	}
 
-		if groupPrefix != "" {
+	// Drop the separator of the first value.
+	if len(buf) > start+1 {
+		buf = append(buf[:start+1], buf[start+2:]...)
+	}
+
+	return append(buf, '}')
+}
+
+// jsonLevelHeader returns the part of a record between the time and the message for the standard levels.
+func jsonLevelHeader(level slog.Level) string {
+	switch level {
+	case LevelTrace:
+		return `","level":"` + levelTraceLabel + `","msg":"`
+	case slog.LevelDebug:
+		return `","level":"` + LevelDebug + `","msg":"`
+	case slog.LevelInfo:
+		return `","level":"` + LevelInfo + `","msg":"`
+	case slog.LevelWarn:
+		return `","level":"` + LevelWarn + `","msg":"`
+	case slog.LevelError:
+		return `","level":"` + LevelError + `","msg":"`
+	default:
+		return ""
+	}
+}
+
+// groupMark separates the group levels of precomputed attrs in the Config.WithAttrsLast mode,
+// it never appears in encoded JSON.
+const groupMark = 0
+
+// appendAttrsFirst writes the precomputed attrs, they end inside precomputedGroups, then opens the groups
+// added after them and writes the record attrs.
+func (b *jsonBuilder) appendAttrsFirst(
+	buf []byte,
+	record slog.Record,
+	precomputedAttrs, precomputedGroups, groupPrefix string,
+) []byte {
+	mark := len(buf)
+	buf = append(buf, ',')
+
+	var isFirst = true
+	var depth int
+	if precomputedAttrs != "" {
+		buf = append(buf, precomputedAttrs...)
+		isFirst = false
+		depth = groupDepth(precomputedGroups)
+	}
+
+	recordMark := len(buf)
+	if pending := groupPrefix[len(precomputedGroups):]; pending != "" {
+		if !isFirst {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, pending...)
+		isFirst = true
+	}
+
+	var pathBuf [128]byte
+	path := b.renames.appendGroupPath(pathBuf[:0], groupPrefix)
+
+	attrsStart := len(buf)
+	record.Attrs(func(attr slog.Attr) bool {
+		buf = b.appendSeparatedAttr(buf, path, attr, &isFirst)
+		return true
+	})
+
+	if len(buf) == attrsStart {
+		// Every record attr was empty, drop the groups opened for them.
+		buf = buf[:recordMark]
+	} else {
+		depth = groupDepth(groupPrefix)
+	}
+
+	if len(buf) == mark+1 {
+		return buf[:mark]
+	}
+
+	// Close every group opened by WithGroup().
+	for range depth {
+		buf = append(buf, '}')
+	}
+	return buf
+}
+
+// appendAttrsLast writes the record attrs first, then the precomputed attrs of every group level
+// from the innermost one out. The precomputed attrs are the top level attrs followed by
+// groupMark+openers+groupMark+attrs for every level.
+func (b *jsonBuilder) appendAttrsLast(
+	buf []byte,
+	record slog.Record,
+	precomputedAttrs, precomputedGroups, groupPrefix string,
+) []byte {
+	mark := len(buf)
+	buf = append(buf, ',')
+
+	top, levels, _ := strings.Cut(precomputedAttrs, string(rune(groupMark)))
+
+	// Open the groups of every level.
+	for s := levels; s != ""; {
+		openers, rest, _ := strings.Cut(s, string(rune(groupMark)))
+		buf = append(buf, openers...)
+		_, s, _ = strings.Cut(rest, string(rune(groupMark)))
+	}
+
+	recordMark := len(buf)
+	pending := groupPrefix[len(precomputedGroups):]
+	buf = append(buf, pending...)
+
+	var isFirst = true
+	var pathBuf [128]byte
+	path := b.renames.appendGroupPath(pathBuf[:0], groupPrefix)
+
+	attrsStart := len(buf)
+	record.Attrs(func(attr slog.Attr) bool {
+		buf = b.appendSeparatedAttr(buf, path, attr, &isFirst)
+		return true
+	})
+
+	if len(buf) == attrsStart {
+		buf = buf[:recordMark]
+	} else {
+		for range groupDepth(pending) {
 			buf = append(buf, '}')
 		}
 	}
 
-	buf = append(buf, '}', '\n')
+	// Write the attrs of every level and close it, from the innermost one.
+	for s := levels; s != ""; {
+		i := strings.LastIndexByte(s, groupMark)
+		attrs, head := s[i+1:], s[:i]
+
+		j := strings.LastIndexByte(head, groupMark)
+		openers := head[j+1:]
+		s = head[:max(j, 0)]
+
+		if buf[len(buf)-1] != '{' {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, attrs...)
+		for range groupDepth(openers) {
+			buf = append(buf, '}')
+		}
+	}
+
+	if top != "" {
+		if len(buf) > mark+1 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, top...)
+	}
 
+	if len(buf) == mark+1 {
+		return buf[:mark]
+	}
 	return buf
 }
 
-func (b *jsonBuilder) appendAttr(buf []byte, _ []byte, attr slog.Attr) []byte {
-	//attr.Value = attr.Value.Resolve()
+// appendAttr appends the attr, path is the dotted path of its groups, built only for keyRenames with paths.
+func (b *jsonBuilder) appendAttr(buf []byte, path []byte, attr slog.Attr) []byte {
+	attr.Value = attr.Value.Resolve()
 
 	if attr.Equal(slog.Attr{}) {
 		return buf
 	}
 
+	// Attrs from Precompile() are already encoded by this builder. JSON groups are objects around the attrs,
+	// only the renames of group paths make the bytes depend on the group (the group prefix for the other builders).
+	if p, ok := precompiledFrom(attr); ok {
+		if p.builder == b && p.path == string(path) {
+			return append(buf, p.encoded...)
+		}
+		attr = p.inline()
+	}
+
 	// Handle nested groups by recursion.
 	if attr.Value.Kind() == slog.KindGroup {
 		group := attr.Value.Group()
@@ -102,26 +350,28 @@ func (b *jsonBuilder) appendAttr(buf []byte, _ []byte, attr slog.Attr) []byte {
 			return buf
 		}
 
+		start := len(buf)
+
 		if attr.Key != "" {
 			buf = append(buf, '"')
 			//buf = append(buf, attr.Key...)
-			buf = appendEscapedJSONString(buf, attr.Key)
+			buf = escape.AppendJSON(buf, attr.Key)
 			buf = append(buf, `":{`...)
 		}
 
+		if attr.Key != "" && b.renames != nil && b.renames.paths {
+			path = append(path, attr.Key...)
+			path = append(path, '.')
+		}
+
 		var isFirst = true
 		for _, v := range group {
-			if v.Equal(slog.Attr{}) {
-				continue
-			}
-
-			if !isFirst {
-				buf = append(buf, ',')
-			} else {
-				isFirst = false
-			}
+			buf = b.appendSeparatedAttr(buf, path, v, &isFirst)
+		}
 
-			buf = b.appendAttr(buf, nil, v)
+		// Groups without non-empty attrs are omitted like in slog.
+		if isFirst {
+			return buf[:start]
 		}
 
 		if attr.Key != "" {
@@ -131,12 +381,25 @@ func (b *jsonBuilder) appendAttr(buf []byte, _ []byte, attr slog.Attr) []byte {
 		return buf
 	}
 
+	if b.renames != nil && attr.Key != "" {
+		attr.Key = b.renames.rename(path, attr.Key)
+	}
+
+	// Both forms of a duration: "latency_ns":15000000,"latency":"15ms".
+	if attr.Value.Kind() == slog.KindDuration && b.durations == DurationBoth && attr.Key != "" {
+		buf = append(buf, '"')
+		buf = escape.AppendJSON(buf, attr.Key)
+		buf = append(buf, `_ns":`...)
+		buf = strconv.AppendInt(buf, attr.Value.Duration().Nanoseconds(), 10)
+		buf = append(buf, ',')
+	}
+
 	// Write key.
 	buf = append(buf, '"')
 	if attr.Key == "" {
 		buf = append(buf, "!EMPTY_KEY"...)
 	} else {
-		buf = appendEscapedJSONString(buf, attr.Key)
+		buf = escape.AppendJSON(buf, attr.Key)
 
 	}
 	buf = append(buf, `":`...)
@@ -152,9 +415,21 @@ func (b *jsonBuilder) writeValue(buf []byte, value slog.Value) []byte {
 	case slog.KindString:
 		buf = b.appendString(buf, value.String())
 	case slog.KindInt64:
-		buf = strconv.AppendInt(buf, value.Int64(), 10)
+		if n := value.Int64(); b.quoteBigInts && (n > maxSafeInteger || n < -maxSafeInteger) {
+			buf = append(buf, '"')
+			buf = strconv.AppendInt(buf, n, 10)
+			buf = append(buf, '"')
+		} else {
+			buf = strconv.AppendInt(buf, n, 10)
+		}
 	case slog.KindUint64:
-		buf = strconv.AppendUint(buf, value.Uint64(), 10)
+		if n := value.Uint64(); b.quoteBigInts && n > maxSafeInteger {
+			buf = append(buf, '"')
+			buf = strconv.AppendUint(buf, n, 10)
+			buf = append(buf, '"')
+		} else {
+			buf = strconv.AppendUint(buf, n, 10)
+		}
 	case slog.KindFloat64:
 		buf = strconv.AppendFloat(buf, value.Float64(), 'f', -1, 64)
 	case slog.KindBool:
@@ -164,16 +439,48 @@ func (b *jsonBuilder) writeValue(buf []byte, value slog.Value) []byte {
 			buf = append(buf, "false"...)
 		}
 	case slog.KindDuration:
-		buf = strconv.AppendInt(buf, value.Duration().Nanoseconds(), 10)
+		if b.durations == DurationNanos {
+			buf = strconv.AppendInt(buf, value.Duration().Nanoseconds(), 10)
+		} else {
+			buf = append(buf, '"')
+			buf = appendDuration(buf, value.Duration())
+			buf = append(buf, '"')
+		}
 	case slog.KindTime:
 		buf = append(buf, '"')
-		buf = value.Time().AppendFormat(buf, time.DateTime)
+		buf = value.Time().AppendFormat(buf, b.timeFormat)
 		buf = append(buf, '"')
 	case slog.KindAny:
 		if err, ok := value.Any().(error); ok {
-			buf = append(buf, err.Error()...)
+			buf = b.appendString(buf, err.Error())
 			return buf
 		}
+		if st, ok := value.Any().(stackTrace); ok {
+			return st.appendJSON(buf)
+		}
+		if hv, ok := value.Any().(histogramValue); ok {
+			return hv.appendJSON(buf)
+		}
+		if chain, ok := value.Any().(errChainValue); ok {
+			return chain.appendJSON(buf)
+		}
+		if uv, ok := value.Any().(unitValue); ok {
+			return b.writeValue(buf, uv.number())
+		}
+		if tv, ok := value.Any().(textValue); ok {
+			return appendJSONText(buf, tv)
+		}
+		if src, ok := anySource(value.Any()); ok {
+			return appendSourceJSON(buf, src)
+		}
+		if tm, ok := anyTime(value.Any()); ok {
+			buf = append(buf, '"')
+			buf = tm.AppendFormat(buf, b.timeFormat)
+			return append(buf, '"')
+		}
+		if structBuf, ok := appendStruct(buf, value.Any()); ok {
+			return structBuf
+		}
 		b, err := json.Marshal(value.Any())
 		if err != nil {
 			buf = append(buf, "!ERR_MARSHAL"...)
@@ -188,104 +495,110 @@ func (b *jsonBuilder) writeValue(buf []byte, value slog.Value) []byte {
 }
 
 func (b *jsonBuilder) appendString(buf []byte, val string) []byte {
+	if b.intern != nil {
+		if encoded, ok := b.intern.load(val); ok {
+			return append(buf, encoded...)
+		}
+	}
+
+	start := len(buf)
+	// val stays the intern key, the cache holds the encoded form of the cut value.
+	value := b.limit.truncate(val)
+
 	buf = append(buf, '"')
-	if val == "" {
+	if value == "" {
 		buf = append(buf, "!EMPTY_VALUE"...)
 	} else {
-		buf = appendEscapedJSONString(buf, val)
+		buf = escape.AppendJSON(buf, value)
 	}
 	buf = append(buf, '"')
 
+	if b.intern != nil {
+		b.intern.store(val, buf[start:])
+	}
+
 	return buf
 }
 
-func (b *jsonBuilder) precomputeAttrs(buf []byte, _ string, attrs []slog.Attr) []byte {
-	var attrsCount = len(attrs) - 1
+func (b *jsonBuilder) precomputeAttrs(buf []byte, precomputedGroups, groupPrefix string, attrs []slog.Attr) []byte {
+	mark := len(buf)
+	// buf may already hold attrs of the parent handler.
+	var isFirst = len(buf) == 0
+
+	// Groups opened after the parent attrs enclose the new ones.
+	if groups := groupPrefix[len(precomputedGroups):]; groups != "" {
+		if b.withAttrsLast {
+			buf = append(buf, groupMark)
+			buf = append(buf, groups...)
+			buf = append(buf, groupMark)
+		} else {
+			if !isFirst {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, groups...)
+		}
+		isFirst = true
+	}
 
-	for i, attr := range attrs {
-		buf = b.appendAttr(buf, nil, attr)
+	var pathBuf [128]byte
+	path := b.renames.appendGroupPath(pathBuf[:0], groupPrefix)
 
-		if attrsCount != i {
-			buf = append(buf, ',')
-		}
+	attrsStart := len(buf)
+	for _, attr := range attrs {
+		buf = b.appendSeparatedAttr(buf, path, attr, &isFirst)
 	}
 
+	if len(buf) == attrsStart {
+		return buf[:mark]
+	}
 	return buf
 }
 
-func (b *jsonBuilder) groupPrefix(oldPrefix string, newPrefix string) string {
-	return oldPrefix + `"` + newPrefix + `":{`
+// appendSeparatedAttr appends attr preceded by a comma unless it is the first one,
+// attrs rendering nothing (empty or inline empty groups) leave buf and isFirst unchanged.
+func (b *jsonBuilder) appendSeparatedAttr(buf []byte, path []byte, attr slog.Attr, isFirst *bool) []byte {
+	start := len(buf)
+	if !*isFirst {
+		buf = append(buf, ',')
+	}
+
+	mark := len(buf)
+	buf = b.appendAttr(buf, path, attr)
+	if len(buf) == mark {
+		return buf[:start]
+	}
+
+	*isFirst = false
+	return buf
 }
 
-// From stdlib.
+func (b *jsonBuilder) groupPrefix(oldPrefix string, newPrefix string) string {
+	buf := make([]byte, 0, len(oldPrefix)+len(newPrefix)+4)
+	buf = append(buf, oldPrefix...)
+	buf = append(buf, '"')
+	buf = escape.AppendJSON(buf, newPrefix)
+	buf = append(buf, `":{`...)
 
-const hex = "0123456789abcdef"
+	return string(buf)
+}
 
-func appendEscapedJSONString(buf []byte, s string) []byte {
-	char := func(b byte) { buf = append(buf, b) }
-	str := func(s string) { buf = append(buf, s...) }
+// groupDepth returns the count of objects opened by the group prefix, braces inside keys are skipped.
+func groupDepth(groupPrefix string) int {
+	var depth int
+	var inString bool
 
-	start := 0
-	for i := 0; i < len(s); {
-		if b := s[i]; b < utf8.RuneSelf {
-			if safeSet[b] {
-				i++
-				continue
-			}
-			if start < i {
-				str(s[start:i])
-			}
-			char('\\')
-			switch b {
-			case '\\', '"':
-				char(b)
-			case '\n':
-				char('n')
-			case '\r':
-				char('r')
-			case '\t':
-				char('t')
-			default:
-				// This encodes bytes < 0x20 except for \t, \n and \r.
-				str(`u00`)
-				char(hex[b>>4])
-				char(hex[b&0xF])
-			}
+	for i := 0; i < len(groupPrefix); i++ {
+		switch c := groupPrefix[i]; {
+		case c == '\\' && inString:
 			i++
-			start = i
-			continue
+		case c == '"':
+			inString = !inString
+		case c == '{' && !inString:
+			depth++
 		}
-		c, size := utf8.DecodeRuneInString(s[i:])
-		if c == utf8.RuneError && size == 1 {
-			if start < i {
-				str(s[start:i])
-			}
-			str(`\ufffd`)
-			i += size
-			start = i
-			continue
-		}
-		// U+2028 is LINE SEPARATOR.
-		// U+2029 is PARAGRAPH SEPARATOR.
-		// They are both technically valid characters in JSON strings,
-		// but don't work in JSONP, which has to be evaluated as JavaScript,
-		// and can lead to security holes there. It is valid JSON to
-		// escape them, so we do so unconditionally.
-		// See http://timelessrepo.com/json-isnt-a-javascript-subset for discussion.
-		if c == '\u2028' || c == '\u2029' {
-			if start < i {
-				str(s[start:i])
-			}
-			str(`\u202`)
-			char(hex[c&0xF])
-			i += size
-			start = i
-			continue
-		}
-		i += size
-	}
-	if start < len(s) {
-		str(s[start:])
 	}
-	return buf
+
+	return depth
 }
+
+const hex = "0123456789abcdef"
@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONDurations(t *testing.T) {
+	for format, want := range map[DurationFormat]string{
+		DurationNanos:  `"latency":15000000}`,
+		DurationString: `"latency":"15ms"}`,
+		DurationBoth:   `"latency_ns":15000000,"latency":"15ms"}`,
+	} {
+		var buf bytes.Buffer
+		slog.New(NewJsonHandler(&buf, &Config{JSONDurations: format})).Info("msg", "latency", 15*time.Millisecond)
+
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("format %d: output %q doesn't contain %q", format, buf.String(), want)
+		}
+	}
+}
+
+func TestJSONTimeFormat(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	logger := slog.New(NewJsonHandler(&buf, &Config{TimeFormat: time.RFC3339}))
+	logger.LogAttrs(context.Background(), slog.LevelInfo, "msg",
+		slog.Time("at", ts), TimeLayout("day", ts, time.DateOnly))
+
+	want := `"at":"2024-05-01T10:30:00Z","day":"2024-05-01"}`
+	if !strings.HasSuffix(strings.TrimSpace(buf.String()), want) {
+		t.Errorf("output %q doesn't end with %q", buf.String(), want)
+	}
+
+	record, _, _ := strings.Cut(buf.String(), `","level"`)
+	if _, err := time.Parse(time.RFC3339, strings.TrimPrefix(record, `{"time":"`)); err != nil {
+		t.Errorf("record time doesn't follow TimeFormat: %v", err)
+	}
+}
+
+func TestJSONWithAttrsPlacement(t *testing.T) {
+	type step func(slog.Handler) slog.Handler
+	with := func(key string, v int) step {
+		return func(h slog.Handler) slog.Handler { return h.WithAttrs([]slog.Attr{slog.Int(key, v)}) }
+	}
+	group := func(name string) step {
+		return func(h slog.Handler) slog.Handler { return h.WithGroup(name) }
+	}
+
+	tests := []struct {
+		name     string
+		steps    []step
+		attrs    []slog.Attr
+		wantLast string
+	}{
+		{"with before group", []step{with("a", 1), group("g")}, []slog.Attr{slog.Int("b", 2)},
+			`","g":{"b":2},"a":1}`},
+		{"with in group", []step{group("g"), with("a", 1)}, []slog.Attr{slog.Int("b", 2)},
+			`","g":{"b":2,"a":1}}`},
+		{"levels", []step{with("a", 1), group("g"), with("c", 3), group("h")}, []slog.Attr{slog.Int("b", 2)},
+			`","g":{"h":{"b":2},"c":3},"a":1}`},
+		{"levels without record attrs", []step{with("a", 1), group("g"), with("c", 3), group("h")}, nil,
+			`","g":{"c":3},"a":1}`},
+		{"nested groups", []step{group("g"), group("h"), with("a", 1), group("i")}, []slog.Attr{slog.Int("b", 2)},
+			`","g":{"h":{"i":{"b":2},"a":1}}}`},
+		{"empty", []step{group("g")}, nil, `"}`},
+	}
+
+	for _, tt := range tests {
+		var ours, std, last bytes.Buffer
+		handlers := []slog.Handler{
+			NewJsonHandler(&ours, nil),
+			slog.NewJSONHandler(&std, nil),
+			NewJsonHandler(&last, &Config{WithAttrsLast: true}),
+		}
+
+		for _, h := range handlers {
+			for _, apply := range tt.steps {
+				h = apply(h)
+			}
+			slog.New(h).LogAttrs(context.Background(), slog.LevelInfo, "m", tt.attrs...)
+		}
+
+		attrsOf := func(line string) string {
+			_, rest, _ := strings.Cut(strings.TrimSpace(line), `"msg":"m`)
+			return rest
+		}
+
+		if got, want := attrsOf(ours.String()), attrsOf(std.String()); got != want {
+			t.Errorf("%s: got %s, slog writes %s", tt.name, got, want)
+		}
+		if got := attrsOf(last.String()); got != tt.wantLast {
+			t.Errorf("%s: WithAttrsLast got %s, want %s", tt.name, got, tt.wantLast)
+		}
+	}
+}
+
+func TestCtxAttrsFirst(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewJsonHandler(&buf, &Config{CtxAttrsFirst: true})
+	ctx := AppendAttrsToCtx(context.Background(), slog.String("request_id", "abc"))
+
+	slog.New(h).InfoContext(ctx, "m", "n", 1)
+
+	if want := `"msg":"m","request_id":"abc","n":1}`; !strings.Contains(buf.String(), want) {
+		t.Errorf("output %q doesn't contain %q", buf.String(), want)
+	}
+}
+
+func TestWithCtxAttrs(t *testing.T) {
+	var std, own bytes.Buffer
+	h := NewJsonHandler(&own, &Config{})
+	ctx := AppendAttrsToCtx(context.Background(), slog.String("request_id", "abc"))
+
+	stdHandler := slog.NewJSONHandler(&std, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+
+	slog.New(NewMultiHandler(WithCtxAttrs(stdHandler), WithCtxAttrs(h))).InfoContext(ctx, "m")
+
+	if want := `{"level":"INFO","msg":"m","request_id":"abc"}` + "\n"; std.String() != want {
+		t.Errorf("stdlib output %q, want %q", std.String(), want)
+	}
+	if n := strings.Count(own.String(), "request_id"); n != 1 {
+		t.Errorf("own handler output %q has %d request_id attrs", own.String(), n)
+	}
+}
+
+func TestMaxGroupDepth(t *testing.T) {
+	var buf bytes.Buffer
+	var h slog.Handler = NewJsonHandler(&buf, &Config{MaxGroupDepth: 2})
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		h = h.WithGroup(name)
+	}
+	slog.New(h).Info("m", "n", 1)
+
+	if want := `"msg":"m","a":{"b":{"!GROUP_LIMIT":"c","n":1}}}`; !strings.Contains(buf.String(), want) {
+		t.Errorf("output %q doesn't contain %q", buf.String(), want)
+	}
+}
+
+func TestQuoteBigInts(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewJsonHandler(&buf, &Config{QuoteBigInts: true})).Info("m",
+		"safe", 1<<53-1, "big", 1<<53, "neg", -(1 << 53), "u", uint64(1<<63))
+
+	want := `"safe":9007199254740991,"big":"9007199254740992","neg":"-9007199254740992","u":"9223372036854775808"}`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("output %q doesn't contain %q", buf.String(), want)
+	}
+}
+
+func TestJSONSourceAndAnyTime(t *testing.T) {
+	var buf bytes.Buffer
+	tm := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	src := &slog.Source{Function: "main.run", File: "/app/main.go", Line: 7}
+	slog.New(NewJsonHandler(&buf, &Config{})).Info("m", "t", tm, "pt", &tm, "source", src)
+
+	var want bytes.Buffer
+	slog.New(NewJsonHandler(&want, &Config{})).Info("m", "t", tm, "pt", tm)
+	wantTimes := want.String()[strings.Index(want.String(), `"t":`) : len(want.String())-2]
+
+	if out := buf.String(); !strings.Contains(out, wantTimes+`,"source":{"function":"main.run","file":"/app/main.go","line":7}}`) {
+		t.Errorf("output %s, want %s", out, wantTimes)
+	}
+}
+
+func TestJSONErrorEscaping(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewJsonHandler(&buf, nil)).Error("m", "err", errors.New("open \"a.txt\":\nno such file"))
+
+	if want := `"err":"open \"a.txt\":\nno such file"}`; !strings.Contains(buf.String(), want) {
+		t.Errorf("output %q doesn't contain %q", buf.String(), want)
+	}
+	if !json.Valid(bytes.TrimSpace(buf.Bytes())) {
+		t.Errorf("invalid JSON %q", buf.String())
+	}
+}
+
+func TestJSONEscapingAndGroups(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewJsonHandler(&buf, nil)
+
+	// The message and the group names are escaped.
+	slog.New(h).WithGroup(`g"1`).Info("say \"hi\"\n", "k", 1)
+	if want := `"msg":"say \"hi\"\n","g\"1":{"k":1}}`; !strings.Contains(buf.String(), want) {
+		t.Errorf("output %q doesn't contain %q", buf.String(), want)
+	}
+
+	// Every group opened by WithGroup is closed, with and without attrs in between.
+	for _, l := range []*slog.Logger{
+		slog.New(h).WithGroup("a").WithGroup("b").WithGroup("c"),
+		slog.New(h).WithGroup("a").With("x", 1).WithGroup("b").With("y", 2).WithGroup("c"),
+	} {
+		buf.Reset()
+		l.Info("m", "n", 1)
+		if line := bytes.TrimSpace(buf.Bytes()); !json.Valid(line) || !bytes.HasSuffix(line, []byte(`"c":{"n":1}}}}`)) {
+			t.Errorf("output %s", line)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// keyRenames renames attr keys while they're encoded (Config.RenameKeys).
+type keyRenames struct {
+	keys map[string]string
+	// paths is set if a renamed key has a group path, only then the paths of attrs are built and looked up.
+	paths bool
+}
+
+func newKeyRenames(cfg *Config) *keyRenames {
+	if len(cfg.RenameKeys) == 0 {
+		return nil
+	}
+
+	r := &keyRenames{keys: maps.Clone(cfg.RenameKeys)}
+	for from := range r.keys {
+		if strings.Contains(from, ".") {
+			r.paths = true
+		}
+	}
+	return r
+}
+
+// rename returns the new key of the attr, the dotted path ("http.request.") wins over the bare key.
+// path is appended to, its array must have no data after len(path).
+func (r *keyRenames) rename(path []byte, key string) string {
+	if r == nil {
+		return key
+	}
+
+	if r.paths && len(path) > 0 {
+		if to, ok := r.keys[string(append(path, key...))]; ok {
+			return to
+		}
+	}
+	if to, ok := r.keys[key]; ok {
+		return to
+	}
+	return key
+}
+
+// appendGroupPath appends the dotted path of the groups opened by a JSON group prefix, e.g. "http.request."
+// for `"http":{"request":{`. It's only built if a renamed key has a group path.
+func (r *keyRenames) appendGroupPath(path []byte, groupPrefix string) []byte {
+	if r == nil || !r.paths {
+		return path
+	}
+
+	for groupPrefix != "" {
+		end := 1
+		for end < len(groupPrefix) && groupPrefix[end] != '"' {
+			if groupPrefix[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(groupPrefix) {
+			break
+		}
+
+		key := groupPrefix[:end+1]
+		if unquoted, err := strconv.Unquote(key); err == nil {
+			path = append(path, unquoted...)
+		} else {
+			path = append(path, key[1:end]...)
+		}
+		path = append(path, '.')
+
+		// Skip the `":{` after the key.
+		groupPrefix = groupPrefix[min(end+3, len(groupPrefix)):]
+	}
+	return path
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRenameKeys(t *testing.T) {
+	cfg := &Config{
+		BufferedOutput: false,
+		RenameKeys: map[string]string{
+			"latency":      "duration_ms",
+			"http.status":  "status_code",
+			"http.req.id":  "request_id",
+			"unused.inner": "never",
+		},
+	}
+
+	textCfg := *cfg
+	textCfg.CoalesceColors = true
+
+	var jsonBuf, textBuf bytes.Buffer
+	for _, h := range []*Handler{NewJsonHandler(&jsonBuf, cfg), NewTextHandler(&textBuf, &textCfg)} {
+		slog.New(h).WithGroup("http").With("status", 200).Info("done",
+			slog.Int("latency", 15),
+			slog.Group("req", slog.String("id", "r-1"), slog.Int("status", 1)),
+		)
+		slog.New(h).Info("top", slog.Int("status", 2), slog.Int("latency", 3))
+	}
+
+	lines := strings.Split(strings.TrimSpace(jsonBuf.String()), "\n")
+	var got map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	http := got["http"].(map[string]any)
+	if http["status_code"] != float64(200) || http["duration_ms"] != float64(15) {
+		t.Errorf("json: %s", lines[0])
+	}
+	if req := http["req"].(map[string]any); req["request_id"] != "r-1" || req["status"] != float64(1) {
+		t.Errorf("json: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"status":2,"duration_ms":3`) {
+		t.Errorf("json: %s", lines[1])
+	}
+
+	text := textBuf.String()
+	for _, want := range []string{"http.status_code=200", "http.duration_ms=15", "http.req.request_id=r-1", "http.req.status=1", "status=2", "duration_ms=3"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text: no %s in %s", want, text)
+		}
+	}
+
+	if err := (&Config{RenameKeys: map[string]string{"latency": ""}}).Validate(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("empty new key: err = %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"log/slog"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// the first bucket holds durations up to 2^minLatencyShift ns (128ns), every next one doubles the bound.
+	minLatencyShift = 7
+	// count of bounded buckets, the last one is up to ~1.07s, slower records go to the overflow bucket.
+	latencyBuckets = 24
+)
+
+// latencyLevels are the levels with their own histograms, custom levels are counted in the closest lower one.
+var latencyLevels = [...]slog.Level{LevelTrace, slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+
+// LatencyHistogram is a snapshot of a latency histogram with exponential buckets.
+type LatencyHistogram struct {
+	// upper bounds of the buckets, Counts has one more element for durations above the last bound.
+	Bounds []time.Duration
+	Counts []uint64
+	// count of measured durations and their sum.
+	Count uint64
+	Sum   time.Duration
+}
+
+// LevelLatency holds the encode and write latency of the records of a level.
+type LevelLatency struct {
+	Level  slog.Level
+	Encode LatencyHistogram
+	Write  LatencyHistogram
+}
+
+// Quantile returns the upper bound of the bucket holding the q-th quantile (0 < q <= 1),
+// so the result overestimates the real value at most twice. It's 0 for an empty histogram.
+func (h LatencyHistogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	rank := uint64(q * float64(h.Count))
+	if rank == 0 {
+		rank = 1
+	}
+
+	var seen uint64
+	for i, n := range h.Counts {
+		seen += n
+		if seen >= rank {
+			if i < len(h.Bounds) {
+				return h.Bounds[i]
+			}
+			break
+		}
+	}
+
+	// The quantile is in the overflow bucket, the largest known bound is the best answer.
+	return h.Bounds[len(h.Bounds)-1]
+}
+
+type histogram struct {
+	counts [latencyBuckets + 1]atomic.Uint64
+	count  atomic.Uint64
+	sum    atomic.Int64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	h.counts[latencyBucket(d)].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+}
+
+// latencyBucket returns the index of the smallest bucket with bound >= d.
+func latencyBucket(d time.Duration) int {
+	if d <= 1<<minLatencyShift {
+		return 0
+	}
+
+	// Bits needed for d-1 give the smallest power of two >= d.
+	i := bits.Len64(uint64(d-1)) - minLatencyShift
+	if i > latencyBuckets {
+		return latencyBuckets
+	}
+	return i
+}
+
+func (h *histogram) snapshot() LatencyHistogram {
+	s := LatencyHistogram{
+		Bounds: make([]time.Duration, latencyBuckets),
+		Counts: make([]uint64, latencyBuckets+1),
+		Count:  h.count.Load(),
+		Sum:    time.Duration(h.sum.Load()),
+	}
+
+	for i := range s.Bounds {
+		s.Bounds[i] = time.Duration(1) << (minLatencyShift + i)
+	}
+	for i := range s.Counts {
+		s.Counts[i] = h.counts[i].Load()
+	}
+
+	return s
+}
+
+// latencyStats are the histograms of Config.ProfileLatency.
+type latencyStats struct {
+	encode [len(latencyLevels)]histogram
+	write  [len(latencyLevels)]histogram
+}
+
+func latencyLevelIndex(level slog.Level) int {
+	for i := len(latencyLevels) - 1; i > 0; i-- {
+		if level >= latencyLevels[i] {
+			return i
+		}
+	}
+	return 0
+}
+
+func (l *latencyStats) observe(level slog.Level, encode, write time.Duration) {
+	i := latencyLevelIndex(level)
+	l.encode[i].observe(encode)
+	l.write[i].observe(write)
+}
+
+func (l *latencyStats) snapshot() []LevelLatency {
+	levels := make([]LevelLatency, len(latencyLevels))
+	for i, level := range latencyLevels {
+		levels[i] = LevelLatency{
+			Level:  level,
+			Encode: l.encode[i].snapshot(),
+			Write:  l.write[i].snapshot(),
+		}
+	}
+	return levels
+}
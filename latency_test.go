@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestLatencyBucket(t *testing.T) {
+	for _, tt := range []struct {
+		d    time.Duration
+		want int
+	}{
+		{0, 0},
+		{128, 0},
+		{129, 1},
+		{256, 1},
+		{257, 2},
+		{time.Hour, latencyBuckets},
+	} {
+		if got := latencyBucket(tt.d); got != tt.want {
+			t.Errorf("latencyBucket(%s) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestProfileLatency(t *testing.T) {
+	h := NewJsonHandler(io.Discard, &Config{Level: int(LevelTrace), ProfileLatency: true})
+	log := slog.New(h)
+	for range 10 {
+		log.Warn("measured")
+	}
+	log.Log(context.Background(), slog.LevelWarn+1, "custom level")
+
+	stats := h.Stats()
+	if len(stats.Latency) != len(latencyLevels) {
+		t.Fatalf("Latency has %d levels, want %d", len(stats.Latency), len(latencyLevels))
+	}
+
+	for _, l := range stats.Latency {
+		want := uint64(0)
+		if l.Level == slog.LevelWarn {
+			want = 11
+		}
+		if l.Encode.Count != want || l.Write.Count != want {
+			t.Errorf("level %s: counts %d/%d, want %d", l.Level, l.Encode.Count, l.Write.Count, want)
+		}
+	}
+
+	if q := stats.Latency[3].Encode.Quantile(0.99); q <= 0 {
+		t.Errorf("Quantile(0.99) = %s", q)
+	}
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"log/slog"
+	"time"
+)
+
+const levelChangedMsg = "log level changed"
+
+// LevelChangeFunc is called after the handler level was changed by SetLevel.
+type LevelChangeFunc func(old, new slog.Level)
+
+// Level returns the current minimal level of the handler.
+func (h *Handler) Level() slog.Level {
+	return h.shared.level.Level()
+}
+
+// SetLevel changes the minimal level of the handler and all its clones.
+// If the level actually changes, OnLevelChange hooks are called and, with Config.AuditLevelChanges,
+// a record with the old and new level and the reason is written regardless of the levels.
+func (h *Handler) SetLevel(level slog.Level, reason string) {
+	s := h.shared
+
+	s.levelMu.Lock()
+	old := s.level.Level()
+	if old == level {
+		s.levelMu.Unlock()
+		return
+	}
+	s.level.Set(level)
+	hooks := s.levelHooks
+	s.levelMu.Unlock()
+
+	for _, hook := range hooks {
+		hook(old, level)
+	}
+
+	if s.auditLevelChanges {
+		record := slog.NewRecord(time.Now(), slog.LevelInfo, levelChangedMsg, 0)
+		record.AddAttrs(
+			slog.String("old_level", levelName(old)),
+			slog.String("new_level", levelName(level)),
+		)
+		if reason != "" {
+			record.AddAttrs(slog.String("reason", reason))
+		}
+
+		_ = h.write(nil, record)
+	}
+}
+
+// OnLevelChange registers fn to be called after every level change made by SetLevel on the handler or its clones.
+// Hooks are called synchronously in the order they were registered.
+func (h *Handler) OnLevelChange(fn LevelChangeFunc) {
+	s := h.shared
+
+	s.levelMu.Lock()
+	// Copy on write, so SetLevel can call the hooks without holding the lock.
+	s.levelHooks = append(s.levelHooks[:len(s.levelHooks):len(s.levelHooks)], fn)
+	s.levelMu.Unlock()
+}
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ttrtcixy/fast-slog-handler/internal/escape"
+)
+
+// levelLabels is Config.LevelLabels prepared for the builders.
+type levelLabels struct {
+	// text labels padded to width.
+	text map[slog.Level]string
+	// json labels escaped for a JSON string.
+	json map[slog.Level]string
+	// width of the text level column, the widest label. Unlabeled levels keep the default
+	// 4 letter form, so it is less than textLevelWidth only if all standard levels are labeled.
+	width int
+}
+
+// newLevelLabels returns nil for an empty map.
+func newLevelLabels(labels map[slog.Level]string) *levelLabels {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	l := &levelLabels{
+		text: make(map[slog.Level]string, len(labels)),
+		json: make(map[slog.Level]string, len(labels)),
+	}
+
+	for _, label := range labels {
+		l.width = max(l.width, utf8.RuneCountInString(label))
+	}
+	for _, level := range standardLevels {
+		if _, ok := labels[level]; !ok {
+			l.width = max(l.width, textLevelWidth)
+			break
+		}
+	}
+
+	for level, label := range labels {
+		l.text[level] = label + strings.Repeat(" ", l.width-utf8.RuneCountInString(label))
+		l.json[level] = string(escape.AppendJSON(nil, label))
+	}
+
+	return l
+}
+
+// appendText appends the padded text label of the level, the default one if it isn't labeled.
+func (l *levelLabels) appendText(buf []byte, level slog.Level) []byte {
+	if label, ok := l.text[level]; ok {
+		return append(buf, label...)
+	}
+
+	start := len(buf)
+	buf = appendTextLevel(buf, level)
+	for n := len(buf) - start; n < l.width; n++ {
+		buf = append(buf, ' ')
+	}
+	return buf
+}
+
+// appendJSON appends the escaped JSON label of the level, the default one if it isn't labeled.
+func (l *levelLabels) appendJSON(buf []byte, level slog.Level) []byte {
+	if label, ok := l.json[level]; ok {
+		return append(buf, label...)
+	}
+	return append(buf, levelName(level)...)
+}
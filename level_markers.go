@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// LevelMarkers is the symbol set written before levels in text output, see Config.LevelMarkers.
+type LevelMarkers int
+
+const (
+	// no markers, the level is told by color only
+	MarkersNone LevelMarkers = iota
+	// 🔍 🐛 ℹ️ ⚠️ ❌
+	MarkersEmoji
+	// · • ✓ ! ✗
+	MarkersSymbols
+)
+
+// markerSets are indexed like standardLevels.
+var markerSets = map[LevelMarkers][len(standardLevels)]string{
+	MarkersEmoji:   {"🔍", "🐛", "ℹ️", "⚠️", "❌"},
+	MarkersSymbols: {"·", "•", "✓", "!", "✗"},
+}
+
+// asciiMarkers replace the markers on terminals without UTF-8.
+var asciiMarkers = [len(standardLevels)]string{".", "-", "+", "!", "x"}
+
+// levelMarkers returns the markers of the mode, ASCII ones if the locale isn't UTF-8, nil for MarkersNone.
+func levelMarkers(mode LevelMarkers) *[len(standardLevels)]string {
+	set, ok := markerSets[mode]
+	if !ok {
+		return nil
+	}
+
+	if !utf8Locale() {
+		set = asciiMarkers
+	}
+	return &set
+}
+
+// utf8Locale reports whether the locale environment selects UTF-8, the first non-empty
+// of LC_ALL, LC_CTYPE and LANG wins like in setlocale. An unset locale is taken as UTF-8,
+// since that's the default of current terminals. Windows consoles don't use these variables.
+func utf8Locale() bool {
+	for _, name := range [...]string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			v = strings.ToLower(v)
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+	return true
+}
+
+// levelMarker returns the marker of the level, custom levels take the marker of their base level.
+func levelMarker(markers *[len(standardLevels)]string, level slog.Level) string {
+	base := levelBase(level)
+	for i, l := range standardLevels {
+		if l == base {
+			return markers[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// levelRules are per-package levels set by SetLevelRules, a new value replaces the old one as a whole.
+type levelRules struct {
+	rules []levelRule
+	// min is the lowest level of all rules, Enabled passes records at or above it to Handle.
+	min slog.Level
+	// cache stores the matched rule for every pc (*levelRule, nil - no rule), call sites are finite in a program.
+	cache sync.Map
+}
+
+type levelRule struct {
+	pattern string
+	// recursive is set for patterns ending with "/*", they match the package and all packages below it.
+	recursive bool
+	level     slog.Level
+}
+
+// SetLevelRules sets levels for the packages matching the patterns, records of other packages use the handler level.
+// Patterns use path.Match syntax against the package of the log call (e.g. "github.com/our/repo/internal/db"),
+// a trailing "/*" matches the package and all packages below it. The longest matching pattern wins.
+// The rules are shared by all clones, nil or empty rules remove them.
+func (h *Handler) SetLevelRules(rules map[string]slog.Level) error {
+	if len(rules) == 0 {
+		h.shared.levelRules.Store(nil)
+		return nil
+	}
+
+	lr := &levelRules{rules: make([]levelRule, 0, len(rules))}
+
+	first := true
+	for pattern, level := range rules {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: level rule %q: %w", ErrInvalidConfig, pattern, err)
+		}
+
+		rule := levelRule{pattern: pattern, level: level}
+		if base, ok := strings.CutSuffix(pattern, "/*"); ok {
+			rule.pattern = base
+			rule.recursive = true
+		}
+		lr.rules = append(lr.rules, rule)
+
+		if first || level < lr.min {
+			lr.min = level
+			first = false
+		}
+	}
+
+	h.shared.levelRules.Store(lr)
+	return nil
+}
+
+// enabled reports whether the record passes the rule for its call site or, without one, the handler level.
+func (r *levelRules) enabled(record slog.Record, level slog.Level) bool {
+	if rule := r.ruleFor(record.PC); rule != nil {
+		level = rule.level
+	}
+	return record.Level >= level
+}
+
+func (r *levelRules) ruleFor(pc uintptr) *levelRule {
+	if pc == 0 {
+		return nil
+	}
+
+	if cached, ok := r.cache.Load(pc); ok {
+		return cached.(*levelRule)
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	pkg, _ := splitFuncName(frame.Function)
+
+	var best *levelRule
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if rule.match(pkg) && (best == nil || len(rule.pattern) > len(best.pattern)) {
+			best = rule
+		}
+	}
+
+	r.cache.Store(pc, best)
+	return best
+}
+
+func (r *levelRule) match(pkg string) bool {
+	if !r.recursive {
+		ok, _ := path.Match(r.pattern, pkg)
+		return ok
+	}
+
+	// Check the package and every parent, so "repo/db/*" matches "repo/db" and "repo/db/pool".
+	for p := pkg; p != "." && p != "/"; p = path.Dir(p) {
+		if ok, _ := path.Match(r.pattern, p); ok {
+			return true
+		}
+	}
+
+	return false
+}
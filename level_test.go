@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewJsonHandler(&buf, &Config{Level: int(slog.LevelInfo), AuditLevelChanges: true})
+	clone := h.WithAttrs([]slog.Attr{slog.String("k", "v")})
+
+	var changes [][2]slog.Level
+	h.OnLevelChange(func(old, new slog.Level) {
+		changes = append(changes, [2]slog.Level{old, new})
+	})
+
+	h.SetLevel(slog.LevelDebug, "incident")
+	h.SetLevel(slog.LevelDebug, "repeated")
+
+	if !clone.Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("clone doesn't see the new level")
+	}
+
+	if len(changes) != 1 || changes[0] != [2]slog.Level{slog.LevelInfo, slog.LevelDebug} {
+		t.Fatalf("hooks got %v, want one INFO -> DEBUG change", changes)
+	}
+
+	out := buf.String()
+	if strings.Count(out, levelChangedMsg) != 1 || !strings.Contains(out, `"old_level":"INFO","new_level":"DEBUG","reason":"incident"`) {
+		t.Fatalf("audit output = %q", out)
+	}
+}
+
+func TestLevelRules(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewJsonHandler(&buf, &Config{Level: int(slog.LevelInfo)})
+	log := slog.New(h)
+
+	if err := h.SetLevelRules(map[string]slog.Level{"example.com/other/*": LevelTrace}); err != nil {
+		t.Fatal(err)
+	}
+	log.Debug("other package rule")
+
+	if err := h.SetLevelRules(map[string]slog.Level{
+		"github.com/ttrtcixy/*":                 slog.LevelError,
+		"github.com/ttrtcixy/fast-slog-handler": slog.LevelDebug,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	log.Debug("longest pattern rule")
+
+	if out := buf.String(); strings.Contains(out, "other package rule") || !strings.Contains(out, "longest pattern rule") {
+		t.Fatalf("output = %q", out)
+	}
+
+	if err := h.SetLevelRules(map[string]slog.Level{"[": slog.LevelDebug}); err == nil {
+		t.Fatal("bad pattern accepted")
+	}
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// WriterAt returns an io.Writer that logs every written line as a record at the given level,
+// with msgPrefix prepended to the message, e.g. for exec.Cmd.Stdout or libraries that accept an io.Writer only.
+// The writer also implements io.Closer, Close logs a pending line that was not terminated by '\n'.
+func (h *Handler) WriterAt(level slog.Level, msgPrefix string) io.Writer {
+	return &levelWriter{
+		handler: h,
+		level:   level,
+		prefix:  msgPrefix,
+	}
+}
+
+type levelWriter struct {
+	mu sync.Mutex
+
+	handler *Handler
+	level   slog.Level
+	prefix  string
+
+	// partial holds the written part of an unterminated line.
+	partial []byte
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.partial = append(w.partial, p...)
+			// Don't grow without bound if the producer never writes a newline.
+			if len(w.partial) >= writerBufSize {
+				if err := w.logPartial(); err != nil {
+					return n, err
+				}
+			}
+			break
+		}
+
+		line := p[:i]
+		if len(w.partial) > 0 {
+			w.partial = append(w.partial, line...)
+			line = w.partial
+		}
+
+		if err := w.log(line); err != nil {
+			return n, err
+		}
+
+		w.partial = w.partial[:0]
+		p = p[i+1:]
+	}
+
+	return n, nil
+}
+
+// Close logs the pending unterminated line.
+func (w *levelWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.logPartial()
+}
+
+func (w *levelWriter) logPartial() error {
+	if len(w.partial) == 0 {
+		return nil
+	}
+
+	err := w.log(w.partial)
+	w.partial = w.partial[:0]
+	return err
+}
+
+func (w *levelWriter) log(line []byte) error {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	if len(line) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	if !w.handler.Enabled(ctx, w.level) {
+		return nil
+	}
+
+	record := slog.NewRecord(time.Now(), w.level, w.prefix+string(line), 0)
+	return w.handler.Handle(ctx, record)
+}
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWriterAt(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewJsonHandler(&buf, &Config{Level: int(slog.LevelInfo)})
+	w := h.WriterAt(slog.LevelWarn, "cmd: ")
+
+	// Lines split across writes are joined, CRLF endings and empty lines are dropped.
+	for _, chunk := range []string{"first li", "ne\r\nsecond\n\n", "tail"} {
+		if n, err := io.WriteString(w, chunk); err != nil || n != len(chunk) {
+			t.Fatalf("write %q: %d, %v", chunk, n, err)
+		}
+	}
+	if err := w.(io.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{`"level":"WARN","msg":"cmd: first line"}`, `"level":"WARN","msg":"cmd: second"}`, `"level":"WARN","msg":"cmd: tail"}`}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d records:\n%s", len(lines), buf.String())
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Errorf("record %d: %s, want suffix %s", i, lines[i], want[i])
+		}
+	}
+
+	// Disabled levels write nothing.
+	buf.Reset()
+	_, _ = io.WriteString(h.WriterAt(slog.LevelDebug, ""), "debug\n")
+	if buf.Len() != 0 {
+		t.Errorf("disabled level written: %s", buf.String())
+	}
+
+	// A line without a newline is logged once it fills the buffer.
+	_, _ = w.Write(bytes.Repeat([]byte("x"), writerBufSize))
+	if got := buf.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, strings.Repeat("x", 64)) {
+		t.Errorf("long line: %.100s", got)
+	}
+}
@@ -0,0 +1,165 @@
+package logattr
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the values of redacted headers and query parameters.
+const Redacted = "[REDACTED]"
+
+const defaultMaxBody = 1024
+
+// DumpOptions controls HTTPRequestDump and HTTPResponseDump.
+type DumpOptions struct {
+	// headers to include (canonical or not), nil - none.
+	Headers []string
+	// headers and query parameters logged as Redacted, nil - DefaultRedact.
+	Redact []string
+	// max bytes of the body preview, 0 - 1024, negative - no body.
+	MaxBody int
+}
+
+// DefaultRedact are the headers and query parameters redacted when DumpOptions.Redact is nil.
+var DefaultRedact = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key",
+	"access_token", "api_key", "token", "password",
+}
+
+// HTTPRequestDump returns the "request" group with the method, url, selected headers and a body preview of r
+// for debugging API integrations. The previewed part of the body is put back, so r can still be sent or read.
+func HTTPRequestDump(r *http.Request, opts DumpOptions) slog.Attr {
+	if r == nil {
+		return slog.Attr{}
+	}
+
+	attrs := []slog.Attr{slog.String("method", r.Method)}
+	if r.URL != nil {
+		attrs = append(attrs, slog.String("url", redactURL(r.URL, opts.redact())))
+	}
+
+	attrs = appendHeaders(attrs, r.Header, opts)
+	attrs = appendBody(attrs, &r.Body, r.ContentLength, opts)
+
+	return slog.Attr{Key: "request", Value: slog.GroupValue(attrs...)}
+}
+
+// HTTPResponseDump returns the "response" group with the status, selected headers and a body preview of resp.
+// The previewed part of the body is put back, so resp can still be read.
+func HTTPResponseDump(resp *http.Response, opts DumpOptions) slog.Attr {
+	if resp == nil {
+		return slog.Attr{}
+	}
+
+	attrs := []slog.Attr{slog.Int("status", resp.StatusCode)}
+	attrs = appendHeaders(attrs, resp.Header, opts)
+	attrs = appendBody(attrs, &resp.Body, resp.ContentLength, opts)
+
+	return slog.Attr{Key: "response", Value: slog.GroupValue(attrs...)}
+}
+
+func (o *DumpOptions) redact() []string {
+	if o.Redact == nil {
+		return DefaultRedact
+	}
+	return o.Redact
+}
+
+func isRedacted(name string, redact []string) bool {
+	for _, r := range redact {
+		if strings.EqualFold(name, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactURL(u *url.URL, redact []string) string {
+	c := *u
+	// Credentials in the URL are never logged.
+	c.User = nil
+
+	if c.RawQuery != "" {
+		query := c.Query()
+		for key := range query {
+			if isRedacted(key, redact) {
+				query[key] = []string{Redacted}
+			}
+		}
+		c.RawQuery = query.Encode()
+	}
+
+	return c.String()
+}
+
+func appendHeaders(attrs []slog.Attr, header http.Header, opts DumpOptions) []slog.Attr {
+	if len(opts.Headers) == 0 || len(header) == 0 {
+		return attrs
+	}
+
+	redact := opts.redact()
+
+	headers := make([]slog.Attr, 0, len(opts.Headers))
+	for _, name := range opts.Headers {
+		values := header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+
+		value := strings.Join(values, ", ")
+		if isRedacted(name, redact) {
+			value = Redacted
+		}
+		headers = append(headers, slog.String(http.CanonicalHeaderKey(name), value))
+	}
+
+	if len(headers) == 0 {
+		return attrs
+	}
+
+	return append(attrs, slog.Attr{Key: "headers", Value: slog.GroupValue(headers...)})
+}
+
+// appendBody reads up to MaxBody bytes of the body and replaces it with a reader returning them first.
+func appendBody(attrs []slog.Attr, body *io.ReadCloser, size int64, opts DumpOptions) []slog.Attr {
+	if opts.MaxBody < 0 || *body == nil || *body == http.NoBody {
+		return attrs
+	}
+
+	limit := opts.MaxBody
+	if limit == 0 {
+		limit = defaultMaxBody
+	}
+
+	// One extra byte tells whether the body is longer than the preview.
+	preview, err := io.ReadAll(io.LimitReader(*body, int64(limit)+1))
+	*body = readCloser{io.MultiReader(bytes.NewReader(preview), *body), *body}
+
+	truncated := len(preview) > limit
+	if truncated {
+		preview = preview[:limit]
+	}
+
+	attrs = append(attrs, slog.String("body", strings.ToValidUTF8(string(preview), "\uFFFD")))
+	if truncated {
+		attrs = append(attrs, slog.Bool("body_truncated", true))
+	}
+	if size > 0 {
+		attrs = append(attrs, slog.Int64("body_size", size))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("body_error", err.Error()))
+	}
+
+	return attrs
+}
+
+// readCloser returns the previewed bytes and then the rest of the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
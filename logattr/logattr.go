@@ -0,0 +1,72 @@
+// Package logattr provides constructors for common attributes, so every service logs
+// HTTP requests, database queries and users with the same group structure.
+//
+// The attrs are plain slog groups, the builders have no special form for them: they flatten to dotted keys
+// in the text handler (http.method=GET http.path=/users) and to nested objects in the JSON handler.
+package logattr
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Group keys used by the constructors.
+const (
+	HTTPKey = "http"
+	DBKey   = "db"
+	UserKey = "user"
+)
+
+// HTTPRequest returns the "http" group with the method, path, query, host, remote address and user agent of r.
+// Credentials in the URL are never logged, headers and body are not included.
+func HTTPRequest(r *http.Request) slog.Attr {
+	if r == nil {
+		return slog.Attr{}
+	}
+
+	attrs := make([]slog.Attr, 0, 7)
+	attrs = append(attrs, slog.String("method", r.Method))
+
+	if r.URL != nil {
+		attrs = append(attrs, slog.String("path", r.URL.Path))
+		if r.URL.RawQuery != "" {
+			attrs = append(attrs, slog.String("query", r.URL.RawQuery))
+		}
+	}
+
+	attrs = append(attrs,
+		slog.String("host", r.Host),
+		slog.String("remote_addr", r.RemoteAddr),
+	)
+
+	if ua := r.UserAgent(); ua != "" {
+		attrs = append(attrs, slog.String("user_agent", ua))
+	}
+
+	if r.ContentLength > 0 {
+		attrs = append(attrs, slog.Int64("content_length", r.ContentLength))
+	}
+
+	return slog.Attr{Key: HTTPKey, Value: slog.GroupValue(attrs...)}
+}
+
+// DBQuery returns the "db" group with the statement, its arguments and duration.
+// Pass nil args for queries with sensitive arguments.
+func DBQuery(statement string, args []any, duration time.Duration) slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("statement", statement),
+		slog.Duration("duration", duration),
+	}
+
+	if len(args) > 0 {
+		attrs = append(attrs, slog.Any("args", args))
+	}
+
+	return slog.Attr{Key: DBKey, Value: slog.GroupValue(attrs...)}
+}
+
+// User returns the "user" group with the user id.
+func User(id string) slog.Attr {
+	return slog.Attr{Key: UserKey, Value: slog.GroupValue(slog.String("id", id))}
+}
@@ -1,11 +1,13 @@
 package logger
 
 import (
-	"bufio"
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,11 +29,20 @@ const (
 	LevelInfo  = "INFO"
 	LevelWarn  = "WARN"
 	LevelError = "ERROR"
+
+	levelTraceLabel = "TRACE"
 )
 
+// LevelTrace is more verbose than slog.LevelDebug, use Logger.Trace to log at it.
+const LevelTrace = slog.Level(-8)
+
 var (
 	ErrNothingToClose = errors.New("use of close() is supported only for buffered logging")
 	ErrAlreadyClosed  = errors.New("logger buffer already closed")
+	ErrNilWriter      = errors.New("logger writer is nil")
+	ErrInvalidConfig  = errors.New("invalid logger config")
+	ErrRecordDropped  = errors.New("log record dropped")
+	ErrWriteTimeout   = errors.New("logger write timeout")
 )
 
 // bufPool uses a pointer to a slice (*[]byte) to minimize overhead.
@@ -43,32 +54,78 @@ var bufPool = sync.Pool{
 	},
 }
 
-type Config struct {
-	// logger level
-	Level int
-	// start buffered output to minimize count of syscall, buff size - 4096
-	BufferedOutput bool
-}
-
 // shared contains resources that must be synchronized across all handler clones.
 type shared struct {
-	// protects the underlying writers (bw and w).
-	mu *sync.Mutex
-
-	// buffered writer (can be nil if buffering is disabled).
-	bw *bufio.Writer
-	// underlying writer.
-	w io.Writer
+	// minimal level of records, changed by SetLevel.
+	level slog.LevelVar
+	// levelMu serializes SetLevel calls and protects levelHooks.
+	levelMu    sync.Mutex
+	levelHooks []LevelChangeFunc
+	// per-package levels set by SetLevelRules (nil if not set).
+	levelRules atomic.Pointer[levelRules]
+	// auditLevelChanges writes a record for every level change.
+	auditLevelChanges bool
+
+	// destination of all records (or of records below LevelWarn if errOut is set).
+	out *output
+	// destination of records >= LevelWarn (nil if Config.ErrorOutput is not set).
+	errOut *output
+	// buffered indicates that at least one output is buffered and the flusher is running.
+	buffered bool
 
 	// used to signal the flusher goroutine to stop.
 	done chan struct{}
 	// closed indicates whether the handler has been closed.
 	closed atomic.Bool
+
+	// clones memoizes WithGroup/WithAttrs results (nil if disabled).
+	clones *cloneCache
+
+	// group for attrs from AppendAttrsToCtx, empty - attrs are added to the record as is.
+	ctxAttrsGroup string
+	// policy for ctx attrs with a repeated key.
+	ctxDuplicates DuplicatePolicy
+	ctxFirst      bool
+	// extractors add attrs derived from ctx to every record.
+	extractors []ContextExtractor
+	// replaceAttr rewrites the attrs before they're encoded (nil if not set).
+	replaceAttr func(groups []string, attr slog.Attr) slog.Attr
+	// stages of the record pipeline, see Handler.pipeline.
+	middleware []Middleware
+	// records >= stackTraceLevel get the stack attr (nil if disabled).
+	stackTraceLevel slog.Leveler
+	// level of the BeginScope summaries (nil if disabled).
+	scopeSummaryLevel slog.Leveler
+
+	// dropOnCtxDone drops records whose ctx is done, including while waiting for a blocked output.
+	dropOnCtxDone bool
+	// counters shared by all clones.
+	stats stats
+
+	// devChecks enables detection of common attr mistakes, panicOnMisuse panics instead of reporting them.
+	devChecks     bool
+	panicOnMisuse bool
+	// schema checks the attrs of every record (nil if Config.Schema is not set).
+	schema *Schema
+
+	// priorityPrefix starts every record with its sd-daemon priority, e.g. "<3>".
+	priorityPrefix bool
+
+	// maxGroupDepth is the count of WithGroup levels after which further groups are flattened.
+	maxGroupDepth int
+
+	// diagnostics is created by the first Diagnostics call (nil - nobody listens).
+	diagnostics atomic.Pointer[chan Diagnostic]
+	// internal logs the diagnostics (nil if Config.InternalLogger is not set).
+	internal *internalLog
+
+	// config is a copy of the Config the handler was created with, reported by Snapshot.
+	config Config
 }
 
 type builder interface {
-	buildLog(buf []byte, record slog.Record, precomputedAttrs string, groupPrefix string) []byte
-	precomputeAttrs(buf []byte, groupPrefix string, attrs []slog.Attr) []byte
+	buildLog(buf []byte, record slog.Record, precomputedAttrs, precomputedGroups, groupPrefix, prefix string) []byte
+	precomputeAttrs(buf []byte, precomputedGroups, groupPrefix string, attrs []slog.Attr) []byte
 	groupPrefix(oldPrefix string, newPrefix string) string
 }
 
@@ -76,8 +133,6 @@ type Handler struct {
 	// holds the state common to all clones of the handler (writer, mutex, flags).
 	shared *shared
 
-	level slog.Level
-
 	// builder implements the log formatting logic (text, json, etc.) abstracting it from the handler control flow.
 	builder builder
 
@@ -87,13 +142,36 @@ type Handler struct {
 
 	// precomputed stores already formatted attributes from WithAttrs()
 	precomputed string
+	// precomputedGroups is the part of groupPrefix the last WithAttrs() attrs were written in,
+	// the JSON builder opens the groups added after it before the record attrs.
+	precomputedGroups string
+
+	// devKeys stores the keys added by WithAttrs() at the current group level, used by Config.DevChecks only.
+	devKeys []string
+
+	// prefix is the static message prefix from WithPrefix().
+	prefix string
+
+	// groupDepth is the count of groups in groupPrefix, groupsFlattened is set once WithGroup exceeded the group limits.
+	groupDepth      int
+	groupsFlattened bool
+	// groups are the names of the groups in groupPrefix, tracked only for Config.ReplaceAttr and Config.Schema.
+	groups []string
+	// schemaAttrs are the WithAttrs attrs at the Config.Schema paths.
+	schemaAttrs []schemaAttr
+
+	// pool is the buffer pool of WithCapacityHint, nil for bufPool.
+	pool *bufferPool
+
+	// pipeline is the Config.Middleware chain ending with writeRecord of this clone (nil if there is no middleware).
+	pipeline HandleFunc
 }
 
 // Close signals the flusher to stop, marks the handler as closed using an atomic flag and flush buffer.
 // Closes buffered output only.
 func (h *Handler) Close(_ context.Context) error {
 	// If buffering was never create.
-	if h.shared.bw == nil {
+	if !h.shared.buffered {
 		return ErrNothingToClose
 	}
 
@@ -125,83 +203,332 @@ func (h *Handler) flusher() {
 	}
 }
 
-// flushBuffer writes any buffered data to the underlying writer.
+// flushBuffer writes any buffered data to the underlying writers.
+// Flush errors are only reported to Diagnostics, the records were already accepted.
 func (h *Handler) flushBuffer() {
-	h.shared.mu.Lock()
-	_ = h.shared.bw.Flush()
-	h.shared.mu.Unlock()
+	h.flushOutput(h.shared.out)
+
+	if h.shared.errOut != nil {
+		h.flushOutput(h.shared.errOut)
+	}
+}
+
+func (h *Handler) flushOutput(o *output) {
+	if err := o.flush(); err != nil {
+		h.shared.diagnose(Diagnostic{Kind: DiagnosticWriteError, Err: err})
+	}
+	h.checkWatchdog(o)
+}
+
+// outputFor returns the output for records of the given level.
+func (s *shared) outputFor(level slog.Level) *output {
+	if s.errOut != nil && level >= slog.LevelWarn {
+		return s.errOut
+	}
+	return s.out
 }
 
-func newHandler(w io.Writer, level slog.Level, builder builder) *Handler {
+// newHandler creates a Handler with the options common to all builders.
+func newHandler(w io.Writer, cfg *Config, builder builder) *Handler {
 	shared := &shared{
-		mu:     &sync.Mutex{},
-		w:      w,
-		done:   make(chan struct{}),
-		closed: atomic.Bool{},
+		out:      newOutput(w, cfg.BufferedOutput, cfg.WriteTimeout, cfg.watchdog(w)),
+		buffered: cfg.BufferedOutput,
+		done:     make(chan struct{}),
+		closed:   atomic.Bool{},
+
+		ctxAttrsGroup: cfg.CtxAttrsGroup,
+		ctxDuplicates: cfg.CtxAttrsDuplicates,
+		ctxFirst:      cfg.CtxAttrsFirst,
+		extractors:    slices.Clone(cfg.ContextExtractors),
+		middleware:    slices.Clone(cfg.Middleware),
+		replaceAttr:   cfg.ReplaceAttr,
+
+		stackTraceLevel:   cfg.StackTraceLevel,
+		scopeSummaryLevel: cfg.ScopeSummaryLevel,
+
+		dropOnCtxDone: cfg.DropOnCtxDone,
+
+		auditLevelChanges: cfg.AuditLevelChanges,
+
+		devChecks:     cfg.DevChecks,
+		panicOnMisuse: cfg.PanicOnMisuse,
+		schema:        cfg.Schema,
+
+		maxGroupDepth: cmp.Or(cfg.MaxGroupDepth, defaultMaxGroupDepth),
+
+		priorityPrefix: cfg.PriorityPrefix,
+
+		internal: newInternalLog(cfg.InternalLogger),
+
+		config: *cfg,
+	}
+
+	if cfg.ErrorOutput != nil {
+		shared.errOut = newOutput(cfg.ErrorOutput, cfg.ErrorOutputBuffered, cfg.WriteTimeout, cfg.watchdog(cfg.ErrorOutput))
+		shared.buffered = shared.buffered || cfg.ErrorOutputBuffered
+	}
+
+	if cfg.HashChain {
+		_, isJSON := builder.(*jsonBuilder)
+		shared.out.chain = newHashChain(isJSON)
+		if shared.errOut != nil {
+			shared.errOut.chain = newHashChain(isJSON)
+		}
+	}
+
+	if cfg.ProfileLatency {
+		shared.stats.latency = &latencyStats{}
 	}
 
-	return &Handler{
+	if cfg.CloneCacheSize > 0 {
+		shared.clones = newCloneCache(cfg.CloneCacheSize)
+	}
+
+	shared.level.Set(slog.Level(cfg.Level))
+
+	handler := &Handler{
 		shared:  shared,
-		level:   level,
 		builder: builder,
 	}
+	handler.setPipeline()
+
+	if shared.buffered {
+		// Start a background routine to periodically flush the buffer.
+		// This ensures logs appear even during low activity periods.
+		go handler.flusher()
+	}
+
+	return handler
 }
 
 func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
 	if h.shared.closed.Load() {
 		return false
 	}
-	return level >= h.level
+	if level >= h.shared.level.Level() {
+		return true
+	}
+
+	rules := h.shared.levelRules.Load()
+	return rules != nil && level >= rules.min
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	persist := mustPersist(ctx)
+
+	if ok, err := h.prepare(ctx, &record, persist); !ok {
+		return err
+	}
+
+	if h.pipeline != nil {
+		return h.pipeline(ctx, record)
+	}
+	return h.handlePrepared(ctx, record, persist)
+}
+
+// handlePrepared writes a record that passed prepare.
+func (h *Handler) handlePrepared(ctx context.Context, record slog.Record, persist bool) (err error) {
+	msg := record.Message
+	record, problems, err := h.checkEncoded(record)
+	if len(problems) > 0 {
+		// The misuse is reported after the record, so the annotation follows it in the output.
+		defer func() {
+			if reportErr := h.reportMisuse(msg, problems); err == nil {
+				err = reportErr
+			}
+		}()
+	}
+	if err != nil {
+		return err
+	}
+
+	var done <-chan struct{}
+	if h.shared.dropOnCtxDone && ctx != nil {
+		done = ctx.Done()
+	}
+
+	// Records of Logger.LogE must reach the writer before it returns.
+	if err = h.write(done, record); err == nil && persist {
+		err = h.shared.outputFor(record.Level).flush()
+	}
+
+	return err
+}
+
+// checkEncoded applies the stages every record passes before it's encoded, whatever the output: the dev checks,
+// the schema and ReplaceAttr. problems are the misuse found by DevChecks, reported after the record is written,
+// err is ErrSchemaViolation for a rejected record.
+func (h *Handler) checkEncoded(record slog.Record) (_ slog.Record, problems []string, err error) {
+	if h.shared.devChecks {
+		problems = h.checkRecord(record)
+	}
+
+	if h.shared.schema != nil {
+		if record, err = h.checkSchema(record); err != nil {
+			return record, problems, err
+		}
+	}
+
+	if h.shared.replaceAttr != nil {
+		record = h.replaceRecordAttrs(record)
+	}
+
+	return record, problems, nil
 }
 
-func (h *Handler) Handle(ctx context.Context, record slog.Record) (err error) {
+// prepare applies the level rules and adds the ctx attrs and stack trace to the record,
+// it reports false if the record must not be written.
+func (h *Handler) prepare(ctx context.Context, record *slog.Record, persist bool) (bool, error) {
 	if h.shared.closed.Load() {
-		return nil
+		if persist {
+			return false, ErrAlreadyClosed
+		}
+		return false, nil
+	}
+
+	// Enabled passes records that only a level rule may allow, the rule for the call site decides.
+	if rules := h.shared.levelRules.Load(); rules != nil && !rules.enabled(*record, h.shared.level.Level()) {
+		return false, nil
+	}
+
+	// Don't spend time on records nobody waits for anymore.
+	if h.shared.dropOnCtxDone && ctx != nil && ctx.Err() != nil {
+		h.shared.stats.dropped.Add(1)
+		err := fmt.Errorf("%w: %w", ErrRecordDropped, ctx.Err())
+		h.shared.diagnoseWrite(*record, err)
+		return false, err
 	}
 
 	// Check the ctx for slog.Args
 	if ctx != nil {
-		if val, ok := ctx.Value(AttrsKey).([]slog.Attr); ok {
-			record.AddAttrs(val...)
+		h.addCtxAttrs(ctx, record)
+		if h.shared.scopeSummaryLevel != nil {
+			h.countScope(ctx)
 		}
 	}
 
+	h.addStackTrace(record)
+
+	return true, nil
+}
+
+// write encodes the record and writes it to the output for its level,
+// waiting for the output is canceled when done is closed.
+func (h *Handler) write(done <-chan struct{}, record slog.Record) error {
+	o := h.shared.outputFor(record.Level)
+
+	err := h.writeTo(o, done, record)
+	h.checkWatchdog(o)
+
+	return err
+}
+
+// writeTo encodes the record and writes it to o.
+func (h *Handler) writeTo(o *output, done <-chan struct{}, record slog.Record) (err error) {
 	// Acquire a buffer from the pool to minimize garbage collection pressure.
-	pBuf := bufPool.Get().(*[]byte)
+	pBuf := h.getBuf()
 	// Reset buffer length but keep capacity.
 	buf := (*pBuf)[:0]
 
-	buf = h.builder.buildLog(buf, record, h.precomputed, h.groupPrefix)
+	latency := h.shared.stats.latency
+
+	var start time.Time
+	if latency != nil {
+		start = time.Now()
+	}
+
+	if h.shared.priorityPrefix {
+		buf = appendPriority(buf, record.Level)
+	}
+
+	buf, err = h.encode(buf, record)
+	if err != nil {
+		h.shared.stats.count(err)
+		h.shared.diagnoseWrite(record, err)
+		// The buffer may be left in any state by the panic, it's not returned to the pool.
+		return err
+	}
 
 	if !h.shared.closed.Load() {
-		h.shared.mu.Lock()
-		if h.shared.bw != nil {
-			_, err = h.shared.bw.Write(buf)
-		} else {
-			_, err = h.shared.w.Write(buf)
+		var encoded time.Time
+		if latency != nil {
+			encoded = time.Now()
+		}
+
+		err = o.write(done, buf)
+		if err == nil {
+			err = o.syncLevel(record.Level)
+		}
+		h.shared.stats.count(err)
+		h.shared.diagnoseWrite(record, err)
+
+		if latency != nil {
+			latency.observe(record.Level, encoded.Sub(start), time.Since(encoded))
 		}
-		h.shared.mu.Unlock()
 	}
 
 	// Return buffer to pool only if it hasn't grown too large.
 	// This prevents one huge handler message from permanently keeping a large chunk of memory.
-	if cap(buf) <= maxPoolBufSize {
-		*pBuf = buf
-		bufPool.Put(pBuf)
-	}
+	h.putBuf(pBuf, buf)
 
 	return err
 }
 
+// encode builds the record, a panic of the builder (e.g. in an Error method of a value) is returned as ErrEncodePanic.
+func (h *Handler) encode(buf []byte, record slog.Record) (_ []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrEncodePanic, r)
+		}
+	}()
+
+	return h.builder.buildLog(buf, record, h.precomputed, h.precomputedGroups, h.groupPrefix, h.prefix), nil
+}
+
+// encodeWith is encode for outputs encoding the record with another builder method, fn appends the encoded record.
+func encodeWith(buf []byte, fn func([]byte) []byte) (_ []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrEncodePanic, r)
+		}
+	}()
+
+	return fn(buf), nil
+}
+
 // WithGroup  returns a new slog.Handler that adds the passed group to all attrs.
 func (h *Handler) WithGroup(name string) slog.Handler {
 	if name == "" {
 		return h
 	}
 
+	if h.groupLimitReached(name) {
+		return h.flattenGroup(name)
+	}
+
+	cache := h.shared.clones
+
+	var key cloneKey
+	if cache != nil {
+		key = h.cloneKey(name, 0)
+		if h2 := cache.get(key, nil); h2 != nil {
+			return h2
+		}
+	}
+
 	h2 := h.clone()
 
 	h2.groupPrefix = h2.builder.groupPrefix(h2.groupPrefix, name) // alloc
+	h2.groupDepth++
+	if h.shared.replaceAttr != nil || h.shared.schema != nil {
+		h2.groups = append(slices.Clip(h.groups), name)
+	}
+	// Keys of the parent can't collide with the keys inside the group.
+	h2.devKeys = nil
+
+	if cache != nil {
+		cache.put(key, nil, h2)
+	}
 
 	return h2
 }
@@ -212,33 +539,82 @@ func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 		return h
 	}
 
-	// Temporary buffer for parsing attributes.
-	buf := make(
-		[]byte,
-		0,
-		512,
-	) // typically the buffer is allocated on the stack as long as the attributes do not exceed 512 bytes
+	cache := h.shared.clones
+
+	var key cloneKey
+	var cacheable bool
+	if cache != nil {
+		var fingerprint uint64
+		if fingerprint, cacheable = cache.fingerprint(attrs); cacheable {
+			key = h.cloneKey("", fingerprint)
+			if h2 := cache.get(key, attrs); h2 != nil {
+				return h2
+			}
+		}
+	}
+
+	// Temporary buffer for parsing attributes, the result is copied to an arena slab.
+	pBuf := bufPool.Get().(*[]byte)
 
 	// Existing precomputed attributes must come first.
-	buf = append(buf, h.precomputed...)
+	buf := append((*pBuf)[:0], h.precomputed...)
+
+	encoded := attrs
+	if h.shared.replaceAttr != nil {
+		encoded = h.shared.replaceAttrs(h.groups, attrs)
+	}
 
-	buf = h.builder.precomputeAttrs(buf, h.groupPrefix, attrs)
+	buf = h.builder.precomputeAttrs(buf, h.precomputedGroups, h.groupPrefix, encoded)
 
 	h2 := h.clone()
 
-	h2.precomputed = string(buf)
+	// Attrs that encode to nothing don't move the precomputed attrs into the current group.
+	if len(buf) != len(h.precomputed) {
+		h2.precomputed = arenaString(buf)
+		h2.precomputedGroups = h.groupPrefix
+	}
+
+	if cap(buf) <= maxPoolBufSize {
+		*pBuf = buf
+		bufPool.Put(pBuf)
+	}
+
+	if h.shared.devChecks {
+		h2.checkWithAttrs(attrs)
+	}
+	if h.shared.schema != nil {
+		h2.withSchemaAttrs(attrs)
+	}
+
+	if cacheable {
+		cache.put(key, attrs, h2)
+	}
+
 	return h2
 }
 
 // clone create new Handler with common state, groupPrefix and precomputed data.
 func (h *Handler) clone() *Handler {
-	return &Handler{
+	h2 := &Handler{
 		shared:      h.shared,
-		level:       h.level,
 		builder:     h.builder,
 		groupPrefix: h.groupPrefix,
 		precomputed: h.precomputed,
+		devKeys:     h.devKeys,
+		prefix:      h.prefix,
+
+		precomputedGroups: h.precomputedGroups,
+
+		groupDepth:      h.groupDepth,
+		groupsFlattened: h.groupsFlattened,
+		groups:          h.groups,
+		schemaAttrs:     h.schemaAttrs,
+		pool:            h.pool,
 	}
+	// The last stage of the chain writes with the clone's attrs.
+	h2.setPipeline()
+
+	return h2
 }
 
 type loggerCtxKey struct {
@@ -246,20 +622,11 @@ type loggerCtxKey struct {
 
 var AttrsKey = loggerCtxKey{}
 
-// AppendAttrsToCtx add []slog.Attr to ctx with AttrsKey, if the ctx already contains arguments, add them to the existing ones.
+// AppendAttrsToCtx adds attrs to ctx like the AppendAttrsToCtx function.
+//
+// Deprecated: use the AppendAttrsToCtx function, the ctx attrs don't depend on the handler.
 func (h *Handler) AppendAttrsToCtx(ctx context.Context, attrs ...slog.Attr) context.Context {
-	if len(attrs) == 0 {
-		return ctx
-	}
-
-	val, ok := ctx.Value(AttrsKey).([]slog.Attr)
-	if ok { // If attrs in ctx.
-		if len(val) != 0 {
-			attrs = append(val[:len(val):len(val)], attrs...)
-		}
-	}
-
-	return context.WithValue(ctx, AttrsKey, attrs)
+	return AppendAttrsToCtx(ctx, attrs...)
 }
 
 //func (h *ColorizedHandler) WithGroup(name string) slog.handler {
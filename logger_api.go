@@ -0,0 +1,93 @@
+package logger
+
+//go:generate go run gen_attr_methods.go
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// Logger extends slog.Logger with the levels and helpers of this package.
+// All slog.Logger methods are available, With and WithGroup return *Logger.
+type Logger struct {
+	*slog.Logger
+}
+
+// New creates a Logger that writes records to h.
+func New(h slog.Handler) *Logger {
+	return &Logger{Logger: slog.New(h)}
+}
+
+// With returns a Logger that includes the given attributes in each output operation.
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{Logger: l.Logger.With(args...)}
+}
+
+// WithGroup returns a Logger that starts a group, see slog.Logger.WithGroup.
+func (l *Logger) WithGroup(name string) *Logger {
+	return &Logger{Logger: l.Logger.WithGroup(name)}
+}
+
+// WithPrefix returns a Logger whose records carry the prefix, e.g. l.WithPrefix("[worker-3] ")
+// for CLI tools that interleave output of several workers.
+func (l *Logger) WithPrefix(prefix string) *Logger {
+	return &Logger{Logger: slog.New(withPrefix(l.Handler(), prefix))}
+}
+
+// WithCapacityHint returns a Logger whose records are encoded into buffers sized for nAttrs attrs with
+// values of avgValueLen bytes, e.g. l.WithCapacityHint(30, 64) for request loggers writing large records.
+// Handlers without hint support are used as they are, see Handler.WithCapacityHint.
+func (l *Logger) WithCapacityHint(nAttrs, avgValueLen int) *Logger {
+	return &Logger{Logger: slog.New(withCapacityHint(l.Handler(), nAttrs, avgValueLen))}
+}
+
+// Trace logs at LevelTrace.
+func (l *Logger) Trace(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, LevelTrace, msg, args...)
+}
+
+// log is the low-level logging method for the methods of Logger, it must be called directly by them,
+// so the source of the record points to their caller.
+func (l *Logger) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	h := l.Handler()
+	if !h.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	// skip [runtime.Callers, log, Logger method]
+	runtime.Callers(3, pcs[:])
+
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	record.Add(args...)
+
+	_ = h.Handle(ctx, record)
+}
+
+// logAttrs is the attr counterpart of log for the generated Info2-like methods, it must be called directly by them.
+// The attrs don't escape, so the slice of the call stays on the stack of the method.
+func (l *Logger) logAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	h := l.Handler()
+	if !h.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	// skip [runtime.Callers, logAttrs, Logger method]
+	runtime.Callers(3, pcs[:])
+
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	record.AddAttrs(attrs...)
+
+	_ = h.Handle(ctx, record)
+}
@@ -1,8 +1,13 @@
 package logger
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -141,3 +146,123 @@ func BenchmarkLoggerJsonHandlerBuffered(b *testing.B) {
 //		}
 //	})
 //}
+
+func BenchmarkJsonHandlerSimpleMessage(b *testing.B) {
+	logger := slog.New(NewJsonHandler(io.Discard, nil))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("request handled")
+	}
+}
+
+func BenchmarkJsonHandlerSimpleRecord(b *testing.B) {
+	h := NewJsonHandler(io.Discard, nil)
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "request handled", 0)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = h.Handle(ctx, record)
+	}
+}
+
+func BenchmarkWithAttrs(b *testing.B) {
+	h := NewJsonHandler(io.Discard, nil)
+	attrs := []slog.Attr{slog.String("request_id", "abc"), slog.Int("shard", 3)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = h.WithAttrs(attrs)
+	}
+}
+
+// panicError panics while it's encoded, slog recovers only LogValuer panics itself.
+type panicError struct{}
+
+func (panicError) Error() string { panic("boom") }
+
+func TestDiagnostics(t *testing.T) {
+	h := NewJsonHandler(failingWriter{}, &Config{})
+	diags := h.Diagnostics()
+	if h.WithGroup("g").(*Handler).Diagnostics() != diags {
+		t.Fatal("clones have another channel")
+	}
+
+	l := slog.New(h)
+	l.Info("write")
+	l.Info("encode", "err", panicError{})
+
+	d := <-diags
+	if d.Kind != DiagnosticWriteError || d.Message != "write" || !errors.Is(d.Err, errDiskFull) {
+		t.Errorf("write diagnostic %+v", d)
+	}
+	d = <-diags
+	if d.Kind != DiagnosticEncodePanic || d.Message != "encode" || !errors.Is(d.Err, ErrEncodePanic) {
+		t.Errorf("panic diagnostic %+v", d)
+	}
+	if stats := h.Stats(); stats.WriteErrors != 2 {
+		t.Errorf("stats %+v", stats)
+	}
+
+	// Events are dropped instead of blocking the logger.
+	for range diagnosticsBufferSize + 1 {
+		l.Info("write")
+	}
+	if len(diags) != diagnosticsBufferSize {
+		t.Errorf("queued %d diagnostics", len(diags))
+	}
+}
+
+func TestInternalLogger(t *testing.T) {
+	var notices bytes.Buffer
+	internal := slog.New(NewJsonHandler(&notices, &Config{Level: int(slog.LevelWarn)}))
+
+	l := slog.New(NewJsonHandler(failingWriter{}, &Config{InternalLogger: internal}))
+	l.Info("first")
+	l.Info("second")
+	l.Info("encode", "err", panicError{})
+
+	lines := strings.Split(strings.TrimSuffix(notices.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("notices %q, want one per kind", notices.String())
+	}
+	if !strings.Contains(lines[0], `"level":"ERROR","msg":"logger: write failed","kind":"write_error","record_level":"INFO","record_msg":"first"`) {
+		t.Errorf("write notice %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"kind":"encode_panic"`) {
+		t.Errorf("panic notice %s", lines[1])
+	}
+
+	// The next notice of a kind after the interval reports the suppressed ones.
+	h := l.Handler().(*Handler)
+	h.shared.internal.last[DiagnosticWriteError] = time.Time{}
+	l.Info("third")
+	if !strings.Contains(notices.String(), `"record_msg":"third","error":"disk full","suppressed":1`) {
+		t.Errorf("notices %s", notices.String())
+	}
+}
+
+func TestPriorityPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewTextHandler(&buf, &Config{Level: int(LevelTrace), PriorityPrefix: true}))
+	l.Log(context.Background(), LevelTrace, "t")
+	l.Info("i")
+	l.Warn("w")
+	l.Error("e", "k", 1)
+
+	var prefixes []string
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		prefixes = append(prefixes, line[:3])
+	}
+	if got := strings.Join(prefixes, ""); got != "<7><6><4><3>" {
+		t.Errorf("prefixes %s in %q", got, buf.String())
+	}
+
+	if err := (&Config{PriorityPrefix: true, HashChain: true}).Validate(); err == nil {
+		t.Error("PriorityPrefix with HashChain is valid")
+	}
+}
@@ -0,0 +1,186 @@
+package loghttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ttrtcixy/fast-slog-handler/logattr"
+)
+
+const defaultCaptureBytes = 1024
+
+// defaultCaptureTypes are the media types captured when CaptureOptions.ContentTypes is nil.
+var defaultCaptureTypes = []string{"application/json", "application/x-www-form-urlencoded", "text/*"}
+
+// CaptureOptions controls the capture of request and response bodies.
+type CaptureOptions struct {
+	// max captured bytes of every body, the rest is only counted, 0 - 1024
+	MaxBytes int
+	// media types of the captured bodies, "text/*" matches all text types,
+	// nil - application/json, application/x-www-form-urlencoded and text/*
+	ContentTypes []string
+	// JSON object keys and form fields logged as logattr.Redacted, nil - logattr.DefaultRedact
+	Redact []string
+}
+
+func (o *CaptureOptions) maxBytes() int {
+	if o.MaxBytes <= 0 {
+		return defaultCaptureBytes
+	}
+	return o.MaxBytes
+}
+
+func (o *CaptureOptions) redact() []string {
+	if o.Redact == nil {
+		return logattr.DefaultRedact
+	}
+	return o.Redact
+}
+
+// captures reports whether bodies of the content type are captured.
+func (o *CaptureOptions) captures(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	types := o.ContentTypes
+	if types == nil {
+		types = defaultCaptureTypes
+	}
+
+	for _, t := range types {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if strings.EqualFold(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyCapture keeps the first max bytes passing through it and counts the rest.
+type bodyCapture struct {
+	max  int
+	data []byte
+	size int64
+}
+
+func (c *bodyCapture) capture(p []byte) {
+	c.size += int64(len(p))
+	if room := c.max - len(c.data); room > 0 {
+		c.data = append(c.data, p[:min(room, len(p))]...)
+	}
+}
+
+// attr returns the body group with the captured data, its size and whether it was cut,
+// the data is omitted for content types that aren't captured.
+func (c *bodyCapture) attr(key, contentType string, opts *CaptureOptions) slog.Attr {
+	attrs := []slog.Attr{slog.Int64("size", c.size)}
+
+	if contentType == "" {
+		contentType = http.DetectContentType(c.data)
+	}
+	if opts.captures(contentType) {
+		truncated := c.size > int64(len(c.data))
+		attrs = append(attrs, slog.String("data", redactBody(c.data, contentType, truncated, opts.redact())))
+		if truncated {
+			attrs = append(attrs, slog.Bool("truncated", true))
+		}
+	}
+
+	return slog.Attr{Key: key, Value: slog.GroupValue(attrs...)}
+}
+
+// redactBody replaces the redacted JSON keys and form fields. A cut body can't be parsed,
+// it's replaced as a whole if it contains a redacted name. A malformed body is always replaced.
+func redactBody(data []byte, contentType string, truncated bool, redact []string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	isJSON := mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+	isForm := mediaType == "application/x-www-form-urlencoded"
+
+	if !isJSON && !isForm {
+		return strings.ToValidUTF8(string(data), "\uFFFD")
+	}
+
+	if truncated {
+		lower := bytes.ToLower(data)
+		for _, name := range redact {
+			if bytes.Contains(lower, []byte(strings.ToLower(name))) {
+				return logattr.Redacted
+			}
+		}
+		return strings.ToValidUTF8(string(data), "\uFFFD")
+	}
+
+	if isForm {
+		form, err := url.ParseQuery(string(data))
+		if err != nil {
+			return logattr.Redacted
+		}
+		for key := range form {
+			if isRedacted(key, redact) {
+				form[key] = []string{logattr.Redacted}
+			}
+		}
+		return form.Encode()
+	}
+
+	// The fields of a malformed body can't be found, it may hold secrets under any key.
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return logattr.Redacted
+	}
+	redacted, err := json.Marshal(redactJSON(v, redact))
+	if err != nil {
+		return logattr.Redacted
+	}
+	return string(redacted)
+}
+
+func redactJSON(v any, redact []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if isRedacted(key, redact) {
+				v[key] = logattr.Redacted
+			} else {
+				v[key] = redactJSON(value, redact)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redactJSON(value, redact)
+		}
+	}
+	return v
+}
+
+func isRedacted(name string, redact []string) bool {
+	for _, r := range redact {
+		if strings.EqualFold(name, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// captureReader captures the request body while the handler reads it.
+type captureReader struct {
+	io.ReadCloser
+	bodyCapture
+}
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.capture(p[:n])
+	return n, err
+}
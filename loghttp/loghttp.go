@@ -0,0 +1,158 @@
+// Package loghttp provides an access log middleware writing one record per request through a logger.Logger.
+// The request ctx is passed to the logger, so ctx attrs and extractors work as usual.
+//
+//	mux := http.NewServeMux()
+//	srv := &http.Server{Handler: loghttp.Middleware(log, loghttp.Options{
+//		SampleRates:   map[int]float64{2: 0.1},
+//		SlowThreshold: time.Second,
+//	})(mux)}
+//
+// Access logging cost is scaled with usefulness by sampling: every status class has its own share of logged
+// requests, while errors and slow requests can always be kept.
+package loghttp
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	logger "github.com/ttrtcixy/fast-slog-handler"
+	"github.com/ttrtcixy/fast-slog-handler/logattr"
+)
+
+const accessMsg = "http request"
+
+// Options controls which requests are logged and how.
+type Options struct {
+	// level of the records, 5xx responses are logged at ERROR and slow requests at WARN, nil - slog.LevelInfo
+	Level slog.Leveler
+	// share of logged requests per status class (1 for 1xx ... 5 for 5xx) in [0, 1],
+	// e.g. {2: 0.1} logs 10% of 2xx responses, unlisted classes - 1
+	SampleRates map[int]float64
+	// requests slower than this are always logged, 0 - disabled
+	SlowThreshold time.Duration
+	// capture the bodies while DEBUG is enabled, they're added to the record as http.request_body
+	// and http.response_body, nil - disabled
+	Capture *CaptureOptions
+}
+
+// Middleware returns a middleware logging the requests of the wrapped handler after they are served.
+// Sampled records get a sample_rate attr, so counts can be scaled back by log queries.
+func Middleware(log *logger.Logger, opts Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w}
+
+			var body *captureReader
+			if opts.Capture != nil && log.Enabled(r.Context(), slog.LevelDebug) {
+				limit := opts.Capture.maxBytes()
+				rw.capture = &bodyCapture{max: limit}
+				if r.Body != nil && r.Body != http.NoBody {
+					body = &captureReader{ReadCloser: r.Body, bodyCapture: bodyCapture{max: limit}}
+					r.Body = body
+				}
+			}
+
+			next.ServeHTTP(rw, r)
+
+			opts.log(log, r, body, rw, time.Since(start))
+		})
+	}
+}
+
+func (o *Options) log(log *logger.Logger, r *http.Request, body *captureReader, rw *responseWriter, duration time.Duration) {
+	status := rw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	slow := o.SlowThreshold > 0 && duration >= o.SlowThreshold
+
+	level := slog.LevelInfo
+	if o.Level != nil {
+		level = o.Level.Level()
+	}
+	switch {
+	case status >= 500:
+		level = slog.LevelError
+	case slow:
+		level = slog.LevelWarn
+	}
+
+	ctx := r.Context()
+	if !log.Enabled(ctx, level) {
+		return
+	}
+
+	rate, sampled := o.SampleRates[status/100]
+	if slow || !sampled || rate >= 1 {
+		rate = 1
+	} else if rate <= 0 || rand.Float64() >= rate {
+		return
+	}
+
+	group := logattr.HTTPRequest(r).Value.Group()
+	group = append(group,
+		slog.Int("status", status),
+		slog.Int64("bytes", rw.bytes),
+		slog.Duration("duration", duration),
+	)
+	if body != nil && body.size > 0 {
+		group = append(group, body.attr("request_body", r.Header.Get("Content-Type"), o.Capture))
+	}
+	if rw.capture != nil && rw.capture.size > 0 {
+		group = append(group, rw.capture.attr("response_body", rw.Header().Get("Content-Type"), o.Capture))
+	}
+
+	attrs := []slog.Attr{{Key: logattr.HTTPKey, Value: slog.GroupValue(group...)}}
+	if rate < 1 {
+		attrs = append(attrs, slog.Float64("sample_rate", rate))
+	}
+
+	log.LogAttrs(ctx, level, accessMsg, attrs...)
+}
+
+// responseWriter records the status and size of the response.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+	// capture is the response body capture (nil if disabled).
+	capture *bodyCapture
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// Informational responses are followed by the final one.
+	if w.status == 0 && status >= 200 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	if w.capture != nil {
+		w.capture.capture(p[:n])
+	}
+	return n, err
+}
+
+// Flush supports streaming handlers, http.ResponseController finds the other interfaces through Unwrap.
+func (w *responseWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
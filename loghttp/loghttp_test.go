@@ -0,0 +1,111 @@
+package loghttp
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	logger "github.com/ttrtcixy/fast-slog-handler"
+)
+
+func TestMiddlewareSampling(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(logger.NewJsonHandler(&buf, &logger.Config{}))
+
+	mw := Middleware(log, Options{
+		SampleRates:   map[int]float64{2: 0, 4: 0.5},
+		SlowThreshold: 50 * time.Millisecond,
+	})
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fail":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		case "/missing":
+			http.NotFound(w, r)
+		case "/slow":
+			time.Sleep(60 * time.Millisecond)
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+
+	for _, path := range []string{"/ok", "/fail", "/slow"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d records: %s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], `"level":"ERROR"`) || !strings.Contains(lines[0], `"path":"/fail"`) || !strings.Contains(lines[0], `"status":500`) {
+		t.Errorf("5xx record %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"level":"WARN"`) || !strings.Contains(lines[1], `"path":"/slow"`) || strings.Contains(lines[1], "sample_rate") {
+		t.Errorf("slow record %s", lines[1])
+	}
+
+	// About half of the 4xx responses are logged with their rate.
+	buf.Reset()
+	for range 200 {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	}
+	if n := strings.Count(buf.String(), `"sample_rate":0.5`); n < 50 || n > 150 {
+		t.Errorf("logged %d of 200 sampled requests", n)
+	}
+}
+
+func TestMiddlewareCapture(t *testing.T) {
+	var buf bytes.Buffer
+	handler := logger.NewJsonHandler(&buf, &logger.Config{Level: int(slog.LevelDebug)})
+	log := logger.New(handler)
+
+	h := Middleware(log, Options{Capture: &CaptureOptions{MaxBytes: 64}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(bytes.Repeat([]byte{0x89}, 100))
+	}))
+
+	body := `{"user":"alice","password":"secret","nested":[{"token":"t"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := buf.String()
+	want := `"request_body":{"size":61,"data":"{\"nested\":[{\"token\":\"[REDACTED]\"}],\"password\":\"[REDACTED]\",\"user\":\"alice\"}"},"response_body":{"size":100}}`
+	if !strings.Contains(out, want) {
+		t.Errorf("output %s, want %s", out, want)
+	}
+
+	// Cut JSON bodies containing a redacted name are replaced as a whole.
+	buf.Reset()
+	req = httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body+strings.Repeat(" ", 64)))
+	req.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if want := `"request_body":{"size":125,"data":"[REDACTED]","truncated":true}`; !strings.Contains(buf.String(), want) {
+		t.Errorf("output %s, want %s", buf.String(), want)
+	}
+
+	// Malformed JSON bodies are replaced as a whole.
+	buf.Reset()
+	req = httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"user":"alice","password":"secret"`))
+	req.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if want := `"request_body":{"size":35,"data":"[REDACTED]"}`; !strings.Contains(buf.String(), want) {
+		t.Errorf("output %s, want %s", buf.String(), want)
+	}
+
+	// Nothing is captured while DEBUG is disabled.
+	buf.Reset()
+	handler.SetLevel(slog.LevelInfo, "test")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+	if strings.Contains(buf.String(), "_body") {
+		t.Errorf("output %s", buf.String())
+	}
+}
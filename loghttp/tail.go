@@ -0,0 +1,333 @@
+package loghttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	logger "github.com/ttrtcixy/fast-slog-handler"
+)
+
+const (
+	defaultTailBuffer = 256
+	// tailPingInterval keeps proxies from closing idle streams.
+	tailPingInterval = 15 * time.Second
+)
+
+// tailFrame is one WithGroup level of a Tail clone with the WithAttrs attrs added in it.
+type tailFrame struct {
+	group string
+	attrs []slog.Attr
+}
+
+// tailFilter is the query of a subscriber.
+type tailFilter struct {
+	level slog.Level
+	// keys are dotted attr paths the record must have, with the value unless any is set.
+	keys []tailKey
+}
+
+type tailKey struct {
+	path  []string
+	value string
+	// any is set for "key=name" filters, the attr only has to exist.
+	any bool
+}
+
+type tailSubscriber struct {
+	filter  tailFilter
+	records chan slog.Record
+	dropped atomic.Uint64
+}
+
+// tailState is shared by a Tail and its clones.
+type tailState struct {
+	buffer int
+
+	mu          sync.RWMutex
+	subscribers map[*tailSubscriber]struct{}
+}
+
+// Tail is a slog.Handler streaming new records to the connected clients of its ServeHTTP endpoint
+// as server-sent events, a "live tail" for admin UIs. Add it next to the primary sink, e.g. with
+// logger.NewMultiHandler: records are only queued for the clients, encoding happens in their request
+// goroutines and a slow client loses records instead of slowing the logger.
+//
+// Clients filter with query params: level=WARN (default INFO) and key=request_id (the attr exists)
+// or key=http.status:500 (the attr has the value), repeated keys must all match.
+// WebSocket is not supported, the module has no dependencies besides the standard library.
+type Tail struct {
+	state *tailState
+	// frames[0] holds the top-level WithAttrs attrs, every WithGroup adds a frame.
+	frames []tailFrame
+}
+
+// NewTail creates a tail queuing up to buffer records per client, 0 - 256.
+func NewTail(buffer int) *Tail {
+	if buffer <= 0 {
+		buffer = defaultTailBuffer
+	}
+
+	return &Tail{
+		state:  &tailState{buffer: buffer, subscribers: make(map[*tailSubscriber]struct{})},
+		frames: []tailFrame{{}},
+	}
+}
+
+func (t *Tail) Enabled(_ context.Context, level slog.Level) bool {
+	s := t.state
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for sub := range s.subscribers {
+		if level >= sub.filter.level {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tail) Handle(ctx context.Context, record slog.Record) error {
+	s := t.state
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.subscribers) == 0 {
+		return nil
+	}
+
+	// The record is encoded in other goroutines, it must not share the caller's attrs.
+	record = logger.CloneRecord(record)
+	if ctx != nil {
+		if attrs, _ := ctx.Value(logger.AttrsKey).([]slog.Attr); len(attrs) > 0 {
+			record.AddAttrs(attrs...)
+		}
+	}
+	record = t.fold(record)
+
+	for sub := range s.subscribers {
+		if !sub.filter.match(record) {
+			continue
+		}
+
+		select {
+		case sub.records <- record:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+
+	return nil
+}
+
+// fold nests the record attrs into the groups of the clone and adds the WithAttrs attrs of every level.
+func (t *Tail) fold(record slog.Record) slog.Record {
+	if len(t.frames) == 1 && len(t.frames[0].attrs) == 0 {
+		return record
+	}
+
+	var attrs []slog.Attr
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	for i := len(t.frames) - 1; i >= 0; i-- {
+		frame := t.frames[i]
+		attrs = append(frame.attrs[:len(frame.attrs):len(frame.attrs)], attrs...)
+		if i > 0 && len(attrs) > 0 {
+			attrs = []slog.Attr{{Key: frame.group, Value: slog.GroupValue(attrs...)}}
+		}
+	}
+
+	r := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	r.AddAttrs(attrs...)
+	return r
+}
+
+func (t *Tail) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return t
+	}
+
+	frames := append([]tailFrame(nil), t.frames...)
+	last := &frames[len(frames)-1]
+	last.attrs = append(last.attrs[:len(last.attrs):len(last.attrs)], attrs...)
+
+	return &Tail{state: t.state, frames: frames}
+}
+
+func (t *Tail) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return t
+	}
+
+	frames := append(t.frames[:len(t.frames):len(t.frames)], tailFrame{group: name})
+	return &Tail{state: t.state, frames: frames}
+}
+
+// ServeHTTP streams the records matching the query as "data: <JSON record>" events until the client disconnects.
+// Records lost because the client was too slow are reported with a "dropped" event.
+func (t *Tail) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseTailFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err = rc.Flush(); err != nil {
+		return
+	}
+
+	sub := &tailSubscriber{filter: filter, records: make(chan slog.Record, t.state.buffer)}
+	t.subscribe(sub)
+	defer t.unsubscribe(sub)
+
+	events := &eventWriter{w: w}
+	h := logger.NewJsonHandler(events, &logger.Config{Level: int(logger.LevelTrace)})
+
+	ping := time.NewTicker(tailPingInterval)
+	defer ping.Stop()
+
+	var reported uint64
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ping.C:
+			_, err = io.WriteString(w, ": ping\n\n")
+		case record := <-sub.records:
+			if dropped := sub.dropped.Load(); dropped != reported {
+				_, err = fmt.Fprintf(w, "event: dropped\ndata: %d\n\n", dropped-reported)
+				reported = dropped
+			}
+			if err == nil {
+				err = h.Handle(r.Context(), record)
+			}
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (t *Tail) subscribe(sub *tailSubscriber) {
+	t.state.mu.Lock()
+	t.state.subscribers[sub] = struct{}{}
+	t.state.mu.Unlock()
+}
+
+func (t *Tail) unsubscribe(sub *tailSubscriber) {
+	t.state.mu.Lock()
+	delete(t.state.subscribers, sub)
+	t.state.mu.Unlock()
+}
+
+func parseTailFilter(r *http.Request) (tailFilter, error) {
+	query := r.URL.Query()
+	filter := tailFilter{level: slog.LevelInfo}
+
+	if name := query.Get("level"); name != "" {
+		level, err := logger.ParseLevelName(name)
+		if err != nil {
+			return filter, fmt.Errorf("invalid level %q", name)
+		}
+		filter.level = level
+	}
+
+	for _, key := range query["key"] {
+		path, value, found := strings.Cut(key, ":")
+		if path == "" {
+			return filter, fmt.Errorf("invalid key filter %q", key)
+		}
+		filter.keys = append(filter.keys, tailKey{path: strings.Split(path, "."), value: value, any: !found})
+	}
+
+	return filter, nil
+}
+
+func (f *tailFilter) match(record slog.Record) bool {
+	if record.Level < f.level {
+		return false
+	}
+
+	for _, key := range f.keys {
+		if !key.match(record) {
+			return false
+		}
+	}
+	return true
+}
+
+func (k *tailKey) match(record slog.Record) bool {
+	var matched bool
+	record.Attrs(func(attr slog.Attr) bool {
+		matched = k.matchAttr(attr, k.path)
+		return !matched
+	})
+	return matched
+}
+
+func (k *tailKey) matchAttr(attr slog.Attr, path []string) bool {
+	value := attr.Value.Resolve()
+
+	// Attrs of a group without a key are inlined in the current group.
+	if attr.Key == "" && value.Kind() == slog.KindGroup {
+		for _, a := range value.Group() {
+			if k.matchAttr(a, path) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if attr.Key != path[0] {
+		return false
+	}
+	if len(path) == 1 {
+		return k.any || value.String() == k.value
+	}
+	if value.Kind() != slog.KindGroup {
+		return false
+	}
+
+	for _, a := range value.Group() {
+		if k.matchAttr(a, path[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// eventWriter frames every record written by the JSON handler as an SSE data event.
+type eventWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (e *eventWriter) Write(p []byte) (int, error) {
+	e.buf = append(e.buf[:0], "data: "...)
+	e.buf = append(e.buf, bytes.TrimSuffix(p, []byte{'\n'})...)
+	e.buf = append(e.buf, "\n\n"...)
+
+	if _, err := e.w.Write(e.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
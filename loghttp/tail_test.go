@@ -0,0 +1,50 @@
+package loghttp
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	logger "github.com/ttrtcixy/fast-slog-handler"
+)
+
+func TestTail(t *testing.T) {
+	tail := NewTail(0)
+	srv := httptest.NewServer(tail)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?level=WARN&key=http.status:500", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	for !tail.Enabled(ctx, slog.LevelWarn) {
+		time.Sleep(time.Millisecond)
+	}
+	if tail.Enabled(ctx, slog.LevelInfo) {
+		t.Error("tail is enabled below the client level")
+	}
+
+	log := logger.New(logger.NewMultiHandler(logger.NewJsonHandler(&strings.Builder{}, nil), tail)).With("svc", "api")
+	log.Error("filtered", slog.Group("http", "status", 404))
+	log.Warn("quiet")
+	log.Error("failed", slog.Group("http", "status", 500))
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(line, "data: {") || !strings.Contains(line, `"msg":"failed","svc":"api","http":{"status":500}}`) {
+		t.Errorf("event %q", line)
+	}
+}
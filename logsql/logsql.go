@@ -0,0 +1,145 @@
+// Package logsql wraps database/sql drivers to log every query with its duration, argument count and error
+// through a logger.Logger. The query ctx is passed to the logger, so ctx attrs and extractors work as usual.
+//
+//	connector, _ := pq.NewConnector(dsn)
+//	db := sql.OpenDB(logsql.WrapConnector(connector, log, logsql.Options{}))
+//
+// A pgx.QueryTracer adapter is not provided, the module has no dependencies besides the standard library;
+// pgx users can log through its stdlib package (pgx/v5/stdlib) wrapped with WrapConnector.
+package logsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"time"
+
+	logger "github.com/ttrtcixy/fast-slog-handler"
+	"github.com/ttrtcixy/fast-slog-handler/logattr"
+)
+
+// Options controls what is logged for every query.
+type Options struct {
+	// log argument values, by default only their count is logged
+	LogArgs bool
+	// level of successful queries, nil - slog.LevelDebug
+	Level slog.Leveler
+	// successful queries slower than this are logged at WARN, 0 - disabled
+	SlowThreshold time.Duration
+}
+
+type tracer struct {
+	log  *logger.Logger
+	opts Options
+}
+
+// Wrap returns a driver logging the queries of d, register it with sql.Register.
+func Wrap(d driver.Driver, log *logger.Logger, opts Options) driver.Driver {
+	return &loggedDriver{Driver: d, t: &tracer{log: log, opts: opts}}
+}
+
+// WrapConnector returns a connector logging the queries of c, use it with sql.OpenDB.
+func WrapConnector(c driver.Connector, log *logger.Logger, opts Options) driver.Connector {
+	return &connector{Connector: c, t: &tracer{log: log, opts: opts}}
+}
+
+func (t *tracer) trace(ctx context.Context, msg, query string, args []driver.NamedValue, start time.Time, err error) {
+	// ErrSkip only asks database/sql to take another path, the query is logged there.
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+
+	duration := time.Since(start)
+
+	level := slog.LevelDebug
+	if t.opts.Level != nil {
+		level = t.opts.Level.Level()
+	}
+	switch {
+	case err != nil:
+		level = slog.LevelError
+	case t.opts.SlowThreshold > 0 && duration >= t.opts.SlowThreshold:
+		level = slog.LevelWarn
+	}
+
+	if !t.log.Enabled(ctx, level) {
+		return
+	}
+
+	db := []slog.Attr{
+		slog.String("statement", query),
+		slog.Duration("duration", duration),
+		slog.Int("args_count", len(args)),
+	}
+	if t.opts.LogArgs && len(args) > 0 {
+		values := make([]any, len(args))
+		for i, arg := range args {
+			values[i] = arg.Value
+		}
+		db = append(db, slog.Any("args", values))
+	}
+
+	attrs := []slog.Attr{{Key: logattr.DBKey, Value: slog.GroupValue(db...)}}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	t.log.LogAttrs(ctx, level, msg, attrs...)
+}
+
+type loggedDriver struct {
+	driver.Driver
+	t *tracer
+}
+
+func (d *loggedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: c, t: d.t}, nil
+}
+
+func (d *loggedDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.Driver.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &connector{Connector: c, t: d.t}, nil
+	}
+
+	return &dsnConnector{name: name, d: d}, nil
+}
+
+type connector struct {
+	driver.Connector
+	t *tracer
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc, t: c.t}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return &loggedDriver{Driver: c.Connector.Driver(), t: c.t}
+}
+
+// dsnConnector opens connections of drivers without DriverContext.
+type dsnConnector struct {
+	name string
+	d    *loggedDriver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.d.Open(c.name)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.d
+}
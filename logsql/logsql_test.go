@@ -0,0 +1,68 @@
+package logsql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	logger "github.com/ttrtcixy/fast-slog-handler"
+)
+
+// fakeDriver supports ExecerContext only, queries fail.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return nil, errors.New("relation does not exist")
+}
+
+func TestWrap(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(logger.NewJsonHandler(&buf, &logger.Config{Level: int(slog.LevelDebug)}))
+
+	sql.Register("logsql-fake", Wrap(fakeDriver{}, log, Options{}))
+	db, err := sql.Open("logsql-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err = db.Exec("UPDATE users SET name = $1 WHERE id = $2", "alice", 7); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Query("SELECT * FROM missing"); err == nil {
+		t.Fatal("query error is lost")
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`"level":"DEBUG","msg":"sql exec","db":{"statement":"UPDATE users SET name = $1 WHERE id = $2"`,
+		`"args_count":2}`,
+		`"level":"ERROR","msg":"sql query"`,
+		`"error":"relation does not exist"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q doesn't contain %q", out, want)
+		}
+	}
+
+	if strings.Contains(out, "alice") {
+		t.Errorf("args are logged by default: %q", out)
+	}
+}
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// HandleFunc handles a prepared record, the last one of a Config.Middleware chain encodes and writes it.
+type HandleFunc func(ctx context.Context, record slog.Record) error
+
+// Middleware is a stage of the record pipeline (redaction, sampling, enrichment, filtering, ...).
+// It may change the record, pass another one or return without calling next to drop it.
+// Records reach the chain after the level check with the ctx attrs and the stack trace added,
+// the WithAttrs attrs are already encoded and can't be changed.
+//
+// The chain is built for every WithAttrs/WithGroup clone, so state shared by all records
+// must be created outside of the Middleware func.
+type Middleware func(next HandleFunc) HandleFunc
+
+// chain wraps next with the middleware, the first one runs first.
+func chain(middleware []Middleware, next HandleFunc) HandleFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		next = middleware[i](next)
+	}
+	return next
+}
+
+// writeRecord is the last stage of the middleware chain.
+func (h *Handler) writeRecord(ctx context.Context, record slog.Record) error {
+	return h.handlePrepared(ctx, record, mustPersist(ctx))
+}
+
+// setPipeline builds the middleware chain of the handler (nil if there is no middleware).
+func (h *Handler) setPipeline() {
+	h.pipeline = h.pipelineTo(h.writeRecord)
+}
+
+// pipelineTo builds the middleware chain of the handler ending with last, nil if there is no middleware.
+// The sinks encoding records themselves run it between prepare and their own last stage.
+func (h *Handler) pipelineTo(last HandleFunc) HandleFunc {
+	if len(h.shared.middleware) == 0 {
+		return nil
+	}
+	return chain(h.shared.middleware, last)
+}
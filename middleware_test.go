@@ -0,0 +1,172 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	var order []string
+	stage := func(name string) Middleware {
+		return func(next HandleFunc) HandleFunc {
+			return func(ctx context.Context, record slog.Record) error {
+				order = append(order, name)
+				return next(ctx, record)
+			}
+		}
+	}
+
+	redact := func(next HandleFunc) HandleFunc {
+		return func(ctx context.Context, record slog.Record) error {
+			r := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+			record.Attrs(func(attr slog.Attr) bool {
+				if attr.Key == "password" {
+					attr.Value = slog.StringValue("***")
+				}
+				r.AddAttrs(attr)
+				return true
+			})
+			return next(ctx, r)
+		}
+	}
+
+	dropHealth := func(next HandleFunc) HandleFunc {
+		return func(ctx context.Context, record slog.Record) error {
+			if strings.HasPrefix(record.Message, "health") {
+				return nil
+			}
+			return next(ctx, record)
+		}
+	}
+
+	var buf bytes.Buffer
+	h := NewJsonHandler(&buf, &Config{Middleware: []Middleware{stage("a"), stage("b"), dropHealth, redact}})
+	l := slog.New(h).With("user", "u1").WithGroup("req")
+
+	ctx := AppendAttrsToCtx(context.Background(), slog.String("password", "ctx"))
+	l.InfoContext(ctx, "login", "password", "secret")
+	l.Info("healthcheck")
+
+	if got := strings.Join(order, ","); got != "a,b,a,b" {
+		t.Errorf("order %s", got)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "secret") || strings.Contains(out, "ctx") || strings.Contains(out, "healthcheck") {
+		t.Errorf("output %s", out)
+	}
+	if !strings.Contains(out, `"user":"u1","req":{"password":"***","password":"***"}`) {
+		t.Errorf("output %s", out)
+	}
+
+	if err := (&Config{Middleware: []Middleware{nil}}).Validate(); err == nil {
+		t.Error("nil middleware is valid")
+	}
+}
+
+func TestSinkMiddleware(t *testing.T) {
+	// tag drops the health checks and adds an attr to the other records.
+	tag := func(next HandleFunc) HandleFunc {
+		return func(ctx context.Context, record slog.Record) error {
+			if strings.HasPrefix(record.Message, "health") {
+				return nil
+			}
+			record = record.Clone()
+			record.AddAttrs(slog.Bool("mw", true))
+			return next(ctx, record)
+		}
+	}
+	cfg := &Config{Middleware: []Middleware{tag}}
+
+	logTo := func(h slog.Handler) {
+		l := slog.New(h).With("svc", "api")
+		l.Info("health check")
+		l.Info("login")
+	}
+
+	t.Run("sql", func(t *testing.T) {
+		d := &rowsDriver{}
+		sql.Register("logger-rows-middleware", d)
+		db, err := sql.Open("logger-rows-middleware", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		sink, err := NewSQLSink(db, SQLSinkOptions{Insert: "INSERT INTO logs VALUES (?, ?, ?, ?)", FlushInterval: time.Hour}, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logTo(sink)
+		if err = sink.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(d.committed) != 1 || d.committed[0][2] != "login" || d.committed[0][3] != `{"svc":"api","mw":true}` {
+			t.Errorf("rows %v", d.committed)
+		}
+	})
+
+	t.Run("partition", func(t *testing.T) {
+		var out bytes.Buffer
+		h, err := NewPartitionHandler(PartitionOptions{
+			Key:  "tenant_id",
+			Open: func(string) (io.Writer, error) { return &out, nil },
+		}, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logTo(h)
+
+		if got := out.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, `"msg":"login","svc":"api","mw":true`) {
+			t.Errorf("got %s", got)
+		}
+	})
+
+	t.Run("publish", func(t *testing.T) {
+		var data []string
+		pub := PublisherFunc(func(_ context.Context, msg *Message) error {
+			data = append(data, string(msg.Data))
+			return nil
+		})
+		logTo(NewPublishHandler(pub, PublishOptions{Topic: "logs"}, cfg))
+
+		if len(data) != 1 || !strings.Contains(data[0], `"msg":"login","svc":"api","mw":true`) {
+			t.Errorf("data %q", data)
+		}
+	})
+
+	for _, async := range []bool{false, true} {
+		t.Run(fmt.Sprintf("batch async=%v", async), func(t *testing.T) {
+			var uploads batchUploads
+			e, err := NewBatchEncoder(BatchOptions{Upload: uploads.upload, FlushInterval: time.Hour}, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var h interface {
+				slog.Handler
+				Close(context.Context) error
+			} = e
+			if async {
+				h = NewAsyncHandler(e, 16)
+			}
+			logTo(h)
+			if err = h.Close(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(uploads.payloads) != 1 || strings.Count(string(uploads.payloads[0]), "\n") != 1 ||
+				!strings.Contains(string(uploads.payloads[0]), `"msg":"login","svc":"api","mw":true`) {
+				t.Errorf("payloads %q", uploads.payloads)
+			}
+		})
+	}
+}
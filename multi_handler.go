@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// MultiHandler sends every record to all handlers that are enabled for its level.
+type MultiHandler struct {
+	handlers []slog.Handler
+
+	// closers are resources owned by the handler (e.g. files opened by NewFromConfigFile), closed after the handlers.
+	closers []io.Closer
+	// source of a handler built from a ConfigFile, used by Reload and Watch (nil otherwise).
+	source *configSource
+}
+
+// NewMultiHandler creates a handler writing records to all handlers, each keeps its own level and format.
+func NewMultiHandler(handlers ...slog.Handler) *MultiHandler {
+	return &MultiHandler{handlers: handlers}
+}
+
+func (m *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m.handlers {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes a clone of the record to every enabled handler, so they can't affect each other's attrs.
+func (m *MultiHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+
+	for _, h := range m.handlers {
+		if !h.Enabled(ctx, record.Level) {
+			continue
+		}
+
+		if err := h.Handle(ctx, record.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return m
+	}
+
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+
+	return &MultiHandler{handlers: handlers, closers: m.closers, source: m.source}
+}
+
+func (m *MultiHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return m
+	}
+
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = h.WithGroup(name)
+	}
+
+	return &MultiHandler{handlers: handlers, closers: m.closers, source: m.source}
+}
+
+// Close closes every handler with a Close(ctx) method and then the owned resources.
+// ErrNothingToClose of unbuffered handlers is ignored.
+func (m *MultiHandler) Close(ctx context.Context) error {
+	var errs []error
+
+	for _, h := range m.handlers {
+		c, ok := h.(interface{ Close(context.Context) error })
+		if !ok {
+			continue
+		}
+
+		if err := c.Close(ctx); err != nil && !errors.Is(err, ErrNothingToClose) {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, c := range m.closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
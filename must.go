@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// persistKey marks the ctx of records logged by Logger.LogE and the Must methods.
+type persistKey struct{}
+
+// mustPersist reports whether the caller waits for the record to reach the writer.
+func mustPersist(ctx context.Context) bool {
+	return ctx != nil && ctx.Value(persistKey{}) != nil
+}
+
+// LogE is like Log but returns the error of the handler instead of dropping it, for audit records
+// that must not be lost silently. Buffered output is flushed after the record, so nil means
+// the record reached the writer. A closed handler reports ErrAlreadyClosed, AsyncHandler writes
+// such records synchronously.
+func (l *Logger) LogE(ctx context.Context, level slog.Level, msg string, args ...any) error {
+	return l.logE(ctx, level, msg, args...)
+}
+
+// MustInfo logs at slog.LevelInfo and panics if the record wasn't written, see LogE.
+func (l *Logger) MustInfo(ctx context.Context, msg string, args ...any) {
+	if err := l.logE(ctx, slog.LevelInfo, msg, args...); err != nil {
+		panic(err)
+	}
+}
+
+// MustWarn logs at slog.LevelWarn and panics if the record wasn't written, see LogE.
+func (l *Logger) MustWarn(ctx context.Context, msg string, args ...any) {
+	if err := l.logE(ctx, slog.LevelWarn, msg, args...); err != nil {
+		panic(err)
+	}
+}
+
+// MustError logs at slog.LevelError and panics if the record wasn't written, see LogE.
+func (l *Logger) MustError(ctx context.Context, msg string, args ...any) {
+	if err := l.logE(ctx, slog.LevelError, msg, args...); err != nil {
+		panic(err)
+	}
+}
+
+// logE is the error reporting counterpart of log, it must be called directly by the methods of Logger.
+func (l *Logger) logE(ctx context.Context, level slog.Level, msg string, args ...any) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	h := l.Handler()
+	if !h.Enabled(ctx, level) {
+		return nil
+	}
+
+	var pcs [1]uintptr
+	// skip [runtime.Callers, logE, Logger method]
+	runtime.Callers(3, pcs[:])
+
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	record.Add(args...)
+
+	return h.Handle(context.WithValue(ctx, persistKey{}, true), record)
+}
@@ -0,0 +1,187 @@
+package logger
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// writeFlusher is implemented by writers keeping data in memory (CompressedWriter, bufio.Writer),
+// the flusher of a buffered handler flushes them after its own buffer.
+type writeFlusher interface {
+	Flush() error
+}
+
+// levelSyncer is implemented by writers committing records at some levels to stable storage (FileWriter).
+type levelSyncer interface {
+	syncsLevel(level slog.Level) bool
+	Sync() error
+}
+
+// writeDeadliner is implemented by writers that can bound a blocked write (net.Conn, *os.File pipes).
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// output is a single destination of encoded records with its own lock and optional buffer.
+// All clones of a handler share its outputs, every record is written under one lock acquisition
+// and the buffer is flushed only at record boundaries, so lines of concurrent records never interleave.
+type output struct {
+	// sem protects the underlying writers (bw and w), unlike a mutex waiting for a blocked writer
+	// can be canceled by ctx or WriteTimeout.
+	sem outputSem
+
+	// buffered writer (can be nil if buffering is disabled).
+	bw *bufio.Writer
+	// underlying writer.
+	w io.Writer
+	// dest is the writer passed by the user (w may wrap it), identifies the output in Handler.Fingerprint.
+	dest io.Writer
+
+	// writeTimeout bounds waiting for the output and, if the writer supports deadlines, the write itself.
+	writeTimeout time.Duration
+	deadliner    writeDeadliner
+
+	// watchdog wraps w when slow writes switch the output to a fallback writer (nil if disabled).
+	watchdog *watchdogWriter
+	// flusher is the underlying writer if it implements Flush (nil otherwise).
+	flusher writeFlusher
+	// syncer is the underlying writer if it syncs records by level (nil otherwise).
+	syncer levelSyncer
+
+	// chain adds prev_hash/hash to every record (nil if Config.HashChain is disabled).
+	chain *hashChain
+}
+
+func newOutput(w io.Writer, buffered bool, writeTimeout time.Duration, watchdog *watchdogWriter) *output {
+	o := &output{
+		w:            w,
+		dest:         w,
+		writeTimeout: writeTimeout,
+	}
+
+	if d, ok := w.(writeDeadliner); ok && writeTimeout > 0 {
+		o.deadliner = d
+	}
+
+	if f, ok := w.(writeFlusher); ok {
+		o.flusher = f
+	}
+
+	if s, ok := w.(levelSyncer); ok {
+		o.syncer = s
+	}
+
+	if watchdog != nil {
+		o.watchdog = watchdog
+		o.w = watchdog
+	}
+
+	if buffered {
+		o.bw = bufio.NewWriterSize(o.w, writerBufSize)
+	}
+
+	return o
+}
+
+// lock waits for the output until done is closed or WriteTimeout expires, nil done waits without a limit.
+func (o *output) lock(done <-chan struct{}) error {
+	return o.sem.acquire(done, o.writeTimeout)
+}
+
+func (o *output) unlock() {
+	o.sem.release()
+}
+
+// write writes a whole record under the output lock.
+func (o *output) write(done <-chan struct{}, buf []byte) (err error) {
+	if err = o.lock(done); err != nil {
+		return err
+	}
+
+	if o.deadliner != nil {
+		_ = o.deadliner.SetWriteDeadline(time.Now().Add(o.writeTimeout))
+	}
+
+	if o.chain != nil {
+		buf = o.chain.seal(buf)
+	}
+
+	err = o.writeRecord(buf)
+	o.unlock()
+
+	return err
+}
+
+// writeRecord writes a whole record, the underlying writer always gets whole lines:
+// the buffer is flushed before a record that doesn't fit into it and records bigger than the buffer
+// are written directly with a single Write, so bufio never splits a record between two writes.
+func (o *output) writeRecord(buf []byte) (err error) {
+	if o.bw == nil {
+		_, err = o.w.Write(buf)
+		return err
+	}
+
+	if len(buf) > o.bw.Available() && o.bw.Buffered() > 0 {
+		if err = o.bw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(buf) > o.bw.Available() {
+		_, err = o.w.Write(buf)
+		return err
+	}
+
+	_, err = o.bw.Write(buf)
+	return err
+}
+
+// syncLevel commits a written record at the level to stable storage if the writer asks for it,
+// the buffer is flushed first.
+func (o *output) syncLevel(level slog.Level) (err error) {
+	if o.syncer == nil || !o.syncer.syncsLevel(level) {
+		return nil
+	}
+
+	if err = o.lock(nil); err != nil {
+		return err
+	}
+
+	if o.bw != nil {
+		err = o.bw.Flush()
+	}
+	if err == nil {
+		err = o.syncer.Sync()
+	}
+	o.unlock()
+
+	return err
+}
+
+// flush writes any buffered data to the underlying writer and flushes it if it supports that.
+func (o *output) flush() (err error) {
+	if o.bw == nil && o.flusher == nil {
+		return nil
+	}
+
+	if err = o.lock(nil); err != nil {
+		return err
+	}
+
+	if o.deadliner != nil {
+		_ = o.deadliner.SetWriteDeadline(time.Now().Add(o.writeTimeout))
+	}
+
+	if o.bw != nil {
+		err = o.bw.Flush()
+	}
+	// After the watchdog switched to the fallback writer the slow one is not touched anymore.
+	if err == nil && o.flusher != nil && (o.watchdog == nil || !o.watchdog.switched) {
+		err = o.flusher.Flush()
+	}
+	o.unlock()
+
+	return err
+}
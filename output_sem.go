@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// outputSem is a binary semaphore with a lock-free uncontended path (a channel semaphore costs about half
+// of a simple record) and cancelable waiting. A release with waiters hands the ownership to the oldest one.
+type outputSem struct {
+	held atomic.Bool
+	// nwait is the count of waiters, it changes under mu only.
+	nwait atomic.Int32
+
+	mu      sync.Mutex
+	waiters []chan struct{}
+}
+
+// acquire takes the semaphore, waiting is aborted when done is closed or after timeout (0 - no timeout).
+func (s *outputSem) acquire(done <-chan struct{}, timeout time.Duration) error {
+	if s.held.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	s.mu.Lock()
+	// Counted before the second try, so a release that doesn't see the waiter is seen by the try.
+	s.nwait.Add(1)
+	if s.held.CompareAndSwap(false, true) {
+		s.nwait.Add(-1)
+		s.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	s.waiters = append(s.waiters, ready)
+	s.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var err error
+	select {
+	case <-ready:
+		return nil
+	case <-done:
+		err = fmt.Errorf("%w: context done", ErrRecordDropped)
+	case <-expired:
+		err = fmt.Errorf("%w: %w", ErrRecordDropped, ErrWriteTimeout)
+	}
+
+	s.mu.Lock()
+	for i, w := range s.waiters {
+		if w == ready {
+			s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+			s.nwait.Add(-1)
+			s.mu.Unlock()
+			return err
+		}
+	}
+	s.mu.Unlock()
+
+	// The ownership was handed over while giving up, pass it on.
+	s.release()
+	return err
+}
+
+func (s *outputSem) release() {
+	if s.nwait.Load() == 0 {
+		s.held.Store(false)
+		// A waiter that queued meanwhile saw the semaphore held, wake it if nobody took it since.
+		if s.nwait.Load() == 0 || !s.held.CompareAndSwap(false, true) {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	// nwait was seen, but the waiter acquired the semaphore itself or gave up.
+	if len(s.waiters) == 0 {
+		s.held.Store(false)
+		s.mu.Unlock()
+		return
+	}
+	ready := s.waiters[0]
+	s.waiters[0] = nil
+	s.waiters = s.waiters[1:]
+	s.nwait.Add(-1)
+	s.mu.Unlock()
+
+	close(ready)
+}
+
+// locked reports whether the semaphore is held.
+func (s *outputSem) locked() bool {
+	return s.held.Load()
+}
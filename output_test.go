@@ -0,0 +1,325 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ttrtcixy/fast-slog-handler/reliab"
+)
+
+// blockingWriter blocks every write until release is closed.
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func TestWriteTimeoutDropsRecords(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	h := NewJsonHandler(w, &Config{Level: int(slog.LevelInfo), WriteTimeout: 10 * time.Millisecond})
+	log := slog.New(h)
+
+	// The first record takes the output and blocks in the writer.
+	go log.Info("wedged")
+	for !h.shared.out.sem.locked() {
+		time.Sleep(time.Millisecond)
+	}
+
+	log.Info("dropped")
+	close(w.release)
+
+	if got := h.Stats().Dropped; got != 1 {
+		t.Fatalf("Dropped = %d, want 1", got)
+	}
+}
+
+func TestDropOnCtxDone(t *testing.T) {
+	h := NewJsonHandler(io.Discard, &Config{Level: int(slog.LevelInfo), DropOnCtxDone: true})
+	log := slog.New(h)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	log.InfoContext(ctx, "written")
+	cancel()
+	log.InfoContext(ctx, "dropped")
+
+	if s := h.Stats(); s.Written != 1 || s.Dropped != 1 {
+		t.Fatalf("Stats = %+v, want 1 written and 1 dropped", s)
+	}
+}
+
+// slowWriter sleeps before every write.
+type slowWriter struct {
+	delay time.Duration
+	n     int
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	w.n++
+	return len(p), nil
+}
+
+func TestSlowWriterSwitchesToFallback(t *testing.T) {
+	slow := &slowWriter{delay: 5 * time.Millisecond}
+	var fallback bytes.Buffer
+
+	h := NewJsonHandler(slow, &Config{
+		Level:              int(slog.LevelInfo),
+		SlowWriteThreshold: time.Millisecond,
+		SlowWriteLimit:     2,
+		SlowWriteFallback:  &fallback,
+	})
+	log := slog.New(h)
+
+	log.Info("first")
+	log.Info("second")
+	log.Info("third")
+
+	if slow.n != 2 {
+		t.Fatalf("slow writer got %d writes, want 2", slow.n)
+	}
+
+	out := fallback.String()
+	if !strings.Contains(out, slowWriterMsg) || !strings.Contains(out, `"msg":"third"`) {
+		t.Fatalf("fallback output = %q, want the diagnostic and the third record", out)
+	}
+}
+
+// lineCheckingWriter fails the test if a Write doesn't consist of whole JSON lines.
+type lineCheckingWriter struct {
+	t *testing.T
+	// writes are serialized by the handler, mu only protects the test from a regression.
+	mu     sync.Mutex
+	writes int
+}
+
+func (w *lineCheckingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writes++
+	if len(p) == 0 || p[len(p)-1] != '\n' {
+		w.t.Errorf("write %d doesn't end at a record boundary: ...%q", w.writes, p[max(0, len(p)-20):])
+		return len(p), nil
+	}
+
+	for _, line := range bytes.Split(p[:len(p)-1], []byte{'\n'}) {
+		if !json.Valid(line) {
+			w.t.Errorf("write %d has a broken line: %.60q", w.writes, line)
+		}
+	}
+
+	return len(p), nil
+}
+
+func TestLineAtomicityAcrossClones(t *testing.T) {
+	for _, buffered := range []bool{false, true} {
+		w := &lineCheckingWriter{t: t}
+		h := NewJsonHandler(w, &Config{BufferedOutput: buffered})
+
+		var wg sync.WaitGroup
+		for i := range 8 {
+			clone := slog.New(h.WithAttrs([]slog.Attr{slog.Int("clone", i)}).WithGroup("g"))
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for n := range 200 {
+					// Sizes around the buffer size make bufio fill in the middle of records.
+					clone.Info("record", "payload", strings.Repeat("x", (n*97)%(2*writerBufSize)))
+				}
+			}()
+		}
+		wg.Wait()
+
+		if buffered {
+			_ = h.Close(context.Background())
+		}
+	}
+}
+
+var errDiskFull = errors.New("disk full")
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errDiskFull }
+
+func TestLogEReportsBufferedWriteErrors(t *testing.T) {
+	h := NewJsonHandler(failingWriter{}, &Config{BufferedOutput: true})
+	defer h.Close(context.Background())
+	l := New(h)
+
+	// Plain logging only fills the buffer, the error appears at the flush.
+	l.Info("buffered")
+
+	if err := l.LogE(context.Background(), slog.LevelInfo, "audit"); !errors.Is(err, errDiskFull) {
+		t.Fatalf("LogE error = %v, want %v", err, errDiskFull)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustError didn't panic")
+		}
+	}()
+	l.MustError(context.Background(), "audit")
+}
+
+func TestOutputSemExclusion(t *testing.T) {
+	var sem outputSem
+	var inside atomic.Int32
+	var wg sync.WaitGroup
+
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 2000 {
+				// Every other goroutine gives up quickly to exercise the cancelation path.
+				timeout := time.Duration(i%2) * time.Microsecond
+				if err := sem.acquire(nil, timeout); err != nil {
+					continue
+				}
+				if inside.Add(1) != 1 {
+					t.Error("semaphore held twice")
+				}
+				inside.Add(-1)
+				sem.release()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if sem.locked() {
+		t.Error("semaphore is held after all releases")
+	}
+}
+
+func TestFlusherWriterKeepsHandlerUnbuffered(t *testing.T) {
+	var out bytes.Buffer
+	for _, w := range []io.Writer{bufio.NewWriter(&out), reliab.New(&out, reliab.Options{})} {
+		h := NewJsonHandler(w, nil)
+		if err := h.Close(context.Background()); !errors.Is(err, ErrNothingToClose) {
+			t.Errorf("%T: Close = %v, want ErrNothingToClose", w, err)
+		}
+	}
+}
+
+func TestErrorOutputRouting(t *testing.T) {
+	var out, errOut bytes.Buffer
+	var mu sync.Mutex
+	read := func(b *bytes.Buffer) string {
+		mu.Lock()
+		defer mu.Unlock()
+		return b.String()
+	}
+
+	for _, buffered := range []bool{false, true} {
+		out.Reset()
+		errOut.Reset()
+
+		h := NewJsonHandler(&syncBuffer{buf: &out, mu: &mu}, &Config{
+			Level: int(slog.LevelDebug), ErrorOutput: &syncBuffer{buf: &errOut, mu: &mu}, ErrorOutputBuffered: buffered,
+		})
+		// Clones share both outputs.
+		l := slog.New(h).With("svc", "api")
+		l.Debug("d")
+		l.Info("i")
+		l.Warn("w")
+		l.Error("e")
+
+		if buffered {
+			if got := read(&errOut); got != "" {
+				t.Errorf("buffered error output written before flush: %s", got)
+			}
+			if err := h.Close(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Records at or above WARN go to the error output only.
+		if got := read(&out); strings.Count(got, "\n") != 2 || !strings.Contains(got, `"msg":"d"`) || !strings.Contains(got, `"msg":"i"`) {
+			t.Errorf("buffered=%t: output %s", buffered, got)
+		}
+		if got := read(&errOut); strings.Count(got, "\n") != 2 || !strings.Contains(got, `"msg":"w","svc":"api"`) || !strings.Contains(got, `"msg":"e","svc":"api"`) {
+			t.Errorf("buffered=%t: error output %s", buffered, got)
+		}
+	}
+}
+
+// funcWriter is an io.Writer of an uncomparable type.
+type funcWriter func(p []byte) (int, error)
+
+func (f funcWriter) Write(p []byte) (int, error) { return f(p) }
+
+func TestSlowWriterUncomparableFallback(t *testing.T) {
+	slow := &slowWriter{delay: 5 * time.Millisecond}
+	var fallback bytes.Buffer
+
+	h := NewJsonHandler(funcWriter(slow.Write), &Config{
+		SlowWriteThreshold: time.Millisecond,
+		SlowWriteLimit:     1,
+		SlowWriteFallback:  funcWriter(fallback.Write),
+	})
+	log := slog.New(h)
+
+	log.Info("first")
+	log.Info("second")
+	log.Info("third")
+
+	if slow.n != 1 {
+		t.Fatalf("slow writer got %d writes, want 1", slow.n)
+	}
+	if out := fallback.String(); strings.Count(out, slowWriterMsg) != 1 || !strings.Contains(out, `"msg":"third"`) {
+		t.Fatalf("fallback output = %q, want one diagnostic and the later records", out)
+	}
+}
+
+// wrappedWriter is a comparable struct type, == still panics if w holds an uncomparable writer.
+type wrappedWriter struct{ w io.Writer }
+
+func (w wrappedWriter) Write(p []byte) (int, error) { return w.w.Write(p) }
+
+func TestSameWriter(t *testing.T) {
+	var buf bytes.Buffer
+	fn := funcWriter(buf.Write)
+
+	tests := []struct {
+		name string
+		a, b io.Writer
+		want bool
+	}{
+		{"same pointer", &buf, &buf, true},
+		{"other pointer", &buf, &bytes.Buffer{}, false},
+		{"funcs", fn, fn, false},
+		{"structs of funcs", wrappedWriter{fn}, wrappedWriter{fn}, false},
+		{"structs of pointers", wrappedWriter{&buf}, wrappedWriter{&buf}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameWriter(tt.a, tt.b); got != tt.want {
+				t.Errorf("sameWriter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// The handler is created without a panic and the watchdog is on.
+	h := NewJsonHandler(wrappedWriter{fn}, &Config{SlowWriteThreshold: time.Second, SlowWriteFallback: wrappedWriter{fn}})
+	slog.New(h).Info("m")
+	if !strings.Contains(buf.String(), `"msg":"m"`) {
+		t.Errorf("output %q", buf.String())
+	}
+}
@@ -0,0 +1,281 @@
+package logger
+
+import (
+	"cmp"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+const defaultMaxPartitions = 64
+
+// PartitionOptions controls how records are routed.
+type PartitionOptions struct {
+	// key of the top-level record or WithAttrs attr selecting the output (e.g. "tenant_id")
+	Key string
+	// Open creates the output of a partition on its first record, value is "" for records without the attr.
+	// Outputs implementing io.Closer are closed when they are evicted or the handler is closed.
+	Open func(value string) (io.Writer, error)
+	// max count of open outputs, the least recently used one is closed above it, 0 - 64
+	MaxOpen int
+	// New creates the encoder of the records, e.g. NewTextHandler, nil - NewJsonHandler
+	New func(w io.Writer, cfg *Config) *Handler
+}
+
+type partition struct {
+	value string
+	// mu serializes writes, closed is set once the output is evicted.
+	mu     sync.Mutex
+	w      io.Writer
+	closed bool
+}
+
+// partitionState is shared by a PartitionHandler and its clones.
+type partitionState struct {
+	key     string
+	open    func(string) (io.Writer, error)
+	maxOpen int
+
+	mu         sync.Mutex
+	lru        *list.List
+	partitions map[string]*list.Element
+}
+
+// PartitionHandler routes records to per-value outputs by an attr (e.g. per-tenant files with their own retention),
+// the outputs are opened lazily and capped with an LRU.
+type PartitionHandler struct {
+	handler *Handler
+	state   *partitionState
+
+	// keyAttrs are the top-level WithAttrs attrs with PartitionOptions.Key.
+	keyAttrs []slog.Attr
+	// pipeline is the Config.Middleware chain ending with handlePrepared, nil without middleware.
+	pipeline HandleFunc
+}
+
+// NewPartitionHandler creates a partitioning handler, the writer and buffering options of cfg are ignored,
+// Config.Middleware runs before the partition is picked.
+func NewPartitionHandler(opts PartitionOptions, cfg *Config) (*PartitionHandler, error) {
+	if opts.Key == "" || opts.Open == nil {
+		return nil, fmt.Errorf("%w: PartitionOptions.Key and Open are required", ErrInvalidConfig)
+	}
+	if opts.MaxOpen < 0 {
+		return nil, fmt.Errorf("%w: MaxOpen must not be negative, got %d", ErrInvalidConfig, opts.MaxOpen)
+	}
+
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	// Records are written by the handler itself, the inner handler only encodes them.
+	c := *cfg
+	c.BufferedOutput = false
+	c.ErrorOutput = nil
+
+	newHandler := opts.New
+	if newHandler == nil {
+		newHandler = NewJsonHandler
+	}
+
+	p := &PartitionHandler{
+		handler: newHandler(io.Discard, &c),
+		state: &partitionState{
+			key:        opts.Key,
+			open:       opts.Open,
+			maxOpen:    cmp.Or(opts.MaxOpen, defaultMaxPartitions),
+			lru:        list.New(),
+			partitions: make(map[string]*list.Element),
+		},
+	}
+	return p.withPipeline(), nil
+}
+
+func (p *PartitionHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return p.handler.Enabled(ctx, level)
+}
+
+func (p *PartitionHandler) Handle(ctx context.Context, record slog.Record) error {
+	h := p.handler
+
+	if ok, err := h.prepare(ctx, &record, false); !ok {
+		return err
+	}
+
+	if p.pipeline != nil {
+		return p.pipeline(ctx, record)
+	}
+	return p.handlePrepared(ctx, record)
+}
+
+// handlePrepared writes a record that passed prepare, it's the last stage of the middleware chain.
+func (p *PartitionHandler) handlePrepared(_ context.Context, record slog.Record) error {
+	h := p.handler
+
+	// The partition is picked by the attrs as logged, ReplaceAttr may redact the key attr.
+	value, _ := topicValue(p.state.key, record, p.keyAttrs)
+
+	msg := record.Message
+	record, problems, err := h.checkEncoded(record)
+	if err == nil {
+		err = p.write(h, value, record)
+	}
+	if len(problems) > 0 {
+		if reportErr := p.write(h.topLevel(), value, h.misuseRecord(msg, problems)); err == nil {
+			err = reportErr
+		}
+	}
+
+	return err
+}
+
+// write encodes the record and writes it to the partition of value.
+func (p *PartitionHandler) write(h *Handler, value string, record slog.Record) error {
+	pBuf := h.getBuf()
+	buf, err := h.encode((*pBuf)[:0], record)
+	if err != nil {
+		h.shared.stats.count(err)
+		h.shared.diagnoseWrite(record, err)
+		return err
+	}
+
+	err = p.state.write(value, buf)
+	h.shared.stats.count(err)
+
+	h.putBuf(pBuf, buf)
+
+	return err
+}
+
+// write writes buf to the output of the partition, opening it if needed.
+func (s *partitionState) write(value string, buf []byte) error {
+	for {
+		part, err := s.get(value)
+		if err != nil {
+			return err
+		}
+
+		part.mu.Lock()
+		// The output was evicted between get and Lock, the next get opens it again.
+		if part.closed {
+			part.mu.Unlock()
+			continue
+		}
+
+		_, err = part.w.Write(buf)
+		part.mu.Unlock()
+
+		return err
+	}
+}
+
+// get returns the partition of the value, evicting the least recently used one above maxOpen.
+func (s *partitionState) get(value string) (*partition, error) {
+	s.mu.Lock()
+
+	if el, ok := s.partitions[value]; ok {
+		s.lru.MoveToFront(el)
+		s.mu.Unlock()
+		return el.Value.(*partition), nil
+	}
+
+	// Outputs are opened under the lock, so concurrent records of a new partition open it once.
+	w, err := s.open(value)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("open partition %q: %w", value, err)
+	}
+
+	part := &partition{value: value, w: w}
+	s.partitions[value] = s.lru.PushFront(part)
+
+	var evicted *partition
+	if s.lru.Len() > s.maxOpen {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		evicted = oldest.Value.(*partition)
+		delete(s.partitions, evicted.value)
+	}
+	s.mu.Unlock()
+
+	if evicted != nil {
+		_ = evicted.close()
+	}
+
+	return part, nil
+}
+
+// close waits for the running write and closes the output.
+func (part *partition) close() error {
+	part.mu.Lock()
+	defer part.mu.Unlock()
+
+	part.closed = true
+	if c, ok := part.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Close closes all open outputs.
+func (p *PartitionHandler) Close(_ context.Context) error {
+	s := p.state
+
+	s.mu.Lock()
+	parts := make([]*partition, 0, s.lru.Len())
+	for el := s.lru.Front(); el != nil; el = el.Next() {
+		parts = append(parts, el.Value.(*partition))
+	}
+	s.lru.Init()
+	clear(s.partitions)
+	s.mu.Unlock()
+
+	var errs []error
+	for _, part := range parts {
+		if err := part.close(); err != nil {
+			errs = append(errs, fmt.Errorf("close partition %q: %w", part.value, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (p *PartitionHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return p
+	}
+
+	p2 := &PartitionHandler{handler: p.handler.WithAttrs(attrs).(*Handler), state: p.state, keyAttrs: p.keyAttrs}
+
+	// Attrs inside groups have other keys than the partition key.
+	if p.handler.groupPrefix == "" {
+		for _, attr := range attrs {
+			if attr.Key == p.state.key {
+				p2.keyAttrs = append(p2.keyAttrs[:len(p2.keyAttrs):len(p2.keyAttrs)], attr)
+			}
+		}
+	}
+
+	return p2.withPipeline()
+}
+
+func (p *PartitionHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return p
+	}
+	return (&PartitionHandler{handler: p.handler.WithGroup(name).(*Handler), state: p.state, keyAttrs: p.keyAttrs}).withPipeline()
+}
+
+// withPipeline builds the middleware chain of the handler.
+func (p *PartitionHandler) withPipeline() *PartitionHandler {
+	p.pipeline = p.handler.pipelineTo(p.handlePrepared)
+	return p
+}
+
+// Stats returns the counters of written (Written) and failed (WriteErrors) records.
+func (p *PartitionHandler) Stats() Stats {
+	return p.handler.Stats()
+}
@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestPartitionHandler(t *testing.T) {
+	outputs := map[string][]*closeRecorder{}
+	var opened []string
+
+	h, err := NewPartitionHandler(PartitionOptions{
+		Key: "tenant_id",
+		Open: func(value string) (io.Writer, error) {
+			opened = append(opened, value)
+			w := &closeRecorder{}
+			outputs[value] = append(outputs[value], w)
+			return w, nil
+		},
+		MaxOpen: 2,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := slog.New(h)
+	l.With("tenant_id", "a").Info("1")
+	l.Info("2", "tenant_id", "b")
+	l.Info("3", "tenant_id", "a")
+	l.Info("4", "tenant_id", "c") // evicts b
+	l.Info("5", "tenant_id", "b")
+	l.Info("6")
+
+	if want := []string{"a", "b", "c", "b", ""}; !slices.Equal(opened, want) {
+		t.Errorf("opened %q, want %q", opened, want)
+	}
+	if !outputs["b"][0].closed {
+		t.Error("evicted output is not closed")
+	}
+	if got := outputs["a"][0].String(); !strings.Contains(got, `"msg":"1"`) || !strings.Contains(got, `"msg":"3"`) {
+		t.Errorf("output of a: %q", got)
+	}
+
+	if err = h.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !outputs[""][0].closed || !outputs["b"][1].closed {
+		t.Error("open outputs are not closed")
+	}
+}
+
+func TestPartitionHandlerRedaction(t *testing.T) {
+	var out bytes.Buffer
+	h, err := NewPartitionHandler(PartitionOptions{
+		Key:  "tenant_id",
+		Open: func(string) (io.Writer, error) { return &out, nil },
+	}, &Config{ReplaceAttr: RedactPaths("req.password", "req.token")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slog.New(h).WithGroup("req").Info("login", "tenant_id", "a", "password", "hunter2", "token", "t0k")
+	if got := out.String(); strings.Contains(got, "hunter2") || strings.Contains(got, "t0k") ||
+		!strings.Contains(got, `"password":"[REDACTED]","token":"[REDACTED]"`) {
+		t.Errorf("got %s", got)
+	}
+}
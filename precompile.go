@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"log/slog"
+	"slices"
+)
+
+// PrecompiledAttrs is an opaque set of attributes already encoded by a Handler.
+// It lets hot loops attach a fixed attr set to records without encoding it on every call.
+type PrecompiledAttrs struct {
+	// builder that encoded the attrs, the bytes are reused only by the same builder.
+	builder builder
+	// groupPrefix the attrs were encoded with (text builder renders it into every key).
+	groupPrefix string
+	// path is the group path of the Config.RenameKeys lookups the JSON builder encoded the attrs with.
+	path string
+
+	// attrs are kept to encode them normally when the token is used by another handler.
+	attrs   []slog.Attr
+	encoded string
+}
+
+// Precompile encodes attrs once with the handler's builder and group, the result is passed to records via Attr().
+func (h *Handler) Precompile(attrs ...slog.Attr) *PrecompiledAttrs {
+	buf := h.builder.precomputeAttrs(nil, h.groupPrefix, h.groupPrefix, attrs)
+
+	p := &PrecompiledAttrs{
+		builder:     h.builder,
+		groupPrefix: h.groupPrefix,
+		attrs:       slices.Clone(attrs),
+		encoded:     string(buf),
+	}
+	if b, ok := h.builder.(*jsonBuilder); ok {
+		p.path = string(b.renames.appendGroupPath(nil, h.groupPrefix))
+	}
+	return p
+}
+
+// Attr returns an attribute carrying the precompiled attrs, e.g. logger.LogAttrs(ctx, level, "msg", p.Attr()).
+// Handlers other than the one that created p encode the original attrs as an inline group.
+func (p *PrecompiledAttrs) Attr() slog.Attr {
+	if p == nil || p.encoded == "" {
+		return slog.Attr{}
+	}
+
+	return slog.Any("", p)
+}
+
+// precompiledFrom returns the token stored in the attr.
+func precompiledFrom(attr slog.Attr) (*PrecompiledAttrs, bool) {
+	if attr.Value.Kind() != slog.KindAny {
+		return nil, false
+	}
+
+	p, ok := attr.Value.Any().(*PrecompiledAttrs)
+	return p, ok && p != nil
+}
+
+// inline returns the original attrs as an inline group for builders that can't reuse the encoded bytes.
+func (p *PrecompiledAttrs) inline() slog.Attr {
+	return slog.Attr{Key: "", Value: slog.GroupValue(p.attrs...)}
+}
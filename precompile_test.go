@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestPrecompile(t *testing.T) {
+	ctx := context.Background()
+	attrs := []slog.Attr{slog.String("svc", "api"), slog.Int("shard", 3)}
+
+	var buf bytes.Buffer
+	h := NewJsonHandler(&buf, &Config{TimeFormat: "-"})
+	p := h.Precompile(attrs...)
+
+	slog.New(h).LogAttrs(ctx, slog.LevelInfo, "m", p.Attr())
+	slog.New(h).LogAttrs(ctx, slog.LevelInfo, "m", attrs...)
+	// Another handler encodes the original attrs.
+	slog.New(NewJsonHandler(&buf, &Config{TimeFormat: "-"})).LogAttrs(ctx, slog.LevelInfo, "m", p.Attr())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != lines[1] || lines[0] != lines[2] {
+		t.Errorf("precompiled and plain attrs differ:\n%s", buf.String())
+	}
+
+	var empty bytes.Buffer
+	slog.New(NewJsonHandler(&empty, nil)).LogAttrs(ctx, slog.LevelInfo, "m", h.Precompile().Attr())
+	if strings.Contains(empty.String(), `"":`) {
+		t.Errorf("empty precompiled attrs: %s", empty.String())
+	}
+}
+
+func TestPrecompileGroups(t *testing.T) {
+	ctx := context.Background()
+
+	// The text builder renders the group into the keys, the bytes of another group are not reused.
+	var text bytes.Buffer
+	th := NewTextHandler(&text, &Config{CoalesceColors: true})
+	p := th.WithGroup("a").(*Handler).Precompile(slog.Int("k", 1))
+	slog.New(th.WithGroup("b")).LogAttrs(ctx, slog.LevelInfo, "m", p.Attr())
+	if got := text.String(); !strings.Contains(got, "b.k=1") || strings.Contains(got, "a.k") {
+		t.Errorf("text: %q", got)
+	}
+
+	// JSON groups are objects, the bytes depend on the group only through the renames of group paths.
+	var json bytes.Buffer
+	jh := NewJsonHandler(&json, &Config{TimeFormat: "-", RenameKeys: map[string]string{"a.k": "renamed"}})
+	p = jh.WithGroup("a").(*Handler).Precompile(slog.Int("k", 1))
+	slog.New(jh.WithGroup("a")).LogAttrs(ctx, slog.LevelInfo, "m", p.Attr())
+	slog.New(jh.WithGroup("b")).LogAttrs(ctx, slog.LevelInfo, "m", p.Attr())
+
+	lines := strings.Split(strings.TrimSpace(json.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], `"a":{"renamed":1}}`) || !strings.HasSuffix(lines[1], `"b":{"k":1}}`) {
+		t.Errorf("json:\n%s", json.String())
+	}
+}
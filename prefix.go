@@ -0,0 +1,59 @@
+package logger
+
+import "log/slog"
+
+// PrefixKey is the JSON key of the WithPrefix prefix.
+const PrefixKey = "prefix"
+
+// WithPrefix returns a handler that prepends a static prefix to the message in text output
+// and writes it as a top-level "prefix" attr in JSON (surrounding spaces trimmed). Nested calls concatenate the prefixes.
+func (h *Handler) WithPrefix(prefix string) slog.Handler {
+	if prefix == "" {
+		return h
+	}
+
+	h2 := h.clone()
+	h2.prefix += prefix
+	return h2
+}
+
+// WithPrefix applies the prefix to every handler.
+func (m *MultiHandler) WithPrefix(prefix string) slog.Handler {
+	if prefix == "" {
+		return m
+	}
+
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = withPrefix(h, prefix)
+	}
+
+	return &MultiHandler{handlers: handlers, closers: m.closers, source: m.source}
+}
+
+// WithPrefix applies the prefix to the wrapped handler.
+func (a *AsyncHandler) WithPrefix(prefix string) slog.Handler {
+	if prefix == "" {
+		return a
+	}
+	return &AsyncHandler{handler: withPrefix(a.handler, prefix), queue: a.queue}
+}
+
+// WithPrefix applies the prefix to the wrapped handler.
+func (s *SamplingHandler) WithPrefix(prefix string) slog.Handler {
+	if prefix == "" {
+		return s
+	}
+	return &SamplingHandler{handler: withPrefix(s.handler, prefix), state: s.state}
+}
+
+// withPrefix falls back to a PrefixKey attr for handlers without prefix support.
+func withPrefix(h slog.Handler, prefix string) slog.Handler {
+	if p, ok := h.(interface{ WithPrefix(string) slog.Handler }); ok {
+		return p.WithPrefix(prefix)
+	}
+	if prefix == "" {
+		return h
+	}
+	return h.WithAttrs([]slog.Attr{slog.String(PrefixKey, prefix)})
+}
@@ -0,0 +1,13 @@
+package logger
+
+import (
+	"log/slog"
+	"strconv"
+)
+
+// appendPriority appends the sd-daemon priority prefix of the level, see Config.PriorityPrefix.
+func appendPriority(buf []byte, level slog.Level) []byte {
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(MapLevel(SeveritySyslog, level).Number), 10)
+	return append(buf, '>')
+}
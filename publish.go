@@ -0,0 +1,505 @@
+package logger
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/ttrtcixy/fast-slog-handler/reliab"
+)
+
+var ErrPublishFailed = errors.New("log record publish failed")
+
+const (
+	// pause before the first retry when PublishOptions.RetryBackoff is 0.
+	defaultPublishBackoff = 100 * time.Millisecond
+	// longest pause between the retries of a Publish.
+	maxPublishBackoff = 5 * time.Second
+	// period of republishing the buffered messages in the background.
+	publishDrainInterval = time.Second
+	// topic token written for placeholders without an attr.
+	missingTopicToken = "_"
+)
+
+// Message is an encoded record handed to a Publisher.
+type Message struct {
+	// Topic is PublishOptions.Topic with the placeholders filled in (NATS subject, routing key, etc.).
+	Topic string
+	// Exchange is PublishOptions.Exchange with the placeholders filled in (AMQP exchange), empty if it is not set.
+	Exchange string
+	// Data is the JSON encoded record, it is reused after Publish returns.
+	// Buffered messages are copies, so brokers get the same bytes on republish.
+	Data []byte
+	// OrderingKey is PublishOptions.OrderingKey with the placeholders filled in (Pub/Sub ordering key),
+	// empty if it is not set.
+	OrderingKey string
+	// Attributes are the values of the PublishOptions.Attributes attrs (Pub/Sub attributes, AMQP headers),
+	// nil if it is not set.
+	Attributes map[string]string
+	// QoS is PublishOptions.QoS (MQTT quality of service).
+	QoS byte
+	// Retained is set for records at or above PublishOptions.RetainLevel (MQTT retained message),
+	// so subscribers connecting later get the last error right away.
+	Retained bool
+	// Level of the record.
+	Level slog.Level
+}
+
+// Publisher sends records to a message broker. The module has no dependencies besides the standard library,
+// so broker clients are adapted by a few lines, e.g. for NATS JetStream:
+//
+//	logger.PublisherFunc(func(ctx context.Context, msg *logger.Message) error {
+//		_, err := js.Publish(ctx, msg.Topic, msg.Data) // waits for the ack
+//		return err
+//	})
+//
+// Publish must return only after the broker accepted the message (ack, publisher confirm),
+// reconnecting is left to the client, failed calls are retried according to PublishOptions.
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+}
+
+// PublisherFunc adapts a function to Publisher.
+type PublisherFunc func(ctx context.Context, msg *Message) error
+
+func (f PublisherFunc) Publish(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+type PublishOptions struct {
+	// topic of every record, "{level}" is replaced with the lowercase level and "{key}" with the value
+	// of the top-level record or WithAttrs attr (e.g. "logs.{service}.{level}"), missing attrs become "_".
+	// Separators and wildcards ('.', '/', '*', '>', '+', '#', spaces) in values are replaced with '_'.
+	Topic string
+	// count of retries of a failed Publish (e.g. a missing ack while the client reconnects), 0 - no retries
+	Retries int
+	// pause before the first retry, doubled after each one up to 5s with full jitter (reliab.Backoff), 0 - 100ms
+	RetryBackoff time.Duration
+	// AMQP exchange, a template like Topic (e.g. "logs.{level}"), the routing key is Topic
+	Exchange string
+	// messages that failed after the retries are kept in memory up to this many bytes (broker outage)
+	// and republished in order before the next records and every second in the background, the oldest are
+	// dropped above it, 0 - disabled. Buffering serializes Publish calls to keep the order, see PublishHandler.Close.
+	BufferBytes int
+	// Pub/Sub ordering key, a template like Topic (e.g. "{tenant}"), records with the same key are delivered in order
+	OrderingKey string
+	// keys of the top-level record or WithAttrs attrs copied to Message.Attributes, "level" is the lowercase level,
+	// missing attrs are omitted
+	Attributes []string
+	// MQTT quality of service of every message (0, 1 or 2), copied to Message.QoS
+	QoS byte
+	// records at or above this level are published as MQTT retained messages, nil - disabled
+	RetainLevel slog.Leveler
+}
+
+// publishState is shared by a PublishHandler and its clones.
+type publishState struct {
+	pub      Publisher
+	topic    *msgTemplate
+	exchange *msgTemplate
+	ordering *msgTemplate
+	// attributes are the attr keys copied to Message.Attributes.
+	attributes  []string
+	qos         byte
+	retainLevel slog.Leveler
+	// names are the placeholders of the templates and the attributes.
+	names   []string
+	retries int
+	backoff time.Duration
+
+	// mu serializes publishing while messages are buffered.
+	mu          sync.Mutex
+	bufferBytes int
+	buffer      []*Message
+	pending     int
+
+	// done stops the background republishing, closed is set by Close.
+	done   chan struct{}
+	closed atomic.Bool
+}
+
+// PublishHandler encodes records like the JSON handler and publishes every record as one message.
+// It has no batch settings, batching is left to the client behind the Publisher (e.g. Pub/Sub PublishSettings).
+type PublishHandler struct {
+	handler *Handler
+	state   *publishState
+
+	// topicAttrs are the top-level WithAttrs attrs named in the templates or PublishOptions.Attributes.
+	topicAttrs []slog.Attr
+	// pipeline is the Config.Middleware chain ending with handlePrepared, nil without middleware.
+	pipeline HandleFunc
+}
+
+// NewPublishHandler creates a handler publishing records to pub, the writer and buffering options of cfg are ignored,
+// Config.Middleware runs before the records are encoded.
+func NewPublishHandler(pub Publisher, opts PublishOptions, cfg *Config) *PublishHandler {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	// Records are never written, the handler only encodes them.
+	c := *cfg
+	c.BufferedOutput = false
+	c.ErrorOutput = nil
+
+	topic, exchange, ordering := parseTemplate(opts.Topic), parseTemplate(opts.Exchange), parseTemplate(opts.OrderingKey)
+
+	p := &PublishHandler{
+		handler: NewJsonHandler(io.Discard, &c),
+		state: &publishState{
+			pub:         pub,
+			topic:       topic,
+			exchange:    exchange,
+			ordering:    ordering,
+			attributes:  slices.Clone(opts.Attributes),
+			qos:         opts.QoS,
+			retainLevel: opts.RetainLevel,
+			names:       slices.Concat(topic.names, exchange.names, ordering.names, opts.Attributes),
+			retries:     max(opts.Retries, 0),
+			backoff:     cmp.Or(max(opts.RetryBackoff, 0), defaultPublishBackoff),
+			bufferBytes: max(opts.BufferBytes, 0),
+			done:        make(chan struct{}),
+		},
+	}
+
+	if p.state.bufferBytes > 0 {
+		// Buffered messages are republished even if no records come after the outage.
+		go p.drainer()
+	}
+
+	return p.withPipeline()
+}
+
+// Close stops the background republishing and publishes the buffered messages with the retries
+// until ctx is done, the messages left are dropped. Records handled after Close are not buffered.
+func (p *PublishHandler) Close(ctx context.Context) error {
+	s := p.state
+	if s.bufferBytes == 0 {
+		return ErrNothingToClose
+	}
+	if s.closed.Swap(true) {
+		return ErrAlreadyClosed
+	}
+	close(s.done)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := p.drain(ctx, true)
+	if err != nil {
+		p.handler.shared.stats.dropped.Add(uint64(len(s.buffer)))
+		s.buffer, s.pending = nil, 0
+	}
+	return err
+}
+
+// drainer republishes the buffered messages until Close.
+func (p *PublishHandler) drainer() {
+	ticker := time.NewTicker(publishDrainInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.state.done:
+			return
+		case <-ticker.C:
+			p.state.mu.Lock()
+			_ = p.drain(context.Background(), false)
+			p.state.mu.Unlock()
+		}
+	}
+}
+
+func (p *PublishHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return p.handler.Enabled(ctx, level)
+}
+
+// Handle publishes the record, waiting for the Publisher and the retries.
+func (p *PublishHandler) Handle(ctx context.Context, record slog.Record) error {
+	h := p.handler
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if ok, err := h.prepare(ctx, &record, mustPersist(ctx)); !ok {
+		return err
+	}
+
+	if p.pipeline != nil {
+		return p.pipeline(ctx, record)
+	}
+	return p.handlePrepared(ctx, record)
+}
+
+// handlePrepared publishes a record that passed prepare, it's the last stage of the middleware chain.
+func (p *PublishHandler) handlePrepared(ctx context.Context, record slog.Record) error {
+	h := p.handler
+
+	msg := record.Message
+	record, problems, err := h.checkEncoded(record)
+	if err == nil {
+		err = p.write(ctx, h, record)
+	}
+	if len(problems) > 0 {
+		if reportErr := p.write(ctx, h.topLevel(), h.misuseRecord(msg, problems)); err == nil {
+			err = reportErr
+		}
+	}
+
+	return err
+}
+
+// write encodes the record and publishes it.
+func (p *PublishHandler) write(ctx context.Context, h *Handler, record slog.Record) error {
+	pBuf := h.getBuf()
+	buf, err := h.encode((*pBuf)[:0], record)
+	if err != nil {
+		h.shared.stats.count(err)
+		h.shared.diagnoseWrite(record, err)
+		return err
+	}
+
+	msg := &Message{
+		Topic:       p.render(p.state.topic, record),
+		Exchange:    p.render(p.state.exchange, record),
+		OrderingKey: p.render(p.state.ordering, record),
+		Attributes:  p.attributes(record),
+		QoS:         p.state.qos,
+		Retained:    p.state.retainLevel != nil && record.Level >= p.state.retainLevel.Level(),
+		// Without the newline, brokers frame the messages themselves.
+		Data:  buf[:len(buf)-1],
+		Level: record.Level,
+	}
+
+	if p.state.bufferBytes > 0 && !p.state.closed.Load() {
+		err = p.publishBuffered(ctx, msg)
+	} else {
+		err = p.publish(ctx, msg)
+		h.shared.stats.count(err)
+	}
+
+	h.putBuf(pBuf, buf)
+
+	return err
+}
+
+// publish calls the Publisher until it succeeds, the retries are exhausted or ctx is done.
+func (p *PublishHandler) publish(ctx context.Context, msg *Message) error {
+	for attempt := 0; ; attempt++ {
+		err := p.state.pub.Publish(ctx, msg)
+		if err == nil {
+			return nil
+		}
+		if attempt == p.state.retries {
+			return fmt.Errorf("%w: %w", ErrPublishFailed, err)
+		}
+
+		// Jittered, so the handlers of a fleet don't retry in lockstep when the broker comes back.
+		timer := time.NewTimer(reliab.Backoff(attempt, p.state.backoff, maxPublishBackoff))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", ErrPublishFailed, err)
+		case <-timer.C:
+		}
+	}
+}
+
+// publishBuffered republishes the buffered messages and then msg, msg is buffered if the broker is still down.
+func (p *PublishHandler) publishBuffered(ctx context.Context, msg *Message) error {
+	s := p.state
+	stats := &p.handler.shared.stats
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Buffered messages are tried once, so records don't wait for the retries during an outage.
+	if err := p.drain(ctx, false); err != nil {
+		s.push(msg, stats)
+		return nil
+	}
+
+	if err := p.publish(ctx, msg); err != nil {
+		s.push(msg, stats)
+		return nil
+	}
+
+	stats.count(nil)
+	return nil
+}
+
+// drain publishes the buffered messages in order until one fails, retry retries them like new records.
+// The caller holds the state mutex.
+func (p *PublishHandler) drain(ctx context.Context, retry bool) error {
+	s := p.state
+
+	for len(s.buffer) > 0 {
+		var err error
+		if retry {
+			err = p.publish(ctx, s.buffer[0])
+		} else if err = s.pub.Publish(ctx, s.buffer[0]); err != nil {
+			err = fmt.Errorf("%w: %w", ErrPublishFailed, err)
+		}
+		if err != nil {
+			return err
+		}
+
+		p.handler.shared.stats.count(nil)
+		s.pending -= len(s.buffer[0].Data)
+		s.buffer[0] = nil
+		s.buffer = s.buffer[1:]
+	}
+
+	return nil
+}
+
+// push buffers a copy of msg, evicting the oldest messages above bufferBytes.
+func (s *publishState) push(msg *Message, stats *stats) {
+	if len(msg.Data) > s.bufferBytes {
+		stats.dropped.Add(1)
+		return
+	}
+
+	c := *msg
+	c.Data = slices.Clone(msg.Data)
+	s.buffer = append(s.buffer, &c)
+	s.pending += len(c.Data)
+
+	for s.pending > s.bufferBytes {
+		s.pending -= len(s.buffer[0].Data)
+		s.buffer[0] = nil
+		s.buffer = s.buffer[1:]
+		stats.dropped.Add(1)
+	}
+}
+
+// attributes returns the values of the PublishOptions.Attributes attrs, nil if there are none.
+func (p *PublishHandler) attributes(record slog.Record) map[string]string {
+	if len(p.state.attributes) == 0 {
+		return nil
+	}
+
+	attrs := make(map[string]string, len(p.state.attributes))
+	for _, key := range p.state.attributes {
+		if value, ok := topicValue(key, record, p.topicAttrs); ok {
+			attrs[key] = value
+		}
+	}
+
+	return attrs
+}
+
+// render fills the placeholders of a topic or exchange template for the record.
+func (p *PublishHandler) render(t *msgTemplate, record slog.Record) string {
+	if len(t.names) == 0 {
+		return t.raw
+	}
+
+	var b strings.Builder
+	for i, name := range t.names {
+		b.WriteString(t.literals[i])
+
+		value, ok := topicValue(name, record, p.topicAttrs)
+		if !ok || value == "" {
+			b.WriteString(missingTopicToken)
+			continue
+		}
+		writeTopicToken(&b, value)
+	}
+	b.WriteString(t.literals[len(t.names)])
+
+	return b.String()
+}
+
+// topicValue returns the value of a topic placeholder, record attrs take precedence over WithAttrs ones.
+func topicValue(name string, record slog.Record, topicAttrs []slog.Attr) (string, bool) {
+	if name == slog.LevelKey {
+		return strings.ToLower(levelName(record.Level)), true
+	}
+
+	var value string
+	var found bool
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == name {
+			value, found = attr.Value.Resolve().String(), true
+			return false
+		}
+		return true
+	})
+	if found {
+		return value, true
+	}
+
+	for i := len(topicAttrs) - 1; i >= 0; i-- {
+		if topicAttrs[i].Key == name {
+			return topicAttrs[i].Value.Resolve().String(), true
+		}
+	}
+
+	return "", false
+}
+
+// writeTopicToken writes the value with the topic separators and wildcards of common brokers replaced.
+func writeTopicToken(b *strings.Builder, value string) {
+	for _, r := range value {
+		switch {
+		case r == '.' || r == '/' || r == '*' || r == '>' || r == '+' || r == '#' || unicode.IsSpace(r):
+			b.WriteByte('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+func (p *PublishHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return p
+	}
+
+	p2 := &PublishHandler{
+		handler:    p.handler.WithAttrs(attrs).(*Handler),
+		state:      p.state,
+		topicAttrs: p.topicAttrs,
+	}
+
+	// Attrs inside groups have other keys than the topic names.
+	if p.handler.groupPrefix == "" {
+		for _, attr := range attrs {
+			for _, name := range p.state.names {
+				if attr.Key == name {
+					p2.topicAttrs = append(p2.topicAttrs[:len(p2.topicAttrs):len(p2.topicAttrs)], attr)
+					break
+				}
+			}
+		}
+	}
+
+	return p2.withPipeline()
+}
+
+func (p *PublishHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return p
+	}
+	return (&PublishHandler{handler: p.handler.WithGroup(name).(*Handler), state: p.state, topicAttrs: p.topicAttrs}).withPipeline()
+}
+
+// withPipeline builds the middleware chain of the handler.
+func (p *PublishHandler) withPipeline() *PublishHandler {
+	p.pipeline = p.handler.pipelineTo(p.handlePrepared)
+	return p
+}
+
+// Stats returns the counters of published (Written) and failed (WriteErrors) records.
+func (p *PublishHandler) Stats() Stats {
+	return p.handler.Stats()
+}
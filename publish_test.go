@@ -0,0 +1,208 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPublishHandler(t *testing.T) {
+	var topics, data []string
+	failures := 2
+
+	pub := PublisherFunc(func(_ context.Context, msg *Message) error {
+		if failures > 0 {
+			failures--
+			return errors.New("no ack")
+		}
+		topics = append(topics, msg.Topic)
+		data = append(data, string(msg.Data))
+		return nil
+	})
+
+	h := NewPublishHandler(pub, PublishOptions{Topic: "logs.{service}.{level}", Retries: 2, RetryBackoff: time.Millisecond}, nil)
+	l := slog.New(h).With("service", "billing.api")
+
+	if err := l.Handler().Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelWarn, "m", 0)); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("n", "service", "auth")
+	slog.New(h).Info("o")
+
+	if want := []string{"logs.billing_api.warn", "logs.auth.info", "logs._.info"}; !slices.Equal(topics, want) {
+		t.Errorf("topics %q, want %q", topics, want)
+	}
+	if want := `{"time":"0001-01-01 00:00:00","level":"WARN","msg":"m","service":"billing.api"}`; data[0] != want {
+		t.Errorf("data %q, want %q", data[0], want)
+	}
+
+	failures = 10
+	if err := l.Handler().Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "m", 0)); !errors.Is(err, ErrPublishFailed) {
+		t.Errorf("err = %v, want ErrPublishFailed", err)
+	}
+	if stats := h.Stats(); stats.Written != 3 || stats.WriteErrors != 1 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestPublishHandlerBuffer(t *testing.T) {
+	var got []string
+	down := true
+
+	pub := PublisherFunc(func(_ context.Context, msg *Message) error {
+		if down {
+			return errors.New("connection refused")
+		}
+		got = append(got, msg.Exchange+"/"+msg.Topic+" "+string(msg.Data[len(msg.Data)-11:]))
+		return nil
+	})
+
+	// Every record is 56 bytes, the buffer keeps two of them.
+	h := NewPublishHandler(pub, PublishOptions{Topic: "{level}", Exchange: "logs", BufferBytes: 120}, nil)
+	record := func(msg string) slog.Record {
+		return slog.NewRecord(time.Time{}, slog.LevelInfo, msg, 0)
+	}
+
+	for _, msg := range []string{"m1", "m2", "m3"} {
+		if err := h.Handle(context.Background(), record(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	down = false
+	_ = h.Handle(context.Background(), record("m4"))
+
+	want := []string{`logs/info "msg":"m2"}`, `logs/info "msg":"m3"}`, `logs/info "msg":"m4"}`}
+	if !slices.Equal(got, want) {
+		t.Errorf("published %q, want %q", got, want)
+	}
+	if stats := h.Stats(); stats.Written != 3 || stats.Dropped != 1 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestPublishHandlerClose(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	var down atomic.Bool
+	down.Store(true)
+
+	pub := PublisherFunc(func(_ context.Context, msg *Message) error {
+		if down.Load() {
+			return errors.New("connection refused")
+		}
+		mu.Lock()
+		got = append(got, string(msg.Data[len(msg.Data)-11:]))
+		mu.Unlock()
+		return nil
+	})
+	published := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(got)
+	}
+
+	h := NewPublishHandler(pub, PublishOptions{Topic: "logs", BufferBytes: 1 << 10}, nil)
+	l := slog.New(h)
+	l.Info("m1")
+	l.Info("m2")
+
+	// The buffer is republished in the background without new records.
+	down.Store(false)
+	for deadline := time.Now().Add(5 * time.Second); len(published()) < 2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if want := []string{`"msg":"m1"}`, `"msg":"m2"}`}; !slices.Equal(published(), want) {
+		t.Fatalf("published %q, want %q", published(), want)
+	}
+
+	// Close publishes the buffered messages.
+	down.Store(true)
+	l.Info("m3")
+	down.Store(false)
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := published(); len(got) != 3 || got[2] != `"msg":"m3"}` {
+		t.Errorf("published %q after Close", got)
+	}
+	if err := h.Close(context.Background()); !errors.Is(err, ErrAlreadyClosed) {
+		t.Errorf("second Close: %v", err)
+	}
+
+	// Messages the broker doesn't take are dropped.
+	h = NewPublishHandler(pub, PublishOptions{Topic: "logs", BufferBytes: 1 << 10}, nil)
+	down.Store(true)
+	slog.New(h).Info("m4")
+	if err := h.Close(context.Background()); !errors.Is(err, ErrPublishFailed) {
+		t.Errorf("Close during an outage: %v", err)
+	}
+	if stats := h.Stats(); stats.Dropped != 1 {
+		t.Errorf("stats %+v", stats)
+	}
+
+	if err := NewPublishHandler(pub, PublishOptions{}, nil).Close(context.Background()); !errors.Is(err, ErrNothingToClose) {
+		t.Errorf("Close without a buffer: %v", err)
+	}
+}
+
+func TestPublishHandlerAttributes(t *testing.T) {
+	var got *Message
+	pub := PublisherFunc(func(_ context.Context, msg *Message) error {
+		got = msg
+		return nil
+	})
+
+	h := NewPublishHandler(pub, PublishOptions{Topic: "logs", OrderingKey: "{tenant}", Attributes: []string{"level", "tenant", "trace_id"}}, nil)
+	slog.New(h).With("tenant", "acme").Error("m", "trace_id", "af82")
+
+	if got.OrderingKey != "acme" {
+		t.Errorf("ordering key %q, want %q", got.OrderingKey, "acme")
+	}
+	if want := map[string]string{"level": "error", "tenant": "acme", "trace_id": "af82"}; !maps.Equal(got.Attributes, want) {
+		t.Errorf("attributes %v, want %v", got.Attributes, want)
+	}
+}
+
+func TestPublishHandlerMQTT(t *testing.T) {
+	var got []Message
+	pub := PublisherFunc(func(_ context.Context, msg *Message) error {
+		got = append(got, *msg)
+		return nil
+	})
+
+	h := NewPublishHandler(pub, PublishOptions{Topic: "devices/{device}/logs", QoS: 1, RetainLevel: slog.LevelError}, nil)
+	l := slog.New(h).With("device", "pump/7")
+	l.Info("m")
+	l.Error("e")
+
+	if got[0].Topic != "devices/pump_7/logs" || got[0].QoS != 1 || got[0].Retained {
+		t.Errorf("info message %+v", got[0])
+	}
+	if !got[1].Retained {
+		t.Errorf("error message is not retained")
+	}
+}
+
+func TestPublishHandlerRedaction(t *testing.T) {
+	var data []string
+	pub := PublisherFunc(func(_ context.Context, msg *Message) error {
+		data = append(data, string(msg.Data))
+		return nil
+	})
+
+	h := NewPublishHandler(pub, PublishOptions{Topic: "logs"}, &Config{ReplaceAttr: RedactPaths("req.password", "req.token")})
+	slog.New(h).WithGroup("req").Info("login", "password", "hunter2", "token", "t0k")
+
+	if len(data) != 1 || strings.Contains(data[0], "hunter2") || strings.Contains(data[0], "t0k") ||
+		!strings.Contains(data[0], `"req":{"password":"[REDACTED]","token":"[REDACTED]"}`) {
+		t.Errorf("data %q", data)
+	}
+}
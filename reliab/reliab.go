@@ -0,0 +1,259 @@
+// Package reliab is a reliability layer for remote log sinks: exponential backoff with jitter,
+// a circuit breaker routing writes to a fallback writer while the sink is down, and half-open probing.
+//
+//	conn, _ := net.Dial("tcp", "logs.internal:5170")
+//	w := reliab.New(conn, reliab.Options{Fallback: os.Stderr})
+//	handler := logger.NewJsonHandler(w, &logger.Config{BufferedOutput: true})
+//
+// Writer wraps any io.Writer, so custom sinks get the same behavior as the built-in ones. The sinks of the
+// logger package that are plain writers (TCP connections, syslog, DatagramWriter) are wrapped the same way,
+// PublishHandler retries with Backoff itself.
+package reliab
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Writer.Write while the circuit is open and there is no fallback writer.
+var ErrOpen = errors.New("reliab: circuit open")
+
+const (
+	defaultBackoff          = 50 * time.Millisecond
+	defaultMaxBackoff       = 5 * time.Second
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 10 * time.Second
+)
+
+// Options controls retries and the circuit breaker.
+type Options struct {
+	// writer used while the circuit is open, nil - writes fail with ErrOpen
+	Fallback io.Writer
+	// retries of a failed write before it counts as a failure, 0 - none; the caller waits for them
+	Retries int
+	// pause before the first retry, doubled for every next one up to MaxBackoff with full jitter, 0 - 50ms
+	Backoff time.Duration
+	// longest pause between retries, 0 - 5s
+	MaxBackoff time.Duration
+	// consecutive failed writes opening the circuit, 0 - 5
+	FailureThreshold int
+	// time the circuit stays open before the next write probes the sink, 0 - 10s
+	OpenTimeout time.Duration
+	// logger of the circuit state changes: WARN when it opens, INFO when the sink recovers; nil - not logged.
+	// It must not write to this Writer.
+	Logger *slog.Logger
+}
+
+// State is the state of the circuit breaker.
+type State int
+
+const (
+	// Closed - writes go to the sink.
+	Closed State = iota
+	// Open - writes go to the fallback writer until the open timeout passes.
+	Open
+	// HalfOpen - the next write probes the sink, it closes the circuit on success and opens it again on failure.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Stats are the counters of a Writer.
+type Stats struct {
+	State State
+	// writes accepted by the sink
+	Written uint64
+	// writes that failed after all retries
+	Failed uint64
+	// retries of failed writes
+	Retried uint64
+	// writes sent to the fallback writer (or rejected with ErrOpen) while the circuit was open
+	Diverted uint64
+	// times the circuit was opened
+	Opened uint64
+}
+
+// Writer wraps a sink with retries and a circuit breaker, writes are serialized.
+type Writer struct {
+	mu sync.Mutex
+
+	w    io.Writer
+	opts Options
+
+	state    State
+	failures int
+	openedAt time.Time
+	lastErr  error
+	stats    Stats
+
+	// sleep is replaced in tests.
+	sleep func(time.Duration)
+}
+
+// New wraps w, zero options use the defaults.
+func New(w io.Writer, opts Options) *Writer {
+	opts.Retries = max(opts.Retries, 0)
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultMaxBackoff
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = defaultFailureThreshold
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = defaultOpenTimeout
+	}
+
+	return &Writer{w: w, opts: opts, sleep: time.Sleep}
+}
+
+// Backoff returns the pause before the retry attempt (0 for the first one): base doubled attempt times,
+// capped at maxBackoff, with full jitter, so clients recovering together don't retry in lockstep.
+func Backoff(attempt int, base, maxBackoff time.Duration) time.Duration {
+	d := maxBackoff
+	if attempt < 62 && base<<attempt > 0 && base<<attempt < maxBackoff {
+		d = base << attempt
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(d))) + 1
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	before := w.state
+	n, err := w.write(p)
+	after, cause, failures := w.state, w.lastErr, w.failures
+	w.mu.Unlock()
+
+	// Logged after the lock is released, the logger may be slow or write to a writer sharing the sink.
+	if w.opts.Logger != nil && before != after {
+		switch after {
+		case Open:
+			w.opts.Logger.LogAttrs(context.Background(), slog.LevelWarn, "reliab: circuit opened",
+				slog.Int("failures", failures), slog.String("error", cause.Error()), slog.Duration("open_timeout", w.opts.OpenTimeout))
+		case Closed:
+			w.opts.Logger.LogAttrs(context.Background(), slog.LevelInfo, "reliab: sink recovered, circuit closed")
+		}
+	}
+
+	return n, err
+}
+
+func (w *Writer) write(p []byte) (int, error) {
+	if w.state == Open {
+		if time.Since(w.openedAt) < w.opts.OpenTimeout {
+			return w.divert(p)
+		}
+		w.state = HalfOpen
+	}
+
+	// A probe isn't retried, the sink is likely still down.
+	retries := w.opts.Retries
+	if w.state == HalfOpen {
+		retries = 0
+	}
+
+	// A retry after a partial write sends only the rest, the sink already has the head.
+	n, err := w.w.Write(p)
+	for attempt := 0; err != nil && attempt < retries; attempt++ {
+		w.stats.Retried++
+		w.sleep(Backoff(attempt, w.opts.Backoff, w.opts.MaxBackoff))
+		var m int
+		m, err = w.w.Write(p[n:])
+		n += m
+	}
+
+	if err == nil {
+		w.stats.Written++
+		w.failures = 0
+		w.state = Closed
+		return n, nil
+	}
+
+	w.stats.Failed++
+	w.failures++
+	w.lastErr = err
+	if w.state == HalfOpen || w.failures >= w.opts.FailureThreshold {
+		w.open()
+	}
+
+	// The record isn't lost if the fallback writer takes it, whole: the head written to the sink is torn.
+	if w.opts.Fallback != nil {
+		return w.divert(p)
+	}
+	return n, err
+}
+
+func (w *Writer) open() {
+	w.state = Open
+	w.openedAt = time.Now()
+	w.stats.Opened++
+}
+
+func (w *Writer) divert(p []byte) (int, error) {
+	w.stats.Diverted++
+	if w.opts.Fallback == nil {
+		return 0, ErrOpen
+	}
+	return w.opts.Fallback.Write(p)
+}
+
+// Flush flushes the sink (or the fallback writer while the circuit is open) if it implements Flush() error.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	target := w.w
+	if w.state == Open && w.opts.Fallback != nil {
+		target = w.opts.Fallback
+	}
+	if f, ok := target.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close closes the sink if it implements io.Closer, the fallback writer is not closed.
+func (w *Writer) Close() error {
+	if c, ok := w.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// State returns the state of the circuit, Open changes to HalfOpen only at the next write.
+func (w *Writer) State() State {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.state
+}
+
+// Stats returns a snapshot of the counters.
+func (w *Writer) Stats() Stats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats := w.stats
+	stats.State = w.state
+	return stats
+}
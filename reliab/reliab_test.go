@@ -0,0 +1,124 @@
+package reliab
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("sink down")
+
+// flakySink fails while down is set.
+type flakySink struct {
+	bytes.Buffer
+	down   bool
+	writes int
+}
+
+func (s *flakySink) Write(p []byte) (int, error) {
+	s.writes++
+	if s.down {
+		return 0, errDown
+	}
+	return s.Buffer.Write(p)
+}
+
+func TestWriterCircuit(t *testing.T) {
+	sink := &flakySink{down: true}
+	var fallback, events bytes.Buffer
+
+	w := New(sink, Options{
+		Fallback: &fallback, Retries: 2, FailureThreshold: 2, OpenTimeout: 20 * time.Millisecond,
+		Logger: slog.New(slog.NewTextHandler(&events, nil)),
+	})
+	var pauses []time.Duration
+	w.sleep = func(d time.Duration) { pauses = append(pauses, d) }
+
+	for range 3 {
+		if _, err := w.Write([]byte("r\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two writes with two retries each open the circuit, the third one skips the sink.
+	if sink.writes != 6 || len(pauses) != 4 || w.State() != Open || fallback.String() != "r\nr\nr\n" {
+		t.Fatalf("writes %d, pauses %v, state %s, fallback %q", sink.writes, pauses, w.State(), fallback.String())
+	}
+
+	// A failed probe opens the circuit again without retries.
+	time.Sleep(25 * time.Millisecond)
+	_, _ = w.Write([]byte("probe\n"))
+	if sink.writes != 7 || w.State() != Open {
+		t.Fatalf("writes %d, state %s", sink.writes, w.State())
+	}
+
+	sink.down = false
+	time.Sleep(25 * time.Millisecond)
+	_, _ = w.Write([]byte("ok\n"))
+	if w.State() != Closed || sink.String() != "ok\n" {
+		t.Fatalf("state %s, sink %q", w.State(), sink.String())
+	}
+
+	stats := w.Stats()
+	if stats.Written != 1 || stats.Failed != 3 || stats.Retried != 4 || stats.Diverted != 4 || stats.Opened != 2 {
+		t.Errorf("stats %+v", stats)
+	}
+
+	// The failed probe doesn't log the circuit opening again.
+	log := events.String()
+	if strings.Count(log, "circuit opened") != 1 || strings.Count(log, "circuit closed") != 1 || !strings.Contains(log, "error=\"sink down\"") {
+		t.Errorf("state changes logged as %q", log)
+	}
+}
+
+func TestWriterWithoutFallback(t *testing.T) {
+	w := New(&flakySink{down: true}, Options{FailureThreshold: 1})
+	if _, err := w.Write([]byte("r")); !errors.Is(err, errDown) {
+		t.Errorf("first write error %v", err)
+	}
+	if _, err := w.Write([]byte("r")); !errors.Is(err, ErrOpen) {
+		t.Errorf("open circuit error %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := range 100 {
+		limit := min(10*time.Millisecond<<min(attempt, 20), time.Second)
+		if d := Backoff(attempt, 10*time.Millisecond, time.Second); d <= 0 || d > limit {
+			t.Fatalf("Backoff(%d) = %s, limit %s", attempt, d, limit)
+		}
+	}
+}
+
+// shortSink accepts at most limit bytes per write and fails short writes.
+type shortSink struct {
+	bytes.Buffer
+	limit int
+}
+
+func (s *shortSink) Write(p []byte) (int, error) {
+	if len(p) > s.limit {
+		n, _ := s.Buffer.Write(p[:s.limit])
+		return n, io.ErrShortWrite
+	}
+	return s.Buffer.Write(p)
+}
+
+func TestWriterPartialWrite(t *testing.T) {
+	sink := &shortSink{limit: 4}
+	w := New(sink, Options{Retries: 3})
+	w.sleep = func(time.Duration) {}
+
+	// Every retry sends the rest of the record.
+	n, err := w.Write([]byte("record\n"))
+	if err != nil || n != 7 || sink.String() != "record\n" {
+		t.Fatalf("n %d, err %v, sink %q", n, err, sink.String())
+	}
+	if stats := w.Stats(); stats.Written != 1 || stats.Retried != 1 {
+		t.Errorf("stats %+v", stats)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !plan9
+
+package logger
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals make MultiHandler.Watch reload the config file.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
@@ -0,0 +1,8 @@
+//go:build plan9
+
+package logger
+
+import "os"
+
+// reloadSignals is empty on plan9, MultiHandler.Watch relies on polling only.
+var reloadSignals []os.Signal
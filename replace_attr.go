@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces the values of attrs matched by RedactPaths.
+const Redacted = "[REDACTED]"
+
+// replaceAttrs calls Config.ReplaceAttr for every non-group attr, groups is the path of the attrs.
+// Group attrs are rebuilt from their replaced attrs and dropped if nothing is left, like by slog handlers.
+func (s *shared) replaceAttrs(groups []string, attrs []slog.Attr) []slog.Attr {
+	replaced := make([]slog.Attr, 0, len(attrs))
+
+	for _, attr := range attrs {
+		// Precompile tokens are replaced as their attrs, the encoded bytes don't pass through ReplaceAttr.
+		if p, ok := precompiledFrom(attr); ok {
+			attr = p.inline()
+		}
+		attr.Value = attr.Value.Resolve()
+
+		if attr.Value.Kind() != slog.KindGroup {
+			if attr = s.replaceAttr(groups, attr); attr.Key != "" {
+				replaced = append(replaced, attr)
+			}
+			continue
+		}
+
+		// Attrs of a group without a key are inlined in the current group.
+		path := groups
+		if attr.Key != "" {
+			path = append(groups[:len(groups):len(groups)], attr.Key)
+		}
+
+		if group := s.replaceAttrs(path, attr.Value.Group()); len(group) > 0 {
+			replaced = append(replaced, slog.Attr{Key: attr.Key, Value: slog.GroupValue(group...)})
+		}
+	}
+
+	return replaced
+}
+
+// replaceRecordAttrs returns a copy of the record with replaced attrs, the path starts with the WithGroup groups of h.
+func (h *Handler) replaceRecordAttrs(record slog.Record) slog.Record {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	r := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	r.AddAttrs(h.shared.replaceAttrs(h.groups, attrs)...)
+	return r
+}
+
+// RedactPaths returns a Config.ReplaceAttr func replacing the values of attrs at the dot-separated paths
+// with Redacted, e.g. "http.request.headers.authorization" matches the authorization attr in the headers group
+// of a handler with the http.request groups. Paths are compared case-insensitively.
+func RedactPaths(paths ...string) func(groups []string, attr slog.Attr) slog.Attr {
+	// Paths by their last segment, so most attrs are skipped without building their path.
+	byKey := make(map[string][]string, len(paths))
+	for _, path := range paths {
+		path = strings.ToLower(path)
+		key := path[strings.LastIndexByte(path, '.')+1:]
+		byKey[key] = append(byKey[key], path)
+	}
+
+	return func(groups []string, attr slog.Attr) slog.Attr {
+		candidates, ok := byKey[strings.ToLower(attr.Key)]
+		if !ok {
+			return attr
+		}
+
+		path := strings.ToLower(strings.Join(append(slices.Clip(groups), attr.Key), "."))
+		if slices.Contains(candidates, path) {
+			attr.Value = slog.StringValue(Redacted)
+		}
+		return attr
+	}
+}
@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReplaceAttrGroups(t *testing.T) {
+	var paths []string
+	var buf bytes.Buffer
+	h := NewJsonHandler(&buf, &Config{
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			paths = append(paths, strings.Join(append(slices.Clip(groups), attr.Key), "."))
+			if attr.Key == "drop" {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+
+	l := slog.New(h).WithGroup("http").With("method", "GET").WithGroup("request")
+	l.Info("m", slog.Group("headers", "accept", "*/*", slog.Group("", "inline", 1)), slog.Group("empty", "drop", 1))
+
+	want := []string{"http.method", "http.request.headers.accept", "http.request.headers.inline", "http.request.empty.drop"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("paths %q, want %q", paths, want)
+	}
+	if want := `"http":{"method":"GET","request":{"headers":{"accept":"*/*","inline":1}}}}`; !strings.Contains(buf.String(), want) {
+		t.Errorf("output %s, want %s", buf.String(), want)
+	}
+}
+
+func TestRedactPaths(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewTextHandler(&buf, &Config{ReplaceAttr: RedactPaths("http.request.headers.Authorization")})
+
+	l := slog.New(h).WithGroup("http").WithGroup("request")
+	l.Info("m", slog.Group("headers", "authorization", "Bearer x", "accept", "*/*"), "authorization", "top")
+
+	out := buf.String()
+	if strings.Contains(out, "Bearer") || !strings.Contains(out, Redacted) || !strings.Contains(out, "top") {
+		t.Errorf("output %q", out)
+	}
+}
+
+func TestReplaceAttrPrecompiled(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewJsonHandler(&buf, &Config{ReplaceAttr: RedactPaths("req.token")})
+
+	g := h.WithGroup("req").(*Handler)
+	p := g.Precompile(slog.String("token", "secret"), slog.String("region", "eu"))
+
+	l := slog.New(g)
+	l.LogAttrs(context.Background(), slog.LevelInfo, "attrs", p.Attr())
+	l.With(p.Attr()).Info("with")
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if strings.Contains(line, "secret") || !strings.Contains(line, `"req":{"token":"`+Redacted+`","region":"eu"}`) {
+			t.Errorf("output %s", line)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reporter accumulates named counters and gauges and writes them as a single INFO record every interval,
+// e.g. "stats requests=1520 errors=3 queue_len=12". Counters are reset after every record,
+// so they show the changes during the interval, gauges keep their last value.
+type Reporter struct {
+	handler slog.Handler
+	msg     string
+
+	mu       sync.Mutex
+	counters map[string]int64
+	gauges   map[string]float64
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewReporter starts a reporter writing a record with msg to h every interval, Close stops it.
+func NewReporter(h slog.Handler, msg string, interval time.Duration) *Reporter {
+	r := &Reporter{
+		handler:  h,
+		msg:      msg,
+		counters: make(map[string]int64),
+		gauges:   make(map[string]float64),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go r.run(interval)
+
+	return r
+}
+
+// Add adds delta to the counter.
+func (r *Reporter) Add(name string, delta int64) {
+	r.mu.Lock()
+	r.counters[name] += delta
+	r.mu.Unlock()
+}
+
+// Set sets the gauge to value.
+func (r *Reporter) Set(name string, value float64) {
+	r.mu.Lock()
+	r.gauges[name] = value
+	r.mu.Unlock()
+}
+
+func (r *Reporter) run(interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.report()
+		case <-r.stop:
+			// Counters of the last interval must not be lost.
+			r.report()
+			return
+		}
+	}
+}
+
+// report writes the record, nothing is written if no counter or gauge was ever set.
+func (r *Reporter) report() {
+	r.mu.Lock()
+	attrs := make([]slog.Attr, 0, len(r.counters)+len(r.gauges))
+	for name, n := range r.counters {
+		attrs = append(attrs, slog.Int64(name, n))
+		r.counters[name] = 0
+	}
+	for name, v := range r.gauges {
+		attrs = append(attrs, slog.Float64(name, v))
+	}
+	r.mu.Unlock()
+
+	if len(attrs) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	if !r.handler.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+
+	// Maps have no order, sorted keys keep the records comparable.
+	slices.SortFunc(attrs, func(a, b slog.Attr) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, r.msg, 0)
+	record.AddAttrs(attrs...)
+	_ = r.handler.Handle(ctx, record)
+}
+
+// Close stops the reporter after writing the last record.
+func (r *Reporter) Close() error {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+	return nil
+}
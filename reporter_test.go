@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewReporter(NewJsonHandler(&buf, nil), "stats", time.Hour)
+
+	r.Add("requests", 2)
+	r.Add("requests", 3)
+	r.Set("queue_len", 1.5)
+	_ = r.Close()
+
+	if out := buf.String(); !strings.Contains(out, `"msg":"stats","queue_len":1.5,"requests":5}`) {
+		t.Fatalf("output = %q", out)
+	}
+}
+
+func TestRuntimeReporter(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewJsonHandler(&buf, &Config{Level: int(slog.LevelDebug)})
+	h.Handle(t.Context(), slog.NewRecord(time.Now(), slog.LevelInfo, "before", 0))
+
+	r := NewRuntimeReporter(h, RuntimeReportOptions{Interval: 10 * time.Millisecond, Level: slog.LevelDebug})
+	h.Handle(t.Context(), slog.NewRecord(time.Now(), slog.LevelInfo, "counted", 0))
+	runtime.GC()
+	time.Sleep(50 * time.Millisecond)
+	_ = r.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) < 3 {
+		t.Fatalf("no stats records: %s", buf.String())
+	}
+
+	var record struct {
+		Level      string
+		Msg        string
+		Goroutines int
+		HeapAlloc  int64 `json:"heap_alloc"`
+		GC         int
+		Logger     struct{ Written uint64 }
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Level != "DEBUG" || record.Msg != "runtime stats" || record.Goroutines == 0 || record.HeapAlloc == 0 {
+		t.Errorf("record = %s", lines[2])
+	}
+	// The changes since the start: one GC and one record.
+	if record.GC < 1 || record.Logger.Written != 1 {
+		t.Errorf("record = %s", lines[2])
+	}
+}
@@ -0,0 +1,133 @@
+package logger
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// RuntimeReportOptions configures NewRuntimeReporter.
+type RuntimeReportOptions struct {
+	// period of the records, 0 - 1m
+	Interval time.Duration
+	// level of the records, default - INFO
+	Level slog.Level
+	// message of the records, default - "runtime stats"
+	Msg string
+}
+
+// RuntimeReporter writes a record with the runtime stats of the process and the handler counters every interval.
+type RuntimeReporter struct {
+	handler slog.Handler
+	opts    RuntimeReportOptions
+
+	// the counters at the previous record, the record carries the changes since it.
+	lastGC    uint32
+	lastPause uint64
+	lastStats Stats
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewRuntimeReporter starts writing one record per interval to h, replacing hand-rolled memstats loggers:
+//
+//	runtime stats goroutines=42 heap_alloc=12.4MiB heap_sys=31.2MiB heap_objects=81234 gc=3 gc_pause=1.2ms
+//	gc_pause_max=0.6ms logger.written=1520 logger.dropped=0 logger.write_errors=0
+//
+// gc, gc_pause and the logger counters are the changes during the interval, the logger group is written
+// if h has a Stats() Stats method (Handler, TeeHandler, BatchEncoder, ...). Close stops the reporter.
+func NewRuntimeReporter(h slog.Handler, opts RuntimeReportOptions) *RuntimeReporter {
+	opts.Interval = cmp.Or(opts.Interval, time.Minute)
+	opts.Msg = cmp.Or(opts.Msg, "runtime stats")
+
+	r := &RuntimeReporter{
+		handler: h,
+		opts:    opts,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	// The first record reports the changes since the start of the reporter.
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	r.lastGC, r.lastPause = m.NumGC, m.PauseTotalNs
+	if s, ok := h.(interface{ Stats() Stats }); ok {
+		r.lastStats = s.Stats()
+	}
+
+	go r.run()
+
+	return r
+}
+
+func (r *RuntimeReporter) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.report()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// report writes the record, the stats are read only if the level is enabled.
+func (r *RuntimeReporter) report() {
+	ctx := context.Background()
+	if !r.handler.Enabled(ctx, r.opts.Level) {
+		return
+	}
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	// PauseNs is a ring of the last 256 pauses, the ones of the interval end at NumGC.
+	gcs := m.NumGC - r.lastGC
+	var maxPause uint64
+	for i := uint32(0); i < min(gcs, uint32(len(m.PauseNs))); i++ {
+		maxPause = max(maxPause, m.PauseNs[(m.NumGC-i+255)%256])
+	}
+
+	record := slog.NewRecord(time.Now(), r.opts.Level, r.opts.Msg, 0)
+	record.AddAttrs(
+		slog.Int("goroutines", runtime.NumGoroutine()),
+		Bytes64("heap_alloc", int64(m.HeapAlloc)),
+		Bytes64("heap_sys", int64(m.HeapSys)),
+		slog.Uint64("heap_objects", m.HeapObjects),
+		slog.Uint64("gc", uint64(gcs)),
+		Millis("gc_pause", time.Duration(m.PauseTotalNs-r.lastPause)),
+		Millis("gc_pause_max", time.Duration(maxPause)),
+	)
+	r.lastGC, r.lastPause = m.NumGC, m.PauseTotalNs
+
+	if s, ok := r.handler.(interface{ Stats() Stats }); ok {
+		stats := s.Stats()
+		record.AddAttrs(slog.Group("logger",
+			slog.Uint64("written", stats.Written-r.lastStats.Written),
+			slog.Uint64("dropped", stats.Dropped-r.lastStats.Dropped),
+			slog.Uint64("write_errors", stats.WriteErrors-r.lastStats.WriteErrors),
+		))
+		r.lastStats = stats
+	}
+
+	_ = r.handler.Handle(ctx, record)
+}
+
+// Close stops the reporter.
+func (r *RuntimeReporter) Close() error {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+	return nil
+}
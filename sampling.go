@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// SampleExemption reports whether a record must never be sampled out.
+type SampleExemption func(record slog.Record) bool
+
+// ExemptLevel exempts records at or above level.
+func ExemptLevel(level slog.Leveler) SampleExemption {
+	return func(record slog.Record) bool {
+		return record.Level >= level.Level()
+	}
+}
+
+// ExemptAttr exempts records carrying a top-level attr with the key and value, e.g. ExemptAttr("audit", true).
+// WithAttrs attrs are not seen by the sampler, pass the attr with the record.
+func ExemptAttr(key string, value any) SampleExemption {
+	want := slog.AnyValue(value)
+	return func(record slog.Record) bool {
+		exempt := false
+		record.Attrs(func(attr slog.Attr) bool {
+			exempt = attr.Key == key && attr.Value.Resolve().Equal(want)
+			return !exempt
+		})
+		return exempt
+	}
+}
+
+type SamplingOptions struct {
+	// one of every Every records is passed through, 0 and 1 - all records
+	Every uint64
+	// records matching any exemption are always passed through and don't advance the counter,
+	// nil - records >= slog.LevelError are exempt
+	Exempt []SampleExemption
+}
+
+// samplingState is shared by a SamplingHandler and its clones.
+type samplingState struct {
+	every   atomic.Uint64
+	exempt  []SampleExemption
+	counter atomic.Uint64
+	sampled atomic.Uint64
+}
+
+// SamplingHandler passes one of every SamplingOptions.Every records to the wrapped handler,
+// safety-critical records (errors, audit records) are exempt and never dropped.
+type SamplingHandler struct {
+	handler slog.Handler
+	state   *samplingState
+}
+
+// NewSamplingHandler wraps h with a sampler.
+func NewSamplingHandler(h slog.Handler, opts SamplingOptions) *SamplingHandler {
+	exempt := opts.Exempt
+	if exempt == nil {
+		exempt = []SampleExemption{ExemptLevel(slog.LevelError)}
+	}
+
+	s := &SamplingHandler{handler: h, state: &samplingState{exempt: exempt}}
+	s.SetEvery(opts.Every)
+	return s
+}
+
+// SetEvery changes SamplingOptions.Every of the handler and its clones, records being sampled are not affected.
+func (s *SamplingHandler) SetEvery(every uint64) {
+	s.state.every.Store(max(every, 1))
+}
+
+func (s *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return s.handler.Enabled(ctx, level)
+}
+
+func (s *SamplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if every := s.state.every.Load(); every > 1 && !s.exempt(record) && (s.state.counter.Add(1)-1)%every != 0 {
+		s.state.sampled.Add(1)
+		return nil
+	}
+	return s.handler.Handle(ctx, record)
+}
+
+func (s *SamplingHandler) exempt(record slog.Record) bool {
+	for _, e := range s.state.exempt {
+		if e(record) {
+			return true
+		}
+	}
+	return false
+}
+
+// Close closes the wrapped handler if it has a Close(ctx) method, ErrNothingToClose otherwise.
+func (s *SamplingHandler) Close(ctx context.Context) error {
+	if c, ok := s.handler.(interface{ Close(context.Context) error }); ok {
+		return c.Close(ctx)
+	}
+	return ErrNothingToClose
+}
+
+// Sampled returns the count of records dropped by the sampler.
+func (s *SamplingHandler) Sampled() uint64 {
+	return s.state.sampled.Load()
+}
+
+func (s *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return s
+	}
+	return &SamplingHandler{handler: s.handler.WithAttrs(attrs), state: s.state}
+}
+
+func (s *SamplingHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return s
+	}
+	return &SamplingHandler{handler: s.handler.WithGroup(name), state: s.state}
+}
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSamplingExemptions(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewSamplingHandler(NewJsonHandler(&buf, &Config{}), SamplingOptions{
+		Every:  10,
+		Exempt: []SampleExemption{ExemptLevel(slog.LevelError), ExemptAttr("audit", true)},
+	})
+	l := slog.New(h)
+
+	for range 20 {
+		l.Info("sampled")
+		l.Info("audit", "audit", true)
+		l.Error("failed")
+	}
+
+	out := buf.String()
+	if n := strings.Count(out, `"msg":"sampled"`); n != 2 {
+		t.Errorf("%d sampled records written, want 2", n)
+	}
+	if n := strings.Count(out, `"msg":"audit"`); n != 20 {
+		t.Errorf("%d audit records written, want 20", n)
+	}
+	if n := strings.Count(out, `"msg":"failed"`); n != 20 {
+		t.Errorf("%d error records written, want 20", n)
+	}
+	if h.Sampled() != 18 {
+		t.Errorf("sampled %d, want 18", h.Sampled())
+	}
+}
+
+func TestSamplingSetEvery(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewSamplingHandler(NewJsonHandler(&buf, &Config{BufferedOutput: true}), SamplingOptions{})
+	l := slog.New(h).With("svc", "api")
+
+	for range 10 {
+		l.Info("m")
+	}
+	h.SetEvery(5)
+	for range 10 {
+		l.Info("m")
+	}
+	h.SetEvery(0)
+	for range 10 {
+		l.Info("m")
+	}
+
+	// The buffered handler is flushed by Close of the sampler.
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), `"msg":"m"`); n != 22 {
+		t.Errorf("%d records written, want 22", n)
+	}
+	if err := NewSamplingHandler(nopHandler{}, SamplingOptions{}).Close(context.Background()); !errors.Is(err, ErrNothingToClose) {
+		t.Errorf("Close without a closer = %v", err)
+	}
+}
+
+func TestSamplingWrappedOptions(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewSamplingHandler(NewJsonHandler(&buf, nil), SamplingOptions{Every: 2})
+
+	// The shared state keeps the count across the clones.
+	l := New(h).WithPrefix("[db]").WithCapacityHint(30, 64)
+	for range 4 {
+		l.Info("m")
+	}
+	if got := buf.String(); strings.Count(got, `"msg":"m","prefix":"[db]"`) != 2 || h.Sampled() != 2 {
+		t.Errorf("got %s, sampled %d", got, h.Sampled())
+	}
+	if inner := l.Handler().(*SamplingHandler).handler.(*Handler); inner.prefix != "[db]" || inner.pool == nil {
+		t.Errorf("options are not applied to the wrapped handler")
+	}
+}
@@ -0,0 +1,188 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// ErrSchemaViolation is returned by Handle for records rejected by Config.Schema.
+var ErrSchemaViolation = errors.New("log record violates the schema")
+
+// SchemaViolationsKey is the attr listing the violations of a record annotated by SchemaAnnotate.
+const SchemaViolationsKey = "schema_violations"
+
+// SchemaAction is what is done with a record violating the schema.
+type SchemaAction int
+
+const (
+	// SchemaAnnotate writes the record with a SchemaViolationsKey attr, e.g. ["missing request_id"].
+	SchemaAnnotate SchemaAction = iota
+	// SchemaReject drops the record, it's counted in Stats.Dropped and Handle returns ErrSchemaViolation.
+	SchemaReject
+)
+
+// Schema is the attrs every record must have, see Config.Schema. Attrs are named by their dotted path,
+// WithGroup groups included ("http.status"), the WithAttrs attrs count.
+type Schema struct {
+	// attrs every record must have with their kind, slog.KindAny accepts every kind
+	Required map[string]slog.Kind
+	// kinds of optional attrs, checked when the record has them
+	Kinds map[string]slog.Kind
+	// what is done with a violating record, default - SchemaAnnotate
+	Action SchemaAction
+	// if set, called with a violating record first, it may add the missing attrs or convert values and returns
+	// the record to check again, the violations left are handled by Action
+	Fix func(record slog.Record, violations []SchemaViolation) slog.Record
+}
+
+// SchemaViolation is a missing attr or an attr of another kind.
+type SchemaViolation struct {
+	Path    string
+	Missing bool
+	Want    slog.Kind
+	Got     slog.Kind
+}
+
+func (v SchemaViolation) String() string {
+	if v.Missing {
+		return "missing " + v.Path
+	}
+	return fmt.Sprintf("%s: want %s, got %s", v.Path, v.Want, v.Got)
+}
+
+// schemaAttr is an attr at a path of the schema found in the WithAttrs or record attrs.
+type schemaAttr struct {
+	path string
+	kind slog.Kind
+}
+
+// schemaAttrs appends the attrs at the schema paths, prefix is the dotted path of their groups.
+// prefix is appended to, its array must have no data after len(prefix).
+func (s *Schema) schemaAttrs(found []schemaAttr, prefix []byte, attrs []slog.Attr) []schemaAttr {
+	for _, attr := range attrs {
+		value := attr.Value.Resolve()
+
+		path := prefix
+		if attr.Key != "" {
+			path = append(prefix, attr.Key...)
+			if s.has(path) {
+				found = append(found, schemaAttr{path: string(path), kind: value.Kind()})
+			}
+		}
+
+		if value.Kind() == slog.KindGroup {
+			// Attrs of a group without a key are inlined in the current group.
+			if attr.Key != "" {
+				path = append(path, '.')
+			}
+			found = s.schemaAttrs(found, path, value.Group())
+		}
+	}
+	return found
+}
+
+func (s *Schema) has(path []byte) bool {
+	if _, ok := s.Required[string(path)]; ok {
+		return true
+	}
+	_, ok := s.Kinds[string(path)]
+	return ok
+}
+
+// violations checks the found attrs, the last attr at a path wins like in parsers of duplicate JSON keys.
+func (s *Schema) violations(found []schemaAttr) []SchemaViolation {
+	var violations []SchemaViolation
+
+	kindOf := func(path string) (slog.Kind, bool) {
+		for i := len(found) - 1; i >= 0; i-- {
+			if found[i].path == path {
+				return found[i].kind, true
+			}
+		}
+		return 0, false
+	}
+
+	for path, want := range s.Required {
+		got, ok := kindOf(path)
+		switch {
+		case !ok:
+			violations = append(violations, SchemaViolation{Path: path, Missing: true, Want: want})
+		case want != slog.KindAny && got != want:
+			violations = append(violations, SchemaViolation{Path: path, Want: want, Got: got})
+		}
+	}
+	for path, want := range s.Kinds {
+		if got, ok := kindOf(path); ok && want != slog.KindAny && got != want {
+			violations = append(violations, SchemaViolation{Path: path, Want: want, Got: got})
+		}
+	}
+
+	// Maps have no order, sorted paths keep the annotations comparable.
+	slices.SortFunc(violations, func(a, b SchemaViolation) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return violations
+}
+
+// groupPath appends the dotted path of the WithGroup groups of h.
+func (h *Handler) groupPath(buf []byte) []byte {
+	for _, group := range h.groups {
+		buf = append(buf, group...)
+		buf = append(buf, '.')
+	}
+	return buf
+}
+
+// withSchemaAttrs stores the WithAttrs attrs at the schema paths.
+func (h *Handler) withSchemaAttrs(attrs []slog.Attr) {
+	var pathBuf [128]byte
+	h.schemaAttrs = h.shared.schema.schemaAttrs(slices.Clip(h.schemaAttrs), h.groupPath(pathBuf[:0]), attrs)
+}
+
+// checkSchema returns the record to write, the fixed or annotated one, or ErrSchemaViolation if it's rejected.
+func (h *Handler) checkSchema(record slog.Record) (slog.Record, error) {
+	s := h.shared.schema
+
+	violations := s.violations(h.recordSchemaAttrs(record))
+	if len(violations) > 0 && s.Fix != nil {
+		record = s.Fix(record, violations)
+		violations = s.violations(h.recordSchemaAttrs(record))
+	}
+	if len(violations) == 0 {
+		return record, nil
+	}
+
+	problems := make([]string, len(violations))
+	for i, v := range violations {
+		problems[i] = v.String()
+	}
+
+	if s.Action == SchemaReject {
+		h.shared.stats.dropped.Add(1)
+		err := fmt.Errorf("%w: %w: %s", ErrRecordDropped, ErrSchemaViolation, strings.Join(problems, ", "))
+		h.shared.diagnoseWrite(record, err)
+		return record, err
+	}
+
+	// The record may be shared with other handlers, the annotation must not be added to their copy.
+	record = record.Clone()
+	record.AddAttrs(slog.Any(SchemaViolationsKey, problems))
+	return record, nil
+}
+
+// recordSchemaAttrs returns the WithAttrs and record attrs at the schema paths.
+func (h *Handler) recordSchemaAttrs(record slog.Record) []schemaAttr {
+	s := h.shared.schema
+	found := slices.Clip(h.schemaAttrs)
+
+	var pathBuf [128]byte
+	prefix := h.groupPath(pathBuf[:0])
+	record.Attrs(func(attr slog.Attr) bool {
+		found = s.schemaAttrs(found, prefix, []slog.Attr{attr})
+		return true
+	})
+	return found
+}
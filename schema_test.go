@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchema(t *testing.T) {
+	schema := &Schema{
+		Required: map[string]slog.Kind{"service": slog.KindString, "http.request_id": slog.KindAny},
+		Kinds:    map[string]slog.Kind{"http.status": slog.KindInt64},
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(NewJsonHandler(&buf, &Config{Schema: schema})).With("service", "api").WithGroup("http")
+
+	logger.Info("ok", "request_id", "r-1", "status", 200)
+	logger.Info("drift", "status", "200")
+	logger.Info("nested", slog.Group("", slog.String("request_id", "r-2")))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if strings.Contains(lines[0], SchemaViolationsKey) || strings.Contains(lines[2], SchemaViolationsKey) {
+		t.Errorf("valid records annotated: %s", buf.String())
+	}
+	want := `"schema_violations":["missing http.request_id","http.status: want Int64, got String"]`
+	if !strings.Contains(lines[1], want) {
+		t.Errorf("got %s\nwant %s", lines[1], want)
+	}
+
+	// Fix adds the missing id, the kind violation left rejects the record.
+	schema.Action = SchemaReject
+	schema.Fix = func(record slog.Record, _ []SchemaViolation) slog.Record {
+		record.AddAttrs(slog.String("request_id", "unknown"))
+		return record
+	}
+
+	buf.Reset()
+	h := NewJsonHandler(&buf, &Config{Schema: schema}).WithAttrs([]slog.Attr{slog.String("service", "api")}).WithGroup("http")
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "fixed", 0)); err != nil {
+		t.Fatal(err)
+	}
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "rejected", 0)
+	record.AddAttrs(slog.String("status", "200"))
+	if err := h.Handle(context.Background(), record); !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("err = %v, want ErrSchemaViolation", err)
+	}
+
+	if got := buf.String(); !strings.Contains(got, `"msg":"fixed","service":"api","http":{"request_id":"unknown"}}`) ||
+		strings.Contains(got, "rejected") {
+		t.Errorf("got %s", got)
+	}
+	if stats := h.(*Handler).Stats(); stats.Dropped != 1 {
+		t.Errorf("dropped = %d, want 1", stats.Dropped)
+	}
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ScopeRecordsKey and ScopeElapsedKey are the attrs of the scope summary, see BeginScope.
+const (
+	ScopeRecordsKey = "scope_records"
+	ScopeElapsedKey = "scope_elapsed"
+)
+
+type scopeCtxKey struct{}
+
+// scope is a BeginScope call, it counts the records logged with its ctx until end.
+type scope struct {
+	ctx    context.Context
+	parent *scope
+	start  time.Time
+
+	records atomic.Int64
+	ended   atomic.Bool
+	// owner is the first handler with Config.ScopeSummaryLevel that handled a record of the scope,
+	// only the records of its clones are counted and it writes the summary.
+	owner atomic.Pointer[Handler]
+	once  sync.Once
+}
+
+// BeginScope adds attrs to ctx like AppendAttrsToCtx and starts a scope, a lightweight trace span:
+//
+//	ctx, end := logger.BeginScope(ctx, slog.String("job", "reindex"))
+//	defer end()
+//
+// Every record logged with the ctx (or a ctx derived from it) carries the attrs. If the handler has
+// Config.ScopeSummaryLevel, end writes a summary at that level with the attrs, the count of the records
+// logged within the scope (ScopeRecordsKey) and its elapsed time (ScopeElapsedKey):
+//
+//	DEBUG scope end job=reindex scope_records=12 scope_elapsed=84.1ms
+//
+// Nested scopes count for the outer ones too. Scopes without records write no summary. end may be called
+// more than once, only the first call ends the scope.
+func BeginScope(ctx context.Context, attrs ...slog.Attr) (context.Context, func()) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ctx = AppendAttrsToCtx(ctx, attrs...)
+
+	parent, _ := ctx.Value(scopeCtxKey{}).(*scope)
+	s := &scope{parent: parent, start: time.Now()}
+	s.ctx = context.WithValue(ctx, scopeCtxKey{}, s)
+
+	return s.ctx, s.end
+}
+
+// countScope counts the record in the scopes of ctx.
+func (h *Handler) countScope(ctx context.Context) {
+	for s, _ := ctx.Value(scopeCtxKey{}).(*scope); s != nil; s = s.parent {
+		if s.ended.Load() {
+			continue
+		}
+
+		owner := s.owner.Load()
+		if owner == nil && s.owner.CompareAndSwap(nil, h) {
+			owner = h
+		} else if owner == nil {
+			owner = s.owner.Load()
+		}
+
+		if owner.shared == h.shared {
+			s.records.Add(1)
+		}
+	}
+}
+
+// end writes the summary with the owner of the scope.
+func (s *scope) end() {
+	s.once.Do(func() {
+		s.ended.Store(true)
+
+		owner := s.owner.Load()
+		if owner == nil {
+			return
+		}
+
+		// The summary is written even if the work of the scope was canceled.
+		ctx := context.WithoutCancel(s.ctx)
+		level := owner.shared.scopeSummaryLevel.Level()
+		if !owner.Enabled(ctx, level) {
+			return
+		}
+
+		record := slog.NewRecord(time.Now(), level, "scope end", 0)
+		record.AddAttrs(
+			slog.Int64(ScopeRecordsKey, s.records.Load()),
+			Millis(ScopeElapsedKey, time.Since(s.start)),
+		)
+		_ = owner.Handle(ctx, record)
+	})
+}
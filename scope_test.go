@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestBeginScope(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(NewJsonHandler(&buf, &Config{Level: int(slog.LevelDebug), ScopeSummaryLevel: slog.LevelDebug}))
+
+	ctx, end := BeginScope(context.Background(), slog.String("job", "reindex"))
+	l.InfoContext(ctx, "start")
+
+	inner, endInner := BeginScope(ctx, slog.Int("batch", 1))
+	l.InfoContext(inner, "batch done")
+	endInner()
+	endInner()
+
+	l.Info("outside")
+	end()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d records:\n%s", len(lines), buf.String())
+	}
+
+	var innerSummary, summary map[string]any
+	if err := json.Unmarshal([]byte(lines[2]), &innerSummary); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[4]), &summary); err != nil {
+		t.Fatal(err)
+	}
+
+	if innerSummary["msg"] != "scope end" || innerSummary["job"] != "reindex" || innerSummary["batch"] != 1.0 ||
+		innerSummary[ScopeRecordsKey] != 1.0 || innerSummary["level"] != "DEBUG" {
+		t.Errorf("inner summary %s", lines[2])
+	}
+	// The inner summary is a record of the outer scope.
+	if summary["job"] != "reindex" || summary["batch"] != nil || summary[ScopeRecordsKey] != 3.0 {
+		t.Errorf("summary %s", lines[4])
+	}
+	if _, ok := summary[ScopeElapsedKey].(float64); !ok {
+		t.Errorf("no elapsed time in %s", lines[4])
+	}
+}
+
+func TestBeginScopeWithoutSummary(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(NewJsonHandler(&buf, nil))
+
+	ctx, end := BeginScope(context.Background(), slog.String("job", "reindex"))
+	l.InfoContext(ctx, "start")
+	end()
+
+	if got := buf.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, `"job":"reindex"`) {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+)
+
+// SeverityScale is an external severity scale records are mapped to.
+type SeverityScale int
+
+const (
+	// SeveritySyslog - RFC 5424 severities 0 (emerg) to 7 (debug), also used by sd-daemon prefixes.
+	SeveritySyslog SeverityScale = iota + 1
+	// SeverityOTel - OpenTelemetry severity numbers 1 (TRACE) to 24 (FATAL4).
+	SeverityOTel
+	// SeverityGCP - Google Cloud Logging severities DEBUG (100) to EMERGENCY (800).
+	SeverityGCP
+	// SeverityDatadog - Datadog statuses with the syslog numbers.
+	SeverityDatadog
+)
+
+// Severity is a level on an external scale.
+type Severity struct {
+	Number int
+	Text   string
+}
+
+// SeverityMapping maps a level to the severity of a scale.
+type SeverityMapping func(level slog.Level) Severity
+
+var severityMappings [SeverityDatadog + 1]atomic.Pointer[SeverityMapping]
+
+// SetSeverityMapping replaces the mapping of the scale for MapLevel and all sinks using it
+// (e.g. Config.PriorityPrefix), nil restores the default one.
+func SetSeverityMapping(scale SeverityScale, mapping SeverityMapping) {
+	if scale < SeveritySyslog || scale > SeverityDatadog {
+		return
+	}
+	if mapping == nil {
+		severityMappings[scale].Store(nil)
+		return
+	}
+	severityMappings[scale].Store(&mapping)
+}
+
+// MapLevel returns the severity of the level on the scale, custom sinks use it to stay consistent with the built-in ones.
+// Levels between the slog ones map to the closest lower severity, e.g. INFO+2 is a syslog notice.
+func MapLevel(scale SeverityScale, level slog.Level) Severity {
+	if scale < SeveritySyslog || scale > SeverityDatadog {
+		return Severity{}
+	}
+	if mapping := severityMappings[scale].Load(); mapping != nil {
+		return (*mapping)(level)
+	}
+
+	switch scale {
+	case SeveritySyslog:
+		return syslogSeverity(level)
+	case SeverityOTel:
+		return otelSeverity(level)
+	case SeverityGCP:
+		return gcpSeverity(level)
+	default:
+		return datadogSeverity(level)
+	}
+}
+
+func syslogSeverity(level slog.Level) Severity {
+	switch {
+	case level >= slog.LevelError+8:
+		return Severity{2, "crit"}
+	case level >= slog.LevelError:
+		return Severity{3, "err"}
+	case level >= slog.LevelWarn:
+		return Severity{4, "warning"}
+	case level >= slog.LevelInfo+2:
+		return Severity{5, "notice"}
+	case level >= slog.LevelInfo:
+		return Severity{6, "info"}
+	default:
+		return Severity{7, "debug"}
+	}
+}
+
+var otelSeverityNames = [...]string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+// otelSeverity follows the slog bridge of the OpenTelemetry log data model: number = level + 9.
+func otelSeverity(level slog.Level) Severity {
+	number := min(max(int(level)+9, 1), 24)
+
+	text := otelSeverityNames[(number-1)/4]
+	if n := (number-1)%4 + 1; n > 1 {
+		text += strconv.Itoa(n)
+	}
+	return Severity{number, text}
+}
+
+func gcpSeverity(level slog.Level) Severity {
+	switch {
+	case level >= slog.LevelError+8:
+		return Severity{600, "CRITICAL"}
+	case level >= slog.LevelError:
+		return Severity{500, "ERROR"}
+	case level >= slog.LevelWarn:
+		return Severity{400, "WARNING"}
+	case level >= slog.LevelInfo+2:
+		return Severity{300, "NOTICE"}
+	case level >= slog.LevelInfo:
+		return Severity{200, "INFO"}
+	default:
+		return Severity{100, "DEBUG"}
+	}
+}
+
+func datadogSeverity(level slog.Level) Severity {
+	s := syslogSeverity(level)
+	switch s.Number {
+	case 2:
+		s.Text = "critical"
+	case 3:
+		s.Text = "error"
+	case 4:
+		s.Text = "warn"
+	}
+	return s
+}
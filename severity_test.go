@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestMapLevel(t *testing.T) {
+	tests := []struct {
+		scale SeverityScale
+		level slog.Level
+		want  Severity
+	}{
+		{SeveritySyslog, slog.LevelError, Severity{3, "err"}},
+		{SeveritySyslog, slog.LevelInfo + 2, Severity{5, "notice"}},
+		{SeveritySyslog, LevelTrace, Severity{7, "debug"}},
+		{SeverityOTel, LevelTrace, Severity{1, "TRACE"}},
+		{SeverityOTel, slog.LevelInfo + 1, Severity{10, "INFO2"}},
+		{SeverityOTel, slog.LevelError, Severity{17, "ERROR"}},
+		{SeverityOTel, slog.LevelError + 100, Severity{24, "FATAL4"}},
+		{SeverityGCP, slog.LevelWarn, Severity{400, "WARNING"}},
+		{SeverityDatadog, slog.LevelWarn, Severity{4, "warn"}},
+		{SeverityScale(0), slog.LevelInfo, Severity{}},
+	}
+	for _, tt := range tests {
+		if got := MapLevel(tt.scale, tt.level); got != tt.want {
+			t.Errorf("MapLevel(%d, %s) = %v, want %v", tt.scale, tt.level, got, tt.want)
+		}
+	}
+
+	SetSeverityMapping(SeveritySyslog, func(slog.Level) Severity { return Severity{Number: 1} })
+	defer SetSeverityMapping(SeveritySyslog, nil)
+	if got := string(appendPriority(nil, slog.LevelInfo)); got != "<1>" {
+		t.Errorf("custom mapping prefix %s", got)
+	}
+}
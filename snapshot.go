@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"time"
+)
+
+// snapshotBundle is the JSON document written by Snapshot.
+type snapshotBundle struct {
+	Generated  time.Time         `json:"generated"`
+	GoVersion  string            `json:"go_version"`
+	Level      string            `json:"level"`
+	LevelRules map[string]string `json:"level_rules,omitempty"`
+	Config     map[string]any    `json:"config"`
+	Stats      Stats             `json:"stats"`
+	Recent     []json.RawMessage `json:"recent,omitempty"`
+}
+
+// Snapshot writes a JSON bundle for bug reports: the handler level, level rules, the non-default Config options,
+// the counters and the records kept by recent (nil - none) encoded like by the JSON handler.
+// The writers and functions of the config are reported by type only. The handler keeps logging meanwhile.
+func Snapshot(w io.Writer, h *Handler, recent *BufferHandler) error {
+	bundle := snapshotBundle{
+		Generated: time.Now(),
+		GoVersion: runtime.Version(),
+		Level:     levelName(h.shared.level.Level()),
+		Config:    configSummary(&h.shared.config),
+		Stats:     h.Stats(),
+	}
+
+	if rules := h.shared.levelRules.Load(); rules != nil {
+		bundle.LevelRules = make(map[string]string, len(rules.rules))
+		for _, rule := range rules.rules {
+			pattern := rule.pattern
+			if rule.recursive {
+				pattern += "/*"
+			}
+			bundle.LevelRules[pattern] = levelName(rule.level)
+		}
+	}
+
+	if recent != nil {
+		var buf bytes.Buffer
+		if err := recent.Replay(context.Background(), NewJsonHandler(&buf, &Config{Level: int(LevelTrace)})); err != nil {
+			return err
+		}
+		for line := range bytes.Lines(buf.Bytes()) {
+			bundle.Recent = append(bundle.Recent, bytes.TrimSuffix(line, []byte{'\n'}))
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bundle)
+}
+
+// configSummary returns the options that differ from their zero values,
+// writers and functions are reported by their type, they can't be encoded and may hold secrets.
+func configSummary(cfg *Config) map[string]any {
+	summary := make(map[string]any)
+
+	v := reflect.ValueOf(cfg).Elem()
+	for i := range v.NumField() {
+		field, value := v.Type().Field(i), v.Field(i)
+		if !field.IsExported() || value.IsZero() {
+			continue
+		}
+
+		switch value.Kind() {
+		case reflect.Func, reflect.Chan:
+			summary[field.Name] = value.Type().String()
+		case reflect.Interface:
+			if leveler, ok := value.Interface().(slog.Leveler); ok {
+				summary[field.Name] = levelName(leveler.Level())
+			} else {
+				summary[field.Name] = fmt.Sprintf("%T", value.Interface())
+			}
+		case reflect.Slice:
+			if value.Type().Elem().Kind() == reflect.Func {
+				summary[field.Name] = fmt.Sprintf("%d %s", value.Len(), value.Type().Elem())
+			} else {
+				summary[field.Name] = value.Interface()
+			}
+		default:
+			summary[field.Name] = value.Interface()
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	h := NewJsonHandler(io.Discard, &Config{AddSource: true, ErrorOutput: io.Discard, StackTraceLevel: slog.LevelError})
+	if err := h.SetLevelRules(map[string]slog.Level{"github.com/our/repo/db/*": slog.LevelDebug}); err != nil {
+		t.Fatal(err)
+	}
+	slog.New(h).Info("m")
+
+	recent := NewBufferHandler(10, 0)
+	slog.New(recent).Warn("recent", "n", 1)
+
+	var buf bytes.Buffer
+	if err := Snapshot(&buf, h, recent); err != nil {
+		t.Fatal(err)
+	}
+
+	var bundle struct {
+		Level      string
+		LevelRules map[string]string `json:"level_rules"`
+		Config     map[string]any
+		Stats      Stats
+		Recent     []map[string]any
+	}
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+
+	if bundle.Level != "INFO" || bundle.LevelRules["github.com/our/repo/db/*"] != "DEBUG" || bundle.Stats.Written != 1 {
+		t.Errorf("bundle %s", buf.String())
+	}
+	if bundle.Config["AddSource"] != true || bundle.Config["ErrorOutput"] != "io.discard" || bundle.Config["StackTraceLevel"] != "ERROR" {
+		t.Errorf("config %v", bundle.Config)
+	}
+	if _, ok := bundle.Config["Level"]; ok {
+		t.Errorf("default option is reported: %v", bundle.Config)
+	}
+	if len(bundle.Recent) != 1 || bundle.Recent[0]["msg"] != "recent" {
+		t.Errorf("recent %v", bundle.Recent)
+	}
+}
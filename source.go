@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"path"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sourceFormatter renders the call site of a record as "internal/api/user.go:42 (GetUser)".
+type sourceFormatter struct {
+	// prefix stripped from file paths, if empty paths are made relative to the module.
+	trimPrefix string
+	// path of the main module, used for module-relative paths.
+	modulePath string
+
+	// cache stores the rendered source for every pc, call sites are finite in a program.
+	cache sync.Map
+}
+
+func newSourceFormatter(trimPrefix string) *sourceFormatter {
+	f := &sourceFormatter{trimPrefix: trimPrefix}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		f.modulePath = info.Main.Path
+	}
+
+	return f
+}
+
+// format returns the rendered source of pc, empty if pc is unknown.
+func (f *sourceFormatter) format(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+
+	if s, ok := f.cache.Load(pc); ok {
+		return s.(string)
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.File == "" {
+		return ""
+	}
+
+	pkg, fn := splitFuncName(frame.Function)
+
+	buf := make([]byte, 0, 64)
+	buf = append(buf, f.file(frame.File, pkg)...)
+	buf = append(buf, ':')
+	buf = strconv.AppendInt(buf, int64(frame.Line), 10)
+	if fn != "" {
+		buf = append(buf, " ("...)
+		buf = append(buf, fn...)
+		buf = append(buf, ')')
+	}
+
+	s, _ := f.cache.LoadOrStore(pc, string(buf))
+	return s.(string)
+}
+
+// file shortens the path: TrimSourcePrefix if set, otherwise the package directory relative to the main module
+// (or the import path for dependencies) joined with the file name.
+func (f *sourceFormatter) file(file, pkg string) string {
+	if f.trimPrefix != "" {
+		if rel, ok := strings.CutPrefix(file, f.trimPrefix); ok {
+			return strings.TrimPrefix(rel, "/")
+		}
+		return file
+	}
+
+	name := path.Base(file)
+
+	switch {
+	case pkg == "" || pkg == "main":
+		return name
+	case f.modulePath != "" && pkg == f.modulePath:
+		return name
+	case f.modulePath != "" && strings.HasPrefix(pkg, f.modulePath+"/"):
+		return pkg[len(f.modulePath)+1:] + "/" + name
+	default:
+		return pkg + "/" + name
+	}
+}
+
+// splitFuncName splits "github.com/our/repo/internal/api.(*T).Get" into the package path and "(*T).Get".
+func splitFuncName(function string) (pkg, fn string) {
+	lastSlash := strings.LastIndexByte(function, '/')
+	dot := strings.IndexByte(function[lastSlash+1:], '.')
+	if dot < 0 {
+		return "", function
+	}
+
+	dot += lastSlash + 1
+	return function[:dot], function[dot+1:]
+}
@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSourceFile(t *testing.T) {
+	const module = "github.com/our/repo"
+
+	tests := []struct {
+		name, trimPrefix, file, pkg, want string
+	}{
+		{"package main", "", "/src/repo/cmd/api/main.go", "main", "main.go"},
+		{"module root", "", "/src/repo/repo.go", module, "repo.go"},
+		{"module package", "", "/src/repo/internal/api/user.go", module + "/internal/api", "internal/api/user.go"},
+		{"dependency", "", "/go/pkg/mod/github.com/x/y@v1/z.go", "github.com/x/y", "github.com/x/y/z.go"},
+		{"module prefix of another path", "", "/go/pkg/mod/z.go", module + "-fork", module + "-fork/z.go"},
+		{"no package", "", "/src/repo/gen.go", "", "gen.go"},
+		{"trim prefix", "/src/repo", "/src/repo/internal/api/user.go", module + "/internal/api", "internal/api/user.go"},
+		{"outside the trim prefix", "/src/repo", "/go/pkg/mod/z.go", "github.com/x/y", "/go/pkg/mod/z.go"},
+	}
+	for _, tt := range tests {
+		f := &sourceFormatter{trimPrefix: tt.trimPrefix, modulePath: module}
+		if got := f.file(tt.file, tt.pkg); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSplitFuncName(t *testing.T) {
+	for function, want := range map[string][2]string{
+		"github.com/our/repo/internal/api.(*T).Get": {"github.com/our/repo/internal/api", "(*T).Get"},
+		"github.com/our/repo.Handle.func1":          {"github.com/our/repo", "Handle.func1"},
+		"main.main":                                 {"main", "main"},
+		"weird":                                     {"", "weird"},
+	} {
+		if pkg, fn := splitFuncName(function); pkg != want[0] || fn != want[1] {
+			t.Errorf("splitFuncName(%q) = %q, %q", function, pkg, fn)
+		}
+	}
+}
+
+func TestSourceFormat(t *testing.T) {
+	f := &sourceFormatter{modulePath: "github.com/ttrtcixy/fast-slog-handler"}
+	if f.format(0) != "" {
+		t.Error("zero pc has a source")
+	}
+
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	got := f.format(pcs[0])
+	if !strings.HasPrefix(got, "source_test.go:") || !strings.HasSuffix(got, " (TestSourceFormat)") {
+		t.Errorf("got %q", got)
+	}
+	if cached := f.format(pcs[0]); cached != got {
+		t.Errorf("cached %q, first %q", cached, got)
+	}
+}
+
+func TestAddSource(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewJsonHandler(&buf, &Config{AddSource: true})).Info("m")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if source, _ := record["source"].(string); !strings.HasPrefix(source, "source_test.go:") ||
+		!strings.HasSuffix(source, " (TestAddSource)") {
+		t.Errorf("source %v", record["source"])
+	}
+}
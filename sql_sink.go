@@ -0,0 +1,306 @@
+package logger
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultSQLBatchSize     = 100
+	defaultSQLFlushInterval = time.Second
+)
+
+// SQLSinkOptions controls how records are inserted.
+type SQLSinkOptions struct {
+	// INSERT statement with placeholders for the time, level, message and attrs (a JSON object) in this order,
+	// e.g. "INSERT INTO logs (time, level, msg, attrs) VALUES ($1, $2, $3, $4)" for a Postgres JSONB column
+	// or "... VALUES (?, ?, ?, ?)" for SQLite
+	Insert string
+	// count of records inserted in one transaction, 0 - 100
+	BatchSize int
+	// max time a record waits for its batch to fill up, 0 - 1s
+	FlushInterval time.Duration
+}
+
+type sqlRow struct {
+	time  time.Time
+	level string
+	msg   string
+	attrs string
+}
+
+// sqlSinkState is shared by an SQLSink and its clones.
+type sqlSinkState struct {
+	db        *sql.DB
+	insert    string
+	batchSize int
+
+	mu   sync.Mutex
+	rows []sqlRow
+	// flushMu keeps the batches in order.
+	flushMu sync.Mutex
+
+	done   chan struct{}
+	wg     sync.WaitGroup
+	closed atomic.Bool
+}
+
+// SQLSink inserts records into a database table in batched transactions, the attrs are stored as a JSON
+// object encoded like by the JSON handler. Close must be called to insert the last batch.
+type SQLSink struct {
+	handler *Handler
+	state   *sqlSinkState
+	// pipeline is the Config.Middleware chain ending with handlePrepared, nil without middleware.
+	pipeline HandleFunc
+}
+
+// NewSQLSink creates a sink inserting records with db, the writer and buffering options of cfg are ignored,
+// Config.Middleware runs before the records are encoded.
+func NewSQLSink(db *sql.DB, opts SQLSinkOptions, cfg *Config) (*SQLSink, error) {
+	if db == nil {
+		return nil, fmt.Errorf("%w: db is nil", ErrInvalidConfig)
+	}
+	if opts.Insert == "" {
+		return nil, fmt.Errorf("%w: SQLSinkOptions.Insert is empty", ErrInvalidConfig)
+	}
+	if opts.BatchSize < 0 || opts.FlushInterval < 0 {
+		return nil, fmt.Errorf("%w: BatchSize and FlushInterval must not be negative", ErrInvalidConfig)
+	}
+
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	// Records are never written, the handler only encodes them.
+	c := *cfg
+	c.BufferedOutput = false
+	c.ErrorOutput = nil
+
+	state := &sqlSinkState{
+		db:        db,
+		insert:    opts.Insert,
+		batchSize: cmp.Or(opts.BatchSize, defaultSQLBatchSize),
+		done:      make(chan struct{}),
+	}
+
+	s := (&SQLSink{handler: NewJsonHandler(io.Discard, &c), state: state}).withPipeline()
+
+	state.wg.Add(1)
+	go s.flusher(cmp.Or(opts.FlushInterval, defaultSQLFlushInterval))
+
+	return s, nil
+}
+
+func (s *SQLSink) Enabled(ctx context.Context, level slog.Level) bool {
+	return !s.state.closed.Load() && s.handler.Enabled(ctx, level)
+}
+
+// Handle queues the record, the record that fills a batch inserts it and reports its error.
+func (s *SQLSink) Handle(ctx context.Context, record slog.Record) error {
+	h := s.handler
+	if s.state.closed.Load() {
+		return ErrAlreadyClosed
+	}
+
+	if ok, err := h.prepare(ctx, &record, false); !ok {
+		return err
+	}
+
+	if s.pipeline != nil {
+		return s.pipeline(ctx, record)
+	}
+	return s.handlePrepared(ctx, record)
+}
+
+// handlePrepared queues a record that passed prepare, it's the last stage of the middleware chain.
+func (s *SQLSink) handlePrepared(ctx context.Context, record slog.Record) error {
+	h := s.handler
+
+	msg := record.Message
+	record, problems, err := h.checkEncoded(record)
+	if err != nil && len(problems) == 0 {
+		return err
+	}
+
+	rows := make([]sqlRow, 0, 2)
+	if err == nil {
+		row, encodeErr := s.row(h, record)
+		if encodeErr != nil {
+			return encodeErr
+		}
+		rows = append(rows, row)
+	}
+	if len(problems) > 0 {
+		row, encodeErr := s.row(h.topLevel(), h.misuseRecord(msg, problems))
+		if encodeErr != nil {
+			return encodeErr
+		}
+		rows = append(rows, row)
+	}
+
+	st := s.state
+	st.mu.Lock()
+	st.rows = append(st.rows, rows...)
+	full := len(st.rows) >= st.batchSize
+	st.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if !full {
+		return nil
+	}
+
+	// The batch must be inserted even if the caller's ctx is canceled right after the call.
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return s.flush(context.WithoutCancel(ctx))
+}
+
+// row encodes the columns of the record.
+func (s *SQLSink) row(h *Handler, record slog.Record) (sqlRow, error) {
+	builder := h.builder.(*jsonBuilder)
+
+	pBuf := h.getBuf()
+	buf, err := encodeWith((*pBuf)[:0], func(buf []byte) []byte {
+		return builder.appendAttrsObject(buf, record, h.precomputed, h.precomputedGroups, h.groupPrefix, h.prefix)
+	})
+	if err != nil {
+		h.shared.stats.count(err)
+		h.shared.diagnoseWrite(record, err)
+		return sqlRow{}, err
+	}
+
+	row := sqlRow{
+		time:  record.Time,
+		level: levelName(record.Level),
+		msg:   record.Message,
+		attrs: string(buf),
+	}
+
+	buf = buf[:0]
+	if msgBuf, ok := appendTemplateMessage(buf, record, appendRaw); ok {
+		row.msg = string(msgBuf)
+		buf = msgBuf
+	}
+
+	h.putBuf(pBuf, buf)
+
+	return row, nil
+}
+
+// flush inserts the queued records in one transaction.
+func (s *SQLSink) flush(ctx context.Context) error {
+	st := s.state
+
+	st.flushMu.Lock()
+	defer st.flushMu.Unlock()
+
+	st.mu.Lock()
+	rows := st.rows
+	st.rows = nil
+	st.mu.Unlock()
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	err := st.insertRows(ctx, rows)
+
+	stats := &s.handler.shared.stats
+	for range rows {
+		stats.count(err)
+	}
+
+	return err
+}
+
+func (st *sqlSinkState) insertRows(ctx context.Context, rows []sqlRow) (err error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, st.insert)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err = stmt.ExecContext(ctx, row.time, row.level, row.msg, row.attrs); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// flusher inserts partial batches every interval.
+func (s *SQLSink) flusher(interval time.Duration) {
+	defer s.state.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.state.done:
+			return
+		case <-ticker.C:
+			_ = s.flush(context.Background())
+		}
+	}
+}
+
+// Close stops the flusher and inserts the queued records, it doesn't close the db.
+func (s *SQLSink) Close(ctx context.Context) error {
+	if !s.state.closed.CompareAndSwap(false, true) {
+		return ErrAlreadyClosed
+	}
+
+	close(s.state.done)
+	s.state.wg.Wait()
+
+	return s.flush(ctx)
+}
+
+func (s *SQLSink) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return s
+	}
+	return (&SQLSink{handler: s.handler.WithAttrs(attrs).(*Handler), state: s.state}).withPipeline()
+}
+
+func (s *SQLSink) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return s
+	}
+	return (&SQLSink{handler: s.handler.WithGroup(name).(*Handler), state: s.state}).withPipeline()
+}
+
+// withPipeline builds the middleware chain of the sink.
+func (s *SQLSink) withPipeline() *SQLSink {
+	s.pipeline = s.handler.pipelineTo(s.handlePrepared)
+	return s
+}
+
+// Stats returns the counters of inserted (Written) and failed (WriteErrors) records.
+func (s *SQLSink) Stats() Stats {
+	return s.handler.Stats()
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rowsDriver keeps the args of every statement executed in a committed transaction.
+type rowsDriver struct {
+	mu        sync.Mutex
+	committed [][]driver.Value
+	commits   int
+}
+
+func (d *rowsDriver) Open(string) (driver.Conn, error) { return &rowsConn{d: d}, nil }
+
+type rowsConn struct {
+	d       *rowsDriver
+	pending [][]driver.Value
+}
+
+func (c *rowsConn) Prepare(string) (driver.Stmt, error) { return &rowsStmt{c: c}, nil }
+func (c *rowsConn) Close() error                        { return nil }
+func (c *rowsConn) Begin() (driver.Tx, error)           { return c, nil }
+
+func (c *rowsConn) Commit() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+
+	c.d.committed = append(c.d.committed, c.pending...)
+	c.d.commits++
+	c.pending = nil
+	return nil
+}
+
+func (c *rowsConn) Rollback() error {
+	c.pending = nil
+	return nil
+}
+
+type rowsStmt struct{ c *rowsConn }
+
+func (s *rowsStmt) Close() error  { return nil }
+func (s *rowsStmt) NumInput() int { return 4 }
+
+func (s *rowsStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.c.pending = append(s.c.pending, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *rowsStmt) Query([]driver.Value) (driver.Rows, error) { return nil, driver.ErrSkip }
+
+func TestSQLSink(t *testing.T) {
+	d := &rowsDriver{}
+	sql.Register("logger-rows", d)
+	db, err := sql.Open("logger-rows", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sink, err := NewSQLSink(db, SQLSinkOptions{Insert: "INSERT INTO logs VALUES (?, ?, ?, ?)", BatchSize: 2, FlushInterval: time.Hour}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := slog.New(sink).With("service", "api").WithGroup("req")
+	l.Info("a", "id", 1)
+	l.Warn("b")
+	slog.New(sink).Error("c")
+
+	if err = sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.commits != 2 || len(d.committed) != 3 {
+		t.Fatalf("%d commits of %d rows, want 2 of 3", d.commits, len(d.committed))
+	}
+	for i, want := range [][2]string{
+		{"INFO", `{"service":"api","req":{"id":1}}`},
+		{"WARN", `{"service":"api"}`},
+		{"ERROR", `{}`},
+	} {
+		row := d.committed[i]
+		if row[1] != want[0] || row[3] != want[1] {
+			t.Errorf("row %d = %v, want level %s and attrs %s", i, row, want[0], want[1])
+		}
+	}
+	if stats := sink.Stats(); stats.Written != 3 {
+		t.Errorf("written %d, want 3", stats.Written)
+	}
+}
+
+func TestSQLSinkRedaction(t *testing.T) {
+	d := &rowsDriver{}
+	sql.Register("logger-rows-redaction", d)
+	db, err := sql.Open("logger-rows-redaction", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sink, err := NewSQLSink(db, SQLSinkOptions{Insert: "INSERT INTO logs VALUES (?, ?, ?, ?)", FlushInterval: time.Hour},
+		&Config{ReplaceAttr: RedactPaths("req.password", "req.token")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slog.New(sink).WithGroup("req").Info("login", "password", "hunter2", "token", "t0k")
+	if err = sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.committed) != 1 || d.committed[0][3] != `{"req":{"password":"[REDACTED]","token":"[REDACTED]"}}` {
+		t.Errorf("rows %v", d.committed)
+	}
+}
@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"log/slog"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/ttrtcixy/fast-slog-handler/internal/escape"
+)
+
+const (
+	// key of the attr added to records >= Config.StackTraceLevel
+	stackKey = "stack"
+	// max count of frames in a stack trace
+	maxStackFrames = 32
+)
+
+// stackTrace is the value of the stack attr, the builders render it as an array of frames in JSON
+// and as an indented multiline block in text.
+type stackTrace []runtime.Frame
+
+// captureStack returns the stack of the current goroutine starting at the logging call site (pc of the record).
+// Frames of slog and the handler itself are trimmed, as well as the runtime frames at the bottom.
+func captureStack(pc uintptr) stackTrace {
+	var pcs [maxStackFrames + 16]uintptr
+	// skip [runtime.Callers, captureStack]
+	n := runtime.Callers(2, pcs[:])
+
+	var site runtime.Frame
+	if pc != 0 {
+		site, _ = runtime.CallersFrames([]uintptr{pc}).Next()
+	}
+
+	frames := runtime.CallersFrames(pcs[:n])
+	stack := make(stackTrace, 0, n)
+	found := false
+
+	for {
+		frame, more := frames.Next()
+
+		switch {
+		case found:
+			stack = append(stack, frame)
+		case pc != 0:
+			found = frame.Function == site.Function && frame.Line == site.Line
+			if found {
+				stack = append(stack, frame)
+			}
+		default:
+			// Without pc the stack starts at the first frame outside slog and this package.
+			found = !isLoggerFrame(frame.Function)
+			if found {
+				stack = append(stack, frame)
+			}
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	// The call site is not in the captured stack (e.g. record forwarded from another goroutine).
+	if len(stack) == 0 && pc != 0 {
+		stack = append(stack, site)
+	}
+
+	// Drop runtime.main/runtime.goexit.
+	for len(stack) > 1 && strings.HasPrefix(stack[len(stack)-1].Function, "runtime.") {
+		stack = stack[:len(stack)-1]
+	}
+
+	if len(stack) > maxStackFrames {
+		stack = stack[:maxStackFrames]
+	}
+
+	return stack
+}
+
+// loggerFramePrefix is the prefix of the functions of this package, taken from its import path,
+// so a fork or a vendored copy trims its own frames.
+var loggerFramePrefix = reflect.TypeFor[stackTrace]().PkgPath() + "."
+
+func isLoggerFrame(function string) bool {
+	return strings.HasPrefix(function, "log/slog.") || strings.HasPrefix(function, loggerFramePrefix)
+}
+
+// appendJSON appends the frames as [{"function":"","file":"","line":1}].
+func (s stackTrace) appendJSON(buf []byte) []byte {
+	buf = append(buf, '[')
+	for i, frame := range s {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, `{"function":"`...)
+		buf = escape.AppendJSON(buf, frame.Function)
+		buf = append(buf, `","file":"`...)
+		buf = escape.AppendJSON(buf, frame.File)
+		buf = append(buf, `","line":`...)
+		buf = strconv.AppendInt(buf, int64(frame.Line), 10)
+		buf = append(buf, '}')
+	}
+	return append(buf, ']')
+}
+
+// appendText appends the frames like a panic trace, every frame on its own lines.
+func (s stackTrace) appendText(buf []byte) []byte {
+	for _, frame := range s {
+		buf = append(buf, "\n\t"...)
+		buf = append(buf, frame.Function...)
+		buf = append(buf, "\n\t\t"...)
+		buf = append(buf, frame.File...)
+		buf = append(buf, ':')
+		buf = strconv.AppendInt(buf, int64(frame.Line), 10)
+	}
+	return buf
+}
+
+// appendQuotedText appends the frames of appendText as one quoted value, so the record stays a single line.
+func (s stackTrace) appendQuotedText(buf []byte) []byte {
+	mark := len(buf)
+	buf = s.appendText(buf)
+	text := string(buf[mark:])
+	return strconv.AppendQuote(buf[:mark], strings.TrimPrefix(text, "\n\t"))
+}
+
+// addStackTrace adds the stack attr if the record level is >= the configured level.
+func (h *Handler) addStackTrace(record *slog.Record) {
+	if h.shared.stackTraceLevel == nil || record.Level < h.shared.stackTraceLevel.Level() {
+		return
+	}
+
+	record.AddAttrs(slog.Any(stackKey, captureStack(record.PC)))
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestIsLoggerFrame(t *testing.T) {
+	for function, want := range map[string]bool{
+		"log/slog.(*Logger).log":                                         true,
+		"github.com/ttrtcixy/fast-slog-handler.(*Handler).Handle":        true,
+		"github.com/ttrtcixy/fast-slog-handler/loghttp.Middleware.func1": false,
+		"main.main": false,
+	} {
+		if got := isLoggerFrame(function); got != want {
+			t.Errorf("isLoggerFrame(%q) = %t", function, got)
+		}
+	}
+}
+
+// stackAt captures the stack at its call site, like a record PC of the caller.
+func stackAt() (stackTrace, uintptr) {
+	var pcs [1]uintptr
+	// skip [runtime.Callers, stackAt]
+	runtime.Callers(2, pcs[:])
+	return captureStack(pcs[0]), pcs[0]
+}
+
+func TestCaptureStack(t *testing.T) {
+	stack, pc := stackAt()
+	if len(stack) == 0 || !strings.HasSuffix(stack[0].Function, ".TestCaptureStack") {
+		t.Fatalf("stack %v", stack)
+	}
+	for _, frame := range stack {
+		if strings.HasSuffix(frame.Function, ".stackAt") {
+			t.Errorf("frame above the call site kept: %v", stack)
+		}
+	}
+
+	// A call site outside the stack (a record forwarded from another goroutine) is the only frame.
+	done := make(chan stackTrace)
+	go func() { done <- captureStack(pc) }()
+	if forwarded := <-done; len(forwarded) != 1 || !strings.HasSuffix(forwarded[0].Function, ".TestCaptureStack") {
+		t.Errorf("forwarded stack %v", forwarded)
+	}
+}
+
+func TestStackTraceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewJsonHandler(&buf, &Config{StackTraceLevel: slog.LevelError})).Error("m")
+
+	var record struct {
+		Stack []struct {
+			Function string `json:"function"`
+			File     string `json:"file"`
+			Line     int    `json:"line"`
+		} `json:"stack"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if len(record.Stack) == 0 || !strings.HasSuffix(record.Stack[0].Function, ".TestStackTraceAttr") ||
+		!strings.HasSuffix(record.Stack[0].File, "stack_test.go") {
+		t.Errorf("stack %+v", record.Stack)
+	}
+
+	buf.Reset()
+	slog.New(NewJsonHandler(&buf, &Config{StackTraceLevel: slog.LevelError})).Warn("m")
+	if strings.Contains(buf.String(), stackKey) {
+		t.Errorf("stack below the level: %s", buf.String())
+	}
+}
+
+func TestTextStackWithHashChain(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewTextHandler(&buf, &Config{StackTraceLevel: slog.LevelError}))
+	l.Error("m")
+	if !strings.Contains(buf.String(), "\n\t") {
+		t.Errorf("text stack is not a block: %q", buf.String())
+	}
+
+	// A hash chain is verified line by line, the stack is one quoted value.
+	buf.Reset()
+	l = slog.New(NewTextHandler(&buf, &Config{StackTraceLevel: slog.LevelError, HashChain: true}))
+	l.Error("m")
+	l.Info("next")
+	if got := ansiRe.ReplaceAllString(buf.String(), ""); strings.Count(got, "\n") != 2 || !strings.Contains(got, `stack="`) {
+		t.Errorf("output %q", got)
+	}
+	if _, err := VerifyHashChain(&buf); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// Stats holds the counters of a handler, they are shared by all its clones.
+type Stats struct {
+	// records written to the output (or its buffer).
+	Written uint64
+	// records dropped because ctx was done (Config.DropOnCtxDone) or the output wasn't available within WriteTimeout.
+	Dropped uint64
+	// records the writer failed to write (or the builder failed to encode).
+	WriteErrors uint64
+	// encode and write latency per level, nil if Config.ProfileLatency is disabled.
+	Latency []LevelLatency
+}
+
+type stats struct {
+	written     atomic.Uint64
+	dropped     atomic.Uint64
+	writeErrors atomic.Uint64
+
+	// latency is nil if Config.ProfileLatency is disabled.
+	latency *latencyStats
+}
+
+// Stats returns a snapshot of the handler counters.
+func (h *Handler) Stats() Stats {
+	s := &h.shared.stats
+	stats := Stats{
+		Written:     s.written.Load(),
+		Dropped:     s.dropped.Load(),
+		WriteErrors: s.writeErrors.Load(),
+	}
+
+	if s.latency != nil {
+		stats.Latency = s.latency.snapshot()
+	}
+
+	return stats
+}
+
+// count updates the counters with the result of a write.
+func (s *stats) count(err error) {
+	switch {
+	case err == nil:
+		s.written.Add(1)
+	case errors.Is(err, ErrRecordDropped):
+		s.dropped.Add(1)
+	default:
+		s.writeErrors.Add(1)
+	}
+}
@@ -0,0 +1,362 @@
+package logger
+
+import (
+	"encoding"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ttrtcixy/fast-slog-handler/internal/escape"
+)
+
+// maxStructDepth limits recursion of the struct encoder, pointer cycles fall back to json.Marshal which reports them.
+const maxStructDepth = 32
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	isZeroerType      = reflect.TypeFor[interface{ IsZero() bool }]()
+)
+
+// structCache stores *structInfo for every struct type seen by appendStruct.
+var structCache sync.Map
+
+// structField holds the precomputed encoding metadata of a single exported field.
+type structField struct {
+	index int
+	// key is the already escaped `"name":` prefix.
+	key       string
+	omitEmpty bool
+	omitZero  bool
+	// marshaler reports that the field type has its own json/text encoding and must go through json.Marshal.
+	marshaler bool
+	// addrMarshaler reports that only the pointer to the field type has it, it's used if the field is addressable.
+	addrMarshaler bool
+}
+
+type structInfo struct {
+	// supported is false for structs the encoder can't reproduce exactly (embedded fields, ",string" option, etc.).
+	supported bool
+	fields    []structField
+}
+
+// appendStruct appends the JSON encoding of a struct (or pointer to struct) directly to buf.
+// It reports false if v is not a struct or can't be encoded without json.Marshal, in that case buf is unchanged.
+func appendStruct(buf []byte, v any) ([]byte, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+			return buf, false
+		}
+	} else if rv.Kind() != reflect.Struct {
+		return buf, false
+	}
+
+	if implementsMarshaler(rv.Type()) {
+		return buf, false
+	}
+
+	start := len(buf)
+	buf, ok := appendReflectValue(buf, rv, 0)
+	if !ok {
+		return buf[:start], false
+	}
+
+	return buf, true
+}
+
+func appendReflectValue(buf []byte, rv reflect.Value, depth int) ([]byte, bool) {
+	if depth > maxStructDepth {
+		return buf, false
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.AppendBool(buf, rv.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(buf, rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(buf, rv.Uint(), 10), true
+	case reflect.Float32:
+		return appendJSONFloat(buf, rv.Float(), 32)
+	case reflect.Float64:
+		return appendJSONFloat(buf, rv.Float(), 64)
+	case reflect.String:
+		buf = append(buf, '"')
+		buf = appendHTMLEscapedJSON(buf, rv.String())
+		return append(buf, '"'), true
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return append(buf, "null"...), true
+		}
+		elem := rv.Elem()
+		if implementsMarshaler(elem.Type()) {
+			return appendMarshaled(buf, elem)
+		}
+		// The elem of a pointer is addressable, encoding/json calls the pointer methods.
+		if rv.Kind() == reflect.Pointer && addrMarshaler(elem.Type()) {
+			return appendMarshaled(buf, elem)
+		}
+		return appendReflectValue(buf, elem, depth+1)
+	case reflect.Struct:
+		return appendReflectStruct(buf, rv, depth)
+	case reflect.Slice:
+		if rv.IsNil() {
+			return append(buf, "null"...), true
+		}
+		// []byte is base64 encoded by encoding/json.
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return appendMarshaled(buf, rv)
+		}
+		return appendReflectList(buf, rv, depth)
+	case reflect.Array:
+		return appendReflectList(buf, rv, depth)
+	default:
+		// Maps are sorted by encoding/json, other kinds are not supported by it at all.
+		return appendMarshaled(buf, rv)
+	}
+}
+
+func appendReflectStruct(buf []byte, rv reflect.Value, depth int) ([]byte, bool) {
+	info := cachedStructInfo(rv.Type())
+	if !info.supported {
+		return appendMarshaled(buf, rv)
+	}
+
+	buf = append(buf, '{')
+
+	var isFirst = true
+	var ok bool
+	for i := range info.fields {
+		f := &info.fields[i]
+		fv := rv.Field(f.index)
+
+		if f.omitEmpty && isEmptyValue(fv) || f.omitZero && fv.IsZero() {
+			continue
+		}
+
+		if !isFirst {
+			buf = append(buf, ',')
+		} else {
+			isFirst = false
+		}
+		buf = append(buf, f.key...)
+
+		if f.marshaler || f.addrMarshaler && fv.CanAddr() {
+			buf, ok = appendMarshaled(buf, fv)
+		} else {
+			buf, ok = appendReflectValue(buf, fv, depth+1)
+		}
+		if !ok {
+			return buf, false
+		}
+	}
+
+	return append(buf, '}'), true
+}
+
+func appendReflectList(buf []byte, rv reflect.Value, depth int) ([]byte, bool) {
+	elemMarshaler := implementsMarshaler(rv.Type().Elem())
+	elemAddrMarshaler := addrMarshaler(rv.Type().Elem())
+
+	buf = append(buf, '[')
+
+	var ok bool
+	for i := range rv.Len() {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+
+		if elem := rv.Index(i); elemMarshaler || elemAddrMarshaler && elem.CanAddr() {
+			buf, ok = appendMarshaled(buf, elem)
+		} else {
+			buf, ok = appendReflectValue(buf, elem, depth+1)
+		}
+		if !ok {
+			return buf, false
+		}
+	}
+
+	return append(buf, ']'), true
+}
+
+// appendMarshaled is the slow path for values the encoder doesn't handle itself.
+// Addressable values are marshaled by pointer, so encoding/json finds the pointer methods like it would.
+func appendMarshaled(buf []byte, rv reflect.Value) ([]byte, bool) {
+	if !rv.CanInterface() {
+		return buf, false
+	}
+	if rv.CanAddr() {
+		rv = rv.Addr()
+	}
+
+	b, err := json.Marshal(rv.Interface())
+	if err != nil {
+		return buf, false
+	}
+
+	return append(buf, b...), true
+}
+
+func cachedStructInfo(t reflect.Type) *structInfo {
+	if info, ok := structCache.Load(t); ok {
+		return info.(*structInfo)
+	}
+
+	info, _ := structCache.LoadOrStore(t, newStructInfo(t))
+	return info.(*structInfo)
+}
+
+func newStructInfo(t reflect.Type) *structInfo {
+	info := &structInfo{supported: true}
+
+	for i := range t.NumField() {
+		sf := t.Field(i)
+
+		// Promotion rules of embedded fields are left to encoding/json.
+		if sf.Anonymous {
+			return &structInfo{}
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if !isValidTagName(name) {
+			name = sf.Name
+		}
+
+		f := structField{
+			index:         i,
+			marshaler:     implementsMarshaler(sf.Type),
+			addrMarshaler: addrMarshaler(sf.Type),
+		}
+
+		for opts != "" {
+			var opt string
+			opt, opts, _ = strings.Cut(opts, ",")
+
+			switch opt {
+			case "omitempty":
+				f.omitEmpty = true
+			case "omitzero":
+				// A custom IsZero method is honored by encoding/json only.
+				if sf.Type.Implements(isZeroerType) {
+					return &structInfo{}
+				}
+				f.omitZero = true
+			case "string":
+				return &structInfo{}
+			}
+		}
+
+		key := make([]byte, 0, len(name)+3)
+		key = append(key, '"')
+		key = appendHTMLEscapedJSON(key, name)
+		key = append(key, `":`...)
+		f.key = string(key)
+
+		info.fields = append(info.fields, f)
+	}
+
+	return info
+}
+
+// appendHTMLEscapedJSON appends s like escape.AppendJSON and escapes <, > and & as well, like json.Marshal does,
+// so the strings of a struct match the ones of the maps and marshalers in it.
+func appendHTMLEscapedJSON(buf []byte, s string) []byte {
+	for {
+		i := strings.IndexAny(s, "<>&")
+		if i < 0 {
+			return escape.AppendJSON(buf, s)
+		}
+
+		buf = escape.AppendJSON(buf, s[:i])
+		buf = append(buf, '\\', 'u', '0', '0', hex[s[i]>>4], hex[s[i]&0xF])
+		s = s[i+1:]
+	}
+}
+
+func implementsMarshaler(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+// addrMarshaler reports that *t has its own json/text encoding and t doesn't, encoding/json uses it
+// for addressable values only (fields of a struct behind a pointer, slice elements).
+func addrMarshaler(t reflect.Type) bool {
+	return t.Kind() != reflect.Pointer && !implementsMarshaler(t) && implementsMarshaler(reflect.PointerTo(t))
+}
+
+// isEmptyValue - From stdlib.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// isValidTagName - From stdlib.
+func isValidTagName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+			// Backslash and quote chars are reserved, but
+			// otherwise any punctuation chars are allowed
+			// in a tag name.
+		case !isLetterOrDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+func isLetterOrDigit(c rune) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c > 127
+}
+
+// appendJSONFloat - From stdlib, encodes floats the same way as encoding/json.
+func appendJSONFloat(buf []byte, f float64, bits int) ([]byte, bool) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return buf, false
+	}
+
+	// Convert as if by ES6 number to string conversion.
+	// This matches most other JSON generators.
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	buf = strconv.AppendFloat(buf, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(buf)
+		if n >= 4 && buf[n-4] == 'e' && buf[n-3] == '-' && buf[n-2] == '0' {
+			buf[n-2] = buf[n-1]
+			buf = buf[:n-1]
+		}
+	}
+
+	return buf, true
+}
@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type testAddress struct {
+	City  string `json:"city"`
+	Zip   string `json:"zip,omitempty"`
+	Lines []string
+}
+
+type testUser struct {
+	ID       int64             `json:"id"`
+	Name     string            `json:"name"`
+	Score    float64           `json:"score"`
+	Ratio    float32           `json:"ratio"`
+	Active   bool              `json:"active"`
+	Secret   string            `json:"-"`
+	Address  *testAddress      `json:"address"`
+	Previous *testAddress      `json:"previous"`
+	Tags     map[string]string `json:"tags"`
+	Created  time.Time         `json:"created"`
+	Timeout  time.Duration     `json:"timeout,omitzero"`
+	Extra    any               `json:"extra"`
+	Raw      []byte            `json:"raw"`
+	hidden   int
+}
+
+type testEmbedded struct {
+	testAddress
+	Name string
+}
+
+type testNode struct {
+	Value int
+	Next  *testNode
+}
+
+// testPtrMarshaler has MarshalJSON on the pointer receiver, encoding/json calls it for addressable values only.
+type testPtrMarshaler struct {
+	N int
+}
+
+func (m *testPtrMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`"custom"`), nil
+}
+
+type testPtrHolder struct {
+	Value   testPtrMarshaler
+	Pointer *testPtrMarshaler
+	Slice   []testPtrMarshaler
+	Array   [1]testPtrMarshaler
+}
+
+func TestAppendStructMatchesJSONMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{"empty", struct{}{}},
+		{"user", testUser{
+			ID:      42,
+			Name:    "qwe \"tte\"\n",
+			Score:   1e-7,
+			Ratio:   0.1,
+			Active:  true,
+			Secret:  "secret",
+			Address: &testAddress{City: "Berlin", Lines: []string{"a", "b"}},
+			Tags:    map[string]string{"b": "2", "a": "1"},
+			Created: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+			Extra:   []int{1, 2, 3},
+			Raw:     []byte("raw"),
+			hidden:  1,
+		}},
+		{"pointer", &testAddress{City: "Paris", Zip: "75001"}},
+		// encoding/json escapes <, > and & in strings and keys, also of the maps in the struct.
+		{"html", struct {
+			Text string            `json:"a<b>&c"`
+			Tags map[string]string `json:"tags"`
+		}{Text: "<script>&amp;", Tags: map[string]string{"<k>": "&v"}}},
+		{"embedded", testEmbedded{testAddress: testAddress{City: "Rome"}, Name: "n"}},
+		{"list", testNode{Value: 1, Next: &testNode{Value: 2}}},
+		// Fields of a struct behind a pointer are addressable, of a struct value they aren't.
+		{"pointer marshaler", &testPtrHolder{Pointer: &testPtrMarshaler{}, Slice: make([]testPtrMarshaler, 1)}},
+		{"pointer marshaler value", testPtrHolder{Pointer: &testPtrMarshaler{}, Slice: make([]testPtrMarshaler, 1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := json.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, ok := appendStruct(nil, tt.v)
+			if !ok {
+				// Unsupported structs must fall back to json.Marshal.
+				if tt.name != "embedded" {
+					t.Fatalf("appendStruct(%s) not supported", tt.name)
+				}
+				return
+			}
+
+			if string(got) != string(want) {
+				t.Errorf("appendStruct(%s)\n got: %s\nwant: %s", tt.name, got, want)
+			}
+		})
+	}
+}
+
+func TestAppendStructRejectsNonStruct(t *testing.T) {
+	for _, v := range []any{1, "str", []int{1}, map[string]int{}, (*testAddress)(nil), time.Now()} {
+		if buf, ok := appendStruct([]byte("prefix"), v); ok || string(buf) != "prefix" {
+			t.Errorf("appendStruct(%T) = %q, %v", v, buf, ok)
+		}
+	}
+}
+
+func BenchmarkAppendStruct(b *testing.B) {
+	v := testAddress{City: "Berlin", Zip: "10115", Lines: []string{"a", "b"}}
+	buf := make([]byte, 0, 256)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		buf, _ = appendStruct(buf[:0], v)
+	}
+}
@@ -0,0 +1,84 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"bytes"
+	"io"
+	"log/syslog"
+	"strconv"
+)
+
+// openSyslog connects to the syslog daemon, every record is sent as a message with the severity
+// MapLevel(SeveritySyslog, level) of its level. The handler writes it as the Config.PriorityPrefix of the line.
+func openSyslog(network, address, tag string) (io.WriteCloser, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogWriter{Writer: w, send: severitySender(w)}, nil
+}
+
+// syslogWriter sends every line with the severity of its priority prefix, lines without one are INFO.
+type syslogWriter struct {
+	*syslog.Writer
+	// send writes the message with the syslog severity (0 - emerg to 7 - debug).
+	send func(severity int, msg string) error
+}
+
+// Write sends every line of p as a message, a buffered handler may write several records at once.
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	for start := 0; start < len(p); {
+		end := bytes.IndexByte(p[start:], '\n')
+		if end < 0 {
+			end = len(p)
+		} else {
+			end += start
+		}
+
+		if end > start {
+			severity, msg := splitPriority(p[start:end])
+			if err := w.send(severity, string(msg)); err != nil {
+				return start, err
+			}
+		}
+		start = end + 1
+	}
+
+	return len(p), nil
+}
+
+// splitPriority cuts the "<N>" priority prefix off the line.
+func splitPriority(line []byte) (severity int, msg []byte) {
+	if len(line) >= 3 && line[0] == '<' {
+		if end := bytes.IndexByte(line, '>'); end > 1 {
+			if n, err := strconv.Atoi(string(line[1:end])); err == nil && n >= 0 && n <= 7 {
+				return n, line[end+1:]
+			}
+		}
+	}
+	return int(syslog.LOG_INFO), line
+}
+
+func severitySender(w *syslog.Writer) func(int, string) error {
+	return func(severity int, msg string) error {
+		switch syslog.Priority(severity) {
+		case syslog.LOG_EMERG:
+			return w.Emerg(msg)
+		case syslog.LOG_ALERT:
+			return w.Alert(msg)
+		case syslog.LOG_CRIT:
+			return w.Crit(msg)
+		case syslog.LOG_ERR:
+			return w.Err(msg)
+		case syslog.LOG_WARNING:
+			return w.Warning(msg)
+		case syslog.LOG_NOTICE:
+			return w.Notice(msg)
+		case syslog.LOG_DEBUG:
+			return w.Debug(msg)
+		default:
+			return w.Info(msg)
+		}
+	}
+}
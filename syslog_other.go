@@ -0,0 +1,12 @@
+//go:build windows || plan9
+
+package logger
+
+import (
+	"fmt"
+	"io"
+)
+
+func openSyslog(_, _, _ string) (io.WriteCloser, error) {
+	return nil, fmt.Errorf("%w: syslog output is not supported on this platform", ErrInvalidConfig)
+}
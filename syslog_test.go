@@ -0,0 +1,40 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+func TestSyslogWriterSeverity(t *testing.T) {
+	var sent []string
+	w := &syslogWriter{send: func(severity int, msg string) error {
+		sent = append(sent, fmt.Sprintf("%d %s", severity, msg))
+		return nil
+	}}
+
+	var buf bytes.Buffer
+	l := slog.New(NewTextHandler(&buf, &Config{Level: int(slog.LevelDebug), PriorityPrefix: true, TimeFormat: "-"}))
+	l.Debug("d")
+	l.Info("i")
+	l.Warn("w")
+	l.Error("e")
+
+	// A buffered handler writes the records at once, the line without a prefix is INFO.
+	if _, err := w.Write(append(buf.Bytes(), "plain\n"...)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int{7, 6, 4, 3, 6}
+	if len(sent) != len(want) {
+		t.Fatalf("sent %q", sent)
+	}
+	for i, severity := range want {
+		if prefix := fmt.Sprintf("%d ", severity); sent[i][:len(prefix)] != prefix || sent[i][len(prefix)] == '<' {
+			t.Errorf("message %d: %q, want severity %d", i, sent[i], severity)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// LeveledChild is an output of a TeeHandler.
+type LeveledChild struct {
+	// destination of the records
+	W io.Writer
+	// minimal level of the records written to W, nil - slog.LevelInfo
+	Level slog.Leveler
+	// json or text, default - json
+	Format string
+	// encoder options, the writer, buffering and Middleware options are ignored; children with the same Format
+	// and Config pointer share one encoding of every record, nil - defaults
+	Config *Config
+}
+
+type teeChild struct {
+	level slog.Leveler
+	out   *output
+}
+
+// teeEncoder encodes the records once for all of its children.
+type teeEncoder struct {
+	handler  *Handler
+	children []teeChild
+}
+
+// TeeHandler writes every record to all children enabled for its level, the record is encoded
+// at most once per distinct format instead of once per child.
+type TeeHandler struct {
+	encoders []teeEncoder
+}
+
+// NewTeeHandler creates a handler writing to the children.
+func NewTeeHandler(children ...LeveledChild) (*TeeHandler, error) {
+	type encoderKey struct {
+		format string
+		cfg    *Config
+	}
+
+	t := &TeeHandler{}
+	index := make(map[encoderKey]int)
+
+	for i, child := range children {
+		if child.W == nil {
+			return nil, fmt.Errorf("%w: child %d", ErrNilWriter, i)
+		}
+
+		level := child.Level
+		if level == nil {
+			level = slog.LevelInfo
+		}
+
+		key := encoderKey{format: child.Format, cfg: child.Config}
+		n, ok := index[key]
+		if !ok {
+			cfg := &Config{}
+			if child.Config != nil {
+				cfg = child.Config
+			}
+
+			// Records are written by the tee, the inner handler only encodes them. Its level is the
+			// lowest one, so it never rejects a record a child wants.
+			c := *cfg
+			c.Level = int(LevelTrace)
+			c.BufferedOutput = false
+			c.ErrorOutput = nil
+			c.ErrorOutputBuffered = false
+
+			h, err := newFormatHandler(child.Format, io.Discard, &c)
+			if err != nil {
+				return nil, fmt.Errorf("child %d: %w", i, err)
+			}
+
+			n = len(t.encoders)
+			index[key] = n
+			t.encoders = append(t.encoders, teeEncoder{handler: h})
+		}
+
+		t.encoders[n].children = append(t.encoders[n].children, teeChild{level: level, out: newOutput(child.W, false, 0, nil)})
+	}
+
+	return t, nil
+}
+
+func (t *TeeHandler) Enabled(_ context.Context, level slog.Level) bool {
+	for _, enc := range t.encoders {
+		if enc.enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *teeEncoder) enabled(level slog.Level) bool {
+	for _, child := range e.children {
+		if level >= child.level.Level() {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *TeeHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+
+	for _, enc := range t.encoders {
+		if !enc.enabled(record.Level) {
+			continue
+		}
+
+		// prepare adds the ctx attrs, every encoder gets its own copy of the record.
+		r := record
+		if len(t.encoders) > 1 {
+			r = record.Clone()
+		}
+
+		if err := enc.handle(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// handle checks the record like Handler.Handle and writes it.
+func (e *teeEncoder) handle(ctx context.Context, record slog.Record) error {
+	h := e.handler
+
+	if ok, err := h.prepare(ctx, &record, false); !ok {
+		return err
+	}
+
+	msg := record.Message
+	record, problems, err := h.checkEncoded(record)
+	if err == nil {
+		err = e.write(h, record)
+	}
+	if len(problems) > 0 {
+		if reportErr := e.write(h.topLevel(), h.misuseRecord(msg, problems)); err == nil {
+			err = reportErr
+		}
+	}
+
+	return err
+}
+
+// write encodes the record once and writes it to the enabled children.
+func (e *teeEncoder) write(h *Handler, record slog.Record) error {
+	pBuf := h.getBuf()
+	buf, err := h.encode((*pBuf)[:0], record)
+	if err != nil {
+		h.shared.stats.count(err)
+		return err
+	}
+
+	var errs []error
+	for _, child := range e.children {
+		if record.Level < child.level.Level() {
+			continue
+		}
+
+		err := child.out.write(nil, buf)
+		h.shared.stats.count(err)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	h.putBuf(pBuf, buf)
+
+	return errors.Join(errs...)
+}
+
+func (t *TeeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return t
+	}
+
+	t2 := &TeeHandler{encoders: make([]teeEncoder, len(t.encoders))}
+	for i, enc := range t.encoders {
+		t2.encoders[i] = teeEncoder{handler: enc.handler.WithAttrs(attrs).(*Handler), children: enc.children}
+	}
+	return t2
+}
+
+func (t *TeeHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return t
+	}
+
+	t2 := &TeeHandler{encoders: make([]teeEncoder, len(t.encoders))}
+	for i, enc := range t.encoders {
+		t2.encoders[i] = teeEncoder{handler: enc.handler.WithGroup(name).(*Handler), children: enc.children}
+	}
+	return t2
+}
+
+// Stats returns the counters of the children writes summed over all formats, a record written to
+// two children counts twice.
+func (t *TeeHandler) Stats() Stats {
+	var stats Stats
+	for _, enc := range t.encoders {
+		s := enc.handler.Stats()
+		stats.Written += s.Written
+		stats.Dropped += s.Dropped
+		stats.WriteErrors += s.WriteErrors
+	}
+	return stats
+}
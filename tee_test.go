@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// countingValuer counts how often the record attrs are encoded.
+type countingValuer struct{ n *atomic.Int32 }
+
+func (v countingValuer) LogValue() slog.Value {
+	v.n.Add(1)
+	return slog.StringValue("v")
+}
+
+func TestTeeHandler(t *testing.T) {
+	var debug, info, text bytes.Buffer
+	cfg := &Config{}
+
+	tee, err := NewTeeHandler(
+		LeveledChild{W: &debug, Level: slog.LevelDebug, Config: cfg},
+		LeveledChild{W: &info, Config: cfg},
+		LeveledChild{W: &text, Level: slog.LevelWarn, Format: "text"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var encoded atomic.Int32
+	l := slog.New(tee).With("svc", "api")
+	l.Debug("d")
+	l.Info("i", "v", countingValuer{&encoded})
+	l.WarnContext(context.Background(), "w")
+
+	if n := strings.Count(debug.String(), "\n"); n != 3 {
+		t.Errorf("debug child got %d records: %s", n, debug.String())
+	}
+	if debug.String()[strings.Index(debug.String(), "\n")+1:] != info.String() {
+		t.Errorf("json children differ:\n%s\n%s", debug.String(), info.String())
+	}
+	if n := strings.Count(text.String(), "\n"); n != 1 || !strings.Contains(ansiRe.ReplaceAllString(text.String(), ""), "svc=api") {
+		t.Errorf("text child %q", text.String())
+	}
+
+	// The JSON children share the encoding, the text child skips the INFO record.
+	if n := encoded.Load(); n != 1 {
+		t.Errorf("record encoded %d times", n)
+	}
+	if stats := tee.Stats(); stats.Written != 6 {
+		t.Errorf("stats %+v", stats)
+	}
+
+	if _, err := NewTeeHandler(LeveledChild{W: &text, Format: "xml"}); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestTeeHandlerSchema(t *testing.T) {
+	var out bytes.Buffer
+	tee, err := NewTeeHandler(LeveledChild{W: &out, Config: &Config{
+		ReplaceAttr: RedactPaths("password"),
+		Schema:      &Schema{Required: map[string]slog.Kind{"service": slog.KindString}, Action: SchemaReject},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := slog.New(tee)
+	l.Info("login", "service", "api", "password", "hunter2")
+	l.Info("no service")
+
+	if got := out.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, `"password":"[REDACTED]"`) {
+		t.Errorf("got %s", got)
+	}
+}
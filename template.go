@@ -0,0 +1,190 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+const (
+	// key of the attr carrying the template of a Msgf record, other handlers render it as the raw template.
+	msgTemplateKey = "msg_template"
+	// value of placeholders without an arg.
+	missingValue = "!MISSING"
+)
+
+// msgTemplate is a parsed Msgf template: literals[0] {names[0]} literals[1] ... {names[n-1]} literals[n].
+type msgTemplate struct {
+	raw      string
+	literals []string
+	names    []string
+}
+
+// templates caches parsed templates, templates are string literals, so their count is bounded.
+var templates sync.Map
+
+func (t *msgTemplate) LogValue() slog.Value {
+	return slog.StringValue(t.raw)
+}
+
+func parseTemplate(raw string) *msgTemplate {
+	if cached, ok := templates.Load(raw); ok {
+		return cached.(*msgTemplate)
+	}
+
+	t := &msgTemplate{raw: raw}
+
+	var literal strings.Builder
+	for s := raw; s != ""; {
+		i := strings.IndexAny(s, "{}")
+		if i < 0 {
+			literal.WriteString(s)
+			break
+		}
+
+		literal.WriteString(s[:i])
+		s = s[i:]
+
+		// "{{" and "}}" are escaped braces.
+		if len(s) > 1 && s[1] == s[0] {
+			literal.WriteByte(s[0])
+			s = s[2:]
+			continue
+		}
+
+		end := strings.IndexAny(s[1:], "{}") + 1
+		if s[0] == '}' || end < 2 || s[end] != '}' {
+			// A stray '}', an unclosed or empty placeholder is kept as is.
+			literal.WriteByte(s[0])
+			s = s[1:]
+			continue
+		}
+
+		t.literals = append(t.literals, literal.String())
+		t.names = append(t.names, s[1:end])
+		literal.Reset()
+		s = s[end+1:]
+	}
+	t.literals = append(t.literals, literal.String())
+
+	templates.Store(raw, t)
+	return t
+}
+
+// Msgf logs a message template: placeholders like {user_id} are replaced with args in order and also added
+// as attrs with the placeholder names, so the message stays readable and the values stay searchable.
+// Args after the placeholders are key/value pairs as in slog.Logger.Log, "{{" and "}}" write literal braces.
+//
+//	log.Msgf(ctx, slog.LevelInfo, "user {user_id} purchased {amount}", 42, 9.99)
+//	// msg="user 42 purchased 9.99" msg_template="user {user_id} purchased {amount}" user_id=42 amount=9.99
+//
+// Handlers of this package render the message, other handlers get the template as the message.
+func (l *Logger) Msgf(ctx context.Context, level slog.Level, template string, args ...any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	h := l.Handler()
+	if !h.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	// skip [runtime.Callers, Msgf]
+	runtime.Callers(2, pcs[:])
+
+	t := parseTemplate(template)
+
+	record := slog.NewRecord(time.Now(), level, template, pcs[0])
+	record.AddAttrs(slog.Any(msgTemplateKey, t))
+
+	for i, name := range t.names {
+		if i < len(args) {
+			record.AddAttrs(slog.Any(name, args[i]))
+		} else {
+			record.AddAttrs(slog.String(name, missingValue))
+		}
+	}
+
+	if len(args) > len(t.names) {
+		record.Add(args[len(t.names):]...)
+	}
+
+	_ = h.Handle(ctx, record)
+}
+
+// appendTemplateMessage appends the message of a Msgf record with the placeholders replaced by their attrs,
+// every part goes through escape. It reports false if the record is not a Msgf one.
+func appendTemplateMessage(buf []byte, record slog.Record, escape func([]byte, string) []byte) ([]byte, bool) {
+	// The message of a Msgf record is its template, other messages without placeholders are not searched.
+	if record.NumAttrs() == 0 || strings.IndexByte(record.Message, '{') < 0 {
+		return buf, false
+	}
+
+	var (
+		t       *msgTemplate
+		matched int
+	)
+
+	// The ctx attrs may come before the marker (Config.CtxAttrsFirst), the placeholder attrs follow it.
+	record.Attrs(func(attr slog.Attr) bool {
+		if t == nil {
+			if attr.Key == msgTemplateKey && attr.Value.Kind() == slog.KindLogValuer {
+				t, _ = attr.Value.LogValuer().(*msgTemplate)
+			}
+			if t == nil {
+				return true
+			}
+
+			buf = escape(buf, t.literals[0])
+			return len(t.names) > 0
+		}
+
+		buf = appendPlainValue(buf, attr.Value.Resolve(), escape)
+		matched++
+		buf = escape(buf, t.literals[matched])
+
+		return matched < len(t.names)
+	})
+
+	return buf, t != nil
+}
+
+// appendPlainValue appends the value as it reads in a message: strings without quotes, numbers and times as is.
+func appendPlainValue(buf []byte, v slog.Value, escape func([]byte, string) []byte) []byte {
+	if v.Kind() == slog.KindString {
+		return escape(buf, v.String())
+	}
+
+	var scratch [64]byte
+	s := scratch[:0]
+
+	switch v.Kind() {
+	case slog.KindInt64:
+		s = strconv.AppendInt(s, v.Int64(), 10)
+	case slog.KindUint64:
+		s = strconv.AppendUint(s, v.Uint64(), 10)
+	case slog.KindFloat64:
+		s = strconv.AppendFloat(s, v.Float64(), 'f', -1, 64)
+	case slog.KindBool:
+		s = strconv.AppendBool(s, v.Bool())
+	case slog.KindDuration:
+		s = appendDuration(s, v.Duration())
+	case slog.KindTime:
+		s = v.Time().AppendFormat(s, time.DateTime)
+	default:
+		s = fmt.Append(s, v.Any())
+	}
+
+	return escape(buf, unsafe.String(unsafe.SliceData(s), len(s)))
+}
+
+func appendRaw(buf []byte, s string) []byte {
+	return append(buf, s...)
+}
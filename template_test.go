@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	for _, tt := range []struct {
+		raw      string
+		literals []string
+		names    []string
+	}{
+		{"no placeholders", []string{"no placeholders"}, nil},
+		{"user {id} paid {amount}!", []string{"user ", " paid ", "!"}, []string{"id", "amount"}},
+		{"{{literal}} {x}", []string{"{literal} ", ""}, []string{"x"}},
+		{"empty {} and {unclosed", []string{"empty {} and {unclosed"}, nil},
+		{"{a {b}", []string{"{a ", ""}, []string{"b"}},
+	} {
+		p := parseTemplate(tt.raw)
+		if !slices.Equal(p.literals, tt.literals) || !slices.Equal(p.names, tt.names) {
+			t.Errorf("parseTemplate(%q) = %q %q, want %q %q", tt.raw, p.literals, p.names, tt.literals, tt.names)
+		}
+	}
+}
+
+func TestMsgf(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(NewJsonHandler(&buf, nil))
+
+	log.Msgf(context.Background(), slog.LevelInfo, `user {user_id} bought "{item}" for {amount}`, 42, "tea", 9.5, "extra", true)
+	log.Msgf(context.Background(), slog.LevelInfo, "{a} and {b}", 1)
+
+	want := []string{
+		`"msg":"user 42 bought \"tea\" for 9.5","msg_template":"user {user_id} bought \"{item}\" for {amount}","user_id":42,"item":"tea","amount":9.5,"extra":true}`,
+		`"msg":"1 and !MISSING"`,
+	}
+	for _, w := range want {
+		if !strings.Contains(buf.String(), w) {
+			t.Errorf("output %q doesn't contain %q", buf.String(), w)
+		}
+	}
+}
+
+func TestMsgfCtxAttrsFirst(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewJsonHandler(&buf, &Config{CtxAttrsFirst: true})
+	ctx := AppendAttrsToCtx(context.Background(), slog.String("trace_id", "af82"))
+
+	New(h).Msgf(ctx, slog.LevelInfo, "user {id} done", 42)
+
+	if got := buf.String(); !strings.Contains(got, `"msg":"user 42 done","trace_id":"af82","msg_template":"user {id} done","id":42}`) {
+		t.Errorf("got %s", got)
+	}
+}
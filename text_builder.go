@@ -1,15 +1,17 @@
 package logger
 
 import (
-	"bufio"
+	"cmp"
 	"encoding/json"
 	"io"
 	"log/slog"
 	"os"
+	"slices"
 	"strconv"
 	"time"
-	"unicode"
-	"unicode/utf8"
+	"unsafe"
+
+	"github.com/ttrtcixy/fast-slog-handler/internal/escape"
 )
 
 //var (
@@ -30,10 +32,42 @@ const (
 	green  = "\u001b[92m"
 	yellow = "\u001b[93m"
 	blue   = "\u001b[94m"
+	cyan   = "\u001b[96m"
 )
 
 type colorizedTextBuilder struct {
 	//colorOpts *colorOptions
+
+	// intern caches encoded string values, nil if Config.InternValues is disabled.
+	intern *internCache
+	// source renders the call site, nil if Config.AddSource is disabled.
+	source *sourceFormatter
+	// limit cuts long string values, nil if Config.MaxValueLen is not set.
+	limit *valueLimit
+	// colors are the escape sequences of the theme.
+	colors *textColors
+	// coalesceColors dims the attrs segment as a whole instead of every key.
+	coalesceColors bool
+	// withAttrsLast writes the WithAttrs attrs after the record attrs.
+	withAttrsLast bool
+	// bareFlags writes true bools as the key only.
+	bareFlags bool
+	// quoteStack writes stack traces as one quoted value instead of a multiline block (Config.HashChain).
+	quoteStack bool
+	// renames replace attr keys, nil if Config.RenameKeys is not set.
+	renames *keyRenames
+	// labels replace the level labels, nil if Config.LevelLabels is not set.
+	labels *levelLabels
+	// markers are the symbols written before levels, nil if Config.LevelMarkers is not set.
+	markers *[len(standardLevels)]string
+	// layout is the order of the line segments.
+	layout []TextSegment
+	// timeFormat is the layout of the record time, attrTimeFormat of time attrs.
+	// Both are Config.TimeFormat when it is set.
+	timeFormat     string
+	attrTimeFormat string
+	// recordTime formats the record time with timeFormat.
+	recordTime *timeCache
 }
 
 func NewTextHandler(w io.Writer, cfg *Config) *Handler {
@@ -45,45 +79,142 @@ func NewTextHandler(w io.Writer, cfg *Config) *Handler {
 		cfg = &Config{Level: 0, BufferedOutput: false}
 	}
 
+	return newHandler(w, cfg, newTextBuilder(cfg))
+}
+
+func newTextBuilder(cfg *Config) *colorizedTextBuilder {
 	textBuilder := &colorizedTextBuilder{
 		//colorOpts: newColorOptions(faint, faint),
 	}
+	if cfg.InternValues {
+		textBuilder.intern = newInternCache()
+	}
+	if cfg.AddSource {
+		textBuilder.source = newSourceFormatter(cfg.TrimSourcePrefix)
+	}
+	textBuilder.limit = newValueLimit(cfg)
+	textBuilder.withAttrsLast = cfg.WithAttrsLast
+	textBuilder.colors = colorsOf(cfg.textTheme)
+	textBuilder.coalesceColors = cfg.CoalesceColors
+	textBuilder.bareFlags = cfg.BareBoolFlags
+	textBuilder.markers = levelMarkers(cfg.LevelMarkers)
+	textBuilder.labels = newLevelLabels(cfg.LevelLabels)
+	textBuilder.layout = defaultTextLayout
+	if len(cfg.TextLayout) > 0 {
+		textBuilder.layout = slices.Clone(cfg.TextLayout)
+	}
+	textBuilder.timeFormat = cmp.Or(cfg.TimeFormat, time.Stamp)
+	textBuilder.attrTimeFormat = cmp.Or(cfg.TimeFormat, time.DateTime)
+	textBuilder.recordTime = newTimeCache(textBuilder.timeFormat)
+	textBuilder.renames = newKeyRenames(cfg)
+	// Hash chains are verified line by line.
+	textBuilder.quoteStack = cfg.HashChain
+
+	return textBuilder
+}
 
-	handler := newHandler(w, slog.Level(cfg.Level), textBuilder)
-
-	if cfg.BufferedOutput {
-		handler.shared.bw = bufio.NewWriterSize(w, writerBufSize)
-		// Start a background routine to periodically flush the buffer.
-		// This ensures logs appear even during low activity periods.
-		go handler.flusher()
+// NewTextHandlerE is like NewTextHandler but reports a nil writer or invalid config instead of replacing them with defaults.
+func NewTextHandlerE(w io.Writer, cfg *Config) (*Handler, error) {
+	if err := validate(w, cfg); err != nil {
+		return nil, err
 	}
 
-	return handler
+	return NewTextHandler(w, cfg), nil
 }
 
 func (b *colorizedTextBuilder) buildLog(
 	buf []byte,
 	record slog.Record,
 	precomputedAttrs string,
+	_ string,
 	groupPrefix string,
+	prefix string,
 ) []byte {
-	// Time
-	buf = append(buf, faint...) // color
-	buf = record.Time.AppendFormat(buf, time.Stamp)
-	buf = append(buf, reset...) // color
-	buf = append(buf, ' ')
+	start := len(buf)
+	event, record, isEvent := splitEvent(record)
 
-	// Level
-	buf = append(buf, levelColor(record.Level)...) // color
-	buf = append(buf, levelBytes(record.Level)[:4]...)
-	buf = append(buf, reset...) // color
-	buf = append(buf, ' ')
+	for i, segment := range b.layout {
+		// Attrs carry their own leading spaces.
+		if segment == SegmentAttrs {
+			buf = b.appendRecordAttrs(buf, record, precomputedAttrs, groupPrefix, i < len(b.layout)-1)
+			continue
+		}
 
-	// Message // todo if no message
-	buf = append(buf, record.Message...)
+		mark := len(buf)
+		if mark > start {
+			buf = append(buf, ' ')
+		}
+
+		switch segment {
+		case SegmentTime:
+			buf = append(buf, b.colors.faint...) // color
+			buf = b.recordTime.appendTime(buf, record.Time)
+			buf = append(buf, b.colors.reset...) // color
+		case SegmentLevel:
+			if b.markers != nil {
+				buf = append(buf, levelMarker(b.markers, record.Level)...)
+				buf = append(buf, ' ')
+			}
+			buf = append(buf, b.colors.level(record.Level)...) // color
+			if b.labels != nil {
+				buf = b.labels.appendText(buf, record.Level)
+			} else {
+				buf = appendTextLevel(buf, record.Level)
+			}
+			buf = append(buf, b.colors.reset...) // color
+		case SegmentSource:
+			source := ""
+			if b.source != nil {
+				source = b.source.format(record.PC)
+			}
+			if source == "" {
+				buf = buf[:mark]
+				continue
+			}
+			buf = append(buf, b.colors.faint...) // color
+			buf = append(buf, source...)
+			buf = append(buf, b.colors.reset...) // color
+		case SegmentMessage: // todo if no message
+			buf = append(buf, prefix...)
+			if isEvent {
+				buf = appendTextEvent(buf, event)
+			} else if msgBuf, ok := appendTemplateMessage(buf, record, appendRaw); ok {
+				buf = msgBuf
+			} else {
+				buf = append(buf, record.Message...)
+			}
+		}
+	}
+
+	// Attrs at the line start.
+	if len(buf) > start && buf[start] == ' ' {
+		buf = append(buf[:start], buf[start+1:]...)
+	}
+
+	buf = append(buf, '\n')
+	return buf
+}
+
+// appendRecordAttrs appends the WithAttrs and record attrs, bracketed puts them in [] for layouts
+// with segments after the attrs.
+func (b *colorizedTextBuilder) appendRecordAttrs(
+	buf []byte,
+	record slog.Record,
+	precomputedAttrs string,
+	groupPrefix string,
+	bracketed bool,
+) []byte {
+	mark := len(buf)
+	if bracketed {
+		buf = append(buf, " ["...)
+	}
+	if b.coalesceColors {
+		buf = append(buf, b.colors.faint...) // color
+	}
+	attrsStart := len(buf)
 
 	// Append precomputed attributes (from WithAttrs)
-	if len(precomputedAttrs) > 0 {
+	if len(precomputedAttrs) > 0 && !b.withAttrsLast {
 		buf = append(buf, precomputedAttrs...)
 	}
 	// Process dynamic attributes (attached to this specific record)
@@ -107,7 +238,24 @@ func (b *colorizedTextBuilder) buildLog(
 		})
 	}
 
-	buf = append(buf, '\n')
+	if len(precomputedAttrs) > 0 && b.withAttrsLast {
+		buf = append(buf, precomputedAttrs...)
+	}
+
+	if len(buf) == attrsStart {
+		return buf[:mark]
+	}
+
+	if bracketed {
+		// Drop the leading space of the first attr.
+		buf = append(buf[:attrsStart], buf[attrsStart+1:]...)
+	}
+	if b.coalesceColors {
+		buf = append(buf, b.colors.reset...) // color
+	}
+	if bracketed {
+		buf = append(buf, ']')
+	}
 	return buf
 }
 
@@ -118,6 +266,14 @@ func (b *colorizedTextBuilder) appendAttr(buf []byte, groupPrefix []byte, attr s
 		return buf
 	}
 
+	// Attrs from Precompile() are already encoded by this builder with the same group prefix.
+	if p, ok := precompiledFrom(attr); ok {
+		if p.builder == b && p.groupPrefix == string(groupPrefix) {
+			return append(buf, p.encoded...)
+		}
+		attr = p.inline()
+	}
+
 	// Handle nested groups by recursion: flattening keys to "prefix.key"
 	if attr.Value.Kind() == slog.KindGroup {
 		if attr.Key != "" {
@@ -132,18 +288,36 @@ func (b *colorizedTextBuilder) appendAttr(buf []byte, groupPrefix []byte, attr s
 	}
 
 	buf = append(buf, ' ')
-	buf = append(buf, faint...) // color
-
-	if len(groupPrefix) > 0 {
-		buf = append(buf, groupPrefix...)
+	if !b.coalesceColors {
+		buf = append(buf, b.colors.faint...) // color
 	}
 
 	if attr.Key == "" {
 		attr.Key = "!EMPTY_KEY"
+	} else if b.renames != nil {
+		attr.Key = b.renames.rename(groupPrefix, attr.Key)
 	}
+
+	// Keys with spaces, '=' or quotes are quoted together with the group prefix, like values.
+	mark := len(buf)
+	buf = append(buf, groupPrefix...)
 	buf = append(buf, attr.Key...)
-	buf = append(buf, '=')
-	buf = append(buf, reset...) // color
+	if escape.NeedsQuoting(unsafe.String(&buf[mark], len(buf)-mark)) {
+		key := string(buf[mark:])
+		buf = strconv.AppendQuote(buf[:mark], key)
+	}
+
+	// A true bool is written as the bare key, e.g. "retry".
+	flag := b.bareFlags && attr.Value.Kind() == slog.KindBool && attr.Value.Bool()
+	if !flag {
+		buf = append(buf, '=')
+	}
+	if !b.coalesceColors {
+		buf = append(buf, b.colors.reset...) // color
+	}
+	if flag {
+		return buf
+	}
 
 	buf = b.writeValue(buf, attr.Value)
 
@@ -167,10 +341,40 @@ func (b *colorizedTextBuilder) writeValue(buf []byte, value slog.Value) []byte {
 			buf = append(buf, "false"...)
 		}
 	case slog.KindDuration:
-		buf = append(buf, value.Duration().String()...)
+		buf = appendDuration(buf, value.Duration())
 	case slog.KindTime:
-		buf = value.Time().AppendFormat(buf, time.DateTime)
+		buf = value.Time().AppendFormat(buf, b.attrTimeFormat)
 	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return b.appendString(buf, err.Error())
+		}
+		if st, ok := value.Any().(stackTrace); ok {
+			if b.quoteStack {
+				return st.appendQuotedText(buf)
+			}
+			return st.appendText(buf)
+		}
+		if hv, ok := value.Any().(histogramValue); ok {
+			return hv.appendSparkline(buf)
+		}
+		if tv, ok := value.Any().(textValue); ok {
+			return appendPlainText(buf, tv)
+		}
+		if src, ok := anySource(value.Any()); ok {
+			mark := len(buf)
+			buf = appendSourceLine(buf, src)
+			if escape.NeedsQuoting(unsafe.String(&buf[mark], len(buf)-mark)) {
+				s := string(buf[mark:])
+				buf = strconv.AppendQuote(buf[:mark], s)
+			}
+			return buf
+		}
+		if tm, ok := anyTime(value.Any()); ok {
+			return tm.AppendFormat(buf, b.attrTimeFormat)
+		}
+		if structBuf, ok := appendStruct(buf, value.Any()); ok {
+			return structBuf
+		}
 		b, err := json.Marshal(value.Any())
 		if err != nil {
 			buf = append(buf, "!ERR_MARSHAL"...)
@@ -183,7 +387,7 @@ func (b *colorizedTextBuilder) writeValue(buf []byte, value slog.Value) []byte {
 	return buf
 }
 
-func (b *colorizedTextBuilder) precomputeAttrs(buf []byte, groupPrefix string, attrs []slog.Attr) []byte {
+func (b *colorizedTextBuilder) precomputeAttrs(buf []byte, _, groupPrefix string, attrs []slog.Attr) []byte {
 	// Prepare the current group prefix for these specific attributes.
 	var groupBuf [128]byte
 	pref := groupBuf[:0]
@@ -205,36 +409,29 @@ func (b *colorizedTextBuilder) groupPrefix(oldPrefix string, newPrefix string) s
 }
 
 func (b *colorizedTextBuilder) appendString(buf []byte, val string) []byte {
-	if val == "" {
+	if b.intern != nil {
+		if encoded, ok := b.intern.load(val); ok {
+			return append(buf, encoded...)
+		}
+	}
+
+	start := len(buf)
+	// val stays the intern key, the cache holds the encoded form of the cut value.
+	value := b.limit.truncate(val)
+
+	if value == "" {
 		buf = append(buf, "!EMPTY_VALUE"...)
 	} else {
-		if needsQuoting(val) {
-			buf = strconv.AppendQuote(buf, val)
+		if escape.NeedsQuoting(value) {
+			buf = strconv.AppendQuote(buf, value)
 		} else {
-			buf = append(buf, val...)
+			buf = append(buf, value...)
 		}
 	}
-	return buf
-}
 
-func needsQuoting(s string) bool {
-	if len(s) == 0 {
-		return true
-	}
-	for i := 0; i < len(s); {
-		b := s[i]
-		if b < utf8.RuneSelf {
-			if b != '\\' && (b == ' ' || b == '=' || !safeSet[b]) {
-				return true
-			}
-			i++
-			continue
-		}
-		r, size := utf8.DecodeRuneInString(s[i:])
-		if r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r) {
-			return true
-		}
-		i += size
+	if b.intern != nil {
+		b.intern.store(val, buf[start:])
 	}
-	return false
+
+	return buf
 }
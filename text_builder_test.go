@@ -0,0 +1,299 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var ansiRe = regexp.MustCompile("\u001b\\[[0-9;]*m")
+
+// renderText returns the attrs part of a text handler line without colors, group is applied with WithGroup.
+func renderText(t *testing.T, group string, attrs ...slog.Attr) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	handler := NewTextHandler(&buf, nil).WithGroup(group)
+
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0)
+	record.AddAttrs(attrs...)
+	if err := handler.Handle(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+
+	line := ansiRe.ReplaceAllString(buf.String(), "")
+	_, rest, _ := strings.Cut(line, " msg")
+	return strings.TrimSuffix(rest, "\n")
+}
+
+func TestTextInlineGroups(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs []slog.Attr
+		want  string
+	}{
+		{
+			name:  "inline",
+			attrs: []slog.Attr{slog.Group("", slog.String("key", "val"))},
+			want:  " key=val",
+		},
+		{
+			name:  "nested inline",
+			attrs: []slog.Attr{slog.Group("", slog.Group("", slog.String("a", "1")), slog.Int("b", 2))},
+			want:  " a=1 b=2",
+		},
+		{
+			name:  "inline inside named",
+			attrs: []slog.Attr{slog.Group("http", slog.Group("", slog.Int("status", 200)), slog.String("method", "GET"))},
+			want:  " http.status=200 http.method=GET",
+		},
+		{
+			name:  "named inside inline",
+			attrs: []slog.Attr{slog.Group("", slog.Group("db", slog.String("table", "users")))},
+			want:  " db.table=users",
+		},
+		{
+			name:  "empty inline",
+			attrs: []slog.Attr{slog.Group("", slog.Group("")), slog.String("k", "v")},
+			want:  " k=v",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderText(t, "", tt.attrs...); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextInlineGroupsWithGroup(t *testing.T) {
+	got := renderText(t, "srv", slog.Group("", slog.Group("", slog.String("a", "1"))), slog.Group("", slog.Int("b", 2)))
+	if want := " srv.a=1 srv.b=2"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestJSONInlineGroups(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewJsonHandler(&buf, nil))
+
+	logger.LogAttrs(context.Background(), slog.LevelInfo, "msg",
+		slog.Group("", slog.Group(""), slog.Group("", slog.String("a", "1"))),
+		slog.Group("g", slog.Group("")),
+		slog.Int("b", 2),
+	)
+
+	_, got, _ := strings.Cut(strings.TrimSpace(buf.String()), `"msg":"msg"`)
+	if want := `,"a":"1","b":2}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestTextLevelLabels(t *testing.T) {
+	for level, want := range map[slog.Level]string{
+		LevelTrace:          "TRAC",
+		slog.LevelError:     "ERRO",
+		slog.LevelInfo + 2:  "I+2 ",
+		slog.LevelDebug - 2: "T+2 ",
+		LevelTrace - 3:      "T-3 ",
+		slog.LevelError + 9: "E+9 ",
+		slog.Level(200):     "ERROR+192",
+	} {
+		if got := string(appendTextLevel(nil, level)); got != want {
+			t.Errorf("level %d: got %q, want %q", level, got, want)
+		}
+	}
+
+	if colorsOf(ThemeBright).level(slog.LevelWarn+1) != yellow {
+		t.Error("custom level doesn't take the color of its base level")
+	}
+}
+
+func TestTextLayout(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := &Config{
+		TextLayout: []TextSegment{SegmentLevel, SegmentTime, SegmentAttrs, SegmentMessage},
+		TimeFormat: time.TimeOnly,
+	}
+	logger := slog.New(NewTextHandler(&buf, cfg)).With("request_id", "abc")
+
+	logger.Info("started", "n", 1)
+	slog.New(NewTextHandler(&buf, cfg)).Info("no attrs")
+
+	lines := strings.Split(ansiRe.ReplaceAllString(buf.String(), ""), "\n")
+	if want := regexp.MustCompile(`^INFO \d\d:\d\d:\d\d \[request_id=abc n=1\] started$`); !want.MatchString(lines[0]) {
+		t.Errorf("unexpected line %q", lines[0])
+	}
+	if want := regexp.MustCompile(`^INFO \d\d:\d\d:\d\d no attrs$`); !want.MatchString(lines[1]) {
+		t.Errorf("unexpected line %q", lines[1])
+	}
+
+	if err := (&Config{TextLayout: []TextSegment{SegmentTime, SegmentTime}}).Validate(); err == nil {
+		t.Error("duplicate segment is accepted")
+	}
+}
+
+func TestWithPrefix(t *testing.T) {
+	var text, js bytes.Buffer
+	multi := NewMultiHandler(NewTextHandler(&text, nil), NewJsonHandler(&js, nil))
+
+	New(multi).WithPrefix("[worker-3] ").WithGroup("job").Info("done", "id", 7)
+
+	if line := ansiRe.ReplaceAllString(text.String(), ""); !strings.Contains(line, " [worker-3] done job.id=7") {
+		t.Errorf("text output %q has no prefix before the message", line)
+	}
+	if !strings.Contains(js.String(), `"msg":"done","prefix":"[worker-3]","job":{"id":7}}`) {
+		t.Errorf("json output %q has no top-level prefix attr", js.String())
+	}
+}
+
+func TestLevelMarkers(t *testing.T) {
+	for lang, want := range map[string]string{
+		"en_US.UTF-8": "⚠️ WARN",
+		"C":           "! WARN",
+	} {
+		t.Setenv("LC_ALL", lang)
+
+		var buf bytes.Buffer
+		slog.New(NewTextHandler(&buf, &Config{LevelMarkers: MarkersEmoji})).Warn("disk almost full")
+
+		if line := ansiRe.ReplaceAllString(buf.String(), ""); !strings.Contains(line, " "+want+" disk") {
+			t.Errorf("LC_ALL=%s: output %q doesn't contain %q", lang, line, want)
+		}
+	}
+}
+
+func TestCoalesceColors(t *testing.T) {
+	var plain, coalesced bytes.Buffer
+	attrs := []any{"a", 1, "b", true, "c", "x"}
+
+	slog.New(NewTextHandler(&plain, nil)).With("w", 0).Info("msg", attrs...)
+	slog.New(NewTextHandler(&coalesced, &Config{CoalesceColors: true})).With("w", 0).Info("msg", attrs...)
+
+	if got, want := ansiRe.ReplaceAllString(coalesced.String(), ""), ansiRe.ReplaceAllString(plain.String(), ""); got != want {
+		t.Errorf("text differs: %q, want %q", got, want)
+	}
+	if saved := plain.Len() - coalesced.Len(); saved != 3*8 {
+		t.Errorf("saved %d bytes, want %d", saved, 3*8)
+	}
+	if !strings.HasSuffix(coalesced.String(), faint+" w=0 a=1 b=true c=x"+reset+"\n") {
+		t.Errorf("attrs are not dimmed as one segment: %q", coalesced.String())
+	}
+}
+
+func TestTextTheme(t *testing.T) {
+	render := func(theme TextTheme, opts TextOptions) string {
+		var buf bytes.Buffer
+		opts.Theme = theme
+		h, err := NewHandler(&buf, &Config{AddSource: true}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slog.New(h).With("w", 0).Warn("msg", "a", 1)
+		return buf.String()
+	}
+
+	bright := render(ThemeBright, TextOptions{})
+	if !strings.Contains(bright, yellow+"WARN"+reset) {
+		t.Errorf("bright: %q", bright)
+	}
+	if basic := render(ThemeBasic, TextOptions{}); !strings.Contains(basic, "[33mWARN"+reset) ||
+		ansiRe.ReplaceAllString(basic, "") != ansiRe.ReplaceAllString(bright, "") {
+		t.Errorf("basic: %q", basic)
+	}
+	for _, opts := range []TextOptions{{}, {CoalesceColors: true}} {
+		if none := render(ThemeNone, opts); none != ansiRe.ReplaceAllString(bright, "") {
+			t.Errorf("none %+v: %q", opts, none)
+		}
+	}
+}
+
+func TestBareBoolFlags(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewTextHandler(&buf, &Config{BareBoolFlags: true})).Info("msg", "retry", true, "cached", false)
+
+	if got, want := ansiRe.ReplaceAllString(buf.String(), ""), "msg retry cached=false\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("output %q doesn't end with %q", got, want)
+	}
+}
+
+func TestTextQuotedKeys(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewTextHandler(&buf, &Config{CoalesceColors: true})).WithGroup("my group").Info("msg", "my key", 1, "a=b", 2, "ok", 3)
+
+	want := ` "my group.my key"=1 "my group.a=b"=2 "my group.ok"=3`
+	if got := ansiRe.ReplaceAllString(buf.String(), ""); !strings.HasSuffix(got, want+"\n") {
+		t.Errorf("output %q doesn't end with %q", got, want)
+	}
+}
+
+func TestLevelLabels(t *testing.T) {
+	labels := map[slog.Level]string{slog.LevelInfo: "ИНФО", slog.LevelWarn: "WARNING"}
+
+	var text, js bytes.Buffer
+	textLogger := slog.New(NewTextHandler(&text, &Config{LevelLabels: labels, CoalesceColors: true}))
+	textLogger.Info("a")
+	textLogger.Warn("b")
+	textLogger.Error("c")
+	slog.New(NewJsonHandler(&js, &Config{LevelLabels: labels})).Info("a")
+
+	lines := strings.Split(ansiRe.ReplaceAllString(text.String(), ""), "\n")
+	for i, want := range []string{"ИНФО    a", "WARNING b", "ERRO    c"} {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("line %q doesn't end with %q", lines[i], want)
+		}
+	}
+	if !strings.Contains(js.String(), `"level":"ИНФО","msg":"a"`) {
+		t.Errorf("json output %q doesn't use the label", js.String())
+	}
+}
+
+func BenchmarkTextHandlerManyAttrs(b *testing.B) {
+	for _, coalesce := range []bool{false, true} {
+		b.Run(fmt.Sprintf("coalesce=%t", coalesce), func(b *testing.B) {
+			var w countingWriter
+			logger := slog.New(NewTextHandler(&w, &Config{CoalesceColors: coalesce}))
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logger.LogAttrs(ctx, slog.LevelInfo, "request",
+					slog.Int("status", 200), slog.Int("bytes", 5120), slog.Bool("cached", false),
+					slog.Int("retries", 0), slog.Bool("tls", true), slog.Int64("user_id", 42),
+					slog.Int("shard", 3), slog.Bool("slow", false))
+			}
+			b.ReportMetric(float64(w.n)/float64(b.N), "bytes/record")
+		})
+	}
+}
+
+// countingWriter counts the written bytes.
+type countingWriter struct{ n int }
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return len(p), nil
+}
+
+func TestTextSourceAndAnyTime(t *testing.T) {
+	var buf, want bytes.Buffer
+	tm := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	slog.New(NewTextHandler(&buf, &Config{})).Info("m", "pt", &tm, "source", slog.Source{File: "/my app/main.go", Line: 7})
+	slog.New(NewTextHandler(&want, &Config{})).Info("m", "pt", tm)
+
+	line := ansiRe.ReplaceAllString(buf.String(), "")
+	wantLine := ansiRe.ReplaceAllString(want.String(), "")
+	wantTime := strings.TrimSpace(wantLine[strings.Index(wantLine, "pt="):])
+
+	if !strings.Contains(line, wantTime+` source="/my app/main.go:7"`) {
+		t.Errorf("output %q, want %q", line, wantTime)
+	}
+}
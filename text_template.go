@@ -0,0 +1,339 @@
+package logger
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ttrtcixy/fast-slog-handler/internal/escape"
+)
+
+// templateField is a placeholder of a line template.
+type templateField int
+
+const (
+	fieldLiteral templateField = iota
+	fieldTime
+	fieldLevel
+	fieldMessage
+	fieldSource
+	fieldAttrs
+)
+
+// templateTimeLayouts are the named layouts of {time:<name>}, other modifiers are Go layouts.
+var templateTimeLayouts = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"RFC1123":     time.RFC1123,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+	"Stamp":       time.Stamp,
+	"StampMilli":  time.StampMilli,
+	"StampMicro":  time.StampMicro,
+	"Kitchen":     time.Kitchen,
+}
+
+// templatePart is a literal or a placeholder with its modifier.
+type templatePart struct {
+	field    templateField
+	literal  string
+	modifier string
+	// time is the layout cache of {time}, nil for unix and unixms.
+	time *timeCache
+}
+
+// templateBuilder renders records with a line template compiled by NewTemplateHandler.
+type templateBuilder struct {
+	// template is the source of parts, part of the handler Fingerprint.
+	template string
+	parts    []templatePart
+	// source renders the call site, nil if Config.AddSource is disabled.
+	source *sourceFormatter
+	// labels replace the level names, nil if Config.LevelLabels is not set.
+	labels *levelLabels
+	// json encodes {attrs:json}, text the logfmt attrs (its appendAttr writes no escape codes with coalesced colors).
+	json *jsonBuilder
+	text *colorizedTextBuilder
+}
+
+// NewTemplateHandler creates a handler writing every record as the line template, e.g.
+// "{time:RFC3339} [{level}] {source} - {msg} {attrs}", so existing parsers of a legacy format keep working.
+// The template is compiled once, "{{" and "}}" write literal braces and trailing spaces of a line are trimmed.
+//
+// Placeholders and their modifiers:
+//   - {time}: Config.TimeFormat (time.DateTime if unset); {time:RFC3339}, {time:StampMilli} and the other
+//     time package layout names, {time:unix} and {time:unixms} for epoch seconds and milliseconds,
+//     anything else is a Go layout, e.g. {time:2006-01-02 15:04:05.000}
+//   - {level}: INFO, WARN, or the Config.LevelLabels label; {level:lower}, {level:short} (I, W, E, D, T)
+//     and {level:pad} (padded to 5 chars)
+//   - {msg}: the message; {msg:quote} quotes it when it contains spaces or quotes, like a logfmt value
+//   - {source}: the call site file:line, empty unless Config.AddSource is set
+//   - {attrs}: the attrs as logfmt (key=value, groups as dotted keys); {attrs:json} as a JSON object
+//
+// TextLayout and the color options are ignored.
+func NewTemplateHandler(w io.Writer, template string, cfg *Config) (*Handler, error) {
+	if w == nil {
+		w = os.Stderr
+	}
+
+	if cfg == nil {
+		cfg = &Config{Level: 0, BufferedOutput: false}
+	}
+
+	parts, err := compileLineTemplate(template, cmp.Or(cfg.TimeFormat, time.DateTime))
+	if err != nil {
+		return nil, err
+	}
+
+	builder := &templateBuilder{template: template, parts: parts}
+	if cfg.AddSource {
+		builder.source = newSourceFormatter(cfg.TrimSourcePrefix)
+	}
+	builder.labels = newLevelLabels(cfg.LevelLabels)
+
+	for _, part := range parts {
+		if part.field == fieldAttrs && part.modifier == "json" {
+			builder.json = newJSONBuilder(cfg)
+		}
+	}
+	if builder.json == nil {
+		builder.text = newTextBuilder(cfg)
+		builder.text.coalesceColors = true
+	}
+
+	return newHandler(w, cfg, builder), nil
+}
+
+// compileLineTemplate splits the template into literals and placeholders and checks the modifiers.
+func compileLineTemplate(template, defaultTimeLayout string) ([]templatePart, error) {
+	var parts []templatePart
+	var literal strings.Builder
+	attrs := false
+
+	for s := template; s != ""; {
+		i := strings.IndexAny(s, "{}")
+		if i < 0 {
+			literal.WriteString(s)
+			break
+		}
+
+		literal.WriteString(s[:i])
+		s = s[i:]
+
+		// "{{" and "}}" are escaped braces.
+		if len(s) > 1 && s[1] == s[0] {
+			literal.WriteByte(s[0])
+			s = s[2:]
+			continue
+		}
+		if s[0] == '}' {
+			return nil, fmt.Errorf("%w: template %q: unexpected '}'", ErrInvalidConfig, template)
+		}
+
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return nil, fmt.Errorf("%w: template %q: unclosed placeholder", ErrInvalidConfig, template)
+		}
+
+		part, err := compilePlaceholder(s[1:end], defaultTimeLayout)
+		if err != nil {
+			return nil, fmt.Errorf("%w: template %q: %w", ErrInvalidConfig, template, err)
+		}
+		if part.field == fieldAttrs {
+			if attrs {
+				return nil, fmt.Errorf("%w: template %q: {attrs} can be used only once", ErrInvalidConfig, template)
+			}
+			attrs = true
+		}
+
+		if literal.Len() > 0 {
+			parts = append(parts, templatePart{literal: literal.String()})
+			literal.Reset()
+		}
+		parts = append(parts, part)
+		s = s[end+1:]
+	}
+
+	if literal.Len() > 0 {
+		parts = append(parts, templatePart{literal: literal.String()})
+	}
+	return parts, nil
+}
+
+func compilePlaceholder(placeholder, defaultTimeLayout string) (templatePart, error) {
+	name, modifier, _ := strings.Cut(placeholder, ":")
+	part := templatePart{modifier: modifier}
+
+	switch name {
+	case "time":
+		part.field = fieldTime
+		switch modifier {
+		case "unix", "unixms":
+		case "":
+			part.time = newTimeCache(defaultTimeLayout)
+		default:
+			part.time = newTimeCache(cmp.Or(templateTimeLayouts[modifier], modifier))
+		}
+		return part, nil
+	case "level":
+		part.field = fieldLevel
+		switch modifier {
+		case "", "lower", "short", "pad":
+			return part, nil
+		}
+	case "msg":
+		part.field = fieldMessage
+		if modifier == "" || modifier == "quote" {
+			return part, nil
+		}
+	case "source":
+		part.field = fieldSource
+		if modifier == "" {
+			return part, nil
+		}
+	case "attrs":
+		part.field = fieldAttrs
+		if modifier == "" || modifier == "logfmt" || modifier == "json" {
+			return part, nil
+		}
+	default:
+		return part, fmt.Errorf("unknown placeholder {%s}", placeholder)
+	}
+
+	return part, fmt.Errorf("unknown modifier {%s}", placeholder)
+}
+
+func (b *templateBuilder) buildLog(
+	buf []byte,
+	record slog.Record,
+	precomputedAttrs string,
+	precomputedGroups string,
+	groupPrefix string,
+	prefix string,
+) []byte {
+	event, record, isEvent := splitEvent(record)
+
+	for _, part := range b.parts {
+		switch part.field {
+		case fieldLiteral:
+			buf = append(buf, part.literal...)
+		case fieldTime:
+			buf = part.appendTime(buf, record.Time)
+		case fieldLevel:
+			buf = b.appendLevel(buf, record.Level, part.modifier)
+		case fieldMessage:
+			mark := len(buf)
+			buf = append(buf, prefix...)
+			if isEvent {
+				buf = appendTextEvent(buf, event)
+			} else if msgBuf, ok := appendTemplateMessage(buf, record, appendRaw); ok {
+				buf = msgBuf
+			} else {
+				buf = append(buf, record.Message...)
+			}
+			if part.modifier == "quote" {
+				if msg := string(buf[mark:]); escape.NeedsQuoting(msg) {
+					buf = strconv.AppendQuote(buf[:mark], msg)
+				}
+			}
+		case fieldSource:
+			if b.source != nil {
+				buf = append(buf, b.source.format(record.PC)...)
+			}
+		case fieldAttrs:
+			buf = b.appendAttrs(buf, record, precomputedAttrs, precomputedGroups, groupPrefix)
+		}
+	}
+
+	// An empty placeholder at the end leaves its separator behind.
+	end := len(buf)
+	for end > 0 && (buf[end-1] == ' ' || buf[end-1] == '\t') {
+		end--
+	}
+	return append(buf[:end], '\n')
+}
+
+func (p *templatePart) appendTime(buf []byte, t time.Time) []byte {
+	switch p.modifier {
+	case "unix":
+		return strconv.AppendInt(buf, t.Unix(), 10)
+	case "unixms":
+		return strconv.AppendInt(buf, t.UnixMilli(), 10)
+	default:
+		return p.time.appendTime(buf, t)
+	}
+}
+
+func (b *templateBuilder) appendLevel(buf []byte, level slog.Level, modifier string) []byte {
+	name := levelName(level)
+	if b.labels != nil {
+		if label, ok := b.labels.text[level]; ok {
+			name = strings.TrimRight(label, " ")
+		}
+	}
+
+	switch modifier {
+	case "lower":
+		return append(buf, strings.ToLower(name)...)
+	case "short":
+		return append(buf, name[0])
+	case "pad":
+		buf = append(buf, name...)
+		for n := len(name); n < 5; n++ {
+			buf = append(buf, ' ')
+		}
+		return buf
+	default:
+		return append(buf, name...)
+	}
+}
+
+// appendAttrs appends the WithAttrs and record attrs as logfmt without a leading space or as a JSON object.
+func (b *templateBuilder) appendAttrs(buf []byte, record slog.Record, precomputedAttrs, precomputedGroups, groupPrefix string) []byte {
+	if b.json != nil {
+		return b.json.appendAttrsObject(buf, record, precomputedAttrs, precomputedGroups, groupPrefix, "")
+	}
+
+	start := len(buf)
+	if !b.text.withAttrsLast {
+		buf = append(buf, precomputedAttrs...)
+	}
+	if record.NumAttrs() > 0 {
+		var groupBuf [128]byte
+		pref := append(groupBuf[:0], groupPrefix...)
+
+		record.Attrs(func(attr slog.Attr) bool {
+			buf = b.text.appendAttr(buf, pref, attr)
+			return true
+		})
+	}
+	if b.text.withAttrsLast {
+		buf = append(buf, precomputedAttrs...)
+	}
+
+	// Every text attr starts with a space, the template places the attrs.
+	if len(buf) > start && buf[start] == ' ' {
+		buf = append(buf[:start], buf[start+1:]...)
+	}
+	return buf
+}
+
+func (b *templateBuilder) precomputeAttrs(buf []byte, precomputedGroups, groupPrefix string, attrs []slog.Attr) []byte {
+	if b.json != nil {
+		return b.json.precomputeAttrs(buf, precomputedGroups, groupPrefix, attrs)
+	}
+	return b.text.precomputeAttrs(buf, precomputedGroups, groupPrefix, attrs)
+}
+
+func (b *templateBuilder) groupPrefix(oldPrefix string, newPrefix string) string {
+	if b.json != nil {
+		return b.json.groupPrefix(oldPrefix, newPrefix)
+	}
+	return b.text.groupPrefix(oldPrefix, newPrefix)
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewTemplateHandler(&buf, "{time:RFC3339} [{level:pad}] {msg} {attrs}", &Config{BufferedOutput: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	record := slog.NewRecord(at, slog.LevelInfo, "user created", 0)
+	record.AddAttrs(slog.Int("id", 7), slog.String("name", "John Smith"))
+
+	logger := h.WithAttrs([]slog.Attr{slog.String("service", "api")}).WithGroup("req")
+	if err = logger.Handle(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.Handle(context.Background(), slog.NewRecord(at, slog.LevelWarn, "bare", 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `2026-01-02T03:04:05Z [INFO ] user created service=api req.id=7 req.name="John Smith"` + "\n" +
+		"2026-01-02T03:04:05Z [WARN ] bare\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if strings.Contains(buf.String(), "\x1b") {
+		t.Error("the template output contains escape codes")
+	}
+}
+
+func TestTemplateHandlerJSONAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewTemplateHandler(&buf, "{time:unix} {level:short} {msg:quote} {attrs:json}", &Config{BufferedOutput: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := slog.NewRecord(time.Unix(1700000000, 0), slog.LevelError, "request failed", 0)
+	record.AddAttrs(slog.Group("http", slog.Int("status", 502)), slog.String("path", "/v1"))
+	if err = h.WithAttrs([]slog.Attr{slog.String("service", "api")}).Handle(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := `1700000000 E "request failed" `
+	line := strings.TrimSuffix(buf.String(), "\n")
+	if !strings.HasPrefix(line, prefix) {
+		t.Fatalf("got %q, want the prefix %q", line, prefix)
+	}
+
+	var attrs map[string]any
+	if err = json.Unmarshal([]byte(strings.TrimPrefix(line, prefix)), &attrs); err != nil {
+		t.Fatalf("attrs are not a JSON object: %v: %s", err, line)
+	}
+	if attrs["service"] != "api" || attrs["path"] != "/v1" || attrs["http"].(map[string]any)["status"] != float64(502) {
+		t.Errorf("attrs = %v", attrs)
+	}
+}
+
+func TestTemplateHandlerInvalid(t *testing.T) {
+	for _, template := range []string{
+		"{time} {lvl}",
+		"{level:upper}",
+		"{attrs} {attrs:json}",
+		"{msg",
+		"{msg} }",
+	} {
+		if _, err := NewTemplateHandler(nil, template, nil); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%q: err = %v, want ErrInvalidConfig", template, err)
+		}
+	}
+
+	if _, err := NewTemplateHandler(nil, "{{literal}} {msg}", nil); err != nil {
+		t.Errorf("escaped braces: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// TextTheme is the color scheme of text output, see TextOptions.Theme.
+type TextTheme int
+
+const (
+	// bright ANSI colors
+	ThemeBright TextTheme = iota
+	// the 8 basic ANSI colors, for terminals with a limited palette
+	ThemeBasic
+	// no escape sequences, for files, CI logs and other readers without a terminal
+	ThemeNone
+)
+
+// themeNames are the names of the themes in config files.
+var themeNames = map[string]TextTheme{
+	"bright": ThemeBright,
+	"basic":  ThemeBasic,
+	"none":   ThemeNone,
+}
+
+// textColors are the escape sequences of a theme, levels are indexed like standardLevels.
+type textColors struct {
+	faint, reset string
+	levels       [len(standardLevels)]string
+}
+
+var themeColors = map[TextTheme]*textColors{
+	ThemeBright: {faint: faint, reset: reset, levels: [...]string{cyan, blue, green, yellow, red}},
+	ThemeBasic: {faint: faint, reset: reset, levels: [...]string{
+		"\u001b[36m", "\u001b[34m", "\u001b[32m", "\u001b[33m", "\u001b[31m",
+	}},
+	ThemeNone: {},
+}
+
+// colorsOf returns the escape sequences of the theme, ThemeBright for unknown themes.
+func colorsOf(theme TextTheme) *textColors {
+	if colors, ok := themeColors[theme]; ok {
+		return colors
+	}
+	return themeColors[ThemeBright]
+}
+
+// level returns the color of the level, custom levels take the color of their base level.
+func (c *textColors) level(level slog.Level) string {
+	base := levelBase(level)
+	for i, l := range standardLevels {
+		if l == base {
+			return c.levels[i]
+		}
+	}
+	return c.faint
+}
+
+// parseTheme parses a theme name of a config file, "" - ThemeBright.
+func parseTheme(name string) (TextTheme, error) {
+	if name == "" {
+		return ThemeBright, nil
+	}
+
+	theme, ok := themeNames[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown theme %q, want bright, basic or none", ErrInvalidConfig, name)
+	}
+	return theme, nil
+}
@@ -0,0 +1,23 @@
+package logger
+
+import (
+	"log/slog"
+	"time"
+)
+
+// timeLayoutValue is the value of a TimeLayout attr.
+type timeLayoutValue struct {
+	t      time.Time
+	layout string
+}
+
+// TimeLayout returns a time attr rendered with its own layout instead of Config.TimeFormat,
+// e.g. logger.TimeLayout("expires", t, time.RFC3339) for a value consumed by another system.
+func TimeLayout(key string, t time.Time, layout string) slog.Attr {
+	return slog.Any(key, timeLayoutValue{t: t, layout: layout})
+}
+
+// LogValue formats the time, so every handler renders it as the same string.
+func (v timeLayoutValue) LogValue() slog.Value {
+	return slog.StringValue(v.t.Format(v.layout))
+}
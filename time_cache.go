@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// timeCache reuses the formatted time of the last second for layouts without fractional seconds
+// (time.DateTime, time.Stamp, ...), formatting is the most expensive part of a simple record.
+type timeCache struct {
+	layout    string
+	cacheable bool
+	last      atomic.Pointer[cachedTime]
+}
+
+type cachedTime struct {
+	sec  int64
+	loc  *time.Location
+	text string
+}
+
+func newTimeCache(layout string) *timeCache {
+	// A layout that doesn't depend on the sub-second part formats both ends of a second the same.
+	start := time.Unix(0, 0).UTC()
+	end := start.Add(time.Second - 1)
+
+	return &timeCache{
+		layout:    layout,
+		cacheable: start.Format(layout) == end.Format(layout),
+	}
+}
+
+func (c *timeCache) appendTime(buf []byte, t time.Time) []byte {
+	if !c.cacheable {
+		return t.AppendFormat(buf, c.layout)
+	}
+
+	sec, loc := t.Unix(), t.Location()
+	if last := c.last.Load(); last != nil && last.sec == sec && last.loc == loc {
+		return append(buf, last.text...)
+	}
+
+	start := len(buf)
+	buf = t.AppendFormat(buf, c.layout)
+	c.last.Store(&cachedTime{sec: sec, loc: loc, text: string(buf[start:])})
+
+	return buf
+}
@@ -2,26 +2,14 @@ package logger
 
 import (
 	"log/slog"
-	"unicode/utf8"
+	"strconv"
+	"strings"
 )
 
-func levelColor(l slog.Level) string {
-	switch l {
-	case slog.LevelDebug:
-		return blue
-	case slog.LevelInfo:
-		return green
-	case slog.LevelWarn:
-		return yellow
-	case slog.LevelError:
-		return red
-	default:
-		return faint
-	}
-}
-
 func ParseLevel(level int) string {
 	switch slog.Level(level) {
+	case LevelTrace:
+		return "TRACE"
 	case slog.LevelDebug:
 		return "DEBUG"
 	case slog.LevelInfo:
@@ -37,6 +25,8 @@ func ParseLevel(level int) string {
 
 func levelBytes(level slog.Level) string {
 	switch level {
+	case LevelTrace:
+		return levelTraceLabel
 	case slog.LevelDebug:
 		return LevelDebug
 	case slog.LevelInfo:
@@ -50,103 +40,68 @@ func levelBytes(level slog.Level) string {
 	}
 }
 
-// safeSet - From stdlib.
-var safeSet = [utf8.RuneSelf]bool{
-	' ':      true,
-	'!':      true,
-	'"':      false,
-	'#':      true,
-	'$':      true,
-	'%':      true,
-	'&':      true,
-	'\'':     true,
-	'(':      true,
-	')':      true,
-	'*':      true,
-	'+':      true,
-	',':      true,
-	'-':      true,
-	'.':      true,
-	'/':      true,
-	'0':      true,
-	'1':      true,
-	'2':      true,
-	'3':      true,
-	'4':      true,
-	'5':      true,
-	'6':      true,
-	'7':      true,
-	'8':      true,
-	'9':      true,
-	':':      true,
-	';':      true,
-	'<':      true,
-	'=':      true,
-	'>':      true,
-	'?':      true,
-	'@':      true,
-	'A':      true,
-	'B':      true,
-	'C':      true,
-	'D':      true,
-	'E':      true,
-	'F':      true,
-	'G':      true,
-	'H':      true,
-	'I':      true,
-	'J':      true,
-	'K':      true,
-	'L':      true,
-	'M':      true,
-	'N':      true,
-	'O':      true,
-	'P':      true,
-	'Q':      true,
-	'R':      true,
-	'S':      true,
-	'T':      true,
-	'U':      true,
-	'V':      true,
-	'W':      true,
-	'X':      true,
-	'Y':      true,
-	'Z':      true,
-	'[':      true,
-	'\\':     false,
-	']':      true,
-	'^':      true,
-	'_':      true,
-	'`':      true,
-	'a':      true,
-	'b':      true,
-	'c':      true,
-	'd':      true,
-	'e':      true,
-	'f':      true,
-	'g':      true,
-	'h':      true,
-	'i':      true,
-	'j':      true,
-	'k':      true,
-	'l':      true,
-	'm':      true,
-	'n':      true,
-	'o':      true,
-	'p':      true,
-	'q':      true,
-	'r':      true,
-	's':      true,
-	't':      true,
-	'u':      true,
-	'v':      true,
-	'w':      true,
-	'x':      true,
-	'y':      true,
-	'z':      true,
-	'{':      true,
-	'|':      true,
-	'}':      true,
-	'~':      true,
-	'\u007f': true,
-	'\u001b': true,
+// standardLevels in ascending order.
+var standardLevels = [...]slog.Level{LevelTrace, slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+
+// levelBase returns the highest standard level not above level, TRACE for anything below it.
+func levelBase(level slog.Level) slog.Level {
+	base := LevelTrace
+	for _, l := range standardLevels {
+		if l <= level {
+			base = l
+		}
+	}
+	return base
+}
+
+// textLevelWidth is the width of the level column in text output.
+const textLevelWidth = 4
+
+// appendTextLevel appends the fixed-width level label of the text handler. Standard levels are cut
+// to 4 letters, custom ones are written as the base level initial and offset ("I+2 ", "T-2 ").
+// Offsets that don't fit fall back to the full name, so the level is never misreported.
+func appendTextLevel(buf []byte, level slog.Level) []byte {
+	base := levelBase(level)
+	if base == level {
+		return append(buf, levelBytes(level)[:textLevelWidth]...)
+	}
+
+	start := len(buf)
+	offset := int64(level - base)
+	if offset > -100 && offset < 100 {
+		buf = append(buf, levelBytes(base)[0])
+		if offset > 0 {
+			buf = append(buf, '+')
+		}
+		buf = strconv.AppendInt(buf, offset, 10)
+	} else {
+		buf = append(buf, levelName(level)...)
+	}
+
+	for len(buf)-start < textLevelWidth {
+		buf = append(buf, ' ')
+	}
+	return buf
+}
+
+// levelName returns the label of a standard level or slog's "INFO+2" form for custom levels.
+func levelName(level slog.Level) string {
+	switch level {
+	case LevelTrace, slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError:
+		return levelBytes(level)
+	default:
+		return level.String()
+	}
+}
+
+// ParseLevelName parses a level label written by the handlers ("TRACE", "DEBUG", "INFO", "WARN", "ERROR"),
+// slog offsets like "INFO+2" are supported as well.
+func ParseLevelName(name string) (slog.Level, error) {
+	if strings.EqualFold(name, levelTraceLabel) {
+		return LevelTrace, nil
+	}
+
+	var level slog.Level
+	err := level.UnmarshalText([]byte(name))
+	return level, err
 }
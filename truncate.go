@@ -0,0 +1,67 @@
+package logger
+
+import "unicode/utf8"
+
+// truncation marker appended to cut values.
+const truncatedMark = "…"
+
+// valueLimit cuts string values longer than max bytes (Config.MaxValueLen).
+type valueLimit struct {
+	max int
+	// hash appends a short hash of the full value, so identical long values can be grouped downstream.
+	hash bool
+}
+
+func newValueLimit(cfg *Config) *valueLimit {
+	if cfg.MaxValueLen <= 0 {
+		return nil
+	}
+	return &valueLimit{max: cfg.MaxValueLen, hash: cfg.TruncateHashSuffix}
+}
+
+// truncate returns s cut at a rune boundary with the marker and, if enabled, "#<hash>" of the full value.
+// Values that fit are returned as is, so only long values are copied.
+func (l *valueLimit) truncate(s string) string {
+	if l == nil || len(s) <= l.max {
+		return s
+	}
+
+	cut := l.max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+
+	buf := make([]byte, 0, cut+len(truncatedMark)+9)
+	buf = append(buf, s[:cut]...)
+	buf = append(buf, truncatedMark...)
+
+	if l.hash {
+		buf = append(buf, '#')
+		buf = appendHash32(buf, fnv32a(s))
+	}
+
+	return string(buf)
+}
+
+// fnv32a is the 32-bit FNV-1a hash of s, inlined to avoid allocating a hash.Hash per value.
+func fnv32a(s string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+
+	h := uint32(offset32)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= prime32
+	}
+	return h
+}
+
+// appendHash32 appends h as 8 lowercase hex digits.
+func appendHash32(buf []byte, h uint32) []byte {
+	for shift := 28; shift >= 0; shift -= 4 {
+		buf = append(buf, hex[h>>shift&0xf])
+	}
+	return buf
+}
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestValueLimit(t *testing.T) {
+	l := &valueLimit{max: 5}
+	if got := l.truncate("short"); got != "short" {
+		t.Errorf("truncate(short) = %q", got)
+	}
+	// "é" is two bytes, the cut moves back to the rune start.
+	if got := l.truncate("abcdéf"); got != "abcd…" {
+		t.Errorf("truncate(abcdéf) = %q", got)
+	}
+
+	l.hash = true
+	a, b, c := l.truncate("payload-1"), l.truncate("payload-1"), l.truncate("payload-2")
+	if a != b || a == c || !strings.HasPrefix(a, "paylo…#") || len(a) != len("paylo…#")+8 {
+		t.Errorf("hashed truncation: %q %q %q", a, b, c)
+	}
+}
+
+func TestMaxValueLen(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(NewJsonHandler(&buf, &Config{MaxValueLen: 4, TruncateHashSuffix: true}))
+	log.Info("msg", "body", strings.Repeat("x", 100), "ok", "tiny")
+
+	out := buf.String()
+	if !strings.Contains(out, `"body":"xxxx…#`) || !strings.Contains(out, `"ok":"tiny"`) {
+		t.Fatalf("output = %q", out)
+	}
+}
@@ -0,0 +1,138 @@
+package logger
+
+import (
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"unsafe"
+
+	"github.com/ttrtcixy/fast-slog-handler/internal/escape"
+)
+
+// textValue is a value the builders render from its text form without the Any/json.Marshal path.
+// MarshalText is used by other handlers.
+type textValue interface {
+	appendText(buf []byte) []byte
+	MarshalText() ([]byte, error)
+}
+
+type ipValue netip.Addr
+
+type urlValue struct{ u *url.URL }
+
+type uuidValue [16]byte
+
+// IP returns an attr rendered as the textual address ("10.0.0.1", "2001:db8::1").
+func IP(key string, addr netip.Addr) slog.Attr {
+	return slog.Any(key, ipValue(addr))
+}
+
+// URL returns an attr rendered as the URL string, a password in the user info is replaced with "xxxxx".
+func URL(key string, u *url.URL) slog.Attr {
+	return slog.Any(key, urlValue{u: u})
+}
+
+// UUID returns an attr rendered in the canonical 8-4-4-4-12 form.
+func UUID(key string, id [16]byte) slog.Attr {
+	return slog.Any(key, uuidValue(id))
+}
+
+func (v ipValue) appendText(buf []byte) []byte {
+	return netip.Addr(v).AppendTo(buf)
+}
+
+func (v urlValue) appendText(buf []byte) []byte {
+	u := v.u
+	if u == nil {
+		return buf
+	}
+
+	// Rare forms are left to url.URL.
+	if u.Opaque != "" || u.User != nil {
+		return append(buf, u.Redacted()...)
+	}
+
+	if u.Scheme != "" {
+		buf = append(buf, u.Scheme...)
+		buf = append(buf, ':')
+	}
+	if u.Scheme != "" || u.Host != "" {
+		buf = append(buf, "//"...)
+		buf = append(buf, u.Host...)
+	}
+
+	path := u.EscapedPath()
+	if path != "" && path[0] != '/' && u.Host != "" {
+		buf = append(buf, '/')
+	}
+	buf = append(buf, path...)
+
+	if u.ForceQuery || u.RawQuery != "" {
+		buf = append(buf, '?')
+		buf = append(buf, u.RawQuery...)
+	}
+	if u.Fragment != "" {
+		buf = append(buf, '#')
+		buf = append(buf, u.EscapedFragment()...)
+	}
+
+	return buf
+}
+
+func (v uuidValue) appendText(buf []byte) []byte {
+	for i, b := range v {
+		if i == 4 || i == 6 || i == 8 || i == 10 {
+			buf = append(buf, '-')
+		}
+		buf = append(buf, hex[b>>4], hex[b&0xF])
+	}
+	return buf
+}
+
+func (v ipValue) MarshalText() ([]byte, error)   { return v.appendText(nil), nil }
+func (v urlValue) MarshalText() ([]byte, error)  { return v.appendText(nil), nil }
+func (v uuidValue) MarshalText() ([]byte, error) { return v.appendText(nil), nil }
+
+// appendJSONText writes v as a JSON string, escaping only if the text needs it.
+func appendJSONText(buf []byte, v textValue) []byte {
+	buf = append(buf, '"')
+	mark := len(buf)
+	buf = v.appendText(buf)
+
+	switch {
+	case len(buf) == mark:
+		buf = append(buf, "!EMPTY_VALUE"...)
+	case !jsonSafe(buf[mark:]):
+		s := string(buf[mark:])
+		buf = escape.AppendJSON(buf[:mark], s)
+	}
+
+	return append(buf, '"')
+}
+
+// appendPlainText writes v like a text handler string value, quoted if needed.
+func appendPlainText(buf []byte, v textValue) []byte {
+	mark := len(buf)
+	buf = v.appendText(buf)
+
+	switch {
+	case len(buf) == mark:
+		buf = append(buf, "!EMPTY_VALUE"...)
+	case escape.NeedsQuoting(unsafe.String(&buf[mark], len(buf)-mark)):
+		s := string(buf[mark:])
+		buf = strconv.AppendQuote(buf[:mark], s)
+	}
+
+	return buf
+}
+
+// jsonSafe reports whether b can be written into a JSON string as is.
+func jsonSafe(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 || c == '"' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// unit of a unitValue, it picks the canonical number and the human form.
+type unit uint8
+
+const (
+	unitMillis unit = iota
+	unitBytes
+)
+
+// unitValue is a number with a unit: JSON and the batch formats get the number in the canonical unit
+// (milliseconds, bytes), text a short human form with the unit ("15.2ms", "1.4MiB").
+type unitValue struct {
+	unit unit
+	n    int64
+}
+
+// Millis returns an attr with the duration in milliseconds: "latency":15.2 in JSON, latency=15.2ms in text.
+func Millis(key string, d time.Duration) slog.Attr {
+	return slog.Any(key, unitValue{unit: unitMillis, n: int64(d)})
+}
+
+// Bytes64 returns an attr with a size in bytes: "size":1468006 in JSON, size=1.4MiB (IEC units) in text.
+func Bytes64(key string, n int64) slog.Attr {
+	return slog.Any(key, unitValue{unit: unitBytes, n: n})
+}
+
+// number returns the value in the canonical unit.
+func (v unitValue) number() slog.Value {
+	if v.unit == unitMillis {
+		return slog.Float64Value(float64(v.n) / float64(time.Millisecond))
+	}
+	return slog.Int64Value(v.n)
+}
+
+func (v unitValue) appendText(buf []byte) []byte {
+	if v.unit == unitMillis {
+		buf = appendOneDecimal(buf, float64(v.n)/float64(time.Millisecond))
+		return append(buf, "ms"...)
+	}
+
+	n := v.n
+	if n < 0 {
+		buf = append(buf, '-')
+		n = -n
+	}
+	if n < 1024 {
+		buf = strconv.AppendInt(buf, n, 10)
+		return append(buf, 'B')
+	}
+
+	size := float64(n) / 1024
+	i := 0
+	for size >= 1024 && i < len(byteUnits)-1 {
+		size /= 1024
+		i++
+	}
+	buf = appendOneDecimal(buf, size)
+	return append(buf, byteUnits[i]...)
+}
+
+var byteUnits = [...]string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// appendOneDecimal appends f with one decimal, "15" instead of "15.0".
+func appendOneDecimal(buf []byte, f float64) []byte {
+	mark := len(buf)
+	buf = strconv.AppendFloat(buf, f, 'f', 1, 64)
+	if n := len(buf); n-mark > 2 && buf[n-2] == '.' && buf[n-1] == '0' {
+		buf = buf[:n-2]
+	}
+	return buf
+}
+
+func (v unitValue) MarshalText() ([]byte, error) { return v.appendText(nil), nil }
+
+// MarshalJSON writes the canonical number for handlers of other packages.
+func (v unitValue) MarshalJSON() ([]byte, error) {
+	if v.unit == unitMillis {
+		return strconv.AppendFloat(nil, v.number().Float64(), 'f', -1, 64), nil
+	}
+	return strconv.AppendInt(nil, v.n, 10), nil
+}
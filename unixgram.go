@@ -0,0 +1,102 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync/atomic"
+	"syscall"
+)
+
+// DatagramStats are the counters of a DatagramWriter in records.
+type DatagramStats struct {
+	// records sent as datagrams
+	Sent uint64
+	// records dropped because the socket buffer was full (EAGAIN, ENOBUFS)
+	Dropped uint64
+}
+
+// DatagramWriter sends every record as one datagram to a unix datagram socket
+// (local collectors, the syslog compatibility socket of journald at /run/systemd/journal/syslog).
+// Writes never block: when the receiver doesn't keep up the record is dropped and counted.
+// Writes of a buffered handler may hold several records, they are split at newlines.
+type DatagramWriter struct {
+	conn *net.UnixConn
+	raw  syscall.RawConn
+
+	sent    atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// NewUnixgramWriter connects to the unix datagram socket at path.
+func NewUnixgramWriter(path string) (*DatagramWriter, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return &DatagramWriter{conn: conn, raw: raw}, nil
+}
+
+// Write sends every line of p as a datagram without the trailing newline, full socket buffers drop the line.
+func (d *DatagramWriter) Write(p []byte) (int, error) {
+	for start := 0; start < len(p); {
+		end := bytes.IndexByte(p[start:], '\n')
+		if end < 0 {
+			end = len(p)
+		} else {
+			end += start
+		}
+
+		if end > start {
+			if err := d.send(p[start:end]); err != nil {
+				return start, err
+			}
+		}
+		start = end + 1
+	}
+
+	return len(p), nil
+}
+
+// send makes a single non-blocking attempt to send the datagram.
+func (d *DatagramWriter) send(datagram []byte) error {
+	var sendErr error
+	err := d.raw.Write(func(fd uintptr) bool {
+		_, sendErr = syscall.Write(int(fd), datagram)
+		// Done even on EAGAIN, waiting for the socket would block the handler.
+		return true
+	})
+	if err == nil {
+		err = sendErr
+	}
+
+	switch {
+	case err == nil:
+		d.sent.Add(1)
+	case errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.ENOBUFS):
+		d.dropped.Add(1)
+	default:
+		return err
+	}
+
+	return nil
+}
+
+// Stats returns the current counters.
+func (d *DatagramWriter) Stats() DatagramStats {
+	return DatagramStats{Sent: d.sent.Load(), Dropped: d.dropped.Load()}
+}
+
+// Close closes the socket.
+func (d *DatagramWriter) Close() error {
+	return d.conn.Close()
+}
@@ -0,0 +1,26 @@
+//go:build windows || plan9
+
+package logger
+
+import "fmt"
+
+// DatagramStats are the counters of a DatagramWriter in records.
+type DatagramStats struct {
+	Sent    uint64
+	Dropped uint64
+}
+
+// DatagramWriter is not supported on this platform.
+type DatagramWriter struct{}
+
+func NewUnixgramWriter(_ string) (*DatagramWriter, error) {
+	return nil, fmt.Errorf("%w: unix datagram sockets are not supported on this platform", ErrInvalidConfig)
+}
+
+func (d *DatagramWriter) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("%w: unix datagram sockets are not supported on this platform", ErrInvalidConfig)
+}
+
+func (d *DatagramWriter) Stats() DatagramStats { return DatagramStats{} }
+
+func (d *DatagramWriter) Close() error { return nil }
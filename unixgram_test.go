@@ -0,0 +1,52 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"log/slog"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnixgramWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer server.Close()
+
+	w, err := NewUnixgramWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	h := NewJsonHandler(w, &Config{})
+	slog.New(h).Info("a")
+	// Several records in one write are sent as separate datagrams.
+	_, _ = w.Write([]byte("b\nc\n"))
+
+	buf := make([]byte, 1024)
+	var got []string
+	for range 3 {
+		n, err := server.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(buf[:n]))
+	}
+
+	if got[0][len(got[0])-1] != '}' || got[1] != "b" || got[2] != "c" {
+		t.Errorf("datagrams %q", got)
+	}
+
+	// Nobody reads the socket, once its buffer is full records are dropped instead of blocking.
+	for range 10000 {
+		_, _ = w.Write([]byte("x"))
+	}
+	if stats := w.Stats(); stats.Sent < 3 || stats.Dropped == 0 {
+		t.Errorf("stats %+v", stats)
+	}
+}
@@ -0,0 +1,533 @@
+package logger
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultWALSegmentSize   = 16 << 20
+	defaultWALSyncInterval  = time.Second
+	defaultWALShipBytes     = 1 << 20
+	defaultWALRetryInterval = time.Second
+
+	walSegmentExt = ".wal"
+	walCursorName = "cursor"
+	// walFrameHeader is the length and the CRC-32 of the data of a frame.
+	walFrameHeader = 8
+)
+
+// WALOptions controls the segments and the shipper of a WALWriter.
+type WALOptions struct {
+	// size of a segment file that starts the next one, 0 - 16 MiB
+	SegmentSize int64
+	// interval of fsync of the current segment, writes since the last one may be lost on a crash, 0 - 1s
+	SyncInterval time.Duration
+	// max size of the records passed to one Ship call, 0 - 1 MiB
+	MaxShipBytes int
+	// delay before a failed Ship is retried, 0 - 1s
+	RetryInterval time.Duration
+}
+
+// WALStats are the counters of a WALWriter.
+type WALStats struct {
+	// writes appended to the log
+	Appended uint64
+	// writes delivered by Ship
+	Shipped uint64
+	// failed Ship calls
+	ShipErrors uint64
+	// bytes of the writes waiting for delivery
+	Pending int64
+	// segment files on disk
+	Segments int
+	// damaged frames found in the segments, the rest of their segment is skipped
+	Corrupted uint64
+}
+
+// ShipFunc delivers records to the network sink, records is a copy of one or more whole writes in order.
+// A nil error confirms the delivery, the records are then trimmed from the log.
+type ShipFunc func(ctx context.Context, records []byte) error
+
+// WALWriter is a write-ahead log for network sinks: every write is appended to a local segment file
+// in dir before it returns, and a background shipper passes the writes to ship in order, trimming segments
+// once they are delivered. Delivery is at least once: a crash after Ship and before the cursor is saved
+// ships the records again on the next start. The data loss on crashes is bounded by SyncInterval.
+//
+// Use it as the handler writer with BufferedOutput disabled, so Handle returns after the record is logged.
+type WALWriter struct {
+	dir  string
+	ship ShipFunc
+	opts WALOptions
+
+	mu      sync.Mutex
+	file    *os.File
+	seq     uint64
+	size    int64
+	dirty   bool
+	frame   []byte
+	closed  bool
+	notify  chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+
+	// cursor is the position of the next undelivered frame, only used by the shipper.
+	cursor    walPosition
+	shipBuf   []byte
+	appended  atomic.Uint64
+	shipped   atomic.Uint64
+	errors    atomic.Uint64
+	pending   atomic.Int64
+	corrupted atomic.Uint64
+}
+
+type walPosition struct {
+	seq    uint64
+	offset int64
+}
+
+// NewWALWriter opens the log in dir, creating it if needed. Writes left by a previous run are shipped first,
+// a frame torn by a crash at the end of the last segment is cut off.
+func NewWALWriter(dir string, ship ShipFunc, opts WALOptions) (*WALWriter, error) {
+	if ship == nil {
+		return nil, fmt.Errorf("%w: ship func is nil", ErrInvalidConfig)
+	}
+	if opts.SegmentSize < 0 || opts.SyncInterval < 0 || opts.MaxShipBytes < 0 || opts.RetryInterval < 0 {
+		return nil, fmt.Errorf("%w: WALOptions must not be negative", ErrInvalidConfig)
+	}
+
+	opts.SegmentSize = cmp.Or(opts.SegmentSize, defaultWALSegmentSize)
+	opts.SyncInterval = cmp.Or(opts.SyncInterval, defaultWALSyncInterval)
+	opts.MaxShipBytes = cmp.Or(opts.MaxShipBytes, defaultWALShipBytes)
+	opts.RetryInterval = cmp.Or(opts.RetryInterval, defaultWALRetryInterval)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	w := &WALWriter{
+		dir:     dir,
+		ship:    ship,
+		opts:    opts,
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	if err := w.recover(); err != nil {
+		return nil, err
+	}
+	if err := w.openSegment(w.seq + 1); err != nil {
+		return nil, err
+	}
+
+	go w.shipper()
+
+	return w, nil
+}
+
+// recover loads the cursor, cuts a torn frame of the last segment and counts the pending bytes.
+func (w *WALWriter) recover() error {
+	segments, err := w.segments()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filepath.Join(w.dir, walCursorName))
+	switch {
+	case err == nil && len(data) == 16:
+		w.cursor = walPosition{seq: binary.LittleEndian.Uint64(data), offset: int64(binary.LittleEndian.Uint64(data[8:]))}
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	for i, seq := range segments {
+		path := w.segmentPath(seq)
+		if seq < w.cursor.seq {
+			// Delivered before the crash, the trim didn't happen.
+			_ = os.Remove(path)
+			continue
+		}
+
+		size, err := validSize(path)
+		if err != nil {
+			return err
+		}
+		if i == len(segments)-1 {
+			if err = os.Truncate(path, size); err != nil {
+				return err
+			}
+		}
+
+		if seq == w.cursor.seq {
+			size -= min(w.cursor.offset, size)
+		}
+		w.pending.Add(size)
+		w.seq = seq
+	}
+
+	if w.cursor.seq == 0 && len(segments) > 0 {
+		w.cursor.seq = segments[0]
+	}
+	// The next segment must come after the cursor even if the delivered segments were removed.
+	w.seq = max(w.seq, w.cursor.seq)
+	return nil
+}
+
+// validSize returns the size of the whole intact frames at the start of the segment.
+func validSize(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for len(data) >= walFrameHeader {
+		n := int(binary.LittleEndian.Uint32(data))
+		if len(data)-walFrameHeader < n || crc32.ChecksumIEEE(data[walFrameHeader:walFrameHeader+n]) != binary.LittleEndian.Uint32(data[4:]) {
+			break
+		}
+		size += int64(walFrameHeader + n)
+		data = data[walFrameHeader+n:]
+	}
+	return size, nil
+}
+
+// segments returns the sequence numbers of the segment files in ascending order.
+func (w *WALWriter) segments() ([]uint64, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var seqs []uint64
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), walSegmentExt)
+		if !ok {
+			continue
+		}
+		if seq, err := strconv.ParseUint(name, 10, 64); err == nil {
+			seqs = append(seqs, seq)
+		}
+	}
+	slices.Sort(seqs)
+	return seqs, nil
+}
+
+func (w *WALWriter) segmentPath(seq uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%020d%s", seq, walSegmentExt))
+}
+
+func (w *WALWriter) openSegment(seq uint64) error {
+	file, err := os.OpenFile(w.segmentPath(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	w.file = file
+	w.seq = seq
+	w.size = 0
+	if w.cursor.seq == 0 {
+		w.cursor.seq = seq
+	}
+	return nil
+}
+
+// Write appends p to the log as one frame, it's shipped as a whole.
+func (w *WALWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	if w.size > 0 && w.size+int64(walFrameHeader+len(p)) > w.opts.SegmentSize {
+		if err := w.sealSegment(); err != nil {
+			return 0, err
+		}
+	}
+
+	frame := binary.LittleEndian.AppendUint32(w.frame[:0], uint32(len(p)))
+	frame = binary.LittleEndian.AppendUint32(frame, crc32.ChecksumIEEE(p))
+	frame = append(frame, p...)
+	w.frame = frame
+
+	n, err := w.file.Write(frame)
+	w.size += int64(n)
+	w.pending.Add(int64(n))
+	if err != nil {
+		// Later frames go to the next segment, the shipper skips the rest of this one at the partial frame.
+		if n > 0 {
+			_ = w.sealSegment()
+		}
+		return 0, err
+	}
+
+	w.dirty = true
+	w.appended.Add(1)
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// sealSegment syncs and closes the current segment and starts the next one.
+func (w *WALWriter) sealSegment() error {
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.dirty = false
+	return w.openSegment(w.seq + 1)
+}
+
+// Sync commits the current segment to stable storage.
+func (w *WALWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.sync()
+}
+
+func (w *WALWriter) sync() error {
+	if !w.dirty {
+		return nil
+	}
+	w.dirty = false
+	return w.file.Sync()
+}
+
+// shipper syncs the segment every SyncInterval and ships new writes until Close.
+func (w *WALWriter) shipper() {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(w.opts.SyncInterval)
+	defer ticker.Stop()
+
+	// retry is set while a failed Ship waits for RetryInterval, new writes don't cut the wait short.
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			_ = w.sync()
+			w.mu.Unlock()
+			if retry != nil {
+				continue
+			}
+		case <-w.notify:
+			if retry != nil {
+				continue
+			}
+		case <-retry:
+			retry = nil
+		}
+
+		if err := w.shipAll(context.Background()); err != nil {
+			retry = time.After(w.opts.RetryInterval)
+		}
+	}
+}
+
+// shipAll ships the frames after the cursor until all are delivered or Ship fails.
+func (w *WALWriter) shipAll(ctx context.Context) error {
+	for {
+		delivered, err := w.shipNext(ctx)
+		if err != nil || !delivered {
+			return err
+		}
+	}
+}
+
+// shipNext ships the frames after the cursor up to MaxShipBytes, it reports false if there were none.
+func (w *WALWriter) shipNext(ctx context.Context) (bool, error) {
+	w.mu.Lock()
+	current, size := w.seq, w.size
+	w.mu.Unlock()
+
+	for {
+		if w.cursor.seq > current {
+			return false, nil
+		}
+
+		limit := size
+		if w.cursor.seq < current {
+			limit = -1
+		}
+
+		records, end, frames, damaged, err := w.readFrames(w.cursor, limit)
+		if err != nil {
+			return false, err
+		}
+
+		if frames == 0 {
+			if w.cursor.seq == current {
+				return false, nil
+			}
+
+			// A sealed segment is delivered or its rest is damaged, move to the next one.
+			if damaged {
+				w.corrupted.Add(1)
+			}
+			if info, err := os.Stat(w.segmentPath(w.cursor.seq)); err == nil {
+				w.pending.Add(-max(info.Size()-w.cursor.offset, 0))
+			}
+			if err = w.advance(walPosition{seq: w.cursor.seq + 1}); err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		if err = w.ship(ctx, records); err != nil {
+			w.errors.Add(1)
+			return false, err
+		}
+
+		w.shipped.Add(uint64(frames))
+		w.pending.Add(-(end - w.cursor.offset))
+		return true, w.advance(walPosition{seq: w.cursor.seq, offset: end})
+	}
+}
+
+// readFrames reads whole frames from pos up to MaxShipBytes of data, limit is the written size
+// of the current segment (-1 for sealed ones). It returns the data, the offset after the last frame
+// and the count of frames, damaged is set if the frames end at a damaged one.
+func (w *WALWriter) readFrames(pos walPosition, limit int64) (records []byte, end int64, frames int, damaged bool, err error) {
+	file, err := os.Open(w.segmentPath(pos.seq))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, pos.offset, 0, false, nil
+	}
+	if err != nil {
+		return nil, 0, 0, false, err
+	}
+	defer file.Close()
+
+	var r io.Reader = io.NewSectionReader(file, pos.offset, 1<<62)
+	if limit >= 0 {
+		r = io.NewSectionReader(file, pos.offset, limit-pos.offset)
+	}
+
+	records = w.shipBuf[:0]
+	end = pos.offset
+	var header [walFrameHeader]byte
+
+	for len(records) < w.opts.MaxShipBytes {
+		if _, err = io.ReadFull(r, header[:]); err != nil {
+			// Only a sealed segment can end with a torn frame, the current one is read up to its written frames.
+			damaged = err == io.ErrUnexpectedEOF
+			break
+		}
+
+		n := int(binary.LittleEndian.Uint32(header[:]))
+		if frames > 0 && len(records)+n > w.opts.MaxShipBytes {
+			break
+		}
+
+		start := len(records)
+		records = slices.Grow(records, n)[:start+n]
+		if _, err = io.ReadFull(r, records[start:]); err != nil || crc32.ChecksumIEEE(records[start:]) != binary.LittleEndian.Uint32(header[4:]) {
+			records = records[:start]
+			damaged = true
+			break
+		}
+
+		end += int64(walFrameHeader + n)
+		frames++
+	}
+
+	w.shipBuf = records
+	return records, end, frames, damaged, nil
+}
+
+// advance saves the cursor and removes the delivered segments before it.
+func (w *WALWriter) advance(pos walPosition) error {
+	var data [16]byte
+	binary.LittleEndian.PutUint64(data[:], pos.seq)
+	binary.LittleEndian.PutUint64(data[8:], uint64(pos.offset))
+
+	tmp := filepath.Join(w.dir, walCursorName+".tmp")
+	if err := os.WriteFile(tmp, data[:], 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(w.dir, walCursorName)); err != nil {
+		return err
+	}
+
+	for seq := w.cursor.seq; seq < pos.seq; seq++ {
+		if err := os.Remove(w.segmentPath(seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	w.cursor = pos
+	return nil
+}
+
+// Stats returns the current counters.
+func (w *WALWriter) Stats() WALStats {
+	segments, _ := w.segments()
+
+	return WALStats{
+		Appended:   w.appended.Load(),
+		Shipped:    w.shipped.Load(),
+		ShipErrors: w.errors.Load(),
+		Pending:    w.pending.Load(),
+		Segments:   len(segments),
+		Corrupted:  w.corrupted.Load(),
+	}
+}
+
+// Close stops accepting writes, syncs the segment and ships the pending writes until ctx is done.
+// Undelivered writes stay in dir and are shipped by the next NewWALWriter.
+func (w *WALWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return os.ErrClosed
+	}
+	w.closed = true
+	err := w.sync()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.mu.Unlock()
+
+	close(w.done)
+	select {
+	case <-w.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if err != nil {
+		return err
+	}
+	return w.shipAll(ctx)
+}
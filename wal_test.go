@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// walSink keeps the shipped records and fails while fail is set.
+type walSink struct {
+	mu      sync.Mutex
+	records bytes.Buffer
+	fail    bool
+	calls   int
+}
+
+func (s *walSink) ship(_ context.Context, records []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.fail {
+		return errors.New("sink is down")
+	}
+	s.records.Write(records)
+	return nil
+}
+
+func (s *walSink) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records.String()
+}
+
+func TestWALWriterShipsAndTrims(t *testing.T) {
+	dir := t.TempDir()
+	sink := &walSink{}
+
+	w, err := NewWALWriter(dir, sink.ship, WALOptions{SegmentSize: 64, RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewJsonHandler(w, &Config{BufferedOutput: false})
+	for range 5 {
+		if err = h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "shipped", 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := bytes.Count([]byte(sink.String()), []byte("\n")); got != 5 {
+		t.Errorf("shipped %d records, want 5: %s", got, sink.String())
+	}
+
+	stats := w.Stats()
+	if stats.Appended != 5 || stats.Shipped != 5 || stats.Pending != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+	// Every record fills a segment, only the last one is kept.
+	if stats.Segments != 1 {
+		t.Errorf("%d segments left, want 1", stats.Segments)
+	}
+}
+
+func TestWALWriterRecovers(t *testing.T) {
+	dir := t.TempDir()
+	down := &walSink{fail: true}
+
+	w, err := NewWALWriter(dir, down.ship, WALOptions{RetryInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"a\n", "b\n"} {
+		if _, err = w.Write([]byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err = w.Close(ctx); err == nil {
+		t.Fatal("Close reported the delivery to a failing sink")
+	}
+
+	// A crash in the middle of a write leaves a torn frame.
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+walSegmentExt))
+	f, err := os.OpenFile(segments[len(segments)-1], os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte{9, 0, 0, 0, 1})
+	_ = f.Close()
+
+	up := &walSink{}
+	w, err = NewWALWriter(dir, up.ship, WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write([]byte("c\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := up.String(); got != "a\nb\nc\n" {
+		t.Errorf("shipped %q, want the old records before the new one", got)
+	}
+	if stats := w.Stats(); stats.Pending != 0 || stats.Corrupted != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestWALWriterRetries(t *testing.T) {
+	sink := &walSink{fail: true}
+
+	w, err := NewWALWriter(t.TempDir(), sink.ship, WALOptions{RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close(context.Background())
+
+	if _, err = w.Write([]byte("a\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for w.Stats().ShipErrors < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("the failed ship was not retried")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	sink.mu.Lock()
+	sink.fail = false
+	sink.mu.Unlock()
+
+	for w.Stats().Shipped != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("the record was not shipped after the sink recovered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := sink.String(); got != "a\n" {
+		t.Errorf("shipped %q", got)
+	}
+}
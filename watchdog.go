@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// default number of consecutive slow writes before switching to the fallback writer.
+	defaultSlowWriteLimit = 3
+
+	slowWriterMsg = "logger output is slow, switched to fallback writer"
+)
+
+// watchdogWriter measures the latency of every write to the underlying writer and replaces it with
+// the fallback writer after limit consecutive writes slower than threshold.
+// It's used under the output lock, so w and slow don't need synchronization.
+type watchdogWriter struct {
+	w        io.Writer
+	fallback io.Writer
+
+	threshold time.Duration
+	limit     int
+	// number of consecutive slow writes.
+	slow int
+	// switched is set once w is replaced by the fallback, the writers are not compared:
+	// == panics on writers of the same uncomparable type (e.g. a func implementing io.Writer).
+	switched bool
+
+	// latency of the write that caused the switch, read after pending is set.
+	latency atomic.Int64
+	// pending is set by the switch and cleared once the handler reported it.
+	pending atomic.Bool
+}
+
+func newWatchdogWriter(w, fallback io.Writer, threshold time.Duration, limit int) *watchdogWriter {
+	if limit <= 0 {
+		limit = defaultSlowWriteLimit
+	}
+
+	return &watchdogWriter{
+		w:         w,
+		fallback:  fallback,
+		threshold: threshold,
+		limit:     limit,
+	}
+}
+
+func (w *watchdogWriter) Write(p []byte) (int, error) {
+	if w.switched {
+		return w.w.Write(p)
+	}
+
+	start := time.Now()
+	n, err := w.w.Write(p)
+
+	latency := time.Since(start)
+	if latency < w.threshold {
+		w.slow = 0
+		return n, err
+	}
+
+	w.slow++
+	if w.slow >= w.limit {
+		w.w = w.fallback
+		w.switched = true
+		w.latency.Store(int64(latency))
+		w.pending.Store(true)
+	}
+
+	return n, err
+}
+
+// checkWatchdog writes a self-diagnostic record to the output once its writer was switched to the fallback.
+func (h *Handler) checkWatchdog(o *output) {
+	if o.watchdog == nil || !o.watchdog.pending.CompareAndSwap(true, false) {
+		return
+	}
+
+	latency := time.Duration(o.watchdog.latency.Load())
+	h.shared.diagnose(Diagnostic{Kind: DiagnosticSlowWriter, Latency: latency})
+
+	record := slog.NewRecord(time.Now(), slog.LevelWarn, slowWriterMsg, 0)
+	record.AddAttrs(
+		slog.Duration("latency", latency),
+		slog.Duration("threshold", o.watchdog.threshold),
+		slog.Int("slow_writes", o.watchdog.limit),
+	)
+
+	_ = h.writeTo(o, nil, record)
+}